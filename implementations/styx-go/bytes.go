@@ -0,0 +1,23 @@
+package styx
+
+import "unsafe"
+
+// ParseBytes parses a Styx document from src without copying it into a
+// string. The returned document refers to src for scalar and comment text,
+// so src must not be modified while the document is in use.
+func ParseBytes(src []byte) (*Document, error) {
+	return ParseWithOptions(bytesToString(src), ParseOptions{})
+}
+
+// ParseBytesWithOptions is like ParseBytes but uses the given options.
+func ParseBytesWithOptions(src []byte, opts ParseOptions) (*Document, error) {
+	return ParseWithOptions(bytesToString(src), opts)
+}
+
+// bytesToString returns a string sharing b's memory.
+func bytesToString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
@@ -0,0 +1,96 @@
+package styx
+
+import (
+	"slices"
+	"sort"
+)
+
+// KeyOrder selects how Canonical orders the entries of an object.
+type KeyOrder int
+
+const (
+	// OrderSource keeps entries in the order they were written.
+	OrderSource KeyOrder = iota
+	// OrderSorted sorts entries by key. Document-level dotted keys sort
+	// by their whole path, key by key, so entries sharing a prefix end up
+	// together. Entries without a key keep their place.
+	OrderSorted
+)
+
+func (o KeyOrder) String() string {
+	switch o {
+	case OrderSource:
+		return "source"
+	case OrderSorted:
+		return "sorted"
+	default:
+		return "unknown"
+	}
+}
+
+// CanonicalOptions configures Canonical.
+type CanonicalOptions struct {
+	// Order orders the entries of every object and of the document.
+	Order KeyOrder
+	// First lists keys that come before the others of their object, in
+	// the order given, whatever Order says; for example the name and
+	// version of a package. A document-level dotted key counts as its
+	// first key.
+	First []string
+}
+
+// Canonical writes the document in canonical form, so that documents that
+// differ only in style are written the same: as Format writes it, with
+// every scalar bare if it can be and quoted otherwise, one entry per line
+// and indentation of four spaces, and with entries ordered as opts says.
+// Comments move with their entries. The document is not modified.
+func Canonical(doc *Document, opts CanonicalOptions) []byte {
+	rank := make(map[string]int, len(opts.First))
+	for i, key := range opts.First {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i
+		}
+	}
+	w := formatWriter{order: func(entries []*Entry, documentLevel bool) []*Entry {
+		if len(rank) == 0 && opts.Order == OrderSource {
+			return entries
+		}
+		sortKeys := make(map[*Entry][]string, len(entries))
+		var keyed []*Entry
+		for _, e := range entries {
+			if e.Key.Implicit {
+				continue
+			}
+			keyed = append(keyed, e)
+			sortKeys[e] = []string{e.KeyText()}
+			if documentLevel && len(e.path) > 1 {
+				sortKeys[e] = e.path
+			}
+		}
+		ranked := func(e *Entry) int {
+			if r, ok := rank[e.KeyText()]; ok {
+				return r
+			}
+			return len(opts.First)
+		}
+		sort.SliceStable(keyed, func(i, j int) bool {
+			a, b := keyed[i], keyed[j]
+			if ra, rb := ranked(a), ranked(b); ra != rb {
+				return ra < rb
+			}
+			return opts.Order == OrderSorted && slices.Compare(sortKeys[a], sortKeys[b]) < 0
+		})
+		// Entries with an implicit key, such as an explicit root object
+		// after a tag, keep their place; the others fill the rest in order.
+		ordered := make([]*Entry, len(entries))
+		for i, e := range entries {
+			if e.Key.Implicit {
+				ordered[i] = e
+				continue
+			}
+			ordered[i], keyed = keyed[0], keyed[1:]
+		}
+		return ordered
+	}}
+	return w.document(doc)
+}
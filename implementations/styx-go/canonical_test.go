@@ -0,0 +1,48 @@
+package styx
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	source := "// the port\nport 80\nname 'x'\nserver.tls.cert r#\"c.pem\"#\nserver.host h\nzone \"eu\"\nopts {b 1, a \"two words\"}\n"
+	tests := []struct {
+		name string
+		opts CanonicalOptions
+		want string
+	}{
+		{"source order", CanonicalOptions{}, "// the port\nport 80\nname 'x'\nserver.tls.cert c.pem\nserver.host h\nzone eu\nopts {\n    b 1\n    a \"two words\"\n}\n"},
+		{"sorted", CanonicalOptions{Order: OrderSorted}, "name 'x'\nopts {\n    a \"two words\"\n    b 1\n}\n// the port\nport 80\nserver.host h\nserver.tls.cert c.pem\nzone eu\n"},
+		{"first", CanonicalOptions{Order: OrderSorted, First: []string{"zone", "b", "server"}}, "zone eu\nserver.host h\nserver.tls.cert c.pem\nname 'x'\nopts {\n    b 1\n    a \"two words\"\n}\n// the port\nport 80\n"},
+		{"first in source order", CanonicalOptions{First: []string{"opts"}}, "opts {\n    b 1\n    a \"two words\"\n}\n// the port\nport 80\nname 'x'\nserver.tls.cert c.pem\nserver.host h\nzone eu\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, source)
+			if got := string(Canonical(doc, tt.opts)); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+			if got := string(Format(doc)); got != tests[0].want {
+				t.Errorf("document modified; Format gives\n%s", got)
+			}
+			// Canonical output is a fixed point.
+			if again := string(Canonical(mustParse(t, tt.want), tt.opts)); again != tt.want {
+				t.Errorf("not idempotent:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestCanonicalSortedPaths(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{"\"a.y\" 3\na.z 2\na.x 1\n", "a.x 1\na.z 2\n\"a.y\" 3\n"},
+		{"obj a{}", "obj a\n{}\n"},
+	}
+	for _, tt := range tests {
+		got := string(Canonical(mustParse(t, tt.source), CanonicalOptions{Order: OrderSorted}))
+		if got != tt.want {
+			t.Errorf("%q: got\n%s\nwant\n%s", tt.source, got, tt.want)
+		}
+		if _, err := Parse(got); err != nil {
+			t.Errorf("%q: canonical form does not parse: %v", tt.source, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+// errorSexp matches the s-expression of a parse error: its span and its
+// quoted message.
+var errorSexp = regexp.MustCompile(`^\(error \[(-?\d+), (-?\d+)\] ("(?:[^"\\]|\\.)*")\)`)
+
+// sexpError returns the error an s-expression output reports, as a
+// diagnostic, or nil if the output is a document.
+func sexpError(output string) *styx.Diagnostic {
+	m := errorSexp.FindStringSubmatch(strings.TrimSpace(output))
+	if m == nil {
+		return nil
+	}
+	start, _ := strconv.Atoi(m[1])
+	end, _ := strconv.Atoi(m[2])
+	message, err := strconv.Unquote(m[3])
+	if err != nil {
+		message = m[3]
+	}
+	// The message repeats the span, which the snippet shows.
+	if rest, ok := strings.CutPrefix(message, "parse error at "); ok {
+		if _, after, ok := strings.Cut(rest, ": "); ok {
+			message = after
+		}
+	}
+	return &styx.Diagnostic{Severity: styx.SeverityError, Message: message, Span: styx.Span{Start: start, End: end}}
+}
+
+// annotateErrors renders the source of a failing file with carets under
+// the error spans of its expected and actual output, so a divergence can
+// be read without opening the file. It returns "" if neither output is an
+// error.
+func (r *fileResult) annotateErrors(color bool) string {
+	expected, actual := sexpError(r.expected), sexpError(r.output)
+	if expected == nil && actual == nil {
+		return ""
+	}
+	opts := styx.RenderOptions{Color: color, Context: 1, Filename: r.relative}
+	var sb strings.Builder
+	if expected != nil {
+		sb.WriteString("expected ")
+		sb.WriteString(styx.RenderDiagnostics(r.source, []*styx.Diagnostic{expected}, opts))
+	} else {
+		sb.WriteString("expected: no error\n")
+	}
+	if actual != nil {
+		sb.WriteString("actual ")
+		sb.WriteString(styx.RenderDiagnostics(r.source, []*styx.Diagnostic{actual}, opts))
+	} else {
+		sb.WriteString("actual: no error\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+// benchResult is the cost of parsing one file runs times.
+type benchResult struct {
+	relative string
+	size     int
+	runs     int
+	elapsed  time.Duration
+	// allocs and bytes are the heap allocations made over all runs.
+	allocs, bytes uint64
+}
+
+// benchFile parses the file at path runs times, measuring the time taken
+// and the memory allocated. Reading the file is not measured.
+func benchFile(path string, src *sources, runs int) (*benchResult, error) {
+	content, err := src.read(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &benchResult{relative: src.relative(path), size: len(content), runs: runs}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		styx.ParseBytes(content)
+	}
+	b.elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+	b.allocs = after.Mallocs - before.Mallocs
+	b.bytes = after.TotalAlloc - before.TotalAlloc
+	return b, nil
+}
+
+// writeBench prints a table of each file's size and its time, throughput
+// and allocations per parse, and a last row totalling the corpus: the
+// cost of parsing every file once.
+func writeBench(w io.Writer, results []*benchResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "bytes\tns/op\tMB/s\tallocs/op\tB/op\t file")
+	var size int
+	var ns, allocs, bytes float64
+	for _, b := range results {
+		writeBenchRow(tw, b.relative, b.size, b.perRun(b.elapsed.Nanoseconds()), b.perRun(int64(b.allocs)), b.perRun(int64(b.bytes)))
+		size += b.size
+		ns += b.perRun(b.elapsed.Nanoseconds())
+		allocs += b.perRun(int64(b.allocs))
+		bytes += b.perRun(int64(b.bytes))
+	}
+	writeBenchRow(tw, fmt.Sprintf("total (%d files)", len(results)), size, ns, allocs, bytes)
+	return tw.Flush()
+}
+
+func writeBenchRow(w io.Writer, name string, size int, ns, allocs, bytes float64) {
+	mbs := 0.0
+	if ns > 0 {
+		mbs = float64(size) / ns * 1e9 / 1e6
+	}
+	fmt.Fprintf(w, "%d\t%.0f\t%.2f\t%.0f\t%.0f\t %s\n", size, ns, mbs, allocs, bytes, name)
+}
+
+// perRun divides a measurement over all runs by their number.
+func (b *benchResult) perRun(n int64) float64 {
+	return float64(n) / float64(b.runs)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Check statuses of a file under --check.
+const (
+	checkPass    = "pass"
+	checkFail    = "fail"
+	checkMissing = "missing"
+)
+
+// golden holds the expected outputs recorded in a golden file such as
+// compliance/golden.sexp, keyed by the slash-separated relative path of
+// their `; file:` headers.
+type golden map[string]string
+
+// readGolden reads the golden file at path.
+func readGolden(path string) (golden, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	g := make(golden)
+	var name string
+	var body strings.Builder
+	flush := func() {
+		if name != "" {
+			g[name] = body.String()
+		}
+		body.Reset()
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<26)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if file, ok := strings.CutPrefix(line, "; file: "); ok {
+			flush()
+			name = filepath.ToSlash(file)
+			continue
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	flush()
+	return g, scanner.Err()
+}
+
+// loadGolden reads the golden file named by flagPath, or else golden.sexp
+// beside the corpus if there is one, returning its path and whether it is
+// in use. A missing file named by flagPath is an error unless missingOK is
+// set, when it reads as empty.
+func loadGolden(flagPath, corpusPath string, missingOK bool) (g golden, path string, useGolden bool, err error) {
+	path = flagPath
+	if path == "" {
+		path = filepath.Join(filepath.Dir(corpusPath), "golden.sexp")
+	}
+	g = golden{}
+	if flagPath == "" && !fileExists(path) {
+		return g, path, false, nil
+	}
+	if read, err := readGolden(path); err == nil {
+		g = read
+	} else if !missingOK || !os.IsNotExist(err) {
+		return nil, "", false, err
+	}
+	return g, path, true, nil
+}
+
+// expectedPath returns the path of the .sexp file beside the corpus file
+// at path.
+func expectedPath(path string) string {
+	return strings.TrimSuffix(path, ".styx") + ".sexp"
+}
+
+// expectedOutput returns the expected output of the corpus file at path:
+// the contents of the .sexp file beside it, or else its entry in g. file
+// is the .sexp file's path, or "" for an entry in g.
+func expectedOutput(path, relative string, g golden) (expected, file string, ok bool, err error) {
+	data, err := os.ReadFile(expectedPath(path))
+	switch {
+	case err == nil:
+		expected := string(data)
+		// The file may start with the header of the combined output.
+		if strings.HasPrefix(expected, "; file: ") {
+			_, expected, _ = strings.Cut(expected, "\n")
+		}
+		return expected, expectedPath(path), true, nil
+	case !os.IsNotExist(err):
+		return "", "", false, err
+	}
+	expected, ok = g[filepath.ToSlash(relative)]
+	return expected, "", ok, nil
+}
+
+// checkResults compares each result's output with its expected output,
+// setting its check status, and returns the number of files that did not
+// pass.
+func checkResults(results []*fileResult, g golden) (failed int, err error) {
+	for _, r := range results {
+		expected, file, ok, err := expectedOutput(r.path, r.relative, g)
+		if err != nil {
+			return 0, err
+		}
+		r.expectedFile = file
+		switch {
+		case !ok:
+			r.check = checkMissing
+		case strings.TrimRight(expected, "\n") == r.output:
+			r.check = checkPass
+		default:
+			r.check, r.expected = checkFail, strings.TrimRight(expected, "\n")
+		}
+		if r.check != checkPass {
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// writeCheck prints a unified diff for each file whose output differs
+// from the expected output, followed by its source annotated with the
+// expected and actual error spans, a line for each file without one, and
+// a summary. color highlights the annotations.
+func writeCheck(w io.Writer, results []*fileResult, color bool) {
+	var passed, failed, missing int
+	for _, r := range results {
+		switch r.check {
+		case checkPass:
+			passed++
+		case checkMissing:
+			missing++
+			fmt.Fprintf(w, "MISSING %s: no expected output\n", r.relative)
+		case checkFail:
+			failed++
+			fmt.Fprintf(w, "FAIL %s\n", r.relative)
+			fmt.Fprint(w, r.checkDiff())
+			fmt.Fprint(w, r.annotateErrors(color))
+		}
+	}
+	fmt.Fprintf(w, "%d passed, %d failed", passed, failed)
+	if missing > 0 {
+		fmt.Fprintf(w, ", %d without expected output", missing)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeGolden writes g to path in the combined output format, sorted by
+// path as the runner lists files.
+func writeGolden(path string, g golden) error {
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString("; file: " + name + "\n" + g[name])
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0o644)
+}
+
+// updateExpected replaces the expected output of each file that did not
+// pass with its output, where the expected output was read from: the .sexp
+// file beside it, or g, written back to goldenPath. Files without one get
+// an entry in g if useGolden is set, and a .sexp file otherwise. It prints
+// the files changed and a summary.
+func updateExpected(w io.Writer, results []*fileResult, g golden, goldenPath string, useGolden bool) error {
+	var updated, added int
+	goldenChanged := false
+	for _, r := range results {
+		if r.check == checkPass {
+			continue
+		}
+		verb := "updated"
+		if r.check == checkMissing {
+			verb = "added"
+			added++
+		} else {
+			updated++
+		}
+		if r.expectedFile != "" || r.check == checkMissing && !useGolden {
+			if err := os.WriteFile(expectedPath(r.path), []byte(r.output+"\n"), 0o644); err != nil {
+				return err
+			}
+		} else {
+			g[filepath.ToSlash(r.relative)] = r.output + "\n"
+			goldenChanged = true
+		}
+		fmt.Fprintf(w, "%s %s\n", verb, r.relative)
+	}
+	if goldenChanged {
+		if err := writeGolden(goldenPath, g); err != nil {
+			return err
+		}
+	}
+	if updated+added == 0 {
+		fmt.Fprintln(w, "expected outputs are up to date")
+		return nil
+	}
+	fmt.Fprintf(w, "%d updated, %d added\n", updated, added)
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bearcove/styx/implementations/styx-go/internal/udiff"
+)
+
+// failureMessage describes a file whose output differs from the expected
+// output.
+const failureMessage = "output differs from the expected output"
+
+// checkDiff returns the unified diff of a failing file's expected and
+// actual output.
+func (r *fileResult) checkDiff() string {
+	name := filepath.ToSlash(r.relative)
+	return udiff.Unified(r.expected+"\n", r.output+"\n", "expected/"+name, "actual/"+name)
+}
+
+// junitSuites is the root of a JUnit XML report, in the dialect CI systems
+// such as Jenkins, GitLab and Buildkite read.
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	// Classname is the file's directory with dots for slashes, which CI
+	// systems show as a package.
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnit writes checked results to w as a JUnit XML report with a test
+// case per file: failing on a difference from the expected output, with
+// the diff and annotated error spans, and skipped without one.
+func writeJUnit(w io.Writer, results []*fileResult) error {
+	suite := junitSuite{Name: "styx-compliance", Cases: []junitCase{}}
+	var total time.Duration
+	for _, r := range results {
+		name := filepath.ToSlash(r.relative)
+		c := junitCase{Classname: suite.Name, Name: name, Time: seconds(r.duration)}
+		if dir := path.Dir(name); dir != "." {
+			c.Classname = strings.ReplaceAll(dir, "/", ".")
+			c.Name = path.Base(name)
+		}
+		switch r.check {
+		case checkFail:
+			c.Failure = &junitMessage{Message: failureMessage, Body: r.checkDiff() + r.annotateErrors(false)}
+			suite.Failures++
+		case checkMissing:
+			c.Skipped = &junitMessage{Message: "no expected output"}
+			suite.Skipped++
+		}
+		total += r.duration
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests, suite.Time = len(results), seconds(total)
+	suites := junitSuites{
+		Name:     suite.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeTAP writes checked results to w in the Test Anything Protocol,
+// version 13: a test point per file, with the diff of a failing file in a
+// YAML block and files without an expected output skipped.
+func writeTAP(w io.Writer, results []*fileResult) {
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", len(results))
+	for i, r := range results {
+		name := filepath.ToSlash(r.relative)
+		switch r.check {
+		case checkFail:
+			fmt.Fprintf(w, "not ok %d - %s\n", i+1, name)
+			fmt.Fprintln(w, "  ---")
+			fmt.Fprintf(w, "  message: %s\n", failureMessage)
+			fmt.Fprintln(w, "  diff: |")
+			for _, line := range strings.Split(strings.TrimSuffix(r.checkDiff(), "\n"), "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+			fmt.Fprintln(w, "  ...")
+		case checkMissing:
+			fmt.Fprintf(w, "ok %d - %s # SKIP no expected output\n", i+1, name)
+		default:
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, name)
+		}
+	}
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
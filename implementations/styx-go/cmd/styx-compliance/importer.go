@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+// fuzzHeader starts the files `go test -fuzz` records inputs in, such as
+// testdata/fuzz/FuzzParse/<hash>.
+const fuzzHeader = "go test fuzz v1\n"
+
+// Corpus directories imported cases go in, by whether they parse.
+const (
+	importValidDir   = "06-edge-cases"
+	importInvalidDir = "07-invalid"
+)
+
+// importCases adds the cases at from, a file or a directory of them, to
+// the corpus: fuzz inputs, decoded, and any other file as it is. Each is
+// written with normalized line endings and a comment naming where it came
+// from, to the edge-case directory if it parses and the invalid one if it
+// does not. Cases whose file already exists are left alone. It prints the
+// files added and a summary.
+func importCases(w io.Writer, from, corpusPath string) error {
+	var paths []string
+	err := filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != from {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var added, existing int
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		source, fuzzed, err := decodeCase(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		source = "// Imported from " + filepath.ToSlash(path) + "\n" + normalizeNewlines(source)
+		if !strings.HasSuffix(source, "\n") {
+			source += "\n"
+		}
+		dir := importValidDir
+		if _, err := styx.Parse(source); err != nil {
+			dir = importInvalidDir
+		}
+		target := filepath.Join(corpusPath, dir, caseName(path, fuzzed))
+		if fileExists(target) {
+			existing++
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(source), 0o644); err != nil {
+			return err
+		}
+		added++
+		fmt.Fprintf(w, "added %s\n", relativePath(target, corpusPath))
+	}
+	fmt.Fprintf(w, "%d added, %d already in the corpus\n", added, existing)
+	return nil
+}
+
+// decodeCase returns the source a case file holds: the input of a fuzz
+// file, which must be a single string, or else the file itself.
+func decodeCase(data []byte) (source string, fuzzed bool, err error) {
+	body, ok := bytes.CutPrefix(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte(fuzzHeader))
+	if !ok {
+		return string(data), false, nil
+	}
+	var values []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	if len(values) != 1 {
+		return "", true, fmt.Errorf("fuzz input has %d values, want 1", len(values))
+	}
+	v := values[0]
+	for _, prefix := range []string{"string(", "[]byte("} {
+		if lit, ok := strings.CutPrefix(v, prefix); ok && strings.HasSuffix(lit, ")") {
+			s, err := strconv.Unquote(strings.TrimSuffix(lit, ")"))
+			if err != nil {
+				return "", true, fmt.Errorf("fuzz input %s: %w", v, err)
+			}
+			return s, true, nil
+		}
+	}
+	return "", true, fmt.Errorf("fuzz input %s is not a string", v)
+}
+
+// normalizeNewlines turns CRLF and lone CR line endings into LF.
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
+
+// caseName names the corpus file for the case at path in the corpus's
+// style, lowercase words joined by hyphens: fuzz-parse-<hash>.styx for an
+// input of FuzzParse, and the file's own name otherwise.
+func caseName(path string, fuzzed bool) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if fuzzed {
+		target := strings.TrimPrefix(filepath.Base(filepath.Dir(path)), "Fuzz")
+		name = "fuzz-" + target + "-" + filepath.Base(path)
+	}
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && sb.Len() > 0 {
+			sb.WriteByte('-')
+			hyphen = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-") + ".styx"
+}
@@ -1,172 +1,598 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	styx "github.com/bearcove/styx/implementations/styx-go"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "Usage: styx-compliance <corpus-directory>")
-		os.Exit(1)
-	}
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, styx.ColorEnabled(os.Stdout)))
+}
 
-	corpusPath := os.Args[1]
-	info, err := os.Stat(corpusPath)
-	if err != nil || !info.IsDir() {
-		fmt.Fprintf(os.Stderr, "Error: %s is not a directory\n", corpusPath)
-		os.Exit(1)
+// run runs the tool with args, reading stdin for a corpus of "-" and
+// writing to stdout and stderr, and returns the exit status. color
+// highlights the text format.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, color bool) int {
+	flags := flag.NewFlagSet("styx-compliance", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "sexp", "output format: sexp, text, json, sarif, report, junit or tap")
+	jobs := flags.Int("j", runtime.GOMAXPROCS(0), "number of files to parse at once")
+	var include, exclude globList
+	flags.Var(&include, "include", "only process files matching a glob; may be repeated")
+	flags.Var(&exclude, "exclude", "skip files matching a glob; may be repeated")
+	check := flags.Bool("check", false, "compare each file's output with the expected output and print diffs")
+	update := flags.Bool("update", false, "rewrite the expected outputs that differ from the output")
+	bench := flags.Int("bench", 0, "parse each file `N` times and print its time, throughput and allocations per parse")
+	importFrom := flags.String("import", "", "add the fuzz inputs or other cases in a file or `directory` to the corpus")
+	threeWay := flags.Bool("three-way", false, "compare each file's output with the reference implementation's and the expected output")
+	styxPath := flags.String("styx", "", "reference implementation's styx `binary` for --three-way (default $"+styxEnv+", a cargo build or the PATH)")
+	goldenPath := flags.String("golden", "", "golden file of expected outputs for --check and --update (default golden.sexp beside the corpus)")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: styx-compliance [flags] <corpus-directory> [path...]")
+		fmt.Fprintln(stderr, "       styx-compliance [--format format] -")
+		fmt.Fprintln(stderr, "\nA corpus of - parses stdin, as a file named <stdin>.")
+		fmt.Fprintln(stderr, "\nPaths, files or directories in the corpus, limit the files processed.")
+		fmt.Fprintln(stderr, "Globs without a / match file names, others paths relative to the corpus.")
+		fmt.Fprintln(stderr, "\nWith --check, the expected output of a file is read from the .sexp file")
+		fmt.Fprintln(stderr, "beside it or from the golden file, and the status is 1 if any differs.")
+		fmt.Fprintln(stderr, "--update rewrites the expected outputs that differ instead.")
+		fmt.Fprintln(stderr, "\n--bench parses the files one at a time, ignoring -j, and prints a table")
+		fmt.Fprintln(stderr, "instead of the output.")
+		fmt.Fprintln(stderr, "\n--import writes each case, such as a go test -fuzz input, to a corpus file")
+		fmt.Fprintln(stderr, "in 06-edge-cases if it parses and 07-invalid if not. Record its expected")
+		fmt.Fprintln(stderr, "output with --update or by regenerating golden.sexp.")
+		fmt.Fprintln(stderr, "\n--three-way runs styx tree --format sexp from the reference implementation")
+		fmt.Fprintln(stderr, "on each file too, and prints where it, the Go parser and the expected output")
+		fmt.Fprintln(stderr, "disagree and a matrix of how often each pair agrees.")
+		fmt.Fprintln(stderr, "\nThe junit and tap formats check files as --check does, reporting a test")
+		fmt.Fprintln(stderr, "per file, failed if its output differs and skipped if none is expected.")
+		fmt.Fprintln(stderr, "\nFlags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *jobs < 1 {
+		fmt.Fprintln(stderr, "Error: -j must be at least 1")
+		return 1
+	}
+	switch *format {
+	case "sexp", "text", "json", "sarif", "report", "junit", "tap":
+	default:
+		flags.Usage()
+		return 1
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 1
+	}
+	switch *format {
+	case "junit", "tap":
+		*check = true
+	case "sexp", "report":
+	default:
+		if *check {
+			fmt.Fprintln(stderr, "Error: --check needs the sexp, report, junit or tap format")
+			return 1
+		}
+	}
+	if *bench < 0 {
+		fmt.Fprintln(stderr, "Error: --bench needs a positive number of runs")
+		return 1
+	}
+	if *bench > 0 && (*check || *update || *format != "sexp") {
+		fmt.Fprintln(stderr, "Error: --bench cannot be combined with --check, --update or --format")
+		return 1
+	}
+	if *threeWay && (*check || *update || *bench > 0 || *importFrom != "" || *format != "sexp") {
+		fmt.Fprintln(stderr, "Error: --three-way cannot be combined with --check, --update, --bench, --import or --format")
+		return 1
+	}
+	if *importFrom != "" && (flags.NArg() > 1 || *check || *update || *bench > 0 || *format != "sexp") {
+		fmt.Fprintln(stderr, "Error: --import takes only the corpus directory")
+		return 1
+	}
+	if *update && (*check || *format != "sexp") {
+		fmt.Fprintln(stderr, "Error: --update cannot be combined with --check or --format")
+		return 1
 	}
 
+	corpusPath := flags.Arg(0)
+	src := &sources{root: corpusPath}
 	var styxFiles []string
-	err = filepath.Walk(corpusPath, func(path string, info os.FileInfo, err error) error {
+	if corpusPath == "-" {
+		if flags.NArg() > 1 || *importFrom != "" {
+			fmt.Fprintln(stderr, "Error: - cannot be combined with paths or --import")
+			return 1
+		}
+		if *check || *update || *threeWay {
+			fmt.Fprintln(stderr, "Error: stdin has no expected output to check or update")
+			return 1
+		}
+		data, err := io.ReadAll(stdin)
 		if err != nil {
-			return err
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		src.stdin = data
+		styxFiles = []string{"-"}
+	} else {
+		info, err := os.Stat(corpusPath)
+		if err != nil || !info.IsDir() {
+			fmt.Fprintf(stderr, "Error: %s is not a directory\n", corpusPath)
+			return 1
 		}
-		if !info.IsDir() && strings.HasSuffix(path, ".styx") {
-			styxFiles = append(styxFiles, path)
+		if *importFrom != "" {
+			if err := importCases(stdout, *importFrom, corpusPath); err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+
+		roots := []string{corpusPath}
+		if flags.NArg() > 1 {
+			roots = roots[:0]
+			for _, arg := range flags.Args()[1:] {
+				if _, err := os.Stat(arg); err != nil {
+					// Paths may also be given relative to the corpus.
+					arg = filepath.Join(corpusPath, arg)
+				}
+				roots = append(roots, arg)
+			}
 		}
-		return nil
+		styxFiles, err = corpusFiles(corpusPath, roots, include, exclude)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	switch *format {
+	case "text", "json", "sarif":
+		diagnosed := make([]*diagnosedFile, len(styxFiles))
+		forEach(len(styxFiles), *jobs, func(i int) {
+			diagnosed[i] = diagnoseFile(styxFiles[i], src)
+		})
+		if *format == "text" {
+			first := true
+			for _, f := range diagnosed {
+				if len(f.diags) == 0 {
+					continue
+				}
+				if !first {
+					fmt.Fprintln(stdout)
+				}
+				first = false
+				fmt.Fprint(stdout, styx.RenderDiagnostics(f.source, f.diags, styx.RenderOptions{Color: color, Context: 1, Filename: f.relative}))
+			}
+			return 0
+		}
+		var diags []styx.JSONDiagnostic
+		for _, f := range diagnosed {
+			for _, d := range f.diags {
+				diags = append(diags, d.JSON(f.relative, f.source))
+			}
+		}
+		write := styx.WriteDiagnosticsJSON
+		if *format == "sarif" {
+			write = styx.WriteSARIF
+		}
+		if err := write(stdout, diags); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *bench > 0 {
+		// Parse serially, so files do not compete for CPU or skew each
+		// other's allocation counts.
+		benched := make([]*benchResult, 0, len(styxFiles))
+		for _, path := range styxFiles {
+			b, err := benchFile(path, src, *bench)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return 1
+			}
+			benched = append(benched, b)
+		}
+		if err := writeBench(stdout, benched); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	results := make([]*fileResult, len(styxFiles))
+	forEach(len(styxFiles), *jobs, func(i int) {
+		results[i] = processFile(styxFiles[i], src)
+	})
+	if *threeWay {
+		return runThreeWay(stdout, stderr, results, *styxPath, *goldenPath, corpusPath, *jobs)
+	}
+	status := 0
+	if *check || *update {
+		// --update may create the golden file.
+		g, path, useGolden, err := loadGolden(*goldenPath, corpusPath, *update)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		failed, err := checkResults(results, g)
+		if err == nil && *update {
+			err = updateExpected(stdout, results, g, path, useGolden)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if *update {
+			return 0
+		}
+		if failed > 0 {
+			status = 1
+		}
+	}
+	switch *format {
+	case "report", "junit":
+		write := writeReport
+		if *format == "junit" {
+			write = writeJUnit
+		}
+		if err := write(stdout, results); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		return status
+	case "tap":
+		writeTAP(stdout, results)
+		return status
+	}
+	if *check {
+		writeCheck(stdout, results, color)
+		return status
+	}
+
+	sexps := make([]string, len(results))
+	for i, r := range results {
+		sexps[i] = r.sexp()
+	}
+	fmt.Fprintln(stdout, strings.Join(sexps, "\n"))
+	return 0
+}
+
+// runThreeWay compares results with the reference implementation's output
+// and the expected output, returning the exit status.
+func runThreeWay(stdout, stderr io.Writer, results []*fileResult, styxPath, goldenPath, corpusPath string, jobs int) int {
+	styx, err := findStyx(styxPath, corpusPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	g, _, _, err := loadGolden(goldenPath, corpusPath, false)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	rust := make([]string, len(results))
+	errs := make([]error, len(results))
+	forEach(len(results), jobs, func(i int) {
+		rust[i], errs[i] = rustOutput(styx, results[i].path)
 	})
+	if err := errors.Join(errs...); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	compared, err := threeWayResults(results, rust, g)
+	if err == nil {
+		var mismatched int
+		mismatched, err = writeThreeWay(stdout, compared)
+		if err == nil && mismatched > 0 {
+			return 1
+		}
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error walking directory: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// forEach calls f with each index below n, running up to jobs calls at
+// once, and returns when all are done. Callers store results by index, so
+// output stays in order whatever order the calls finish in.
+func forEach(n, jobs int, f func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(jobs, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				f(i)
+			}
+		}()
 	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
 
-	sort.Strings(styxFiles)
+// globList is a repeatable flag of glob patterns.
+type globList []string
+
+func (g *globList) String() string {
+	return strings.Join(*g, ",")
+}
 
-	var results []string
-	for _, path := range styxFiles {
-		result := processFile(path, corpusPath)
-		results = append(results, result)
+func (g *globList) Set(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%q: %w", pattern, err)
 	}
+	*g = append(*g, pattern)
+	return nil
+}
 
-	fmt.Println(strings.Join(results, "\n"))
+// match reports whether any pattern matches the file at relative, a
+// slash-separated path in the corpus. Patterns without a slash match the
+// file name.
+func (g globList) match(relative string) bool {
+	for _, pattern := range g {
+		name := relative
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(relative)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// corpusFiles returns the sorted .styx files under roots, files or
+// directories in the corpus, that match an include pattern if there are
+// any and no exclude pattern. Files named as roots are kept whatever their
+// extension.
+func corpusFiles(corpusPath string, roots []string, include, exclude globList) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, root := range roots {
+		if rel, err := filepath.Rel(corpusPath, root); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is not in the corpus %s", root, corpusPath)
+		}
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || p != root && !strings.HasSuffix(p, ".styx") {
+				return nil
+			}
+			relative := filepath.ToSlash(mustRelPath(corpusPath, p))
+			if len(include) > 0 && !include.match(relative) || exclude.match(relative) || seen[p] {
+				return nil
+			}
+			seen[p] = true
+			files = append(files, p)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
 }
 
-func processFile(path, corpusRoot string) string {
-	// Get parent directory name for "compliance/corpus/..."
+// relativePath names path the way the golden output does, starting at the
+// corpus directory's parent.
+func relativePath(path, corpusRoot string) string {
 	corpusParent := filepath.Dir(corpusRoot)
-	relative := filepath.Join(filepath.Base(corpusParent), filepath.Base(corpusRoot), mustRelPath(corpusRoot, path))
+	return filepath.Join(filepath.Base(corpusParent), filepath.Base(corpusRoot), mustRelPath(corpusRoot, path))
+}
 
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Sprintf("; file: %s\n(error [0, 0] \"read error: %s\")", relative, err)
+// sources reads the files of the corpus at root, or stdin for the path
+// "-".
+type sources struct {
+	root  string
+	stdin []byte
+}
+
+func (s *sources) read(path string) ([]byte, error) {
+	if path == "-" {
+		return s.stdin, nil
 	}
+	return os.ReadFile(path)
+}
 
-	doc, parseErr := styx.Parse(string(content))
-	if parseErr != nil {
-		if pe, ok := parseErr.(*styx.ParseError); ok {
-			return fmt.Sprintf("; file: %s\n%s", relative, formatError(pe))
-		}
-		return fmt.Sprintf("; file: %s\n(error [0, 0] \"parse error: %s\")", relative, parseErr)
+// relative names path in output: as relativePath does, or <stdin>.
+func (s *sources) relative(path string) string {
+	if path == "-" {
+		return "<stdin>"
 	}
+	return relativePath(path, s.root)
+}
 
-	return fmt.Sprintf("; file: %s\n%s", relative, formatDocument(doc))
+// diagnosedFile is a file's relative name, its source and every problem
+// found in it.
+type diagnosedFile struct {
+	relative string
+	source   string
+	diags    []*styx.Diagnostic
 }
 
-func mustRelPath(base, target string) string {
-	rel, err := filepath.Rel(base, target)
+// diagnoseFile parses a file in recovery mode.
+func diagnoseFile(path string, src *sources) *diagnosedFile {
+	f := &diagnosedFile{relative: src.relative(path)}
+	content, err := src.read(path)
 	if err != nil {
-		return target
+		d := &styx.Diagnostic{Severity: styx.SeverityError, Message: "read error: " + err.Error(), Span: styx.Span{Start: -1, End: -1}}
+		f.diags = []*styx.Diagnostic{d}
+		return f
 	}
-	return rel
+	f.source = string(content)
+	_, f.diags = styx.Diagnose(f.source, styx.ParseOptions{})
+	return f
 }
 
-func escapeString(s string) string {
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "\"", "\\\"")
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	s = strings.ReplaceAll(s, "\r", "\\r")
-	s = strings.ReplaceAll(s, "\t", "\\t")
-	return s
+// fileResult is the outcome of parsing one corpus file.
+type fileResult struct {
+	path     string
+	relative string
+	source   string
+	// output is the file's s-expression: its tree, or its error.
+	output string
+	// err is the parse or read error, if any.
+	err      error
+	duration time.Duration
+	// check is the file's --check status, and expected the output
+	// expected of a failing file. expectedFile is the .sexp file the
+	// expected output was read from, if not the golden file.
+	check        string
+	expected     string
+	expectedFile string
 }
 
-func formatValue(value *styx.Value, indent int) string {
-	prefix := strings.Repeat("  ", indent)
+func (r *fileResult) sexp() string {
+	return fmt.Sprintf("; file: %s\n%s", r.relative, r.output)
+}
 
-	// Unit value (no tag, no payload)
-	if value.Tag == nil && value.PayloadKind == styx.PayloadNone {
-		return fmt.Sprintf("(unit [%d, %d])", value.Span.Start, value.Span.End)
-	}
+func processFile(path string, src *sources) *fileResult {
+	r := &fileResult{path: path, relative: src.relative(path)}
+	start := time.Now()
+	defer func() { r.duration = time.Since(start) }()
 
-	// Tag only (no payload)
-	if value.Tag != nil && value.PayloadKind == styx.PayloadNone {
-		return fmt.Sprintf("(tag [%d, %d] \"%s\")", value.Span.Start, value.Span.End, value.Tag.Name)
+	content, err := src.read(path)
+	if err != nil {
+		r.err = err
+		r.output = fmt.Sprintf("(error [0, 0] \"read error: %s\")", err)
+		return r
 	}
+	r.source = string(content)
 
-	// Tag with payload
-	if value.Tag != nil && value.PayloadKind != styx.PayloadNone {
-		payloadStr := formatPayload(value, indent+1)
-		return fmt.Sprintf("(tag [%d, %d] \"%s\"\n%s  %s)", value.Span.Start, value.Span.End, value.Tag.Name, prefix, payloadStr)
+	doc, parseErr := styx.ParseBytes(content)
+	if parseErr != nil {
+		r.err = parseErr
+		r.output = styx.FormatErrorSexp(parseErr)
+		return r
 	}
+	r.output = styx.FormatSexp(doc)
+	return r
+}
 
-	// Just payload
-	if value.PayloadKind != styx.PayloadNone {
-		return formatPayload(value, indent)
-	}
+// report is the --format report output: one entry per file, in the order
+// of the s-expression output, and totals.
+type report struct {
+	Files   []fileReport  `json:"files"`
+	Summary reportSummary `json:"summary"`
+}
 
-	return fmt.Sprintf("(unit [%d, %d])", value.Span.Start, value.Span.End)
+type fileReport struct {
+	Path string `json:"path"`
+	// Status is "ok" for a file that parsed and "error" for one that did
+	// not, which the corpus expects of some files.
+	Status string `json:"status"`
+	Output string `json:"output"`
+	// Check is "pass", "fail" or "missing" under --check, the last for a
+	// file without an expected output.
+	Check      string                `json:"check,omitempty"`
+	Expected   string                `json:"expected,omitempty"`
+	Errors     []styx.JSONDiagnostic `json:"errors"`
+	DurationMS float64               `json:"duration_ms"`
 }
 
-func formatPayload(value *styx.Value, indent int) string {
-	prefix := strings.Repeat("  ", indent)
+type reportSummary struct {
+	Files      int           `json:"files"`
+	OK         int           `json:"ok"`
+	Errors     int           `json:"errors"`
+	DurationMS float64       `json:"duration_ms"`
+	Check      *checkSummary `json:"check,omitempty"`
+}
 
-	switch value.PayloadKind {
-	case styx.PayloadScalar:
-		escaped := escapeString(value.Scalar.Text)
-		return fmt.Sprintf("(scalar [%d, %d] %s \"%s\")", value.Scalar.Span.Start, value.Scalar.Span.End, value.Scalar.Kind, escaped)
+// checkSummary counts the files of each --check status.
+type checkSummary struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Missing int `json:"missing"`
+}
 
-	case styx.PayloadSequence:
-		seq := value.Sequence
-		if len(seq.Items) == 0 {
-			return fmt.Sprintf("(sequence [%d, %d])", seq.Span.Start, seq.Span.End)
+// writeReport writes results to w as an indented JSON report.
+func writeReport(w io.Writer, results []*fileResult) error {
+	rep := report{Files: []fileReport{}}
+	var total time.Duration
+	for _, r := range results {
+		f := fileReport{
+			Path:       r.relative,
+			Status:     "ok",
+			Output:     r.output,
+			Check:      r.check,
+			Expected:   r.expected,
+			Errors:     []styx.JSONDiagnostic{},
+			DurationMS: milliseconds(r.duration),
 		}
-		var items []string
-		for _, item := range seq.Items {
-			items = append(items, fmt.Sprintf("%s  %s", prefix, formatValue(item, indent+1)))
-		}
-		return fmt.Sprintf("(sequence [%d, %d]\n%s)", seq.Span.Start, seq.Span.End, strings.Join(items, "\n"))
-
-	case styx.PayloadObject:
-		obj := value.Object
-		if len(obj.Entries) == 0 {
-			return fmt.Sprintf("(object [%d, %d])", obj.Span.Start, obj.Span.End)
+		if r.err != nil {
+			f.Status = "error"
+			d := &styx.Diagnostic{Severity: styx.SeverityError, Message: r.err.Error(), Span: styx.Span{Start: -1, End: -1}}
+			if pe, ok := r.err.(*styx.ParseError); ok {
+				d = pe.Diagnostic()
+			}
+			f.Errors = append(f.Errors, d.JSON(r.relative, r.source))
+			rep.Summary.Errors++
+		} else {
+			rep.Summary.OK++
 		}
-		var entries []string
-		for _, entry := range obj.Entries {
-			entries = append(entries, formatEntry(entry, indent+1))
+		if r.check != "" {
+			if rep.Summary.Check == nil {
+				rep.Summary.Check = &checkSummary{}
+			}
+			switch r.check {
+			case checkPass:
+				rep.Summary.Check.Passed++
+			case checkFail:
+				rep.Summary.Check.Failed++
+			case checkMissing:
+				rep.Summary.Check.Missing++
+			}
 		}
-		return fmt.Sprintf("(object [%d, %d]\n%s\n%s)", obj.Span.Start, obj.Span.End, strings.Join(entries, "\n"), prefix)
+		total += r.duration
+		rep.Files = append(rep.Files, f)
 	}
-
-	return "(unknown)"
+	rep.Summary.Files = len(results)
+	rep.Summary.DurationMS = milliseconds(total)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(rep)
 }
 
-func formatEntry(entry *styx.Entry, indent int) string {
-	prefix := strings.Repeat("  ", indent)
-	keyStr := formatValue(entry.Key, indent+1)
-	valueStr := formatValue(entry.Value, indent+1)
-	return fmt.Sprintf("%s(entry\n%s  %s\n%s  %s)", prefix, prefix, keyStr, prefix, valueStr)
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
 }
 
-func formatDocument(doc *styx.Document) string {
-	if len(doc.Entries) == 0 {
-		return "(document [-1, -1]\n)"
-	}
-	var entries []string
-	for _, entry := range doc.Entries {
-		entries = append(entries, formatEntry(entry, 1))
-	}
-	return fmt.Sprintf("(document [-1, -1]\n%s\n)", strings.Join(entries, "\n"))
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
-func formatError(err *styx.ParseError) string {
-	escapedMsg := strings.ReplaceAll(err.Message, "\\", "\\\\")
-	return fmt.Sprintf("(error [%d, %d] \"parse error at %d-%d: %s\")", err.Span.Start, err.Span.End, err.Span.Start, err.Span.End, escapedMsg)
+func mustRelPath(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return rel
 }
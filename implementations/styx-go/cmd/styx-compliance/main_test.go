@@ -0,0 +1,512 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeCorpus writes files to a corpus directory and returns its path.
+func writeCorpus(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "corpus")
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// runTool runs the tool with args and returns its output and exit status.
+func runTool(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	return runToolStdin(t, "", args...)
+}
+
+// runToolStdin runs the tool as runTool does, with stdin as its input.
+func runToolStdin(t *testing.T, stdin string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut, false)
+	return out.String(), errOut.String(), code
+}
+
+func TestReport(t *testing.T) {
+	corpus := writeCorpus(t, map[string]string{
+		"a/ok.styx":  "name app\n",
+		"b/bad.styx": "a {\n",
+	})
+	stdout, stderr, code := runTool(t, "--format", "report", corpus)
+	if code != 0 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	var rep report
+	if err := json.Unmarshal([]byte(stdout), &rep); err != nil {
+		t.Fatalf("%v\n%s", err, stdout)
+	}
+	if len(rep.Files) != 2 || rep.Summary.Files != 2 || rep.Summary.OK != 1 || rep.Summary.Errors != 1 {
+		t.Fatalf("report = %+v", rep)
+	}
+	ok, bad := rep.Files[0], rep.Files[1]
+	if ok.Path != filepath.Join(filepath.Base(filepath.Dir(corpus)), "corpus", "a", "ok.styx") || ok.Status != "ok" || !strings.HasPrefix(ok.Output, "(document") || len(ok.Errors) != 0 {
+		t.Errorf("ok file = %+v", ok)
+	}
+	if bad.Status != "error" || !strings.HasPrefix(bad.Output, "(error") || len(bad.Errors) != 1 || bad.Errors[0].Code != "STYX0003" || bad.Errors[0].Span.StartLine != 1 {
+		t.Errorf("bad file = %+v", bad)
+	}
+
+	// The report carries the same output as the s-expression format.
+	sexp, _, _ := runTool(t, corpus)
+	if want := "; file: " + ok.Path + "\n" + ok.Output + "\n; file: " + bad.Path + "\n" + bad.Output + "\n"; sexp != want {
+		t.Errorf("sexp output = %q, want %q", sexp, want)
+	}
+}
+
+func TestStdin(t *testing.T) {
+	stdout, stderr, code := runToolStdin(t, "name app\n", "-")
+	if code != 0 || !strings.HasPrefix(stdout, "; file: <stdin>\n(document") {
+		t.Errorf("sexp: status %d, output %q, stderr %q", code, stdout, stderr)
+	}
+
+	stdout, _, _ = runToolStdin(t, "a {\n", "--format", "report", "-")
+	var rep report
+	if err := json.Unmarshal([]byte(stdout), &rep); err != nil {
+		t.Fatalf("%v\n%s", err, stdout)
+	}
+	if len(rep.Files) != 1 || rep.Files[0].Path != "<stdin>" || rep.Files[0].Status != "error" {
+		t.Errorf("report = %+v", rep)
+	}
+
+	stdout, _, _ = runToolStdin(t, "a {\n", "--format", "json", "-")
+	var diags struct {
+		Diagnostics []struct{ File string }
+	}
+	if err := json.Unmarshal([]byte(stdout), &diags); err != nil || len(diags.Diagnostics) != 1 || diags.Diagnostics[0].File != "<stdin>" {
+		t.Errorf("json: %v, output %q", err, stdout)
+	}
+
+	for _, args := range [][]string{{"--check", "-"}, {"--update", "-"}, {"--format", "tap", "-"}, {"-", "a.styx"}} {
+		if _, stderr, code := runToolStdin(t, "name app\n", args...); code != 1 || !strings.HasPrefix(stderr, "Error: ") {
+			t.Errorf("%v: status %d, stderr %q", args, code, stderr)
+		}
+	}
+}
+
+func TestParallel(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		files[fmt.Sprintf("d%d/f%02d.styx", i%3, i)] = strings.Repeat("a {b 1}\n", i%5) + fmt.Sprintf("n %d\n", i)
+	}
+	files["bad.styx"] = "a {\n"
+	corpus := writeCorpus(t, files)
+	for _, format := range []string{"sexp", "text", "json"} {
+		serial, _, _ := runTool(t, "--format", format, "-j", "1", corpus)
+		parallel, _, _ := runTool(t, "--format", format, "-j", "8", corpus)
+		if serial != parallel {
+			t.Errorf("%s: -j 8 output differs from -j 1", format)
+		}
+	}
+	if _, stderr, code := runTool(t, "-j", "0", corpus); code != 1 || !strings.Contains(stderr, "-j") {
+		t.Errorf("-j 0: status %d, stderr %q", code, stderr)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	corpus := writeCorpus(t, map[string]string{
+		"00-basic/a.styx":       "a 1\n",
+		"00-basic/heredoc.styx": "b 2\n",
+		"01-more/heredoc.styx":  "c 3\n",
+		"01-more/d.styx":        "d 4\n",
+		"01-more/notes.txt":     "e 5\n",
+	})
+	files := func(args ...string) string {
+		t.Helper()
+		stdout, stderr, code := runTool(t, args...)
+		if code != 0 {
+			t.Fatalf("%v: status %d: %s", args, code, stderr)
+		}
+		var names []string
+		for _, line := range strings.Split(stdout, "\n") {
+			if name, ok := strings.CutPrefix(line, "; file: "); ok {
+				names = append(names, filepath.ToSlash(name)[strings.Index(filepath.ToSlash(name), "corpus/")+len("corpus/"):])
+			}
+		}
+		return strings.Join(names, " ")
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{corpus}, "00-basic/a.styx 00-basic/heredoc.styx 01-more/d.styx 01-more/heredoc.styx"},
+		{[]string{"--include", "heredoc*", corpus}, "00-basic/heredoc.styx 01-more/heredoc.styx"},
+		{[]string{"--include", "01-*/*", "--exclude", "heredoc.styx", corpus}, "01-more/d.styx"},
+		{[]string{"--exclude", "a.styx", "--exclude", "d.styx", corpus}, "00-basic/heredoc.styx 01-more/heredoc.styx"},
+		{[]string{corpus, "01-more"}, "01-more/d.styx 01-more/heredoc.styx"},
+		{[]string{corpus, filepath.Join(corpus, "00-basic", "a.styx"), "01-more/notes.txt"}, "00-basic/a.styx 01-more/notes.txt"},
+	}
+	for _, tt := range tests {
+		if got := files(tt.args...); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.args, got, tt.want)
+		}
+	}
+
+	if _, stderr, code := runTool(t, corpus, t.TempDir()); code != 1 || !strings.Contains(stderr, "not in the corpus") {
+		t.Errorf("path outside the corpus: status %d, stderr %q", code, stderr)
+	}
+	if _, _, code := runTool(t, "--include", "[", corpus); code != 1 {
+		t.Errorf("bad glob: status %d", code)
+	}
+}
+
+// checkCorpus writes a corpus whose files, as --check sees them, are
+// a/fail.styx, failing against golden.sexp, a/pass.styx, passing, and in b
+// sibling.styx, passing against a .sexp file, and missing.styx, without an
+// expected output. It returns the corpus and the prefix of its relative
+// names.
+func checkCorpus(t *testing.T) (corpus, prefix string) {
+	t.Helper()
+	corpus = writeCorpus(t, map[string]string{
+		"a/pass.styx":    "a 1\n",
+		"a/fail.styx":    "b 2\n",
+		"b/sibling.styx": "c 3\n",
+		"b/missing.styx": "d 4\n",
+	})
+	outputs := make(map[string]string)
+	sexp, _, _ := runTool(t, corpus)
+	for _, section := range strings.Split(sexp, "; file: ")[1:] {
+		name, body, _ := strings.Cut(section, "\n")
+		outputs[filepath.Base(name)] = body
+	}
+	prefix = filepath.Base(filepath.Dir(corpus)) + "/corpus/"
+	goldenFile := "; file: " + prefix + "a/fail.styx\n" + strings.Replace(outputs["fail.styx"], `"2"`, `"3"`, 1) +
+		"; file: " + prefix + "a/pass.styx\n" + outputs["pass.styx"]
+	if err := os.WriteFile(filepath.Join(filepath.Dir(corpus), "golden.sexp"), []byte(goldenFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(corpus, "b", "sibling.sexp"), []byte(outputs["sibling.styx"]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return corpus, prefix
+}
+
+func TestCheck(t *testing.T) {
+	corpus, prefix := checkCorpus(t)
+	stdout, stderr, code := runTool(t, "--check", corpus)
+	if code != 1 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	for _, want := range []string{
+		"FAIL " + filepath.Join(filepath.Dir(prefix), "a", "fail.styx"),
+		"--- expected/" + prefix + "a/fail.styx\n+++ actual/" + prefix + "a/fail.styx\n@@ -1,5 +1,5 @@\n",
+		"-    (scalar [2, 3] bare \"3\"))\n+    (scalar [2, 3] bare \"2\"))\n",
+		"MISSING " + filepath.Join(filepath.Dir(prefix), "b", "missing.styx"),
+		"2 passed, 1 failed, 1 without expected output\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "pass.styx") || strings.Contains(stdout, "sibling.styx") {
+		t.Errorf("passing files reported:\n%s", stdout)
+	}
+
+	stdout, _, code = runTool(t, "--check", "--format", "report", corpus)
+	var rep report
+	if err := json.Unmarshal([]byte(stdout), &rep); err != nil || code != 1 {
+		t.Fatalf("report: status %d, %v", code, err)
+	}
+	if c := rep.Summary.Check; c == nil || c.Passed != 2 || c.Failed != 1 || c.Missing != 1 || rep.Files[0].Check != "fail" || rep.Files[0].Expected == "" {
+		t.Errorf("report summary = %+v, first file %+v", c, rep.Files[0])
+	}
+
+	if stdout, _, code := runTool(t, "--check", corpus, "a/pass.styx", "b/sibling.styx"); code != 0 || stdout != "2 passed, 0 failed\n" {
+		t.Errorf("passing files: status %d, output %q", code, stdout)
+	}
+	if _, _, code := runTool(t, "--check", "--golden", filepath.Join(corpus, "none.sexp"), corpus); code != 1 {
+		t.Errorf("missing golden file: status %d", code)
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	corpus, prefix := checkCorpus(t)
+	stdout, stderr, code := runTool(t, "--format", "junit", corpus)
+	if code != 1 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal([]byte(stdout), &suites); err != nil {
+		t.Fatalf("%v\n%s", err, stdout)
+	}
+	if suites.Tests != 4 || suites.Failures != 1 || suites.Skipped != 1 || len(suites.Suites) != 1 {
+		t.Fatalf("suites = %+v", suites)
+	}
+	cases := suites.Suites[0].Cases
+	fail, missing := cases[0], cases[2]
+	if fail.Name != "fail.styx" || !strings.HasSuffix(fail.Classname, ".corpus.a") || fail.Failure == nil || !strings.Contains(fail.Failure.Body, "+++ actual/"+prefix+"a/fail.styx") {
+		t.Errorf("failing case = %+v", fail)
+	}
+	if missing.Name != "missing.styx" || missing.Skipped == nil || missing.Failure != nil {
+		t.Errorf("missing case = %+v", missing)
+	}
+	if c := cases[1]; c.Name != "pass.styx" || c.Failure != nil || c.Skipped != nil {
+		t.Errorf("passing case = %+v", c)
+	}
+}
+
+func TestTAP(t *testing.T) {
+	corpus, prefix := checkCorpus(t)
+	stdout, stderr, code := runTool(t, "--format", "tap", corpus)
+	if code != 1 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	want := "TAP version 13\n1..4\n" +
+		"not ok 1 - " + prefix + "a/fail.styx\n" +
+		"  ---\n  message: output differs from the expected output\n  diff: |\n" +
+		"    --- expected/" + prefix + "a/fail.styx\n"
+	if !strings.HasPrefix(stdout, want) {
+		t.Errorf("output does not start with %q:\n%s", want, stdout)
+	}
+	for _, line := range []string{
+		"  ...\nok 2 - " + prefix + "a/pass.styx\n",
+		"ok 3 - " + prefix + "b/missing.styx # SKIP no expected output\n",
+		"ok 4 - " + prefix + "b/sibling.styx\n",
+	} {
+		if !strings.Contains(stdout, line) {
+			t.Errorf("output lacks %q:\n%s", line, stdout)
+		}
+	}
+	if _, _, code := runTool(t, "--format", "tap", corpus, "a/pass.styx"); code != 0 {
+		t.Errorf("passing file: status %d", code)
+	}
+}
+
+func TestAnnotateErrors(t *testing.T) {
+	corpus := writeCorpus(t, map[string]string{"x.styx": "a {\nb 1\n"})
+	golden := "; file: " + filepath.Base(filepath.Dir(corpus)) + "/corpus/x.styx\n" +
+		`(error [4, 5] "parse error at 4-5: a \"quoted\" message")` + "\n"
+	if err := os.WriteFile(filepath.Join(filepath.Dir(corpus), "golden.sexp"), []byte(golden), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, code := runTool(t, "--check", corpus)
+	want := "expected error: a \"quoted\" message\n" +
+		" --> " + filepath.Join(filepath.Base(filepath.Dir(corpus)), "corpus", "x.styx") + ":2:1\n" +
+		"1 | a {\n2 | b 1\n  | ^\n" +
+		"actual error: unclosed object (missing `}`)\n"
+	if code != 1 || !strings.Contains(stdout, want) {
+		t.Errorf("status %d, output lacks %q:\n%s", code, want, stdout)
+	}
+
+	r := &fileResult{source: "a 1\n", expected: `(error [2, 3] "bad")`, output: "(document [-1, -1]\n)"}
+	if got := r.annotateErrors(false); !strings.HasSuffix(got, "actual: no error\n") || !strings.HasPrefix(got, "expected error: bad\n") {
+		t.Errorf("document output: got %q", got)
+	}
+	r.expected = "(document [-1, -1]\n  (entry))"
+	if got := r.annotateErrors(false); got != "" {
+		t.Errorf("no errors: got %q", got)
+	}
+}
+
+func TestBench(t *testing.T) {
+	corpus := writeCorpus(t, map[string]string{
+		"a.styx": "name app\nport 80\n",
+		"b.styx": "a {\n",
+	})
+	stdout, stderr, code := runTool(t, "--bench", "3", corpus)
+	if code != 0 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 4 || strings.Fields(lines[0])[1] != "ns/op" {
+		t.Fatalf("output:\n%s", stdout)
+	}
+	for i, want := range []string{"a.styx", "b.styx", "files)"} {
+		fields := strings.Fields(lines[i+1])
+		if !strings.HasSuffix(lines[i+1], want) || len(fields) < 6 || fields[3] == "0" {
+			t.Errorf("row %d = %q, want %s with allocations", i+1, lines[i+1], want)
+		}
+	}
+	if fields := strings.Fields(lines[3]); fields[0] != "21" || fields[len(fields)-2] != "(2" {
+		t.Errorf("total = %q", lines[3])
+	}
+
+	for _, args := range [][]string{{"--bench", "-1", corpus}, {"--bench", "2", "--check", corpus}, {"--bench", "2", "--format", "report", corpus}} {
+		if _, _, code := runTool(t, args...); code != 1 {
+			t.Errorf("%v: status %d", args, code)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	corpus := writeCorpus(t, map[string]string{"00-basic/a.styx": "a 1\n"})
+	cases := writeCorpus(t, map[string]string{
+		"FuzzParse/0a1b":         "go test fuzz v1\nstring(\"a 1\\r\\nb 2\")\n",
+		"FuzzParse/2c3d":         "go test fuzz v1\n[]byte(\"a {\")\n",
+		"generated/Odd Name.txt": "x\r\n",
+	})
+	stdout, stderr, code := runTool(t, "--import", cases, corpus)
+	if code != 0 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	if !strings.HasSuffix(stdout, "3 added, 0 already in the corpus\n") {
+		t.Errorf("output = %q", stdout)
+	}
+	for name, want := range map[string]string{
+		"06-edge-cases/fuzz-parse-0a1b.styx": "// Imported from " + filepath.ToSlash(filepath.Join(cases, "FuzzParse", "0a1b")) + "\na 1\nb 2\n",
+		"07-invalid/fuzz-parse-2c3d.styx":    "// Imported from " + filepath.ToSlash(filepath.Join(cases, "FuzzParse", "2c3d")) + "\na {\n",
+		"06-edge-cases/odd-name.styx":        "// Imported from " + filepath.ToSlash(filepath.Join(cases, "generated", "Odd Name.txt")) + "\nx\n",
+	} {
+		if data, err := os.ReadFile(filepath.Join(corpus, name)); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+
+	if stdout, _, _ := runTool(t, "--import", cases, corpus); stdout != "0 added, 3 already in the corpus\n" {
+		t.Errorf("second import: output = %q", stdout)
+	}
+	bad := writeCorpus(t, map[string]string{"FuzzX/1": "go test fuzz v1\nint(1)\n"})
+	if _, stderr, code := runTool(t, "--import", bad, corpus); code != 1 || !strings.Contains(stderr, "is not a string") {
+		t.Errorf("non-string input: status %d, stderr %q", code, stderr)
+	}
+	if _, _, code := runTool(t, "--import", cases, "--check", corpus); code != 1 {
+		t.Errorf("--import with --check: status %d", code)
+	}
+}
+
+func TestThreeWay(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake styx binary is a shell script")
+	}
+	corpus := writeCorpus(t, map[string]string{
+		"agree.styx":  "a 1\n",
+		"go.styx":     "b 2\n",
+		"stale.styx":  "c 3\n",
+		"no-exp.styx": "d 4\n",
+	})
+	outputs := make(map[string]string)
+	sexp, _, _ := runTool(t, corpus)
+	for _, section := range strings.Split(sexp, "; file: ")[1:] {
+		name, body, _ := strings.Cut(section, "\n")
+		outputs[filepath.Base(name)] = body
+	}
+	other := func(name string) string { return strings.Replace(outputs[name], "[0, 1]", "[0, 9]", 1) }
+	// The fake reference implementation prints the .rust file beside each
+	// corpus file, as the real one would print its tree.
+	for name, rust := range map[string]string{
+		"agree.styx":  outputs["agree.styx"],
+		"go.styx":     other("go.styx"),
+		"stale.styx":  outputs["stale.styx"],
+		"no-exp.styx": other("no-exp.styx"),
+	} {
+		if err := os.WriteFile(filepath.Join(corpus, strings.TrimSuffix(name, ".styx")+".rust"), []byte("; file: "+name+"\n"+rust), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]string{"agree.styx": outputs["agree.styx"], "go.styx": other("go.styx"), "stale.styx": other("stale.styx")} {
+		if err := os.WriteFile(filepath.Join(corpus, strings.TrimSuffix(name, ".styx")+".sexp"), []byte(want), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	styx := filepath.Join(t.TempDir(), "styx")
+	if err := os.WriteFile(styx, []byte("#!/bin/sh\ncat \"${4%.styx}.rust\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("STYX_CLI", styx)
+	stdout, stderr, code := runTool(t, "--three-way", corpus)
+	if code != 1 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	prefix := filepath.Base(filepath.Dir(corpus)) + "/corpus/"
+	for _, want := range []string{
+		"MISMATCH " + filepath.FromSlash(prefix+"go.styx") + ": go differs\n--- expected/" + prefix + "go.styx\n+++ go/" + prefix + "go.styx\n",
+		"MISMATCH " + filepath.FromSlash(prefix+"no-exp.styx") + ": go and rust differ, no expected output\n--- rust/",
+		"MISMATCH " + filepath.FromSlash(prefix+"stale.styx") + ": expected output differs\n",
+		"      rust  expected\ngo    2/4   1/3\nrust        2/3\n",
+		"4 files, 1 agree, 3 mismatched\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "agree.styx") || strings.Count(stdout, "+++ rust/") != 0 {
+		t.Errorf("unexpected output:\n%s", stdout)
+	}
+
+	if stdout, _, code := runTool(t, "--three-way", corpus, "agree.styx"); code != 0 || !strings.HasSuffix(stdout, "1 files, 1 agree, 0 mismatched\n") {
+		t.Errorf("agreeing file: status %d, output %q", code, stdout)
+	}
+	if _, stderr, code := runTool(t, "--three-way", "--styx", filepath.Join(t.TempDir(), "none"), corpus); code != 1 || !strings.HasPrefix(stderr, "Error: ") {
+		t.Errorf("missing binary: status %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRustStderrError(t *testing.T) {
+	if got, want := rustStderrError("error: parse error at 2-3: unexpected `}`\n"), `(error [2, 3] "parse error at 2-3: unexpected `+"`}`"+`")`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, want := rustStderrError("boom\n"), `(error [-1, -1] "boom")`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	corpus := writeCorpus(t, map[string]string{
+		"a/golden.styx":  "a 1\n",
+		"a/sibling.styx": "b 2\n",
+		"a/new.styx":     "c 3\n",
+	})
+	goldenFile := filepath.Join(filepath.Dir(corpus), "golden.sexp")
+	prefix := filepath.Base(filepath.Dir(corpus)) + "/corpus/"
+	if err := os.WriteFile(goldenFile, []byte("; file: "+prefix+"a/golden.styx\n(stale)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sibling := filepath.Join(corpus, "a", "sibling.sexp")
+	if err := os.WriteFile(sibling, []byte("(stale)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := runTool(t, "--update", corpus)
+	if code != 0 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	for _, want := range []string{"updated " + filepath.Join(filepath.Dir(prefix), "a", "golden.styx"), "added " + filepath.Join(filepath.Dir(prefix), "a", "new.styx"), "2 updated, 1 added\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+	data, _ := os.ReadFile(sibling)
+	if !strings.HasPrefix(string(data), "(document") {
+		t.Errorf("sibling .sexp not updated: %q", data)
+	}
+	data, _ = os.ReadFile(goldenFile)
+	if strings.Count(string(data), "; file: ") != 2 || strings.Contains(string(data), "stale") {
+		t.Errorf("golden file:\n%s", data)
+	}
+
+	if stdout, _, code := runTool(t, "--check", corpus); code != 0 || stdout != "3 passed, 0 failed\n" {
+		t.Errorf("after update: status %d, output %q", code, stdout)
+	}
+	if stdout, _, _ := runTool(t, "--update", corpus); stdout != "expected outputs are up to date\n" {
+		t.Errorf("second update: %q", stdout)
+	}
+
+	// Without a golden file, new outputs go beside their files.
+	corpus = writeCorpus(t, map[string]string{"x.styx": "x 1\n"})
+	if _, stderr, code := runTool(t, "--update", corpus); code != 0 || !fileExists(filepath.Join(corpus, "x.sexp")) {
+		t.Errorf("no golden file: status %d, %s", code, stderr)
+	}
+	if _, _, code := runTool(t, "--update", "--check", corpus); code != 1 {
+		t.Errorf("--update --check: status %d", code)
+	}
+}
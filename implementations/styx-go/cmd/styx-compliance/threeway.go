@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bearcove/styx/implementations/styx-go/internal/udiff"
+)
+
+// styxEnv names the environment variable that gives the path of the
+// reference implementation's styx binary.
+const styxEnv = "STYX_CLI"
+
+// findStyx returns the path of the reference implementation's styx binary:
+// flagPath, $STYX_CLI, a build in the repository holding the corpus, or
+// else the one on the PATH.
+func findStyx(flagPath, corpusPath string) (string, error) {
+	if flagPath != "" {
+		return flagPath, nil
+	}
+	if path := os.Getenv(styxEnv); path != "" {
+		return path, nil
+	}
+	// The corpus is compliance/corpus in the repository; prefer a local
+	// build, with any changes not yet installed.
+	root := filepath.Dir(filepath.Dir(corpusPath))
+	for _, build := range []string{"debug", "release"} {
+		if path := filepath.Join(root, "target", build, "styx"); fileExists(path) {
+			return path, nil
+		}
+	}
+	if path, err := exec.LookPath("styx"); err == nil {
+		return path, nil
+	}
+	return "", fmt.Errorf("styx binary not found; build it with cargo build, or give its path with --styx or $%s", styxEnv)
+}
+
+// rustOutput returns the s-expression the reference implementation prints
+// for the file at path.
+func rustOutput(styx, path string) (string, error) {
+	cmd := exec.Command(styx, "tree", "--format", "sexp", path)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && stderr.Len() > 0 {
+		// Older builds report parse errors on stderr.
+		return rustStderrError(stderr.String()), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", styx, err)
+	}
+	return stdout.String(), nil
+}
+
+var rustParseError = regexp.MustCompile(`^error: parse error at (\d+)-(\d+): (.+)`)
+
+// rustStderrError converts the error a styx binary wrote to stderr into an
+// error s-expression.
+func rustStderrError(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		if m := rustParseError.FindStringSubmatch(line); m != nil {
+			return fmt.Sprintf("(error [%s, %s] %q)", m[1], m[2], "parse error at "+m[1]+"-"+m[2]+": "+m[3])
+		}
+	}
+	return fmt.Sprintf("(error [-1, -1] %q)", strings.TrimSpace(stderr))
+}
+
+// normalizeSexp drops the `; file:` headers, blank lines and trailing
+// spaces of an s-expression output, which differ between runners without
+// changing the tree.
+func normalizeSexp(output string) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "; file:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// threeWayResult holds a file's outputs from the Go parser, the reference
+// implementation and the golden file, normalized.
+type threeWayResult struct {
+	relative          string
+	goOut, rust, want string
+	// hasWant is set if the file has an expected output.
+	hasWant bool
+}
+
+// threeWayResults pairs each result of the Go parser with the reference
+// implementation's output for the file, from rust, and its expected
+// output, from the .sexp file beside it or g.
+func threeWayResults(results []*fileResult, rust []string, g golden) ([]*threeWayResult, error) {
+	out := make([]*threeWayResult, len(results))
+	for i, r := range results {
+		want, _, ok, err := expectedOutput(r.path, r.relative, g)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = &threeWayResult{
+			relative: r.relative,
+			goOut:    normalizeSexp(r.output),
+			rust:     normalizeSexp(rust[i]),
+			want:     normalizeSexp(want),
+			hasWant:  ok,
+		}
+	}
+	return out, nil
+}
+
+// agreement counts the files two sources agree on, of those compared.
+type agreement struct {
+	agree, total int
+}
+
+func (a *agreement) add(x, y string) bool {
+	a.total++
+	if x == y {
+		a.agree++
+		return true
+	}
+	return false
+}
+
+func (a agreement) String() string {
+	return fmt.Sprintf("%d/%d", a.agree, a.total)
+}
+
+// writeThreeWay prints, for each file on which the Go parser, the
+// reference implementation and the expected output do not all agree, a
+// line naming the sources that differ and a unified diff of each against
+// the expected output, or of Go against Rust for a file without one. It
+// ends with a matrix of how many files each pair agrees on, and returns
+// the number of files with a disagreement.
+func writeThreeWay(w io.Writer, results []*threeWayResult) (int, error) {
+	var goRust, goWant, rustWant agreement
+	mismatched := 0
+	for _, r := range results {
+		name := filepath.ToSlash(r.relative)
+		sameGoRust := goRust.add(r.goOut, r.rust)
+		if !r.hasWant {
+			if !sameGoRust {
+				mismatched++
+				fmt.Fprintf(w, "MISMATCH %s: go and rust differ, no expected output\n", r.relative)
+				fmt.Fprint(w, udiff.Unified(r.rust+"\n", r.goOut+"\n", "rust/"+name, "go/"+name))
+			}
+			continue
+		}
+		sameGo, sameRust := goWant.add(r.goOut, r.want), rustWant.add(r.rust, r.want)
+		if sameGo && sameRust {
+			continue
+		}
+		mismatched++
+		var differ []string
+		switch {
+		case !sameGo && !sameRust && sameGoRust:
+			differ = append(differ, "expected output")
+		default:
+			if !sameGo {
+				differ = append(differ, "go")
+			}
+			if !sameRust {
+				differ = append(differ, "rust")
+			}
+		}
+		verb := "differs"
+		if len(differ) > 1 {
+			verb = "differ"
+		}
+		fmt.Fprintf(w, "MISMATCH %s: %s %s\n", r.relative, strings.Join(differ, " and "), verb)
+		if !sameGo {
+			fmt.Fprint(w, udiff.Unified(r.want+"\n", r.goOut+"\n", "expected/"+name, "go/"+name))
+		}
+		if !sameRust && !sameGoRust {
+			fmt.Fprint(w, udiff.Unified(r.want+"\n", r.rust+"\n", "expected/"+name, "rust/"+name))
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\trust\texpected")
+	fmt.Fprintf(tw, "go\t%s\t%s\n", goRust, goWant)
+	fmt.Fprintf(tw, "rust\t\t%s\n", rustWant)
+	if err := tw.Flush(); err != nil {
+		return 0, err
+	}
+	fmt.Fprintf(w, "%d files, %d agree, %d mismatched\n", len(results), len(results)-mismatched, mismatched)
+	return mismatched, nil
+}
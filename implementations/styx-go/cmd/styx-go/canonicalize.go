@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+	"github.com/bearcove/styx/implementations/styx-go/internal/udiff"
+)
+
+var canonicalizeCommand = &command{
+	name:    "canonicalize",
+	usage:   "[--order source|sorted] [--first keys] [--check] [path...]",
+	summary: "Rewrite Styx files in canonical form, or check that they are.",
+	run:     runCanonicalize,
+}
+
+var keyOrders = map[string]styx.KeyOrder{
+	"source": styx.OrderSource,
+	"sorted": styx.OrderSorted,
+}
+
+// runCanonicalize rewrites each file, or each .styx file under a
+// directory, in the canonical form of styx.Canonical, listing the files
+// changed; stdin, the default, is written to stdout. With --check nothing
+// is written: the command prints a unified diff for each file not in
+// canonical form and exits with status 1 if there are any. Files that fail
+// to parse, hold comments the canonical form would drop, or whose canonical
+// form would not read back with the same content, are reported and left
+// alone, and also exit with status 1.
+func runCanonicalize(c *cli, cmd *command, args []string) error {
+	flags := c.flagSet(cmd)
+	orderName := flags.String("order", "source", "order of entries: source or sorted")
+	first := flags.String("first", "", "comma-separated keys to put before the others of their object, in this order")
+	check := flags.Bool("check", false, "print a diff for each file not in canonical form instead of rewriting it")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	order, ok := keyOrders[*orderName]
+	if !ok {
+		return usageError(fmt.Sprintf("unknown order %q, expected source or sorted", *orderName))
+	}
+	opts := styx.CanonicalOptions{Order: order}
+	for _, key := range strings.Split(*first, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			opts.First = append(opts.First, key)
+		}
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	files, err := styxFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, path := range files {
+		name, content, err := c.readFile(path)
+		if err != nil {
+			return err
+		}
+		doc, err := c.parse(name, content)
+		var exit exitError
+		if errors.As(err, &exit) {
+			failed = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if attached := attachedComments(doc.Entries); attached < len(doc.Comments) {
+			fmt.Fprintf(c.stderr, "styx-go canonicalize: %s: skipped, as %s not before or after an entry would be lost\n", name, plural(len(doc.Comments)-attached, "comment"))
+			failed = true
+			continue
+		}
+		canonical := styx.Canonical(doc, opts)
+		// Never write a canonical form that reads back as something else.
+		// Diff compares content as Hash does, but key by key, so sorting
+		// entries does not count.
+		if again, err := styx.Parse(string(canonical)); err != nil || len(styx.Diff(doc, again)) > 0 {
+			fmt.Fprintf(c.stderr, "styx-go canonicalize: %s: skipped, as its canonical form does not read back the same\n", name)
+			failed = true
+			continue
+		}
+		switch {
+		case *check:
+			if !bytes.Equal(content, canonical) {
+				fmt.Fprint(c.stdout, udiff.Unified(string(content), string(canonical), name, name+" (canonical)"))
+				failed = true
+			}
+		case path == "-":
+			if _, err := c.stdout.Write(canonical); err != nil {
+				return err
+			}
+		case !bytes.Equal(content, canonical):
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, canonical, info.Mode().Perm()); err != nil {
+				return err
+			}
+			fmt.Fprintln(c.stdout, name)
+		}
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}
+
+// attachedComments counts the leading and trailing comments of entries and
+// the entries nested in their keys and values, which are the comments
+// Format writes.
+func attachedComments(entries []*styx.Entry) int {
+	n := 0
+	for _, e := range entries {
+		n += len(e.LeadingComments)
+		if e.TrailingComment != nil {
+			n++
+		}
+		n += valueComments(e.Key) + valueComments(e.Value)
+	}
+	return n
+}
+
+func valueComments(v *styx.Value) int {
+	switch v.PayloadKind {
+	case styx.PayloadObject:
+		return attachedComments(v.Object.Entries)
+	case styx.PayloadSequence:
+		n := 0
+		for _, item := range v.Sequence.Items {
+			n += valueComments(item)
+		}
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	messy := "// app\nname \"app\"\nopts {b 1, a r#\"two words\"#}\nport 80 // http\n"
+	canonical := "// app\nname app\nopts {\n    b 1\n    a \"two words\"\n}\nport 80 // http\n"
+	path := writeFile(t, "app.styx", messy)
+
+	stdout, stderr, code := runCLI(t, "", "canonicalize", "--check", path)
+	if code != 1 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	for _, want := range []string{"--- " + path + "\n+++ " + path + " (canonical)\n", "-name \"app\"\n", "+name app\n", "+    a \"two words\"\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("diff lacks %q:\n%s", want, stdout)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != messy {
+		t.Errorf("--check rewrote the file:\n%s", data)
+	}
+
+	stdout, _, code = runCLI(t, "", "canonicalize", path)
+	if code != 0 || stdout != path+"\n" {
+		t.Errorf("rewrite: status %d, output %q", code, stdout)
+	}
+	if data, _ := os.ReadFile(path); string(data) != canonical {
+		t.Errorf("rewritten file:\n%s\nwant\n%s", data, canonical)
+	}
+	if stdout, _, code := runCLI(t, "", "canonicalize", "--check", path); code != 0 || stdout != "" {
+		t.Errorf("canonical file: status %d, output %q", code, stdout)
+	}
+
+	stdout, _, _ = runCLI(t, messy, "canonicalize", "--order", "sorted", "--first", "port")
+	if want := "port 80 // http\n// app\nname app\nopts {\n    a \"two words\"\n    b 1\n}\n"; stdout != want {
+		t.Errorf("stdin, sorted: got\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	dangling := writeFile(t, "dangling.styx", "a 1\n// trailing\n")
+	if _, stderr, code := runCLI(t, "", "canonicalize", dangling); code != 1 || !strings.Contains(stderr, "1 comment not before or after an entry") {
+		t.Errorf("dangling comment: status %d, stderr %q", code, stderr)
+	}
+	if data, _ := os.ReadFile(dangling); string(data) != "a 1\n// trailing\n" {
+		t.Errorf("file with a dangling comment rewritten:\n%s", data)
+	}
+	bad := writeFile(t, "bad.styx", "a {\n")
+	if _, stderr, code := runCLI(t, "", "canonicalize", bad); code != 1 || !strings.Contains(stderr, "error[STYX0003]") {
+		t.Errorf("parse error: status %d, stderr %q", code, stderr)
+	}
+	if _, _, code := runCLI(t, "", "canonicalize", "--order", "random"); code != 2 {
+		t.Errorf("unknown order: status %d, want 2", code)
+	}
+}
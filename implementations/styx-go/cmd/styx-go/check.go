@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+var checkCommand = &command{
+	name:    "check",
+	usage:   "[path...]",
+	summary: "Check that Styx files parse, for a CI gate.",
+	run:     runCheck,
+}
+
+// runCheck parses every .styx file under the paths, which default to the
+// current directory, printing each file's errors and then how many files
+// passed and failed. It exits with status 1 if any file has an error, or
+// if there are no files, which is more likely a mistake than a pass.
+// Warnings are left to lint.
+func runCheck(c *cli, cmd *command, args []string) error {
+	flags := c.flagSet(cmd)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := styxFiles(paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("no .styx files found")
+	}
+
+	failed := 0
+	for _, path := range files {
+		path, content, err := c.readFile(path)
+		if err != nil {
+			return err
+		}
+		source := string(content)
+		_, diags := styx.Diagnose(source, styx.ParseOptions{Filename: path})
+		var errs []*styx.Diagnostic
+		for _, d := range diags {
+			if d.Severity == styx.SeverityError {
+				errs = append(errs, d)
+			}
+		}
+		if len(errs) == 0 {
+			continue
+		}
+		if failed > 0 {
+			fmt.Fprintln(c.stdout)
+		}
+		failed++
+		fmt.Fprint(c.stdout, styx.RenderDiagnostics(source, errs, styx.RenderOptions{Color: c.colorStdout, Context: 1, Filename: path}))
+	}
+
+	if failed > 0 {
+		fmt.Fprintln(c.stdout)
+	}
+	fmt.Fprintf(c.stdout, "%s checked: %d passed, %d failed\n", plural(len(files), "file"), len(files)-failed, failed)
+	if failed > 0 {
+		return exitError(1)
+	}
+	return nil
+}
+
+// plural formats n and noun, adding an s unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
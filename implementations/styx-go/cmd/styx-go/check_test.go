@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"good.styx":       "name: app\n",
+		"nested/bad.styx": "a {\nb (1\n",
+		"other.txt":       "a {\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, stderr, code := runCLI(t, "", "check", dir)
+	if code != 1 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	if n := strings.Count(stdout, "error[STYX"); n < 2 {
+		t.Errorf("want every error of bad.styx, got %d:\n%s", n, stdout)
+	}
+	for _, want := range []string{filepath.Join("nested", "bad.styx") + ":1:3", "\n\n2 files checked: 1 passed, 1 failed\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "warning") || strings.Contains(stdout, "other.txt") {
+		t.Errorf("warnings or non-.styx files reported:\n%s", stdout)
+	}
+
+	if stdout, _, code := runCLI(t, "", "check", filepath.Join(dir, "good.styx")); code != 0 || stdout != "1 file checked: 1 passed, 0 failed\n" {
+		t.Errorf("good file: status %d, output %q", code, stdout)
+	}
+	if stdout, _, code := runCLI(t, "a 1\n", "check", "-"); code != 0 || stdout != "1 file checked: 1 passed, 0 failed\n" {
+		t.Errorf("stdin: status %d, output %q", code, stdout)
+	}
+	if _, stderr, code := runCLI(t, "", "check", t.TempDir()); code != 1 || !strings.Contains(stderr, "no .styx files found") {
+		t.Errorf("empty directory: status %d, stderr %q", code, stderr)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+	"github.com/bearcove/styx/implementations/styx-go/styxtoml"
+	"github.com/bearcove/styx/implementations/styx-go/styxyaml"
+)
+
+var convertCommand = &command{
+	name:    "convert",
+	usage:   "[--from format] [--to format] [file]",
+	summary: "Convert between Styx, JSON, YAML and TOML.",
+	run:     runConvert,
+}
+
+// formatExtensions maps file extensions to the formats convert reads.
+var formatExtensions = map[string]string{
+	".styx": "styx",
+	".json": "json",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".toml": "toml",
+}
+
+var tagModes = map[string]styx.TagMode{
+	"wrap":          styx.TagWrap,
+	"discriminator": styx.TagDiscriminator,
+	"drop":          styx.TagDrop,
+}
+
+// runConvert converts the file, or stdin, to another format on stdout.
+// Conversions between two other formats go through Styx.
+func runConvert(c *cli, cmd *command, args []string) error {
+	fs := c.flagSet(cmd)
+	from := fs.String("from", "", "input format: styx, json, yaml or toml (default from the file extension, or styx)")
+	to := fs.String("to", "", "output format: styx, json, yaml or toml (default styx, or json from styx)")
+	tags := fs.String("tags", "wrap", "how JSON represents tags: wrap, discriminator or drop")
+	typed := fs.Bool("infer-types", true, "write bare numbers and booleans as JSON numbers and booleans")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *from == "" {
+		*from = "styx"
+		if fs.NArg() == 1 {
+			if format, ok := formatExtensions[strings.ToLower(filepath.Ext(fs.Arg(0)))]; ok {
+				*from = format
+			}
+		}
+	}
+	if *to == "" {
+		*to = "styx"
+		if *from == "styx" {
+			*to = "json"
+		}
+	}
+	for _, format := range []string{*from, *to} {
+		switch format {
+		case "styx", "json", "yaml", "toml":
+		default:
+			return usageError(fmt.Sprintf("unknown format %q, expected styx, json, yaml or toml", format))
+		}
+	}
+	mode, ok := tagModes[*tags]
+	if !ok {
+		return usageError(fmt.Sprintf("unknown tag mode %q, expected wrap, discriminator or drop", *tags))
+	}
+	jsonOpts := styx.JSONOptions{Tags: mode, InferTypes: *typed, Indent: "  "}
+
+	name, data, err := c.readInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	var doc *styx.Document
+	switch *from {
+	case "styx":
+		doc, err = c.parse(name, data)
+	case "json":
+		doc, err = styx.FromJSON(data, jsonOpts)
+	case "yaml":
+		doc, err = styxyaml.FromYAML(data)
+	case "toml":
+		doc, err = styxtoml.FromTOML(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	switch *to {
+	case "styx":
+		out = styx.Format(doc)
+	case "json":
+		out = append(styx.ToJSON(doc, jsonOpts), '\n')
+	case "yaml":
+		out, err = styxyaml.ToYAML(doc)
+	case "toml":
+		out, err = styxtoml.ToTOML(doc)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(out)
+	return err
+}
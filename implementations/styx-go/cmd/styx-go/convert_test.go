@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	yaml := writeFile(t, "app.yaml", "name: app\nserver:\n  port: 8080\n")
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+	}{
+		{"styx to json by default", "a.b (1 x)\nc @env\"X\"\n", nil, `{
+  "a": {
+    "b": [
+      1,
+      "x"
+    ]
+  },
+  "c": {
+    "$tag": "env",
+    "$value": "X"
+  }
+}
+`},
+		{"json to styx by default", `{"a": {"$tag": "pg", "host": "db"}}`, []string{"--from", "json", "--tags", "discriminator"}, "a @pg{\n    host db\n}\n"},
+		{"format from extension", "", []string{yaml}, "name app\nserver {\n    port 8080\n}\n"},
+		{"yaml to toml", "", []string{"--to", "toml", yaml}, "name = \"app\"\n\n[server]\nport = 8080\n"},
+		{"styx to yaml", "a (1 2)\n", []string{"--to", "yaml"}, "a:\n  - 1\n  - 2\n"},
+		{"untyped json", "n 1\n", []string{"--infer-types=false"}, "{\n  \"n\": \"1\"\n}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runCLI(t, tt.stdin, append([]string{"convert"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("status %d: %s", code, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	if _, stderr, code := runCLI(t, "a {", "convert"); code != 1 || !strings.Contains(stderr, "<stdin>:1:3") {
+		t.Errorf("parse error: status %d, stderr %q", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, "[1]", "convert", "--from", "json"); code != 1 || !strings.Contains(stderr, "JSON object") {
+		t.Errorf("json error: status %d, stderr %q", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, "a @x", "convert", "--to", "toml"); code != 1 || stderr == "" {
+		t.Errorf("toml error: status %d, stderr %q", code, stderr)
+	}
+	for _, args := range [][]string{
+		{"--from", "ini"},
+		{"--to", "xml"},
+		{"--tags", "keep"},
+		{"a.styx", "b.styx"},
+	} {
+		if _, _, code := runCLI(t, "", append([]string{"convert"}, args...)...); code != 2 {
+			t.Errorf("%v: status %d, want 2", args, code)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+var diffCommand = &command{
+	name:    "diff",
+	usage:   "[--ignore-formatting] old.styx new.styx",
+	summary: "Print the paths whose values differ between two documents.",
+	run:     runDiff,
+}
+
+// ANSI colors of diff lines by their first character.
+var diffColors = map[byte]string{
+	'+': "\x1b[32m",
+	'-': "\x1b[31m",
+	'~': "\x1b[33m",
+}
+
+// runDiff prints the changes styx.Diff finds, one per line as
+// styx.FormatDiff writes them. As with diff, it exits with status 1 if the
+// files differ and 2 on trouble. Files that differ only in comments or
+// formatting are reported as such, or equal under --ignore-formatting.
+func runDiff(c *cli, cmd *command, args []string) error {
+	fs := c.flagSet(cmd)
+	ignore := fs.Bool("ignore-formatting", false, "treat files differing only in comments or formatting as equal")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError("expected two files")
+	}
+	var docs [2]*styx.Document
+	var sources [2][]byte
+	for i, path := range fs.Args() {
+		name, data, err := c.readFile(path)
+		if err == nil {
+			docs[i], err = c.parse(name, data)
+		}
+		if err != nil {
+			// Status 1 means the files differ, so trouble exits with 2.
+			if _, reported := err.(exitError); !reported {
+				fmt.Fprintf(c.stderr, "styx-go diff: %v\n", err)
+			}
+			return exitError(2)
+		}
+		sources[i] = data
+	}
+
+	changes := styx.Diff(docs[0], docs[1])
+	if len(changes) == 0 {
+		if *ignore || bytes.Equal(sources[0], sources[1]) {
+			return nil
+		}
+		fmt.Fprintln(c.stdout, "The files differ only in comments or formatting.")
+		return exitError(1)
+	}
+	for _, line := range strings.SplitAfter(styx.FormatDiff(docs[0], docs[1], changes), "\n") {
+		if line == "" {
+			continue
+		}
+		if color, ok := diffColors[line[0]]; ok && c.colorStdout {
+			line = color + strings.TrimSuffix(line, "\n") + "\x1b[0m\n"
+		}
+		fmt.Fprint(c.stdout, line)
+	}
+	return exitError(1)
+}
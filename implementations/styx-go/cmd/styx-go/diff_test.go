@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := writeFile(t, "old.styx", "// settings\nserver {host localhost, port 8080}\nhosts (a b)\nname app\n")
+	changed := writeFile(t, "new.styx", "server.host localhost\nserver.port 9090\nhosts (a)\nmode fast\nname app\n")
+	reformatted := writeFile(t, "same.styx", "name app\nhosts (a b)\nserver {\n  port 8080\n  host localhost\n}\n")
+
+	stdout, stderr, code := runCLI(t, "", "diff", old, changed)
+	if code != 1 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	if want := "~ server.port 8080 -> 9090\n- hosts.1 b\n+ mode fast\n"; stdout != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout, want)
+	}
+
+	c := &cli{stdin: strings.NewReader(""), stdout: &strings.Builder{}, stderr: &strings.Builder{}, colorStdout: true}
+	c.run([]string{"diff", old, changed})
+	if colored := c.stdout.(*strings.Builder).String(); !strings.Contains(colored, "\x1b[33m~ server.port") {
+		t.Errorf("no color: %q", colored)
+	}
+
+	stdout, _, code = runCLI(t, "", "diff", old, reformatted)
+	if code != 1 || !strings.Contains(stdout, "only in comments or formatting") {
+		t.Errorf("reformatted: status %d, output %q", code, stdout)
+	}
+	if stdout, _, code := runCLI(t, "", "diff", "--ignore-formatting", old, reformatted); code != 0 || stdout != "" {
+		t.Errorf("--ignore-formatting: status %d, output %q", code, stdout)
+	}
+	if stdout, _, code := runCLI(t, "", "diff", old, old); code != 0 || stdout != "" {
+		t.Errorf("same file: status %d, output %q", code, stdout)
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	good := writeFile(t, "good.styx", "a 1\n")
+	bad := writeFile(t, "bad.styx", "a {\n")
+	if _, _, code := runCLI(t, "", "diff", good); code != 2 {
+		t.Errorf("one file: status %d, want 2", code)
+	}
+	if _, stderr, code := runCLI(t, "", "diff", good, bad); code != 2 || !strings.Contains(stderr, "bad.styx:1:3") {
+		t.Errorf("parse error: status %d, stderr %q", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, "", "diff", good, good+".missing"); code != 2 || !strings.Contains(stderr, "missing") {
+		t.Errorf("missing file: status %d, stderr %q", code, stderr)
+	}
+}
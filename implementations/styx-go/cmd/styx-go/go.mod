@@ -0,0 +1,20 @@
+module github.com/bearcove/styx/implementations/styx-go/cmd/styx-go
+
+go 1.22
+
+require (
+	github.com/bearcove/styx/implementations/styx-go v0.0.0
+	github.com/bearcove/styx/implementations/styx-go/styxtoml v0.0.0
+	github.com/bearcove/styx/implementations/styx-go/styxyaml v0.0.0
+)
+
+require (
+	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/bearcove/styx/implementations/styx-go => ../../
+	github.com/bearcove/styx/implementations/styx-go/styxtoml => ../../styxtoml
+	github.com/bearcove/styx/implementations/styx-go/styxyaml => ../../styxyaml
+)
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+var highlightCommand = &command{
+	name:    "highlight",
+	usage:   "[--format ansi|html] [--fragment] [file]",
+	summary: "Print a file with syntax highlighting, for a terminal or as HTML.",
+	run:     runHighlight,
+}
+
+// highlightANSI is the terminal style of each kind of region.
+var highlightANSI = map[styx.HighlightKind]string{
+	styx.HighlightKey:              "\x1b[34m",
+	styx.HighlightScalar:           "\x1b[33m",
+	styx.HighlightString:           "\x1b[32m",
+	styx.HighlightTag:              "\x1b[35m",
+	styx.HighlightPunctuation:      "\x1b[90m",
+	styx.HighlightComment:          "\x1b[3;90m",
+	styx.HighlightHeredocDelimiter: "\x1b[36m",
+	styx.HighlightError:            "\x1b[4;31m",
+}
+
+// highlightCSS styles the classes of the HTML format in a standalone page.
+const highlightCSS = `pre.styx { padding: 1em; background: #fafafa; color: #24292e; }
+.styx-key { color: #005cc5; }
+.styx-scalar { color: #b08800; }
+.styx-string { color: #22863a; }
+.styx-tag { color: #6f42c1; }
+.styx-punctuation { color: #6a737d; }
+.styx-comment { color: #6a737d; font-style: italic; }
+.styx-heredoc-delimiter { color: #0598bc; }
+.styx-error { color: #cb2431; text-decoration: wavy underline; }
+`
+
+// runHighlight prints a file, or stdin, highlighted with ANSI colors, even
+// when stdout is not a terminal, so that it can be piped to less -R, or as
+// an HTML page whose regions are spans with the classes styx-key,
+// styx-string and so on. With --fragment, the HTML is only the <pre>
+// element, to embed in a page that supplies its own styles. Highlighting
+// works from the tokens, so invalid files are highlighted too, their bad
+// text as errors.
+func runHighlight(c *cli, cmd *command, args []string) error {
+	fs := c.flagSet(cmd)
+	format := fs.String("format", "ansi", "output format: ansi or html")
+	fragment := fs.Bool("fragment", false, "print only the <pre> element of the html format")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch *format {
+	case "ansi":
+		if *fragment {
+			return usageError("--fragment needs the html format")
+		}
+	case "html":
+	default:
+		return usageError(fmt.Sprintf("unknown format %q, expected ansi or html", *format))
+	}
+	name, data, err := c.readInput(fs.Args())
+	if err != nil {
+		return err
+	}
+	source := string(data)
+
+	if *format == "ansi" {
+		writeHighlighted(c.stdout, source, func(kind styx.HighlightKind, text string) string {
+			// Reset at each line break, so that pagers showing part of a
+			// region still style it.
+			lines := strings.Split(text, "\n")
+			for i, line := range lines {
+				if line != "" {
+					lines[i] = highlightANSI[kind] + line + "\x1b[0m"
+				}
+			}
+			return strings.Join(lines, "\n")
+		}, func(text string) string { return text })
+		return nil
+	}
+
+	if !*fragment {
+		fmt.Fprintf(c.stdout, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n", html.EscapeString(name), highlightCSS)
+	}
+	fmt.Fprint(c.stdout, `<pre class="styx"><code>`)
+	// The closing tag follows the last line, not a blank one.
+	source = strings.TrimSuffix(source, "\n")
+	writeHighlighted(c.stdout, source, func(kind styx.HighlightKind, text string) string {
+		return `<span class="styx-` + kind.String() + `">` + html.EscapeString(text) + "</span>"
+	}, html.EscapeString)
+	fmt.Fprintln(c.stdout, "</code></pre>")
+	if !*fragment {
+		fmt.Fprintln(c.stdout, "</body>\n</html>")
+	}
+	return nil
+}
+
+// writeHighlighted writes source to w, each highlighted region as region
+// formats it and the text between regions as plain formats it.
+func writeHighlighted(w io.Writer, source string, region func(styx.HighlightKind, string) string, plain func(string) string) {
+	var sb strings.Builder
+	pos := 0
+	for _, h := range styx.Highlight(source) {
+		sb.WriteString(plain(source[pos:h.Span.Start]))
+		sb.WriteString(region(h.Kind, source[h.Span.Start:h.Span.End]))
+		pos = h.Span.End
+	}
+	sb.WriteString(plain(source[pos:]))
+	io.WriteString(w, sb.String())
+}
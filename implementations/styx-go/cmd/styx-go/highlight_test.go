@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	path := writeFile(t, "app.styx", "// app\nname \"a<b\"\nh <<EOF\n  x\n  EOF\n")
+
+	stdout, stderr, code := runCLI(t, "", "highlight", path)
+	if code != 0 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	want := "\x1b[3;90m// app\x1b[0m\n" +
+		"\x1b[34mname\x1b[0m \x1b[32m\"a<b\"\x1b[0m\n" +
+		"\x1b[34mh\x1b[0m \x1b[36m<<EOF\x1b[0m\n" +
+		"\x1b[32m  x\x1b[0m\n" +
+		"  \x1b[36mEOF\x1b[0m\n"
+	if stdout != want {
+		t.Errorf("ansi: got %q, want %q", stdout, want)
+	}
+
+	stdout, _, _ = runCLI(t, "", "highlight", "--format", "html", "--fragment", path)
+	want = `<pre class="styx"><code><span class="styx-comment">// app</span>` + "\n" +
+		`<span class="styx-key">name</span> <span class="styx-string">&#34;a&lt;b&#34;</span>` + "\n" +
+		`<span class="styx-key">h</span> <span class="styx-heredoc-delimiter">&lt;&lt;EOF</span>` + "\n" +
+		`<span class="styx-string">  x</span>` + "\n" +
+		`  <span class="styx-heredoc-delimiter">EOF</span></code></pre>` + "\n"
+	if stdout != want {
+		t.Errorf("html fragment: got\n%s\nwant\n%s", stdout, want)
+	}
+
+	stdout, _, _ = runCLI(t, "bad \"\\q\"\n", "highlight", "--format", "html")
+	for _, part := range []string{"<!DOCTYPE html>", "<title>&lt;stdin&gt;</title>", ".styx-key {", `<span class="styx-error">\q</span>`, "</html>\n"} {
+		if !strings.Contains(stdout, part) {
+			t.Errorf("html page lacks %q:\n%s", part, stdout)
+		}
+	}
+
+	for _, args := range [][]string{{"--format", "svg"}, {"--fragment"}, {path, path}} {
+		if _, _, code := runCLI(t, "", append([]string{"highlight"}, args...)...); code != 2 {
+			t.Errorf("%v: status %d, want 2", args, code)
+		}
+	}
+}
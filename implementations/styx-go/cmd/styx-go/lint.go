@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+var lintCommand = &command{
+	name:    "lint",
+	usage:   "[--format text|json|sarif] [--enable rules] [--disable rules] [path...]",
+	summary: "Report errors and suspicious constructs in Styx files.",
+	run:     runLint,
+}
+
+// lintRule is a warning the parser, or the command itself, can report,
+// named for the command line.
+type lintRule struct {
+	name    string
+	code    styx.Code
+	summary string
+	// optIn rules are only reported when --enable names them.
+	optIn bool
+}
+
+var lintRules = []lintRule{
+	{"key-colon", styx.CodeKeyEndsWithColon, "bare key ending in `:`, as in YAML", false},
+	{"mixed-indentation", styx.CodeMixedIndentation, "heredoc indentation mixing tabs and spaces", false},
+	{"deep-nesting", styx.CodeDeepNesting, "nesting deeper than --nesting-depth", false},
+	{"invisible-characters", styx.CodeInvisibleCharacter, "invisible or ambiguous characters in bare scalars", false},
+	{"canonical", styx.CodeNotCanonical, "file not in the form 'styx-go canonicalize' writes", true},
+}
+
+// findLintRule returns the rule with the given name or code.
+func findLintRule(name string) (lintRule, bool) {
+	for _, rule := range lintRules {
+		if rule.name == name || string(rule.code) == name {
+			return rule, true
+		}
+	}
+	return lintRule{}, false
+}
+
+// ruleSet parses a comma-separated list of rule names or codes.
+func ruleSet(list string) (map[styx.Code]bool, error) {
+	set := make(map[styx.Code]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		rule, ok := findLintRule(name)
+		if !ok {
+			return nil, usageError(fmt.Sprintf("unknown rule %q; run 'styx-go lint --rules' for the list", name))
+		}
+		set[rule.code] = true
+	}
+	return set, nil
+}
+
+// runLint reports the problems in every .styx file under the paths, which
+// default to the current directory, and exits with status 1 if there are
+// any. Parse errors are always reported; the rules select warnings.
+func runLint(c *cli, cmd *command, args []string) error {
+	flags := c.flagSet(cmd)
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	enable := flags.String("enable", "", "comma-separated rules to report, instead of all of them")
+	disable := flags.String("disable", "", "comma-separated rules not to report")
+	depth := flags.Int("nesting-depth", styx.DefaultNestingWarningDepth, "nesting depth past which deep-nesting reports")
+	list := flags.Bool("rules", false, "list the rules and exit")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *list {
+		w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		for _, rule := range lintRules {
+			summary := rule.summary
+			if rule.optIn {
+				summary += " (off unless enabled)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", rule.name, rule.code, summary)
+		}
+		return w.Flush()
+	}
+	switch *format {
+	case "text", "json", "sarif":
+	default:
+		return usageError(fmt.Sprintf("unknown format %q, expected text, json or sarif", *format))
+	}
+	enabled, err := ruleSet(*enable)
+	if err != nil {
+		return err
+	}
+	if *enable == "" {
+		for _, rule := range lintRules {
+			enabled[rule.code] = !rule.optIn
+		}
+	}
+	disabled, err := ruleSet(*disable)
+	if err != nil {
+		return err
+	}
+	for code := range disabled {
+		delete(enabled, code)
+	}
+	opts := styx.ParseOptions{
+		NestingWarningDepth:      *depth,
+		CheckInvisibleCharacters: enabled[styx.CodeInvisibleCharacter],
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := styxFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	var found []styx.JSONDiagnostic
+	problems := 0
+	for _, path := range files {
+		path, content, err := c.readFile(path)
+		if err != nil {
+			return err
+		}
+		source := string(content)
+		opts.Filename = path
+		doc, diags := styx.Diagnose(source, opts)
+		if enabled[styx.CodeNotCanonical] && !hasErrors(diags) {
+			if d := canonicalDiagnostic(source, doc); d != nil {
+				diags = append(diags, d)
+			}
+		}
+		var kept []*styx.Diagnostic
+		for _, d := range diags {
+			if d.Severity == styx.SeverityError || enabled[d.Code] {
+				kept = append(kept, d)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		problems += len(kept)
+		if *format != "text" {
+			for _, d := range kept {
+				found = append(found, d.JSON(path, source))
+			}
+			continue
+		}
+		if problems > len(kept) {
+			fmt.Fprintln(c.stdout)
+		}
+		fmt.Fprint(c.stdout, styx.RenderDiagnostics(source, kept, styx.RenderOptions{Color: c.colorStdout, Context: 1, Filename: path}))
+	}
+
+	switch *format {
+	case "json":
+		err = styx.WriteDiagnosticsJSON(c.stdout, found)
+	case "sarif":
+		err = styx.WriteSARIF(c.stdout, found)
+	}
+	if err != nil {
+		return err
+	}
+	if problems > 0 {
+		return exitError(1)
+	}
+	return nil
+}
+
+// canonicalDiagnostic reports a document that differs from its canonical
+// form at the first line that does, with the rewrite as a fix. Documents
+// canonicalize would leave alone, for the comments it would drop, are not
+// reported.
+func canonicalDiagnostic(source string, doc *styx.Document) *styx.Diagnostic {
+	if attachedComments(doc.Entries) < len(doc.Comments) {
+		return nil
+	}
+	canonical := string(styx.Canonical(doc, styx.CanonicalOptions{}))
+	if source == canonical {
+		return nil
+	}
+	i := 0
+	for i < len(source) && i < len(canonical) && source[i] == canonical[i] {
+		i++
+	}
+	start := strings.LastIndexByte(source[:i], '\n') + 1
+	end := len(source)
+	if n := strings.IndexByte(source[start:], '\n'); n >= 0 {
+		end = start + n
+	}
+	return &styx.Diagnostic{
+		Code:     styx.CodeNotCanonical,
+		Severity: styx.SeverityWarning,
+		Message:  "not in canonical form",
+		Span:     styx.Span{Start: start, End: end},
+		Help:     "run 'styx-go canonicalize' to rewrite the file",
+		Fixes: []styx.Fix{{
+			Message: "rewrite in canonical form",
+			Edits:   []styx.Edit{{Span: styx.Span{Start: 0, End: len(source)}, Text: canonical}},
+		}},
+	}
+}
+
+// styxFiles returns the paths that name files, and the .styx files under
+// those that name directories, in order.
+func styxFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if path == "-" {
+			files = append(files, path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		var found []string
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.HasSuffix(p, ".styx") {
+				found = append(found, p)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"clean.styx":      "name app\n",
+		"colon.styx":      "name: app\n",
+		"nested/bad.styx": "a {\n",
+		"deep.styx":       "a {b {c {d 1}}}\n",
+		"other.txt":       "a {\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, stderr, code := runCLI(t, "", "lint", "--nesting-depth", "2", dir)
+	if code != 1 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	for _, want := range []string{"warning[STYX0023]", "warning[STYX0025]", "error[STYX0003]", filepath.Join("nested", "bad.styx")} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %s:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "other.txt") {
+		t.Errorf("non-.styx file linted:\n%s", stdout)
+	}
+
+	stdout, _, _ = runCLI(t, "", "lint", "--format", "json", "--disable", "key-colon,STYX0025", "--nesting-depth", "2", dir)
+	var report struct{ Diagnostics []map[string]any }
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("%v\n%s", err, stdout)
+	}
+	if diags := report.Diagnostics; len(diags) != 1 || diags[0]["code"] != "STYX0003" {
+		t.Errorf("disabled rules reported: %s", stdout)
+	}
+
+	stdout, _, _ = runCLI(t, "", "lint", "--format", "sarif", "--enable", "key-colon", "--nesting-depth", "2", filepath.Join(dir, "colon.styx"), filepath.Join(dir, "deep.styx"))
+	if !strings.Contains(stdout, `"STYX0023"`) || strings.Contains(stdout, `"STYX0025"`) {
+		t.Errorf("--enable did not select key-colon alone:\n%s", stdout)
+	}
+
+	if stdout, _, code := runCLI(t, "", "lint", filepath.Join(dir, "clean.styx")); code != 0 || stdout != "" {
+		t.Errorf("clean file: status %d, output %q", code, stdout)
+	}
+}
+
+func TestLintCanonical(t *testing.T) {
+	dir := t.TempDir()
+	styled := filepath.Join(dir, "styled.styx")
+	canonical := filepath.Join(dir, "canonical.styx")
+	if err := os.WriteFile(styled, []byte("name app\nserver {host h, port 80}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(canonical, []byte("name app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rule is off unless enabled.
+	if stdout, _, code := runCLI(t, "", "lint", styled); code != 0 || stdout != "" {
+		t.Errorf("default rules: status %d, output %q", code, stdout)
+	}
+	stdout, _, code := runCLI(t, "", "lint", "--enable", "canonical", styled, canonical)
+	if code != 1 || !strings.Contains(stdout, "warning[STYX0037]") || !strings.Contains(stdout, "styled.styx:2:1") || strings.Contains(stdout, "canonical.styx") {
+		t.Errorf("status %d, output:\n%s", code, stdout)
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	stdout, _, code := runCLI(t, "", "lint", "--rules")
+	if code != 0 || !strings.Contains(stdout, "deep-nesting") {
+		t.Errorf("status %d, output %q", code, stdout)
+	}
+	for _, args := range [][]string{
+		{"--enable", "nope"},
+		{"--disable", "STYX9999"},
+		{"--format", "xml"},
+	} {
+		if _, _, code := runCLI(t, "", append([]string{"lint"}, args...)...); code != 2 {
+			t.Errorf("%v: status %d, want 2", args, code)
+		}
+	}
+	if _, _, code := runCLI(t, "", "lint", filepath.Join(t.TempDir(), "missing")); code != 1 {
+		t.Errorf("missing path: status %d, want 1", code)
+	}
+}
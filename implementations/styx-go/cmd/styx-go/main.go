@@ -0,0 +1,209 @@
+// Command styx-go inspects and converts Styx documents with the Go
+// implementation, so that it can stand in for the reference `styx` tool on
+// machines without a Rust toolchain.
+//
+// Usage:
+//
+//	styx-go <command> [flags] [file...]
+//
+// A file argument of - reads stdin, as does a command given no file where
+// it takes one. Run `styx-go help` for the list of commands.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+// command is a subcommand. run parses the command's flags with
+// cli.parseFlags and returns an error to exit with status 1, a usageError
+// for status 2 or an exitError for a status of its own.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(c *cli, cmd *command, args []string) error
+}
+
+var commands = []*command{
+	treeCommand,
+	convertCommand,
+	canonicalizeCommand,
+	queryCommand,
+	lintCommand,
+	checkCommand,
+	statCommand,
+	diffCommand,
+	validateCommand,
+	highlightCommand,
+}
+
+// cli holds the streams commands read and write.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// color and colorStdout highlight diagnostics written to stderr and
+	// stdout.
+	color       bool
+	colorStdout bool
+	// stdinRead records that stdin has been read, so that it is not given
+	// twice.
+	stdinRead bool
+}
+
+// usageError reports a command invoked wrongly.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// exitError exits with a status without printing anything more, for
+// commands whose output already explains the failure.
+type exitError int
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func main() {
+	c := &cli{
+		stdin:       os.Stdin,
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		color:       styx.ColorEnabled(os.Stderr),
+		colorStdout: styx.ColorEnabled(os.Stdout),
+	}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// run runs the command named by args[0] and returns the exit status.
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return 2
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		if len(args) == 1 {
+			c.usage()
+			return 0
+		}
+		name, args = args[1], []string{args[1], "-h"}
+	}
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(c.stderr, "styx-go: unknown command %q\n\n", name)
+		c.usage()
+		return 2
+	}
+	err := cmd.run(c, cmd, args[1:])
+	var usage usageError
+	var exit exitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exit):
+		return int(exit)
+	case errors.As(err, &usage):
+		fmt.Fprintf(c.stderr, "styx-go %s: %v\nRun 'styx-go help %s' for usage.\n", cmd.name, err, cmd.name)
+		return 2
+	}
+	fmt.Fprintf(c.stderr, "styx-go %s: %v\n", cmd.name, err)
+	return 1
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "Usage: styx-go <command> [flags] [file...]")
+	fmt.Fprintln(c.stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(c.stderr, "\nA file argument of - reads stdin.")
+	fmt.Fprintln(c.stderr, "Run 'styx-go help <command>' for a command's flags.")
+}
+
+// flagSet returns an empty flag set for cmd, printing its usage on errors.
+func (c *cli) flagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet("styx-go "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: styx-go %s %s\n\n%s\n", cmd.name, cmd.usage, cmd.summary)
+		flags := false
+		fs.VisitAll(func(*flag.Flag) { flags = true })
+		if flags {
+			fmt.Fprintln(c.stderr, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseFlags parses args with fs. The flag package has already reported
+// any error, so the error returned only sets the exit status.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return exitError(0)
+	case err != nil:
+		return exitError(2)
+	}
+	return nil
+}
+
+// readInput reads the file named by args, or stdin if there is none, and
+// returns a name for it and its contents.
+func (c *cli) readInput(args []string) (name string, data []byte, err error) {
+	switch len(args) {
+	case 0:
+		return c.readFile("-")
+	case 1:
+		return c.readFile(args[0])
+	}
+	return "", nil, usageError("more than one file given")
+}
+
+// readFile reads the file at path, or stdin if path is "-", and returns a
+// name for it: path, or "<stdin>".
+func (c *cli) readFile(path string) (name string, data []byte, err error) {
+	if path != "-" {
+		data, err = os.ReadFile(path)
+		return path, data, err
+	}
+	if c.stdinRead {
+		return "", nil, usageError("stdin given more than once")
+	}
+	c.stdinRead = true
+	data, err = io.ReadAll(c.stdin)
+	return "<stdin>", data, err
+}
+
+// parse parses the Styx source data named name, printing a parse error as
+// a rendered diagnostic.
+func (c *cli) parse(name string, data []byte) (*styx.Document, error) {
+	source := string(data)
+	doc, err := styx.ParseWithOptions(source, styx.ParseOptions{Filename: name})
+	var pe *styx.ParseError
+	if errors.As(err, &pe) {
+		opts := styx.RenderOptions{Color: c.color, Filename: name}
+		fmt.Fprint(c.stderr, styx.RenderDiagnostics(source, []*styx.Diagnostic{pe.Diagnostic()}, opts))
+		return nil, exitError(1)
+	}
+	return doc, err
+}
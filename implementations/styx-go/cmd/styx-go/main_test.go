@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs styx-go with args and returns its output and exit status.
+func runCLI(t *testing.T, stdin string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &out, stderr: &errOut}
+	code = c.run(args)
+	return out.String(), errOut.String(), code
+}
+
+// writeFile writes content to name in a temporary directory and returns
+// its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUsage(t *testing.T) {
+	if _, stderr, code := runCLI(t, ""); code != 2 || !strings.Contains(stderr, "tree") {
+		t.Errorf("no arguments: status %d, stderr %q", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, "", "help"); code != 0 || !strings.Contains(stderr, "Commands:") {
+		t.Errorf("help: status %d, stderr %q", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, "", "help", "tree"); code != 0 || !strings.Contains(stderr, "-format") {
+		t.Errorf("help tree: status %d, stderr %q", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, "", "frobnicate"); code != 2 || !strings.Contains(stderr, `unknown command "frobnicate"`) {
+		t.Errorf("unknown command: status %d, stderr %q", code, stderr)
+	}
+	if _, _, code := runCLI(t, "", "tree", "--bogus"); code != 2 {
+		t.Errorf("unknown flag: status %d", code)
+	}
+}
+
+func TestStdin(t *testing.T) {
+	file := writeFile(t, "a.styx", "a 1\n")
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"tree", file, "-"}, 0, "; file: <stdin>\n"},
+		{[]string{"convert", "-"}, 0, `"a": 2`},
+		{[]string{"query", "a", "-"}, 0, "2\n"},
+		{[]string{"diff", file, "-"}, 1, "~ a 1 -> 2\n"},
+		{[]string{"lint", "-"}, 0, ""},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := runCLI(t, "a 2\n", tt.args...)
+		if code != tt.code || !strings.Contains(stdout, tt.want) {
+			t.Errorf("%v: status %d, output %q, stderr %q", tt.args, code, stdout, stderr)
+		}
+	}
+	if stdout, _, code := runCLI(t, "a {", "lint", "-"); code != 1 || !strings.Contains(stdout, "<stdin>:1:3") {
+		t.Errorf("lint stdin with an error: status %d, output %q", code, stdout)
+	}
+	if _, stderr, code := runCLI(t, "a 1", "diff", "-", "-"); code != 2 || !strings.Contains(stderr, "stdin given more than once") {
+		t.Errorf("stdin twice: status %d, stderr %q", code, stderr)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+var queryCommand = &command{
+	name:    "query",
+	usage:   "[--output raw|json|lines] query [file]",
+	summary: "Print the values matching a path query, such as services.*.port.",
+	run:     runQuery,
+}
+
+// runQuery prints the values of the file, or stdin, that match the query,
+// one per line, and exits with status 1 if none does. Queries are those of
+// styx.CompileQuery.
+func runQuery(c *cli, cmd *command, args []string) error {
+	fs := c.flagSet(cmd)
+	output := fs.String("output", "raw", "output format: raw (scalar text, other values as Styx), json (one JSON value per line) or lines (path=value)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch *output {
+	case "raw", "json", "lines":
+	default:
+		return usageError(fmt.Sprintf("unknown output format %q, expected raw, json or lines", *output))
+	}
+	if fs.NArg() == 0 {
+		return usageError("no query given")
+	}
+	q, err := styx.CompileQuery(fs.Arg(0))
+	if err != nil {
+		return usageError(err.Error())
+	}
+	name, data, err := c.readInput(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	doc, err := c.parse(name, data)
+	if err != nil {
+		return err
+	}
+
+	matches := q.Match(doc)
+	if len(matches) == 0 {
+		return exitError(1)
+	}
+	jsonOpts := styx.JSONOptions{InferTypes: true}
+	for _, m := range matches {
+		switch *output {
+		case "raw":
+			fmt.Fprintln(c.stdout, rawValue(m.Value))
+		case "json":
+			fmt.Fprintf(c.stdout, "%s\n", styx.ValueToJSON(m.Value, jsonOpts))
+		case "lines":
+			value := string(styx.ValueToJSON(m.Value, jsonOpts))
+			if m.Value.Tag == nil && m.Value.PayloadKind == styx.PayloadScalar {
+				value = m.Value.Scalar.Text
+			}
+			fmt.Fprintf(c.stdout, "%s=%s\n", strings.Join(m.Path, "."), value)
+		}
+	}
+	return nil
+}
+
+// rawValue returns the text of an untagged scalar, and other values as
+// Styx source.
+func rawValue(v *styx.Value) string {
+	if v.Tag == nil && v.PayloadKind == styx.PayloadScalar {
+		return v.Scalar.Text
+	}
+	return string(bytes.TrimSuffix(styx.FormatValue(v), []byte("\n")))
+}
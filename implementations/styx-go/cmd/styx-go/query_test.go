@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestQuery(t *testing.T) {
+	file := writeFile(t, "app.styx", `services {
+  api {port 80, host "a b"}
+  web {port 8080, tls @on}
+}
+list (1 {x y})
+`)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"services.*.port", file}, "80\n8080\n"},
+		{[]string{"services.api.host", file}, "a b\n"},
+		{[]string{"services.web.tls", file}, "@on\n"},
+		{[]string{"list.1", file}, "{\n    x y\n}\n"},
+		{[]string{"--output", "json", "**.port", file}, "80\n8080\n"},
+		{[]string{"--output", "json", "list", file}, "[1,{\"x\":\"y\"}]\n"},
+		{[]string{"--output", "lines", "services.*.*", file}, "services.api.port=80\nservices.api.host=a b\nservices.web.port=8080\nservices.web.tls={\"$tag\":\"on\"}\n"},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := runCLI(t, "", append([]string{"query"}, tt.args...)...)
+		if code != 0 || stdout != tt.want {
+			t.Errorf("%v: status %d, got %q, want %q; stderr %q", tt.args, code, stdout, tt.want, stderr)
+		}
+	}
+
+	stdout, _, code := runCLI(t, "a.b 1\n", "query", "a.b")
+	if code != 0 || stdout != "1\n" {
+		t.Errorf("stdin: status %d, got %q", code, stdout)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	if stdout, stderr, code := runCLI(t, "a 1\n", "query", "b"); code != 1 || stdout != "" || stderr != "" {
+		t.Errorf("no match: status %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	for _, args := range [][]string{
+		{},
+		{"a..b"},
+		{"--output", "yaml", "a"},
+		{"a", "x.styx", "y.styx"},
+	} {
+		if _, _, code := runCLI(t, "", append([]string{"query"}, args...)...); code != 2 {
+			t.Errorf("%v: status %d, want 2", args, code)
+		}
+	}
+	if _, _, code := runCLI(t, "a {", "query", "a"); code != 1 {
+		t.Errorf("parse error: status %d", code)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+var statCommand = &command{
+	name:    "stat",
+	usage:   "[--format text|json] [path...]",
+	summary: "Report size, nesting, scalar and tag metrics of Styx files.",
+	run:     runStat,
+}
+
+// fileStats are the metrics of a file, or the totals of several.
+type fileStats struct {
+	File    string `json:"file,omitempty"`
+	Bytes   int    `json:"bytes"`
+	Lines   int    `json:"lines"`
+	Entries int    `json:"entries"`
+	// Depth is the deepest nesting of entries and sequence items, the
+	// document's own entries being at depth 1.
+	Depth int `json:"depth"`
+	// Scalars counts the scalar values of each kind; keys are not
+	// counted.
+	Scalars map[string]int `json:"scalars"`
+	// Tags counts the uses of each tag, by name with its `@`.
+	Tags map[string]int `json:"tags"`
+}
+
+func newFileStats(file string) *fileStats {
+	s := &fileStats{File: file, Scalars: make(map[string]int), Tags: make(map[string]int)}
+	for _, kind := range []styx.ScalarKind{styx.ScalarBare, styx.ScalarQuoted, styx.ScalarRaw, styx.ScalarHeredoc} {
+		s.Scalars[kind.String()] = 0
+	}
+	return s
+}
+
+func (s *fileStats) entries(entries []*styx.Entry, depth int) {
+	if len(entries) > 0 {
+		s.Depth = max(s.Depth, depth)
+	}
+	for _, e := range entries {
+		s.Entries++
+		s.tag(e.Key)
+		s.value(e.Value, depth)
+	}
+}
+
+func (s *fileStats) tag(v *styx.Value) {
+	if v.Tag != nil {
+		s.Tags["@"+v.Tag.Name]++
+	}
+}
+
+// value counts v, found in entries or items at depth.
+func (s *fileStats) value(v *styx.Value, depth int) {
+	s.tag(v)
+	switch v.PayloadKind {
+	case styx.PayloadScalar:
+		s.Scalars[v.Scalar.Kind.String()]++
+	case styx.PayloadSequence:
+		if len(v.Sequence.Items) > 0 {
+			s.Depth = max(s.Depth, depth+1)
+		}
+		for _, item := range v.Sequence.Items {
+			s.value(item, depth+1)
+		}
+	case styx.PayloadObject:
+		s.entries(v.Object.Entries, depth+1)
+	}
+}
+
+// add adds the metrics of o to s, taking the deeper depth.
+func (s *fileStats) add(o *fileStats) {
+	s.Bytes += o.Bytes
+	s.Lines += o.Lines
+	s.Entries += o.Entries
+	s.Depth = max(s.Depth, o.Depth)
+	for kind, n := range o.Scalars {
+		s.Scalars[kind] += n
+	}
+	for tag, n := range o.Tags {
+		s.Tags[tag] += n
+	}
+}
+
+func (s *fileStats) scalarCount() int {
+	n := 0
+	for _, count := range s.Scalars {
+		n += count
+	}
+	return n
+}
+
+func (s *fileStats) tagCount() int {
+	n := 0
+	for _, count := range s.Tags {
+		n += count
+	}
+	return n
+}
+
+// runStat reports the metrics of each file, or each .styx file under a
+// directory, the paths defaulting to the current directory. The text
+// format tables the files, with a total row for several, and then how
+// often each scalar kind and tag occurs across them all. Files that fail
+// to parse are reported and skipped, and make the command exit with
+// status 1.
+func runStat(c *cli, cmd *command, args []string) error {
+	flags := c.flagSet(cmd)
+	format := flags.String("format", "text", "output format: text or json")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return usageError(fmt.Sprintf("unknown format %q, expected text or json", *format))
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := styxFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	var stats []*fileStats
+	total := newFileStats("")
+	failed := false
+	for _, path := range files {
+		name, content, err := c.readFile(path)
+		if err != nil {
+			return err
+		}
+		doc, err := c.parse(name, content)
+		var exit exitError
+		if errors.As(err, &exit) {
+			failed = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s := newFileStats(name)
+		s.Bytes = len(content)
+		s.Lines = strings.Count(string(content), "\n")
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			s.Lines++
+		}
+		s.entries(doc.Entries, 1)
+		stats = append(stats, s)
+		total.add(s)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		if stats == nil {
+			stats = []*fileStats{}
+		}
+		err = enc.Encode(struct {
+			Files []*fileStats `json:"files"`
+			Total *fileStats   `json:"total"`
+		}{stats, total})
+	} else if len(stats) > 0 {
+		err = writeStats(c.stdout, stats, total)
+	}
+	if err != nil {
+		return err
+	}
+	if failed {
+		return exitError(1)
+	}
+	return nil
+}
+
+// writeStats prints the table and distributions of the text format.
+func writeStats(w io.Writer, stats []*fileStats, total *fileStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "bytes\tlines\tentries\tdepth\tscalars\ttags\t file")
+	row := func(s *fileStats, name string) {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t %s\n", s.Bytes, s.Lines, s.Entries, s.Depth, s.scalarCount(), s.tagCount(), name)
+	}
+	for _, s := range stats {
+		row(s, s.File)
+	}
+	if len(stats) > 1 {
+		row(total, fmt.Sprintf("total (%d files)", len(stats)))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nscalars: %s\n", counts(total.Scalars, []string{"bare", "quoted", "raw", "heredoc"}))
+	if len(total.Tags) > 0 {
+		fmt.Fprintf(w, "tags: %s\n", counts(total.Tags, nil))
+	}
+	return nil
+}
+
+// counts formats the counts of m as "name n, ...": in the order of names
+// if given, and otherwise most frequent first.
+func counts(m map[string]int, names []string) string {
+	if names == nil {
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if m[names[i]] != m[names[j]] {
+				return m[names[i]] > m[names[j]]
+			}
+			return names[i] < names[j]
+		})
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, m[name])
+	}
+	return strings.Join(parts, ", ")
+}
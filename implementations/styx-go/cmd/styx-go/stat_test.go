@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStat(t *testing.T) {
+	path := writeFile(t, "app.styx", "name app\nenv @env{a 1}\nservers ({host \"a\", port 1} {host r#\"b\"#, tags (@x @x)})\nnote <<EOF\n  hi\n  EOF\n")
+
+	stdout, stderr, code := runCLI(t, "", "stat", path)
+	if code != 0 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	want := "  bytes  lines  entries  depth  scalars  tags file\n" +
+		"    102      6        9      4        6     3 " + path + "\n" +
+		"\nscalars: bare 3, quoted 1, raw 1, heredoc 1\n" +
+		"tags: @x 2, @env 1\n"
+	if stdout != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout, want)
+	}
+
+	other := writeFile(t, "other.styx", "a {b {c (1)}}")
+	stdout, _, _ = runCLI(t, "", "stat", "--format", "json", path, other)
+	var got struct {
+		Files []fileStats
+		Total fileStats
+	}
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("%v\n%s", err, stdout)
+	}
+	if len(got.Files) != 2 || got.Files[1].Depth != 4 || got.Files[1].Lines != 1 || got.Files[1].Entries != 3 {
+		t.Errorf("files = %+v", got.Files)
+	}
+	if got.Total.Bytes != 115 || got.Total.Entries != 12 || got.Total.Scalars["bare"] != 4 || got.Total.Tags["@env"] != 1 {
+		t.Errorf("total = %+v", got.Total)
+	}
+
+	stdout, _, _ = runCLI(t, "", "stat", path, other)
+	if !strings.Contains(stdout, "    115      7       12      4        7     3 total (2 files)\n") {
+		t.Errorf("no total row:\n%s", stdout)
+	}
+
+	bad := writeFile(t, "bad.styx", "a {\n")
+	stdout, stderr, code = runCLI(t, "", "stat", bad, other)
+	if code != 1 || !strings.Contains(stderr, "error[STYX0003]") || !strings.Contains(stdout, filepath.Base(other)) {
+		t.Errorf("parse error: status %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+}
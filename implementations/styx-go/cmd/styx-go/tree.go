@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+var treeCommand = &command{
+	name:    "tree",
+	usage:   "[--format sexp|json] [file...]",
+	summary: "Print the parse tree of each file.",
+	run:     runTree,
+}
+
+// runTree prints the tree of each file, or of stdin, or its parse error,
+// in the format of the reference implementation's `styx tree`: run over
+// the corpus, it prints the compliance suite's golden output. Parse errors
+// are output rather than failures.
+func runTree(c *cli, cmd *command, args []string) error {
+	fs := c.flagSet(cmd)
+	format := fs.String("format", "sexp", "output format: sexp or json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *format != "sexp" && *format != "json" {
+		return usageError(fmt.Sprintf("unknown format %q, expected sexp or json", *format))
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	var trees []jsonTree
+	for _, path := range paths {
+		name, content, err := c.readFile(path)
+		if err != nil {
+			return err
+		}
+		doc, parseErr := styx.ParseBytes(content)
+		if *format == "json" {
+			trees = append(trees, newJSONTree(name, doc, parseErr))
+			continue
+		}
+		fmt.Fprintf(c.stdout, "; file: %s\n", name)
+		if parseErr != nil {
+			fmt.Fprintln(c.stdout, styx.FormatErrorSexp(parseErr))
+		} else {
+			fmt.Fprintln(c.stdout, styx.FormatSexp(doc))
+		}
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(trees)
+	}
+	return nil
+}
+
+// jsonTree is a file's tree in JSON, holding the same nodes and spans as
+// the s-expression form.
+type jsonTree struct {
+	File     string     `json:"file"`
+	Document *jsonNode  `json:"document,omitempty"`
+	Error    *jsonError `json:"error,omitempty"`
+}
+
+type jsonError struct {
+	Span    [2]int `json:"span"`
+	Message string `json:"message"`
+}
+
+// jsonNode is a document, scalar, unit, tag, sequence or object, as Type
+// says.
+type jsonNode struct {
+	Type    string           `json:"type"`
+	Span    *[2]int          `json:"span,omitempty"`
+	Kind    *styx.ScalarKind `json:"kind,omitempty"`
+	Text    *string          `json:"text,omitempty"`
+	Name    string           `json:"name,omitempty"`
+	Payload *jsonNode        `json:"payload,omitempty"`
+	Items   []*jsonNode      `json:"items,omitempty"`
+	Entries []jsonEntry      `json:"entries,omitempty"`
+}
+
+type jsonEntry struct {
+	Key   *jsonNode `json:"key"`
+	Value *jsonNode `json:"value"`
+}
+
+func newJSONTree(path string, doc *styx.Document, err error) jsonTree {
+	tree := jsonTree{File: path}
+	if err != nil {
+		tree.Error = &jsonError{Span: [2]int{-1, -1}, Message: err.Error()}
+		var pe *styx.ParseError
+		if errors.As(err, &pe) {
+			tree.Error = &jsonError{Span: [2]int{pe.Span.Start, pe.Span.End}, Message: pe.Message}
+		}
+		return tree
+	}
+	tree.Document = &jsonNode{Type: "document", Entries: jsonEntries(doc.Entries)}
+	return tree
+}
+
+func jsonEntries(entries []*styx.Entry) []jsonEntry {
+	out := make([]jsonEntry, len(entries))
+	for i, e := range entries {
+		out[i] = jsonEntry{Key: jsonValue(e.Key), Value: jsonValue(e.Value)}
+	}
+	return out
+}
+
+func jsonSpan(s styx.Span) *[2]int {
+	return &[2]int{s.Start, s.End}
+}
+
+func jsonValue(v *styx.Value) *jsonNode {
+	switch {
+	case v.Tag == nil && v.PayloadKind == styx.PayloadNone:
+		return &jsonNode{Type: "unit", Span: jsonSpan(v.Span)}
+	case v.Tag != nil:
+		node := &jsonNode{Type: "tag", Span: jsonSpan(v.Span), Name: v.Tag.Name}
+		if v.PayloadKind != styx.PayloadNone {
+			node.Payload = jsonPayload(v)
+		}
+		return node
+	}
+	return jsonPayload(v)
+}
+
+func jsonPayload(v *styx.Value) *jsonNode {
+	switch v.PayloadKind {
+	case styx.PayloadScalar:
+		return &jsonNode{Type: "scalar", Span: jsonSpan(v.Scalar.Span), Kind: &v.Scalar.Kind, Text: &v.Scalar.Text}
+	case styx.PayloadSequence:
+		node := &jsonNode{Type: "sequence", Span: jsonSpan(v.Sequence.Span), Items: []*jsonNode{}}
+		for _, item := range v.Sequence.Items {
+			node.Items = append(node.Items, jsonValue(item))
+		}
+		return node
+	}
+	return &jsonNode{Type: "object", Span: jsonSpan(v.Object.Span), Entries: jsonEntries(v.Object.Entries)}
+}
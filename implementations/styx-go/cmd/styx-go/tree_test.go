@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTreeGolden(t *testing.T) {
+	root := filepath.Join("..", "..", "..", "..")
+	var files []string
+	err := filepath.Walk(filepath.Join(root, "compliance", "corpus"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(path, ".styx") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	golden, err := os.ReadFile(filepath.Join(root, "compliance", "golden.sexp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := runCLI(t, "", append([]string{"tree"}, files...)...)
+	if code != 0 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	got := strings.ReplaceAll(stdout, "; file: "+filepath.ToSlash(root)+"/", "; file: ")
+	if got != string(golden) {
+		t.Error("tree output differs from compliance/golden.sexp")
+	}
+}
+
+func TestTreeJSON(t *testing.T) {
+	good := writeFile(t, "good.styx", "name @app{x (1)}\nflag\n")
+	bad := writeFile(t, "bad.styx", "a {")
+	stdout, stderr, code := runCLI(t, "", "tree", "--format", "json", good, bad)
+	if code != 0 {
+		t.Fatalf("status %d: %s", code, stderr)
+	}
+	var trees []map[string]any
+	if err := json.Unmarshal([]byte(stdout), &trees); err != nil {
+		t.Fatalf("%v\n%s", err, stdout)
+	}
+	if len(trees) != 2 || trees[0]["file"] != good || trees[1]["error"] == nil {
+		t.Fatalf("got %s", stdout)
+	}
+	for _, want := range []string{
+		`"type": "tag"`,
+		`"name": "app"`,
+		`"kind": "bare"`,
+		`"text": "name"`,
+		`"type": "unit"`,
+		`"span": [`,
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %s:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestTreeErrors(t *testing.T) {
+	if stdout, _, code := runCLI(t, "a 1", "tree"); code != 0 || !strings.HasPrefix(stdout, "; file: <stdin>\n(document") {
+		t.Errorf("no files: status %d, output %q", code, stdout)
+	}
+	if _, _, code := runCLI(t, "", "tree", "--format", "debug", "x.styx"); code != 2 {
+		t.Errorf("unknown format: status %d", code)
+	}
+	if _, stderr, code := runCLI(t, "", "tree", filepath.Join(t.TempDir(), "missing.styx")); code != 1 || !strings.Contains(stderr, "missing.styx") {
+		t.Errorf("missing file: status %d, stderr %q", code, stderr)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+var validateCommand = &command{
+	name:    "validate",
+	usage:   "[--schema file] [--format text|json|sarif] [file...]",
+	summary: "Check documents against a Styx schema.",
+	run:     runValidate,
+}
+
+// runValidate checks each file, or stdin, against the schema given with
+// --schema or, failing that, the one its `@schema` entry names, relative
+// to the file.
+// It reports schema violations and parse errors and exits with status 1 if
+// there are errors; warnings, such as for deprecated fields, do not fail.
+// A schema that cannot be read exits with status 2.
+func runValidate(c *cli, cmd *command, args []string) error {
+	flags := c.flagSet(cmd)
+	schemaPath := flags.String("schema", "", "schema file, instead of the one each file declares with @schema")
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	switch *format {
+	case "text", "json", "sarif":
+	default:
+		return usageError(fmt.Sprintf("unknown format %q, expected text, json or sarif", *format))
+	}
+
+	schemas := make(map[string]*styx.Schema)
+	loadSchema := func(path string) (*styx.Schema, error) {
+		if s, ok := schemas[path]; ok {
+			return s, nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "styx-go validate: %v\n", err)
+			return nil, exitError(2)
+		}
+		s, err := styx.ParseSchema(string(data))
+		var pe *styx.ParseError
+		if errors.As(err, &pe) {
+			opts := styx.RenderOptions{Color: c.color, Filename: path}
+			fmt.Fprint(c.stderr, styx.RenderDiagnostics(string(data), []*styx.Diagnostic{pe.Diagnostic()}, opts))
+			return nil, exitError(2)
+		}
+		schemas[path] = s
+		return s, err
+	}
+
+	var found []styx.JSONDiagnostic
+	errs, reported := 0, 0
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	for _, path := range paths {
+		path, content, err := c.readFile(path)
+		if err != nil {
+			return err
+		}
+		source := string(content)
+		doc, diags := styx.Diagnose(source, styx.ParseOptions{Filename: path})
+		// Parser warnings, such as for a colon after a key, leave a
+		// document that can still be validated.
+		if !hasErrors(diags) {
+			schema := *schemaPath
+			if schema == "" {
+				declared, ok := declaredSchema(doc)
+				if !ok {
+					return fmt.Errorf("%s: no --schema given and the file has no @schema entry", path)
+				}
+				schema = filepath.Join(filepath.Dir(path), declared)
+			}
+			s, err := loadSchema(schema)
+			if err != nil {
+				return err
+			}
+			diags = append(diags, s.Validate(doc)...)
+			sort.SliceStable(diags, func(i, j int) bool { return diags[i].Span.Start < diags[j].Span.Start })
+		}
+		for _, d := range diags {
+			if d.Severity == styx.SeverityError {
+				errs++
+			}
+		}
+		if len(diags) == 0 {
+			continue
+		}
+		if *format != "text" {
+			for _, d := range diags {
+				found = append(found, d.JSON(path, source))
+			}
+			continue
+		}
+		if reported > 0 {
+			fmt.Fprintln(c.stdout)
+		}
+		reported++
+		fmt.Fprint(c.stdout, styx.RenderDiagnostics(source, diags, styx.RenderOptions{Color: c.colorStdout, Context: 1, Filename: path}))
+	}
+
+	var err error
+	switch *format {
+	case "json":
+		err = styx.WriteDiagnosticsJSON(c.stdout, found)
+	case "sarif":
+		err = styx.WriteSARIF(c.stdout, found)
+	}
+	if err != nil {
+		return err
+	}
+	if errs > 0 {
+		return exitError(1)
+	}
+	return nil
+}
+
+// hasErrors reports whether any of diags is an error.
+func hasErrors(diags []*styx.Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == styx.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// declaredSchema returns the path given by the document's `@schema` entry.
+func declaredSchema(doc *styx.Document) (string, bool) {
+	for _, e := range doc.Entries {
+		if e.KeyText() == "@schema" && e.Value.PayloadKind == styx.PayloadScalar {
+			return e.Value.Scalar.Text, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	schema := writeFile(t, "app.schema.styx", "meta {id app}\nschema {\n  @ @object{name @string, port @int{max 65535}, old @optional(@deprecated(\"gone\" @bool))}\n}\n")
+	valid := writeFile(t, "valid.styx", "name app\nport 80\n")
+	invalid := writeFile(t, "invalid.styx", "name app\nprot 80\n")
+
+	stdout, stderr, code := runCLI(t, "", "validate", "--schema", schema, valid)
+	if code != 0 || stdout != "" {
+		t.Errorf("valid file: status %d, output %q %q", code, stdout, stderr)
+	}
+
+	stdout, _, code = runCLI(t, "", "validate", "--schema", schema, valid, invalid)
+	if code != 1 {
+		t.Errorf("invalid file: status %d", code)
+	}
+	for _, want := range []string{"error[STYX0033]: unknown field `prot`", "help: did you mean `port`?", "error[STYX0034]: missing required field `port`", "invalid.styx:2:1"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+
+	// Warnings alone do not fail.
+	deprecated := writeFile(t, "deprecated.styx", "name app\nport 80\nold true\n")
+	if stdout, _, code := runCLI(t, "", "validate", "--schema", schema, deprecated); code != 0 || !strings.Contains(stdout, "warning[STYX0036]") {
+		t.Errorf("deprecated field: status %d, output:\n%s", code, stdout)
+	}
+
+	// A parser warning does not stop the schema from being checked.
+	warned := writeFile(t, "warned.styx", "name: app\nport notanint\n")
+	stdout, _, code = runCLI(t, "", "validate", "--schema", schema, warned)
+	if code != 1 || !strings.Contains(stdout, "warning[STYX0023]") || !strings.Contains(stdout, "error[") {
+		t.Errorf("warning and violation: status %d, output:\n%s", code, stdout)
+	}
+
+	stdout, _, _ = runCLI(t, "", "validate", "--schema", schema, "--format", "json", invalid)
+	var report struct{ Diagnostics []map[string]any }
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil || len(report.Diagnostics) != 2 {
+		t.Errorf("json: %v\n%s", err, stdout)
+	}
+
+	// Without --schema, the schema named by @schema is used, relative to the file.
+	declared := filepath.Join(filepath.Dir(schema), "declared.styx")
+	if err := os.WriteFile(declared, []byte("@schema app.schema.styx\nname app\nport 70000\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if stdout, _, code := runCLI(t, "", "validate", declared); code != 1 || !strings.Contains(stdout, "value too large") {
+		t.Errorf("declared schema: status %d, output:\n%s", code, stdout)
+	}
+	if _, stderr, code := runCLI(t, "", "validate", valid); code != 1 || !strings.Contains(stderr, "no --schema given") {
+		t.Errorf("undeclared schema: status %d, stderr %q", code, stderr)
+	}
+
+	if stdout, _, code := runCLI(t, "name app\nprot 80\n", "validate", "--schema", schema); code != 1 || !strings.Contains(stdout, "<stdin>:2:1") {
+		t.Errorf("stdin: status %d, output:\n%s", code, stdout)
+	}
+
+	broken := writeFile(t, "broken.schema.styx", "schema {@ @Missing}\n")
+	if _, stderr, code := runCLI(t, "", "validate", "--schema", broken, valid); code != 2 || !strings.Contains(stderr, "unknown type @Missing") {
+		t.Errorf("invalid schema: status %d, stderr %q", code, stderr)
+	}
+
+	parseError := writeFile(t, "unclosed.styx", "name {\n")
+	if stdout, _, code := runCLI(t, "", "validate", "--schema", schema, parseError); code != 1 || !strings.Contains(stdout, "error[STYX0003]") {
+		t.Errorf("parse error: status %d, output:\n%s", code, stdout)
+	}
+}
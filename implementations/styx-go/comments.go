@@ -0,0 +1,92 @@
+package styx
+
+import (
+	"sort"
+	"strings"
+)
+
+// Comment represents a `//` line comment. Text includes the leading slashes
+// but not the line terminator.
+type Comment struct {
+	Text string
+	Span Span
+}
+
+// IsDoc returns true for `///` doc comments.
+func (c *Comment) IsDoc() bool {
+	return strings.HasPrefix(c.Text, "///")
+}
+
+// attachComments associates comments with the entries around them. A comment
+// that follows code on the same line becomes the trailing comment of the
+// entry ending on that line; any other comment becomes a leading comment of
+// the next entry in the same object. Comments with no such entry remain
+// unattached and are only available through Document.Comments.
+func attachComments(source string, entries []*Entry, comments []*Comment) {
+	for _, c := range comments {
+		attachComment(source, entries, c)
+	}
+}
+
+// attachComment attaches c to entries, which are in source order. Lookups
+// use binary search so that attaching is not quadratic in the number of
+// comments and entries.
+func attachComment(source string, entries []*Entry, c *Comment) {
+	// Descend into the entry whose value contains the comment, if any.
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Span().End >= c.Span.End
+	})
+	if i < len(entries) && entries[i].Span().Start <= c.Span.Start {
+		objs := nestedObjects(entries[i].Value)
+		j := sort.Search(len(objs), func(j int) bool {
+			return objs[j].Span.End >= c.Span.End
+		})
+		if j < len(objs) && objs[j].Span.Start < c.Span.Start {
+			attachComment(source, objs[j].Entries, c)
+		}
+		return
+	}
+
+	if !startsLine(source, c.Span.Start) {
+		// The last entry ending before the comment, if on the same line.
+		j := sort.Search(len(entries), func(j int) bool {
+			return entries[j].Span().End > c.Span.Start
+		}) - 1
+		if j >= 0 {
+			prev := entries[j]
+			if end := prev.Span().End; !strings.Contains(source[end:c.Span.Start], "\n") && prev.TrailingComment == nil {
+				prev.TrailingComment = c
+				return
+			}
+		}
+	}
+
+	j := sort.Search(len(entries), func(j int) bool {
+		return entries[j].Span().Start >= c.Span.End
+	})
+	if j < len(entries) {
+		entries[j].LeadingComments = append(entries[j].LeadingComments, c)
+	}
+}
+
+// startsLine reports whether only whitespace precedes pos on its line.
+func startsLine(source string, pos int) bool {
+	lineStart := strings.LastIndexByte(source[:pos], '\n') + 1
+	return strings.TrimLeft(source[lineStart:pos], " \t\r") == ""
+}
+
+// nestedObjects returns the objects directly reachable from v, looking
+// through sequences.
+func nestedObjects(v *Value) []*Object {
+	switch v.PayloadKind {
+	case PayloadObject:
+		return []*Object{v.Object}
+	case PayloadSequence:
+		var objs []*Object
+		for _, item := range v.Sequence.Items {
+			objs = append(objs, nestedObjects(item)...)
+		}
+		return objs
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package styx
+
+import "testing"
+
+func commentTexts(comments []*Comment) []string {
+	var texts []string
+	for _, c := range comments {
+		texts = append(texts, c.Text)
+	}
+	return texts
+}
+
+func TestCommentAttachment(t *testing.T) {
+	doc := mustParse(t, `// header
+/// The server.
+server {
+  // before host
+  host localhost // the host
+  port 8080
+  // dangling
+}
+name hello // inline
+`)
+	if n := len(doc.Comments); n != 6 {
+		t.Fatalf("got %d comments, want 6", n)
+	}
+
+	server := doc.Entries[0]
+	if got := commentTexts(server.LeadingComments); len(got) != 2 || got[0] != "// header" || got[1] != "/// The server." {
+		t.Errorf("server leading = %q", got)
+	}
+	if !server.LeadingComments[1].IsDoc() {
+		t.Errorf("doc comment not recognized")
+	}
+
+	host := server.Value.Object.Entries[0]
+	if got := commentTexts(host.LeadingComments); len(got) != 1 || got[0] != "// before host" {
+		t.Errorf("host leading = %q", got)
+	}
+	if host.TrailingComment == nil || host.TrailingComment.Text != "// the host" {
+		t.Errorf("host trailing = %v", host.TrailingComment)
+	}
+
+	port := server.Value.Object.Entries[1]
+	if port.LeadingComments != nil || port.TrailingComment != nil {
+		t.Errorf("port has comments: %v %v", port.LeadingComments, port.TrailingComment)
+	}
+
+	name := doc.Entries[1]
+	if name.LeadingComments != nil {
+		t.Errorf("name leading = %q", commentTexts(name.LeadingComments))
+	}
+	if name.TrailingComment == nil || name.TrailingComment.Text != "// inline" {
+		t.Errorf("name trailing = %v", name.TrailingComment)
+	}
+}
@@ -39,14 +39,33 @@ func TestCompliance(t *testing.T) {
 
 	sort.Strings(files)
 
-	for _, file := range files {
-		relPath, _ := filepath.Rel(corpusPath, file)
-		t.Run(relPath, func(t *testing.T) {
-			compareOutput(t, file, styxCLI)
+	for _, mode := range complianceModes {
+		t.Run(mode.name, func(t *testing.T) {
+			for _, file := range files {
+				relPath, _ := filepath.Rel(corpusPath, file)
+				t.Run(relPath, func(t *testing.T) {
+					compareOutput(t, file, styxCLI, mode)
+				})
+			}
 		})
 	}
 }
 
+// complianceMode pairs Go parse options with the reference CLI arguments
+// selecting the same behavior. The reference implementation currently has no
+// configurable strictness, so only the default mode exists; when it gains a
+// knob, add the matching ParseOptions field and a mode here so both
+// implementations are compared under every setting.
+type complianceMode struct {
+	name    string
+	opts    ParseOptions
+	cliArgs []string
+}
+
+var complianceModes = []complianceMode{
+	{name: "default"},
+}
+
 func findCorpusPath(t *testing.T) string {
 	// Try relative paths from the test file location
 	candidates := []string{
@@ -96,17 +115,17 @@ func findStyxCLI(t *testing.T) string {
 	return ""
 }
 
-func compareOutput(t *testing.T, file string, styxCLI string) {
+func compareOutput(t *testing.T, file string, styxCLI string, mode complianceMode) {
 	content, err := os.ReadFile(file)
 	if err != nil {
 		t.Fatalf("failed to read file: %v", err)
 	}
 
 	// Get Go parser output
-	goOutput := getGoOutput(string(content))
+	goOutput := getGoOutput(string(content), mode.opts)
 
 	// Get Rust reference output
-	rustOutput := getRustOutput(t, file, styxCLI)
+	rustOutput := getRustOutput(t, file, styxCLI, mode.cliArgs)
 
 	// Normalize both outputs for comparison
 	goNorm := normalizeOutput(goOutput)
@@ -133,7 +152,7 @@ func annotateErrorDiff(source, goOutput, rustOutput string) string {
 
 	if rustSpan != nil {
 		sb.WriteString("Expected error:\n")
-		sb.WriteString(annotateSpan(source, rustSpan[0], rustSpan[1], rustMsg))
+		sb.WriteString(RenderDiagnostic(source, &ParseError{Message: rustMsg, Span: Span{rustSpan[0], rustSpan[1]}}))
 		sb.WriteString("\n")
 	} else {
 		sb.WriteString("Expected: no error\n\n")
@@ -141,7 +160,7 @@ func annotateErrorDiff(source, goOutput, rustOutput string) string {
 
 	if goSpan != nil {
 		sb.WriteString("Got error:\n")
-		sb.WriteString(annotateSpan(source, goSpan[0], goSpan[1], goMsg))
+		sb.WriteString(RenderDiagnostic(source, &ParseError{Message: goMsg, Span: Span{goSpan[0], goSpan[1]}}))
 	} else {
 		sb.WriteString("Got: no error\n")
 	}
@@ -161,79 +180,17 @@ func parseErrorSpan(output string) ([]int, string) {
 	return []int{start, end}, m[3]
 }
 
-// annotateSpan shows source with carets under the error span, handling multi-line spans
-func annotateSpan(source string, start, end int, msg string) string {
-	if start < 0 || end < 0 || start > len(source) {
-		return fmt.Sprintf("  [invalid span %d-%d]\n", start, end)
-	}
-	if end > len(source) {
-		end = len(source)
-	}
-
-	// Find all lines that overlap with the span
-	type lineInfo struct {
-		text      string
-		lineStart int
-		lineEnd   int
-	}
-	var lines []lineInfo
-	pos := 0
-	for _, lineText := range strings.Split(source, "\n") {
-		lineStart := pos
-		lineEnd := pos + len(lineText)
-		// Check if this line overlaps with [start, end)
-		if lineEnd >= start && lineStart < end {
-			lines = append(lines, lineInfo{lineText, lineStart, lineEnd})
-		}
-		pos = lineEnd + 1 // +1 for the newline
-		if lineStart >= end {
-			break
-		}
-	}
-
-	if len(lines) == 0 {
-		return fmt.Sprintf("  [span %d-%d not found]\n", start, end)
-	}
-
-	var sb strings.Builder
-	for _, li := range lines {
-		sb.WriteString("  ")
-		sb.WriteString(li.text)
-		sb.WriteString("\n  ")
-		// Calculate caret positions for this line
-		caretStart := start - li.lineStart
-		if caretStart < 0 {
-			caretStart = 0
-		}
-		caretEnd := end - li.lineStart
-		if caretEnd > len(li.text) {
-			caretEnd = len(li.text)
-		}
-		width := caretEnd - caretStart
-		if width < 1 {
-			width = 1
-		}
-		sb.WriteString(strings.Repeat(" ", caretStart))
-		sb.WriteString(strings.Repeat("^", width))
-		sb.WriteString("\n")
-	}
-	sb.WriteString(fmt.Sprintf("  %s (%d-%d)\n", msg, start, end))
-	return sb.String()
-}
-
-func getGoOutput(content string) string {
-	doc, err := Parse(content)
+func getGoOutput(content string, opts ParseOptions) string {
+	doc, err := ParseWithOptions(content, opts)
 	if err != nil {
-		if pe, ok := err.(*ParseError); ok {
-			return formatErrorSexp(pe)
-		}
-		return fmt.Sprintf("(error [-1, -1] \"parse error: %s\")", escapeStringSexp(err.Error()))
+		return FormatErrorSexp(err)
 	}
-	return formatDocumentSexp(doc)
+	return FormatSexp(doc)
 }
 
-func getRustOutput(t *testing.T, file string, styxCLI string) string {
-	cmd := exec.Command(styxCLI, "tree", "--format", "sexp", file)
+func getRustOutput(t *testing.T, file string, styxCLI string, cliArgs []string) string {
+	args := append([]string{"tree", "--format", "sexp"}, cliArgs...)
+	cmd := exec.Command(styxCLI, append(args, file)...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -260,11 +217,11 @@ func extractErrorFromStderr(stderr string) string {
 			// Extract span from "parse error at X-Y: message"
 			re := regexp.MustCompile(`parse error at (\d+)-(\d+): (.+)`)
 			if m := re.FindStringSubmatch(line[7:]); m != nil {
-				return fmt.Sprintf("(error [%s, %s] \"parse error at %s-%s: %s\")", m[1], m[2], m[1], m[2], escapeStringSexp(m[3]))
+				return fmt.Sprintf("(error [%s, %s] \"parse error at %s-%s: %s\")", m[1], m[2], m[1], m[2], escapeSexp(m[3]))
 			}
 		}
 	}
-	return fmt.Sprintf("(error [-1, -1] \"%s\")", escapeStringSexp(strings.TrimSpace(stderr)))
+	return fmt.Sprintf("(error [-1, -1] \"%s\")", escapeSexp(strings.TrimSpace(stderr)))
 }
 
 func normalizeOutput(output string) string {
@@ -283,96 +240,3 @@ func normalizeOutput(output string) string {
 	}
 	return strings.Join(result, "\n")
 }
-
-func escapeStringSexp(s string) string {
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "\"", "\\\"")
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	s = strings.ReplaceAll(s, "\r", "\\r")
-	s = strings.ReplaceAll(s, "\t", "\\t")
-	return s
-}
-
-func formatErrorSexp(err *ParseError) string {
-	escapedMsg := escapeStringSexp(err.Message)
-	return fmt.Sprintf("(error [%d, %d] \"parse error at %d-%d: %s\")", err.Span.Start, err.Span.End, err.Span.Start, err.Span.End, escapedMsg)
-}
-
-func formatDocumentSexp(doc *Document) string {
-	if len(doc.Entries) == 0 {
-		return "(document [-1, -1]\n)"
-	}
-	var entries []string
-	for _, entry := range doc.Entries {
-		entries = append(entries, formatEntrySexp(entry, 1))
-	}
-	return fmt.Sprintf("(document [-1, -1]\n%s\n)", strings.Join(entries, "\n"))
-}
-
-func formatEntrySexp(entry *Entry, indent int) string {
-	prefix := strings.Repeat("  ", indent)
-	keyStr := formatValueSexp(entry.Key, indent+1)
-	valueStr := formatValueSexp(entry.Value, indent+1)
-	return fmt.Sprintf("%s(entry\n%s  %s\n%s  %s)", prefix, prefix, keyStr, prefix, valueStr)
-}
-
-func formatValueSexp(value *Value, indent int) string {
-	prefix := strings.Repeat("  ", indent)
-
-	// Unit value (no tag, no payload)
-	if value.Tag == nil && value.PayloadKind == PayloadNone {
-		return fmt.Sprintf("(unit [%d, %d])", value.Span.Start, value.Span.End)
-	}
-
-	// Tag only (no payload)
-	if value.Tag != nil && value.PayloadKind == PayloadNone {
-		return fmt.Sprintf("(tag [%d, %d] \"%s\")", value.Span.Start, value.Span.End, value.Tag.Name)
-	}
-
-	// Tag with payload
-	if value.Tag != nil && value.PayloadKind != PayloadNone {
-		payloadStr := formatPayloadSexp(value, indent+1)
-		return fmt.Sprintf("(tag [%d, %d] \"%s\"\n%s  %s)", value.Span.Start, value.Span.End, value.Tag.Name, prefix, payloadStr)
-	}
-
-	// Just payload
-	if value.PayloadKind != PayloadNone {
-		return formatPayloadSexp(value, indent)
-	}
-
-	return fmt.Sprintf("(unit [%d, %d])", value.Span.Start, value.Span.End)
-}
-
-func formatPayloadSexp(value *Value, indent int) string {
-	prefix := strings.Repeat("  ", indent)
-
-	switch value.PayloadKind {
-	case PayloadScalar:
-		escaped := escapeStringSexp(value.Scalar.Text)
-		return fmt.Sprintf("(scalar [%d, %d] %s \"%s\")", value.Scalar.Span.Start, value.Scalar.Span.End, value.Scalar.Kind, escaped)
-
-	case PayloadSequence:
-		seq := value.Sequence
-		if len(seq.Items) == 0 {
-			return fmt.Sprintf("(sequence [%d, %d])", seq.Span.Start, seq.Span.End)
-		}
-		var items []string
-		for _, item := range seq.Items {
-			items = append(items, fmt.Sprintf("%s  %s", prefix, formatValueSexp(item, indent+1)))
-		}
-		return fmt.Sprintf("(sequence [%d, %d]\n%s)", seq.Span.Start, seq.Span.End, strings.Join(items, "\n"))
-
-	case PayloadObject:
-		obj := value.Object
-		if len(obj.Entries) == 0 {
-			return fmt.Sprintf("(object [%d, %d])", obj.Span.Start, obj.Span.End)
-		}
-		var entries []string
-		for _, entry := range obj.Entries {
-			entries = append(entries, formatEntrySexp(entry, indent+1))
-		}
-		return fmt.Sprintf("(object [%d, %d]\n%s\n%s)", obj.Span.Start, obj.Span.End, strings.Join(entries, "\n"), prefix)
-	}
-
-	return "(unknown)"
-}
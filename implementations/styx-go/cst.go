@@ -0,0 +1,522 @@
+package styx
+
+import "strings"
+
+// CSTNode is a node of a concrete syntax tree produced by the
+// tree-sitter-styx grammar, as read by FromCST. Its methods mirror those of
+// go-tree-sitter's Node, so that this package does not depend on a
+// tree-sitter binding; the treesitter module adapts the binding's nodes.
+type CSTNode interface {
+	// Kind returns the grammar rule that produced the node, such as
+	// "entry" or "bare_scalar".
+	Kind() string
+	StartByte() uint
+	EndByte() uint
+	// IsError and IsMissing report nodes inserted by error recovery.
+	IsError() bool
+	IsMissing() bool
+	// HasError reports whether the node or any node below it is an error
+	// or missing node.
+	HasError() bool
+	ChildCount() uint
+	Child(i uint) CSTNode
+	// FieldNameForChild returns the grammar field of child i, or "".
+	FieldNameForChild(i uint) string
+}
+
+// FromCST builds a Document from a tree-sitter-styx parse tree of source,
+// given its root node. The result is the document Parse would return:
+// scalars are decoded by the same lexer, dotted keys expanded and keys
+// checked by the same rules, and comments attached in the same way, so
+// editors parsing incrementally with tree-sitter can hand their trees to
+// code written against this package.
+//
+// A tree with syntax errors is rejected with the error Parse reports for
+// source, so diagnostics read the same either way. The grammar is stricter
+// than the parser in a few places, such as objects mixing comma and
+// newline separators; sources the parser accepts but the grammar does not
+// are rejected with an error at the first error node of the tree.
+func FromCST(source string, root CSTNode) (*Document, error) {
+	if e := firstError(root); e != nil {
+		if _, err := Parse(source); err != nil {
+			return nil, err
+		}
+		msg := "unexpected token"
+		if e.IsMissing() {
+			msg = "missing `" + e.Kind() + "`"
+		}
+		err := &ParseError{Code: CodeUnexpectedToken, Message: msg, Span: cstSpan(e)}
+		return nil, finishError(err, source, ParseOptions{})
+	}
+	b := &cstBuilder{p: newParser(source, ParseOptions{}), source: source}
+	doc, err := b.document(root)
+	return doc, finishError(err, source, ParseOptions{})
+}
+
+type cstBuilder struct {
+	p      *parser
+	source string
+}
+
+func cstSpan(n CSTNode) Span {
+	return Span{int(n.StartByte()), int(n.EndByte())}
+}
+
+// cstChildren returns the children of n that are not comments.
+func cstChildren(n CSTNode) []CSTNode {
+	var children []CSTNode
+	for i := uint(0); i < n.ChildCount(); i++ {
+		child := n.Child(i)
+		if kind := child.Kind(); kind != "line_comment" && kind != "doc_comment" {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// cstField returns the child of n in field name, or nil.
+func cstField(n CSTNode, name string) CSTNode {
+	for i := uint(0); i < n.ChildCount(); i++ {
+		if n.FieldNameForChild(i) == name {
+			return n.Child(i)
+		}
+	}
+	return nil
+}
+
+// firstError returns the first error or missing node below n, in source
+// order, or nil.
+func firstError(n CSTNode) CSTNode {
+	if n.IsError() || n.IsMissing() {
+		return n
+	}
+	if !n.HasError() {
+		return nil
+	}
+	for i := uint(0); i < n.ChildCount(); i++ {
+		if e := firstError(n.Child(i)); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// collectComments records the comments below n with the lexer, as lexing
+// the whole source would. A doc comment node spans several lines.
+func (b *cstBuilder) collectComments(n CSTNode) {
+	switch n.Kind() {
+	case "line_comment":
+		b.addComment(cstSpan(n))
+		return
+	case "doc_comment":
+		span := cstSpan(n)
+		for offset := span.Start; offset < span.End; {
+			line := b.source[offset:span.End]
+			if i := strings.IndexByte(line, '\n'); i >= 0 {
+				line = line[:i]
+			}
+			trimmed := strings.TrimLeft(line, " \t")
+			if trimmed != "" {
+				start := offset + len(line) - len(trimmed)
+				b.addComment(Span{start, offset + len(line)})
+			}
+			offset += len(line) + 1
+		}
+		return
+	}
+	for i := uint(0); i < n.ChildCount(); i++ {
+		b.collectComments(n.Child(i))
+	}
+}
+
+func (b *cstBuilder) addComment(span Span) {
+	text := strings.TrimSuffix(span.Slice(b.source), "\r")
+	b.p.lexer.comments = append(b.p.lexer.comments, &Comment{Text: text, Span: Span{span.Start, span.Start + len(text)}})
+}
+
+// token lexes the leaf n, which must be a single token of type typ.
+func (b *cstBuilder) token(n CSTNode, typ TokenType) (*Token, error) {
+	l := b.p.lexer
+	l.pos, l.bytePos = int(n.StartByte()), int(n.StartByte())
+	tok, err := l.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if tok.Type != typ || tok.Span != cstSpan(n) {
+		return nil, &ParseError{Code: CodeUnexpectedToken, Message: "unexpected token", Span: cstSpan(n)}
+	}
+	return tok, nil
+}
+
+// next lexes the token following offset, without recording comments, or
+// returns nil if it does not lex.
+func (b *cstBuilder) next(offset int) *Token {
+	l := b.p.lexer
+	l.pos, l.bytePos = offset, offset
+	skip := l.skipComments
+	l.skipComments = true
+	defer func() { l.skipComments = skip }()
+	tok, err := l.nextToken()
+	if err != nil {
+		return nil
+	}
+	return tok
+}
+
+func (b *cstBuilder) document(root CSTNode) (*Document, error) {
+	b.collectComments(root)
+	b.p.current = &Token{Type: TokenEOF, Span: Span{len(b.source), len(b.source)}}
+
+	nodes := cstChildren(root)
+	start := len(b.source)
+	if len(nodes) > 0 {
+		start = int(nodes[0].StartByte())
+	}
+	entries := []*Entry{}
+
+	// An object opening the document is its explicit root object.
+	if len(nodes) > 0 {
+		if key := cstField(nodes[0], "key"); key != nil && isRootObject(key) {
+			value, err := b.value(key)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, &Entry{Key: &Value{Span: Span{-1, -1}, Implicit: true}, Value: value})
+			trailing := nodes[1:]
+			if v := cstField(nodes[0], "value"); v != nil {
+				trailing = append([]CSTNode{v}, trailing...)
+			}
+			if len(trailing) > 0 {
+				return nil, &ParseError{
+					Code:    CodeTrailingContent,
+					Message: "trailing content after explicit root object",
+					Span:    Span{int(trailing[0].StartByte()), len(b.source)},
+				}
+			}
+			return b.p.document(entries, start), nil
+		}
+	}
+
+	ps := newPathState()
+	for _, n := range nodes {
+		entry, err := b.documentEntry(n, ps)
+		if err == nil {
+			err = b.p.countEntry(entry)
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return b.p.document(entries, start), nil
+}
+
+// isRootObject reports whether the expr n is an untagged object.
+func isRootObject(n CSTNode) bool {
+	payload := cstField(n, "payload")
+	return cstField(n, "tag") == nil && payload != nil && payload.Kind() == "object"
+}
+
+// documentEntry converts a document-level entry, expanding dotted keys
+// and checking key paths as parseEntryWithPathCheck does.
+func (b *cstBuilder) documentEntry(n CSTNode, ps *pathState) (*Entry, error) {
+	key, err := b.value(cstField(n, "key"))
+	if err != nil {
+		return nil, err
+	}
+	if key.PayloadKind == PayloadObject {
+		return &Entry{Key: &Value{Span: Span{-1, -1}, Implicit: true}, Value: key}, nil
+	}
+	value, err := b.entryValue(n, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.PayloadKind == PayloadScalar && key.Scalar.Kind == ScalarBare && strings.Contains(key.Scalar.Text, ".") {
+		return b.expandDottedPath(key, value, ps)
+	}
+	if err := b.p.validateKey(key); err != nil {
+		return nil, err
+	}
+	entry := &Entry{Key: key, Value: value}
+	if text := keyText(key); text != "" {
+		kind := pathValueTerminal
+		if value.PayloadKind == PayloadObject {
+			kind = pathValueObject
+		}
+		if err := b.p.checkPath(ps, []string{text}, key.Span, kind); err != nil {
+			return nil, err
+		}
+		entry.path, entry.pathKind = []string{text}, kind
+	}
+	return entry, nil
+}
+
+// entryValue converts the value of entry n, or returns the implicit unit
+// value of key.
+func (b *cstBuilder) entryValue(n CSTNode, key *Value) (*Value, error) {
+	if v := cstField(n, "value"); v != nil {
+		return b.value(v)
+	}
+	return &Value{Span: key.Span, Implicit: true}, nil
+}
+
+// expandDottedPath builds the nested objects a dotted key implies, as
+// expandDottedPathWithState does.
+func (b *cstBuilder) expandDottedPath(key, value *Value, ps *pathState) (*Entry, error) {
+	span := key.Span
+	segments := strings.Split(key.Scalar.Text, ".")
+	for _, s := range segments {
+		if s == "" {
+			return nil, &ParseError{Code: CodeInvalidKey, Message: "invalid key", Span: span}
+		}
+	}
+	rawSegments := strings.Split(span.Slice(b.source), ".")
+	segmentSpans := make([]Span, len(segments))
+	offset := span.Start
+	for i, segment := range rawSegments {
+		segmentSpans[i] = Span{offset, offset + len(segment)}
+		offset += len(segment) + 1
+	}
+	if value.Implicit {
+		value.Span = segmentSpans[len(segments)-1]
+	}
+
+	kind := pathValueTerminal
+	if value.PayloadKind == PayloadObject {
+		kind = pathValueObject
+	}
+	if err := b.p.checkPath(ps, segments, span, kind); err != nil {
+		return nil, err
+	}
+
+	lastKeyEnd := segmentSpans[len(segments)-1].End
+	result := value
+	for i := len(segments) - 1; i > 0; i-- {
+		segSpan := segmentSpans[i]
+		segmentKey := &Value{
+			Span:        segSpan,
+			PayloadKind: PayloadScalar,
+			Scalar:      &Scalar{Text: segments[i], Raw: rawSegments[i], Kind: ScalarBare, Span: segSpan},
+		}
+		objSpan := Span{segmentSpans[i-1].Start, lastKeyEnd}
+		result = &Value{
+			Span:        objSpan,
+			PayloadKind: PayloadObject,
+			Object:      &Object{Entries: []*Entry{{Key: segmentKey, Value: result}}, Span: objSpan},
+		}
+	}
+	firstSpan := segmentSpans[0]
+	outerKey := &Value{
+		Span:        firstSpan,
+		PayloadKind: PayloadScalar,
+		Scalar:      &Scalar{Text: segments[0], Raw: rawSegments[0], Kind: ScalarBare, Span: firstSpan},
+	}
+	return &Entry{Key: outerKey, Value: result, path: segments, pathKind: kind}, nil
+}
+
+// objectEntry converts an entry of a braced object, checking for duplicate
+// keys as parseEntryWithDupCheck does.
+func (b *cstBuilder) objectEntry(n CSTNode, seenKeys map[string]Span) (*Entry, error) {
+	key, err := b.value(cstField(n, "key"))
+	if err != nil {
+		return nil, err
+	}
+	if key.PayloadKind == PayloadObject {
+		return &Entry{Key: &Value{Span: Span{-1, -1}, Implicit: true}, Value: key}, nil
+	}
+	if text := keyText(key); text != "" {
+		if first, exists := seenKeys[text]; exists {
+			return nil, &ParseError{
+				Code:    CodeDuplicateKey,
+				Message: "duplicate key",
+				Span:    key.Span,
+				Related: []Label{{Message: "first defined here", Span: first}},
+			}
+		}
+		seenKeys[text] = key.Span
+	}
+	if err := b.p.validateKey(key); err != nil {
+		return nil, err
+	}
+	value, err := b.entryValue(n, key)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Key: key, Value: value}, nil
+}
+
+// value converts an expr node, or a payload node standing alone as an
+// attribute value.
+func (b *cstBuilder) value(n CSTNode) (*Value, error) {
+	if n.Kind() != "expr" {
+		return b.payload(n)
+	}
+	children := cstChildren(n)
+	if len(children) == 1 && children[0].Kind() == "attributes" {
+		return b.attributes(children[0])
+	}
+	payload := cstField(n, "payload")
+	tagNode := cstField(n, "tag")
+	if tagNode == nil {
+		return b.payload(payload)
+	}
+	tok, err := b.token(tagNode, TokenTag)
+	if err != nil {
+		return nil, err
+	}
+	tag := &Tag{Name: tok.Text, Span: tok.Span, NameSpan: Span{tok.Span.Start + 1, tok.Span.End}}
+	if payload == nil {
+		// The parser reads a scalar touching the tag as a broken tag name,
+		// as in `@org/package`.
+		if next := b.next(tok.Span.End); next != nil && !next.HadWhitespaceBefore && next.Type == TokenScalar {
+			return nil, &ParseError{Code: CodeInvalidTagName, Message: "invalid tag name", Span: Span{tok.Span.Start, next.Span.End}}
+		}
+		return &Value{Span: tok.Span, Tag: tag}, nil
+	}
+	v, err := b.payload(payload)
+	if err != nil {
+		return nil, err
+	}
+	v.Tag = tag
+	return v, nil
+}
+
+// payload converts an untagged scalar, sequence, object or unit node.
+func (b *cstBuilder) payload(n CSTNode) (*Value, error) {
+	switch n.Kind() {
+	case "scalar":
+		return b.payload(cstChildren(n)[0])
+	case "bare_scalar", "quoted_scalar", "raw_scalar", "heredoc":
+		scalar, err := b.scalar(n)
+		if err != nil {
+			return nil, err
+		}
+		return &Value{Span: scalar.Span, PayloadKind: PayloadScalar, Scalar: scalar}, nil
+	case "unit":
+		tok, err := b.token(n, TokenAt)
+		if err != nil {
+			return nil, err
+		}
+		// Anything but a delimiter touching the `@` makes a broken tag name,
+		// as in `@123`.
+		if next := b.next(tok.Span.End); next != nil && !next.HadWhitespaceBefore {
+			switch next.Type {
+			case TokenEOF, TokenRBrace, TokenRParen, TokenComma, TokenLBrace, TokenLParen:
+			default:
+				return nil, &ParseError{Code: CodeInvalidTagName, Message: "invalid tag name", Span: Span{tok.Span.Start, next.Span.End}}
+			}
+		}
+		return &Value{Span: tok.Span}, nil
+	case "sequence":
+		seq, err := b.sequence(n)
+		if err != nil {
+			return nil, err
+		}
+		return &Value{Span: seq.Span, PayloadKind: PayloadSequence, Sequence: seq}, nil
+	case "object":
+		obj, err := b.object(n)
+		if err != nil {
+			return nil, err
+		}
+		return &Value{Span: obj.Span, PayloadKind: PayloadObject, Object: obj}, nil
+	}
+	return nil, &ParseError{Code: CodeUnexpectedToken, Message: "unexpected token", Span: cstSpan(n)}
+}
+
+var scalarTokens = map[string]struct {
+	typ  TokenType
+	kind ScalarKind
+}{
+	"bare_scalar":   {TokenScalar, ScalarBare},
+	"quoted_scalar": {TokenQuoted, ScalarQuoted},
+	"raw_scalar":    {TokenRaw, ScalarRaw},
+	"heredoc":       {TokenHeredoc, ScalarHeredoc},
+}
+
+func (b *cstBuilder) scalar(n CSTNode) (*Scalar, error) {
+	t := scalarTokens[n.Kind()]
+	tok, err := b.token(n, t.typ)
+	if err != nil {
+		return nil, err
+	}
+	scalar := &Scalar{Text: tok.Text, Raw: b.p.sourceText(tok.Span), Kind: t.kind, Span: tok.Span}
+	if t.kind == ScalarHeredoc {
+		if err := b.p.parseHeredocOpening(scalar); err != nil {
+			return nil, err
+		}
+	}
+	return scalar, nil
+}
+
+func (b *cstBuilder) sequence(n CSTNode) (*Sequence, error) {
+	span := cstSpan(n)
+	if err := b.p.enter(Span{span.Start, span.Start + 1}); err != nil {
+		return nil, err
+	}
+	defer b.p.leave()
+	items := []*Value{}
+	for _, child := range cstChildren(n) {
+		if child.Kind() != "expr" {
+			continue
+		}
+		item, err := b.value(child)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return &Sequence{Items: items, Span: span}, nil
+}
+
+func (b *cstBuilder) object(n CSTNode) (*Object, error) {
+	span := cstSpan(n)
+	if err := b.p.enter(Span{span.Start, span.Start + 1}); err != nil {
+		return nil, err
+	}
+	defer b.p.leave()
+	entries := []*Entry{}
+	seenKeys := make(map[string]Span)
+	for _, child := range cstChildren(n) {
+		if child.Kind() != "entry" {
+			continue
+		}
+		entry, err := b.objectEntry(child, seenKeys)
+		if err == nil {
+			err = b.p.countEntry(entry)
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return &Object{Entries: entries, Span: span}, nil
+}
+
+// attributes converts `key>value` pairs to an attribute object, as
+// parseAttributesAfterGT does.
+func (b *cstBuilder) attributes(n CSTNode) (*Value, error) {
+	var attrs []*Entry
+	for _, attr := range cstChildren(n) {
+		scalar, err := b.scalar(cstField(attr, "key"))
+		if err != nil {
+			return nil, err
+		}
+		key := &Value{Span: scalar.Span, PayloadKind: PayloadScalar, Scalar: scalar}
+		value, err := b.value(cstField(attr, "value"))
+		if err != nil {
+			return nil, err
+		}
+		entry := &Entry{Key: key, Value: value}
+		if err := b.p.countEntry(entry); err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, entry)
+	}
+	obj := &Object{
+		Entries:    attrs,
+		Span:       Span{attrs[0].Key.Span.Start, attrs[len(attrs)-1].Value.Span.End},
+		Attributes: true,
+	}
+	return &Value{Span: obj.Span, PayloadKind: PayloadObject, Object: obj}, nil
+}
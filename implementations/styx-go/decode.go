@@ -0,0 +1,197 @@
+package styx
+
+import (
+	"encoding"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// Decode stores the document's content in the value pointed to by target,
+// treating the document as an object, or as its root object for a document
+// consisting of one. See Value.Decode.
+func (d *Document) Decode(target any) error {
+	entries, _ := d.rootEntries()
+	root := &Value{Span: d.Span, PayloadKind: PayloadObject, Object: &Object{Entries: *entries, Span: d.Span}}
+	return root.Decode(target)
+}
+
+// Decode stores the value in the value pointed to by target, converting as
+// follows:
+//
+//   - a type implementing encoding.TextUnmarshaler, strings, bools (`true`
+//     or `false`), numbers (integers as Go literals, so `0x1F` and `1_000`
+//     are accepted) and time.Duration (as for time.ParseDuration) decode
+//     from scalars;
+//   - slices decode from sequences, item by item;
+//   - maps with string keys, and structs, decode from objects;
+//   - pointers are allocated as needed, and an empty interface receives
+//     the value's Interface().
+//
+// A struct field receives the entry whose key is given by the field's
+// `styx` tag, as in `styx:"listen_addr"`, or else matches the field name
+// case-insensitively; a tag of "-" skips the field. Keys without a field
+// are ignored, and fields without a key are left untouched, so target may
+// hold defaults. Tags on values are ignored. A value of the wrong shape
+// fails with a *ConversionError naming its path.
+func (v *Value) Decode(target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("styx: Decode needs a non-nil pointer")
+	}
+	return decodeValue(nil, v, rv.Elem())
+}
+
+func decodeValue(path []string, v *Value, rv reflect.Value) error {
+	fail := func(err error) error {
+		return &ConversionError{Path: strings.Join(path, "."), Type: rv.Type().String(), Span: v.FullSpan(), Err: err}
+	}
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeValue(path, v, rv.Elem())
+	}
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		rv.Set(reflect.ValueOf(v.Interface()))
+		return nil
+	}
+	if reflect.PointerTo(rv.Type()).Implements(textUnmarshalerType) {
+		if v.PayloadKind != PayloadScalar {
+			return fail(errNotScalar)
+		}
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v.Scalar.Text)); err != nil {
+			return fail(err)
+		}
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice:
+		if v.PayloadKind != PayloadSequence {
+			return fail(errNotSequence)
+		}
+		items := reflect.MakeSlice(rv.Type(), len(v.Sequence.Items), len(v.Sequence.Items))
+		for i, item := range v.Sequence.Items {
+			if err := decodeValue(extendPath(path, strconv.Itoa(i)), item, items.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(items)
+		return nil
+	case reflect.Map:
+		if v.PayloadKind != PayloadObject {
+			return fail(errNotObject)
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return fail(errors.New("map keys must be strings"))
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for _, entry := range combineSplitEntries(v.Object.Entries) {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(extendPath(path, entry.KeyText()), entry.Value, elem); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(entry.KeyText()).Convert(rv.Type().Key()), elem)
+		}
+		return nil
+	case reflect.Struct:
+		if v.PayloadKind != PayloadObject {
+			return fail(errNotObject)
+		}
+		for _, entry := range combineSplitEntries(v.Object.Entries) {
+			field, ok := structField(rv, entry.KeyText())
+			if !ok {
+				continue
+			}
+			if err := decodeValue(extendPath(path, entry.KeyText()), entry.Value, field); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if v.PayloadKind != PayloadScalar {
+		return fail(errNotScalar)
+	}
+	text := v.Scalar.Text
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(text)
+	case reflect.Bool:
+		switch text {
+		case "true":
+			rv.SetBool(true)
+		case "false":
+			rv.SetBool(false)
+		default:
+			return fail(errors.New(strconv.Quote(text) + " is neither true nor false"))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Type() == durationType {
+			d, err := time.ParseDuration(text)
+			if err != nil {
+				return fail(err)
+			}
+			rv.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(text, 0, rv.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(text, 0, rv.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, rv.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		rv.SetFloat(f)
+	default:
+		return fail(errors.New("unsupported type"))
+	}
+	return nil
+}
+
+// structField returns the exported field of the struct rv that receives
+// key, as described for Value.Decode.
+func structField(rv reflect.Value, key string) (reflect.Value, bool) {
+	t := rv.Type()
+	var folded reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, tagged := f.Tag.Lookup("styx")
+		name, _, _ = strings.Cut(name, ",")
+		switch {
+		case name == "-":
+			continue
+		case tagged && name != "":
+			if name == key {
+				return rv.Field(i), true
+			}
+		case f.Name == key:
+			return rv.Field(i), true
+		case !folded.IsValid() && strings.EqualFold(f.Name, key):
+			folded = rv.Field(i)
+		}
+	}
+	return folded, folded.IsValid()
+}
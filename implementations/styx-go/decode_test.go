@@ -0,0 +1,87 @@
+package styx
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type decodeConfig struct {
+	Name    string
+	Port    uint16
+	Debug   bool
+	Ratio   float64
+	Timeout time.Duration
+	Listen  netip.Addr `styx:"listen_addr"`
+	Hosts   []string
+	Limits  map[string]int
+	TLS     *struct {
+		Cert string
+	}
+	Extra   any
+	Skipped string `styx:"-"`
+	Kept    string
+}
+
+func TestDecode(t *testing.T) {
+	doc, err := Parse(`name app
+port 0x1F90
+debug true
+ratio 0.5
+timeout 1m
+listen_addr 127.0.0.1
+hosts (a b)
+limits.cpu 2
+limits.mem 512
+tls {cert /etc/cert.pem}
+extra {k v}
+skipped x
+unknown 1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := decodeConfig{Kept: "default"}
+	if err := doc.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := decodeConfig{
+		Name:    "app",
+		Port:    8080,
+		Debug:   true,
+		Ratio:   0.5,
+		Timeout: time.Minute,
+		Listen:  netip.MustParseAddr("127.0.0.1"),
+		Hosts:   []string{"a", "b"},
+		Limits:  map[string]int{"cpu": 2, "mem": 512},
+		TLS:     &struct{ Cert string }{"/etc/cert.pem"},
+		Extra:   map[string]any{"k": "v"},
+		Kept:    "default",
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v\nwant %+v", cfg, want)
+	}
+
+	for source, path := range map[string]string{
+		"port 70000":        "port",
+		"hosts (a {b c})":   "hosts.1",
+		"debug yes":         "debug",
+		"listen_addr nope":  "listen_addr",
+		"limits {cpu many}": "limits.cpu",
+		"tls (x)":           "tls",
+	} {
+		doc, err := Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var conv *ConversionError
+		if err := doc.Decode(&decodeConfig{}); !errors.As(err, &conv) || conv.Path != path {
+			t.Errorf("%s: got %v, want an error at %s", source, err, path)
+		}
+	}
+	if err := doc.Decode(decodeConfig{}); err == nil {
+		t.Error("non-pointer target: want error")
+	}
+}
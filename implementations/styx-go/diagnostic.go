@@ -0,0 +1,220 @@
+package styx
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Severity ranks a diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText returns the severity's name, as String does.
+func (s Severity) MarshalText() ([]byte, error) {
+	return marshalEnum(s, "Severity")
+}
+
+// UnmarshalText sets the severity from its name.
+func (s *Severity) UnmarshalText(text []byte) error {
+	return unmarshalEnum(s, text, "Severity", SeverityInfo)
+}
+
+// Code is a stable identifier for a kind of diagnostic, such as "STYX0001".
+// Unlike messages, codes do not change between releases, so tools can match
+// on them.
+type Code string
+
+// Codes of the errors and warnings reported by the parser and by schema
+// validation. Codes are never reused or renumbered; new kinds of diagnostic
+// get new codes.
+const (
+	// CodeUnexpectedToken marks a token that cannot appear where it was found.
+	CodeUnexpectedToken Code = "STYX0001"
+	// CodeExpectedValue marks a missing value, such as after `>` or a tag's `@`.
+	CodeExpectedValue Code = "STYX0002"
+	// CodeUnclosedObject marks an object without its closing `}`.
+	CodeUnclosedObject Code = "STYX0003"
+	// CodeUnclosedSequence marks a sequence without its closing `)`.
+	CodeUnclosedSequence Code = "STYX0004"
+	// CodeUnclosedString marks a quoted scalar without its closing quote on the same line.
+	CodeUnclosedString Code = "STYX0005"
+	// CodeUnclosedRawString marks a raw string without its closing delimiter.
+	CodeUnclosedRawString Code = "STYX0006"
+	// CodeUnclosedHeredoc marks a heredoc without its closing delimiter line.
+	CodeUnclosedHeredoc Code = "STYX0007"
+	// CodeInvalidHeredoc marks a malformed heredoc opening, delimiter or option.
+	CodeInvalidHeredoc Code = "STYX0008"
+	// CodeInvalidEscape marks a malformed or unknown escape sequence.
+	CodeInvalidEscape Code = "STYX0009"
+	// CodeInvalidUTF8 marks invalid UTF-8, when rejected.
+	CodeInvalidUTF8 Code = "STYX0010"
+	// CodeDuplicateKey marks a key defined twice in the same object.
+	CodeDuplicateKey Code = "STYX0011"
+	// CodeReopenedPath marks a dotted path reopened after a sibling appeared.
+	CodeReopenedPath Code = "STYX0012"
+	// CodeNestIntoTerminal marks a dotted path nesting into a key with a terminal value.
+	CodeNestIntoTerminal Code = "STYX0013"
+	// CodeInvalidKey marks a value that cannot be a key, or an empty dotted path segment.
+	CodeInvalidKey Code = "STYX0014"
+	// CodeInvalidTagName marks a malformed tag name.
+	CodeInvalidTagName Code = "STYX0015"
+	// CodeCommaInSequence marks a `,` between sequence items.
+	CodeCommaInSequence Code = "STYX0016"
+	// CodeTrailingContent marks content after an explicit root object or a standalone value.
+	CodeTrailingContent Code = "STYX0017"
+	// CodeInputTooLarge marks input over ParseOptions.MaxInputSize.
+	CodeInputTooLarge Code = "STYX0018"
+	// CodeScalarTooLong marks a scalar or heredoc body over its size limit.
+	CodeScalarTooLong Code = "STYX0019"
+	// CodeTooDeep marks nesting over ParseOptions.MaxDepth.
+	CodeTooDeep Code = "STYX0020"
+	// CodeTooManyEntries marks entries over ParseOptions.MaxEntries.
+	CodeTooManyEntries Code = "STYX0021"
+	// CodeTooComplex marks input exceeding the parser's internal work budgets.
+	CodeTooComplex Code = "STYX0022"
+	// CodeKeyEndsWithColon warns of a bare key ending in `:`, as in the
+	// YAML-style `key: value`, which defines the key `key:`.
+	CodeKeyEndsWithColon Code = "STYX0023"
+	// CodeMixedIndentation warns of a dedented heredoc whose indentation
+	// mixes tabs and spaces, which dedenting counts alike.
+	CodeMixedIndentation Code = "STYX0024"
+	// CodeDeepNesting warns of nesting deeper than
+	// ParseOptions.NestingWarningDepth.
+	CodeDeepNesting Code = "STYX0025"
+	// CodeTooManyErrors marks the end of a recovering parse that reached
+	// ParseOptions.MaxErrors.
+	CodeTooManyErrors Code = "STYX0026"
+	// STYX0027 and STYX0028 are reserved.
+
+	// CodeInvisibleCharacter warns of an invisible or ambiguous character
+	// in a bare scalar, reported under
+	// ParseOptions.CheckInvisibleCharacters.
+	CodeInvisibleCharacter Code = "STYX0029"
+	// CodeInvalidSchema marks a schema file ParseSchema cannot use, such as
+	// one referring to an undefined type.
+	CodeInvalidSchema Code = "STYX0030"
+	// CodeTypeMismatch marks a value of another type than its schema
+	// requires, such as an object where a string is expected.
+	CodeTypeMismatch Code = "STYX0031"
+	// CodeInvalidValue marks a value of the right type that its schema
+	// rejects: out of range, too long, not matching a pattern or not one
+	// of the allowed values.
+	CodeInvalidValue Code = "STYX0032"
+	// CodeUnknownField marks a key its object's schema does not declare.
+	CodeUnknownField Code = "STYX0033"
+	// CodeMissingField marks an object lacking a required field.
+	CodeMissingField Code = "STYX0034"
+	// CodeUnknownVariant marks a tag that names none of an enum's variants.
+	CodeUnknownVariant Code = "STYX0035"
+	// CodeDeprecatedField warns of a value its schema marks deprecated.
+	CodeDeprecatedField Code = "STYX0036"
+	// CodeNotCanonical warns of a document not in the canonical form
+	// Canonical writes. The parser does not report it; linters do.
+	CodeNotCanonical Code = "STYX0037"
+)
+
+// warningSummaries describes the warning codes, as the sentinel errors
+// describe the error codes.
+var warningSummaries = map[Code]string{
+	CodeKeyEndsWithColon:   "key ends with `:`",
+	CodeMixedIndentation:   "mixed tabs and spaces in heredoc indentation",
+	CodeDeepNesting:        "deep nesting",
+	CodeInvisibleCharacter: "invisible or ambiguous character",
+	CodeDeprecatedField:    "deprecated field",
+	CodeNotCanonical:       "not in canonical form",
+}
+
+// Diagnostic describes a problem found in a document, in the shape editors,
+// linters and command-line tools report: a code, a severity, a message
+// anchored at a primary span, secondary labeled spans, an optional help
+// text and suggested fixes.
+type Diagnostic struct {
+	Code     Code
+	Severity Severity
+	Message  string
+	Span     Span
+	Labels   []Label
+	Help     string
+	Fixes    []Fix
+
+	// args holds the values interpolated into Message, which ParseError
+	// passes on as Args.
+	args []string
+}
+
+// Fix is a suggested change that resolves a diagnostic. Its edits are
+// given in terms of the parsed source and can be applied with ApplyEdits.
+type Fix struct {
+	Message string
+	Edits   []Edit
+}
+
+func (d *Diagnostic) String() string {
+	severity := d.Severity.String()
+	if d.Code != "" {
+		severity += "[" + string(d.Code) + "]"
+	}
+	return fmt.Sprintf("%s at %d-%d: %s", severity, d.Span.Start, d.Span.End, d.Message)
+}
+
+// Diagnostic returns the error as an error-severity diagnostic.
+func (e *ParseError) Diagnostic() *Diagnostic {
+	return &Diagnostic{
+		Code:     e.Code,
+		Severity: SeverityError,
+		Message:  e.Message,
+		Span:     e.Span,
+		Labels:   e.Related,
+		Help:     e.Help,
+		Fixes:    e.Fixes,
+		args:     e.Args,
+	}
+}
+
+// ParseError converts the diagnostic back to a ParseError, for APIs that
+// report errors as ParseErrors. The severity is dropped.
+func (d *Diagnostic) ParseError() *ParseError {
+	return &ParseError{
+		Message: d.Message,
+		Span:    d.Span,
+		Related: d.Labels,
+		Code:    d.Code,
+		Help:    d.Help,
+		Fixes:   d.Fixes,
+		Args:    d.args,
+	}
+}
+
+// Diagnose parses source in recovery mode, like ParseRecover, and reports
+// every problem found as a diagnostic, in source order: the errors, and the
+// document's warnings.
+func Diagnose(source string, opts ParseOptions) (*Document, []*Diagnostic) {
+	doc, errs := ParseRecover(source, opts)
+	diags := make([]*Diagnostic, len(errs))
+	for i, err := range errs {
+		diags[i] = err.Diagnostic()
+	}
+	if doc != nil && len(doc.Warnings) > 0 {
+		diags = append(diags, doc.Warnings...)
+		sort.SliceStable(diags, func(i, j int) bool { return diags[i].Span.Start < diags[j].Span.Start })
+	}
+	return doc, diags
+}
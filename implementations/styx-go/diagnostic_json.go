@@ -0,0 +1,104 @@
+package styx
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONDiagnostic is the JSON form of a diagnostic, for CI systems and editor
+// plugins. Its schema is stable:
+//
+//	{
+//	  "file": "config.styx",          // omitted when unknown
+//	  "code": "STYX0011",             // omitted for diagnostics without a code
+//	  "severity": "error",            // "error", "warning" or "info"
+//	  "message": "duplicate key",
+//	  "span": {"start": 4, "end": 5, "start_line": 2, "start_column": 1, "end_line": 2, "end_column": 2},
+//	  "labels": [{"message": "first defined here", "span": {...}}], // omitted when empty
+//	  "help": "...",                  // omitted when empty
+//	  "fixes": [{"message": "remove the `,`", "edits": [{"span": {...}, "text": ""}]}] // omitted when empty
+//	}
+//
+// Offsets are bytes from the start of the file. Lines and columns are
+// 1-based, with columns counted in characters.
+type JSONDiagnostic struct {
+	File     string      `json:"file,omitempty"`
+	Code     Code        `json:"code,omitempty"`
+	Severity string      `json:"severity"`
+	Message  string      `json:"message"`
+	Span     JSONSpan    `json:"span"`
+	Labels   []JSONLabel `json:"labels,omitempty"`
+	Help     string      `json:"help,omitempty"`
+	Fixes    []JSONFix   `json:"fixes,omitempty"`
+}
+
+// JSONSpan is a span with its start and end positions resolved to lines and
+// columns.
+type JSONSpan struct {
+	Start       int `json:"start"`
+	End         int `json:"end"`
+	StartLine   int `json:"start_line"`
+	StartColumn int `json:"start_column"`
+	EndLine     int `json:"end_line"`
+	EndColumn   int `json:"end_column"`
+}
+
+// JSONLabel is the JSON form of a Label.
+type JSONLabel struct {
+	Message string   `json:"message"`
+	Span    JSONSpan `json:"span"`
+}
+
+// JSONFix is the JSON form of a Fix.
+type JSONFix struct {
+	Message string     `json:"message"`
+	Edits   []JSONEdit `json:"edits"`
+}
+
+// JSONEdit is the JSON form of an Edit: the text replacing the span.
+type JSONEdit struct {
+	Span JSONSpan `json:"span"`
+	Text string   `json:"text"`
+}
+
+// JSON returns the JSON form of the diagnostic, resolving spans against
+// source. file names the source in the output and may be empty.
+func (d *Diagnostic) JSON(file, source string) JSONDiagnostic {
+	out := JSONDiagnostic{
+		File:     file,
+		Code:     d.Code,
+		Severity: d.Severity.String(),
+		Message:  d.Message,
+		Span:     jsonSpan(source, d.Span),
+		Help:     d.Help,
+	}
+	for _, label := range d.Labels {
+		out.Labels = append(out.Labels, JSONLabel{Message: label.Message, Span: jsonSpan(source, label.Span)})
+	}
+	for _, fix := range d.Fixes {
+		f := JSONFix{Message: fix.Message, Edits: []JSONEdit{}}
+		for _, edit := range fix.Edits {
+			f.Edits = append(f.Edits, JSONEdit{Span: jsonSpan(source, edit.Span), Text: edit.Text})
+		}
+		out.Fixes = append(out.Fixes, f)
+	}
+	return out
+}
+
+func jsonSpan(source string, span Span) JSONSpan {
+	s := JSONSpan{Start: span.Start, End: span.End}
+	s.StartLine, s.StartColumn = position(source, span.Start)
+	s.EndLine, s.EndColumn = position(source, span.End)
+	return s
+}
+
+// WriteDiagnosticsJSON writes diags to w as a single JSON object of the form
+// {"diagnostics": [...]}, followed by a newline.
+func WriteDiagnosticsJSON(w io.Writer, diags []JSONDiagnostic) error {
+	if diags == nil {
+		diags = []JSONDiagnostic{}
+	}
+	return json.NewEncoder(w).Encode(struct {
+		Diagnostics []JSONDiagnostic `json:"diagnostics"`
+	}{diags})
+}
@@ -0,0 +1,120 @@
+package styx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	doc, diags := Diagnose("a 1\nb {\nc r#\"x\n", ParseOptions{})
+	if doc == nil || len(diags) == 0 {
+		t.Fatalf("got doc %v, %d diagnostics", doc, len(diags))
+	}
+	d := diags[0]
+	if d.Severity != SeverityError || d.Help == "" || len(d.Labels) != 2 {
+		t.Errorf("got %+v", d)
+	}
+	if d.String() != "error[STYX0006] at 10-15: unclosed raw string (missing `\"#`)" {
+		t.Errorf("got %q", d.String())
+	}
+
+	pe := d.ParseError()
+	if !reflect.DeepEqual(pe.Diagnostic(), d) {
+		t.Errorf("round trip through ParseError changed the diagnostic: %+v", pe)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		source string
+		code   Code
+	}{
+		{"a 1\na 2", CodeDuplicateKey},
+		{"a {b 1", CodeUnclosedObject},
+		{"a (1, 2)", CodeCommaInSequence},
+		{"a @org/pkg", CodeInvalidTagName},
+		{`a "\q"`, CodeInvalidEscape},
+		{`a "x`, CodeUnclosedString},
+		{"a r#\"x", CodeUnclosedRawString},
+		{"a <<EOF\nx\n", CodeUnclosedHeredoc},
+		{"a.b 1\nc 2\na.d 3", CodeReopenedPath},
+		{"a 1\na.b 2", CodeNestIntoTerminal},
+		{"a }", CodeUnexpectedToken},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.source)
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q: got %v, want a parse error", tt.source, err)
+			continue
+		}
+		if pe.Code != tt.code {
+			t.Errorf("%q: got code %s (%s), want %s", tt.source, pe.Code, pe.Message, tt.code)
+		}
+		if !errors.Is(err, codeErrors[tt.code]) || errors.Is(err, ErrTooDeep) {
+			t.Errorf("%q: errors.Is does not match the code's sentinel", tt.source)
+		}
+	}
+
+	_, err := Parse("a 1\na 2")
+	if wrapped := fmt.Errorf("loading config: %w", err); !errors.Is(wrapped, ErrDuplicateKey) {
+		t.Errorf("wrapped error does not match ErrDuplicateKey")
+	}
+}
+
+func TestDiagnosticsJSON(t *testing.T) {
+	source := "a 1\nä 2\nä 3"
+	_, diags := Diagnose(source, ParseOptions{})
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics", len(diags))
+	}
+	var b strings.Builder
+	if err := WriteDiagnosticsJSON(&b, []JSONDiagnostic{diags[0].JSON("x.styx", source)}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"diagnostics":[{"file":"x.styx","code":"STYX0011","severity":"error","message":"duplicate key",` +
+		`"span":{"start":9,"end":11,"start_line":3,"start_column":1,"end_line":3,"end_column":2},` +
+		`"labels":[{"message":"first defined here","span":{"start":4,"end":6,"start_line":2,"start_column":1,"end_line":2,"end_column":2}}]}]}` + "\n"
+	if b.String() != want {
+		t.Errorf("got %s", b.String())
+	}
+
+	b.Reset()
+	WriteDiagnosticsJSON(&b, nil)
+	if b.String() != `{"diagnostics":[]}`+"\n" {
+		t.Errorf("empty: got %s", b.String())
+	}
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	tests := []struct {
+		source string
+		fixed  string
+	}{
+		{"x (1,2)", "x (1 2)"},
+		{"x (1, 2)", "x (1 2)"},
+		{"x (1 2,)", "x (1 2)"},
+		{"x a> b", "x a>b"},
+		{"x a>\ny 1", "x a\ny 1"},
+		{"x a>1 b>", "x a>1 b"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.source)
+		pe, ok := err.(*ParseError)
+		if !ok || len(pe.Fixes) != 1 {
+			t.Errorf("%q: got %v, want one fix", tt.source, err)
+			continue
+		}
+		fixed, err := ApplyEdits(tt.source, pe.Fixes[0].Edits)
+		if err != nil || fixed != tt.fixed {
+			t.Errorf("%q: fix %q gave %q, %v", tt.source, pe.Fixes[0].Message, fixed, err)
+			continue
+		}
+		if _, err := Parse(fixed); err != nil {
+			t.Errorf("%q: fixed source does not parse: %v", fixed, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package styx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ChangeKind classifies a Change.
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Change is a difference found by Diff.
+type Change struct {
+	Kind ChangeKind
+	// Path holds the keys and sequence indices leading to the change.
+	Path []string
+	// Old is the value in the first document, nil when added.
+	Old *Value
+	// New is the value in the second document, nil when removed.
+	New *Value
+}
+
+// OldSpan returns the location of the old value in the first document's
+// source, or an invalid span when the value was added.
+func (c Change) OldSpan() Span {
+	if c.Old == nil {
+		return noSpan
+	}
+	return c.Old.FullSpan()
+}
+
+// NewSpan returns the location of the new value in the second document's
+// source, or an invalid span when the value was removed.
+func (c Change) NewSpan() Span {
+	if c.New == nil {
+		return noSpan
+	}
+	return c.New.FullSpan()
+}
+
+// Diff returns the differences between documents a and b by content, as
+// Hash compares them: how values are written, comments and entry order do
+// not count, and dotted keys compare equal to the objects they imply.
+// Objects are compared key by key, and sequences item by item, with
+// surplus items added or removed at the end. Values of different shapes or
+// tags, and different scalars, are modified. Changes come in the order of
+// a's keys, then of the keys b adds.
+func Diff(a, b *Document) []Change {
+	aEntries, _ := a.rootEntries()
+	bEntries, _ := b.rootEntries()
+	var d differ
+	d.entries(nil, *aEntries, *bEntries)
+	return d.changes
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, path []string, old, new *Value) {
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Old: old, New: new})
+}
+
+func (d *differ) entries(path []string, a, b []*Entry) {
+	a, b = combineSplitEntries(a), combineSplitEntries(b)
+	inB := make(map[string]*Entry, len(b))
+	for _, e := range b {
+		inB[e.KeyText()] = e
+	}
+	inA := make(map[string]bool, len(a))
+	for _, e := range a {
+		inA[e.KeyText()] = true
+		child := extendPath(path, e.KeyText())
+		if other, ok := inB[e.KeyText()]; ok {
+			d.value(child, e.Value, other.Value)
+		} else {
+			d.add(ChangeRemoved, child, e.Value, nil)
+		}
+	}
+	for _, e := range b {
+		if !inA[e.KeyText()] {
+			d.add(ChangeAdded, extendPath(path, e.KeyText()), nil, e.Value)
+		}
+	}
+}
+
+func (d *differ) value(path []string, a, b *Value) {
+	if a.PayloadKind == b.PayloadKind && tagName(a) == tagName(b) {
+		switch a.PayloadKind {
+		case PayloadObject:
+			d.entries(path, a.Object.Entries, b.Object.Entries)
+			return
+		case PayloadSequence:
+			aItems, bItems := a.Sequence.Items, b.Sequence.Items
+			for i := 0; i < max(len(aItems), len(bItems)); i++ {
+				child := extendPath(path, strconv.Itoa(i))
+				switch {
+				case i >= len(aItems):
+					d.add(ChangeAdded, child, nil, bItems[i])
+				case i >= len(bItems):
+					d.add(ChangeRemoved, child, aItems[i], nil)
+				default:
+					d.value(child, aItems[i], bItems[i])
+				}
+			}
+			return
+		}
+	}
+	if a.Hash() != b.Hash() {
+		d.add(ChangeModified, path, a, b)
+	}
+}
+
+func extendPath(path []string, step string) []string {
+	return append(path[:len(path):len(path)], step)
+}
+
+// FormatDiff renders changes found by Diff(a, b) one per line, quoting
+// values as written in their document. Lines start with `+` for added
+// values, `-` for removed ones and `~` for modified ones, as in
+// "~ server.port 8080 -> 9090".
+func FormatDiff(a, b *Document, changes []Change) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		path := strings.Join(c.Path, ".")
+		switch c.Kind {
+		case ChangeAdded:
+			sb.WriteString("+ " + path + " " + valueText(b, c.New) + "\n")
+		case ChangeRemoved:
+			sb.WriteString("- " + path + " " + valueText(a, c.Old) + "\n")
+		case ChangeModified:
+			sb.WriteString("~ " + path + " " + valueText(a, c.Old) + " -> " + valueText(b, c.New) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// valueText returns v as written in doc, on one line, or a summary when v
+// has no source text.
+func valueText(doc *Document, v *Value) string {
+	if v.IsImplicitUnit() {
+		return "@"
+	}
+	if text, ok := doc.SourceFor(v); ok && !strings.Contains(text, "\n") {
+		return text
+	}
+	summary := tagName(v)
+	switch v.PayloadKind {
+	case PayloadScalar:
+		summary += strconv.Quote(v.Scalar.Text)
+	case PayloadSequence:
+		summary += "(...)"
+	case PayloadObject:
+		summary += "{...}"
+	default:
+		if summary == "" {
+			summary = "@"
+		}
+	}
+	return summary
+}
@@ -0,0 +1,55 @@
+package styx
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	a, err := Parse(`server.host localhost
+server.port 8080
+hosts (a b c)
+log info
+db @postgres{host db}
+motd <<EOF
+hello
+EOF
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse(`server {
+  host "localhost"
+  port 9090
+}
+hosts (a x)
+db @sqlite{host db}
+env prod
+motd <<EOF
+hi
+EOF
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(a, b)
+	want := `~ server.port 8080 -> 9090
+~ hosts.1 b -> x
+- hosts.2 c
+- log info
+~ db @postgres{host db} -> @sqlite{host db}
+~ motd "hello\n" -> "hi\n"
++ env prod
+`
+	if got := FormatDiff(a, b, changes); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if c := changes[0]; c.Kind != ChangeModified || c.OldSpan().Slice(a.Source()) != "8080" || c.NewSpan().Slice(b.Source()) != "9090" {
+		t.Errorf("changes[0] = %+v", c)
+	}
+	if c := changes[len(changes)-1]; c.Kind != ChangeAdded || c.Old != nil || c.OldSpan().Valid(len(a.Source())) {
+		t.Errorf("last change = %+v", c)
+	}
+
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Errorf("Diff(a, a) = %v", changes)
+	}
+}
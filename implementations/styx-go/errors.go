@@ -0,0 +1,83 @@
+package styx
+
+import "errors"
+
+// Sentinel errors, one per error code. A *ParseError wraps the sentinel of
+// its code, so callers can test for a kind of error with errors.Is:
+//
+//	if errors.Is(err, styx.ErrDuplicateKey) {
+//		// ...
+//	}
+var (
+	ErrUnexpectedToken   = errors.New("unexpected token")
+	ErrExpectedValue     = errors.New("expected a value")
+	ErrUnclosedObject    = errors.New("unclosed object")
+	ErrUnclosedSequence  = errors.New("unclosed sequence")
+	ErrUnclosedString    = errors.New("unclosed string")
+	ErrUnclosedRawString = errors.New("unclosed raw string")
+	ErrUnclosedHeredoc   = errors.New("unclosed heredoc")
+	ErrInvalidHeredoc    = errors.New("invalid heredoc")
+	ErrInvalidEscape     = errors.New("invalid escape sequence")
+	ErrInvalidUTF8       = errors.New("invalid UTF-8")
+	ErrDuplicateKey      = errors.New("duplicate key")
+	ErrReopenedPath      = errors.New("cannot reopen path")
+	ErrNestIntoTerminal  = errors.New("cannot nest into terminal value")
+	ErrInvalidKey        = errors.New("invalid key")
+	ErrInvalidTagName    = errors.New("invalid tag name")
+	ErrCommaInSequence   = errors.New("comma in sequence")
+	ErrTrailingContent   = errors.New("trailing content")
+	ErrInputTooLarge     = errors.New("input too large")
+	ErrScalarTooLong     = errors.New("scalar too long")
+	ErrTooDeep           = errors.New("maximum nesting depth exceeded")
+	ErrTooManyEntries    = errors.New("too many entries")
+	ErrTooComplex        = errors.New("input too complex")
+	ErrTooManyErrors     = errors.New("too many errors")
+	ErrInvalidSchema     = errors.New("invalid schema")
+	ErrTypeMismatch      = errors.New("type mismatch")
+	ErrInvalidValue      = errors.New("invalid value")
+	ErrUnknownField      = errors.New("unknown field")
+	ErrMissingField      = errors.New("missing field")
+	ErrUnknownVariant    = errors.New("unknown variant")
+)
+
+var codeErrors = map[Code]error{
+	CodeUnexpectedToken:   ErrUnexpectedToken,
+	CodeExpectedValue:     ErrExpectedValue,
+	CodeUnclosedObject:    ErrUnclosedObject,
+	CodeUnclosedSequence:  ErrUnclosedSequence,
+	CodeUnclosedString:    ErrUnclosedString,
+	CodeUnclosedRawString: ErrUnclosedRawString,
+	CodeUnclosedHeredoc:   ErrUnclosedHeredoc,
+	CodeInvalidHeredoc:    ErrInvalidHeredoc,
+	CodeInvalidEscape:     ErrInvalidEscape,
+	CodeInvalidUTF8:       ErrInvalidUTF8,
+	CodeDuplicateKey:      ErrDuplicateKey,
+	CodeReopenedPath:      ErrReopenedPath,
+	CodeNestIntoTerminal:  ErrNestIntoTerminal,
+	CodeInvalidKey:        ErrInvalidKey,
+	CodeInvalidTagName:    ErrInvalidTagName,
+	CodeCommaInSequence:   ErrCommaInSequence,
+	CodeTrailingContent:   ErrTrailingContent,
+	CodeInputTooLarge:     ErrInputTooLarge,
+	CodeScalarTooLong:     ErrScalarTooLong,
+	CodeTooDeep:           ErrTooDeep,
+	CodeTooManyEntries:    ErrTooManyEntries,
+	CodeTooComplex:        ErrTooComplex,
+	CodeTooManyErrors:     ErrTooManyErrors,
+	CodeInvalidSchema:     ErrInvalidSchema,
+	CodeTypeMismatch:      ErrTypeMismatch,
+	CodeInvalidValue:      ErrInvalidValue,
+	CodeUnknownField:      ErrUnknownField,
+	CodeMissingField:      ErrMissingField,
+	CodeUnknownVariant:    ErrUnknownVariant,
+}
+
+// Unwrap returns the sentinel error for the error's code, or the underlying
+// error for errors that did not originate in the parser, such as context
+// cancellation recorded during recovery.
+func (e *ParseError) Unwrap() error {
+	if e.cause != nil {
+		return e.cause
+	}
+	return codeErrors[e.Code]
+}
@@ -0,0 +1,78 @@
+package styx
+
+import (
+	"flag"
+)
+
+// SetFlags sets each flag of fs not given on the command line from the
+// value at the path the flag names, so that flags take precedence over the
+// configuration file:
+//
+//	port := fs.Int("server.port", 80, "listen port")
+//	config := fs.String("config", "", "configuration file")
+//	fs.Parse(os.Args[1:])
+//	doc, err := styx.Parse(...) // read *config
+//	err = styx.SetFlags(fs, doc)
+//
+// Scalars are passed to the flag's Set method and sequences item by item,
+// for flags that accumulate values. A unit sets a boolean flag. Tags are
+// ignored, and paths no flag names are left alone. Values a flag rejects,
+// or with no flag form, fail with a *ConversionError.
+func SetFlags(fs *flag.FlagSet, doc *Document) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] {
+			return
+		}
+		if v, ok := doc.Lookup(f.Name); ok {
+			err = setFlag(fs, f, v)
+		}
+	})
+	return err
+}
+
+func setFlag(fs *flag.FlagSet, f *flag.Flag, v *Value) error {
+	fail := func(err error) error {
+		return &ConversionError{Path: f.Name, Type: "-" + f.Name + " value", Span: v.FullSpan(), Err: err}
+	}
+	switch v.PayloadKind {
+	case PayloadScalar:
+		if err := fs.Set(f.Name, v.Scalar.Text); err != nil {
+			return fail(err)
+		}
+	case PayloadSequence:
+		for _, item := range v.Sequence.Items {
+			if item.PayloadKind != PayloadScalar {
+				return fail(errNotScalar)
+			}
+			if err := fs.Set(f.Name, item.Scalar.Text); err != nil {
+				return fail(err)
+			}
+		}
+	case PayloadNone:
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			return fail(errNotScalar)
+		}
+		if err := fs.Set(f.Name, "true"); err != nil {
+			return fail(err)
+		}
+	default:
+		return fail(errNotScalar)
+	}
+	return nil
+}
+
+// FlagOverrides returns the flags of fs given on the command line, by
+// name, for Loader.AddOverrides. Delete flags that are not configuration,
+// such as the one naming the configuration file, before adding them.
+func FlagOverrides(fs *flag.FlagSet) map[string]string {
+	overrides := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		overrides[f.Name] = f.Value.String()
+	})
+	return overrides
+}
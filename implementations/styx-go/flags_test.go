@@ -0,0 +1,79 @@
+package styx
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listFlag collects every value it is set to.
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(s string) error { *l = append(*l, s); return nil }
+
+func TestSetFlags(t *testing.T) {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	host := fs.String("server.host", "localhost", "")
+	port := fs.Int("server.port", 80, "")
+	verbose := fs.Bool("verbose", false, "")
+	timeout := fs.Duration("timeout", time.Second, "")
+	untouched := fs.String("name", "app", "")
+	var hosts listFlag
+	fs.Var(&hosts, "hosts", "")
+	if err := fs.Parse([]string{"-server.port", "9090"}); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := mustParse(t, "server {host example.com, port 8080}\nverbose\ntimeout @dur\"30s\"\nhosts (a b)\nother 1\n")
+	if err := SetFlags(fs, doc); err != nil {
+		t.Fatal(err)
+	}
+	if *host != "example.com" || *port != 9090 || !*verbose || *timeout != 30*time.Second || *untouched != "app" {
+		t.Errorf("got host %q, port %d, verbose %v, timeout %v, name %q", *host, *port, *verbose, *timeout, *untouched)
+	}
+	if strings.Join(hosts, " ") != "a b" {
+		t.Errorf("hosts = %v", hosts)
+	}
+
+	overrides := FlagOverrides(fs)
+	if len(overrides) != 5 || overrides["server.port"] != "9090" || overrides["verbose"] != "true" {
+		t.Errorf("overrides = %v", overrides)
+	}
+}
+
+func TestSetFlagsErrors(t *testing.T) {
+	for _, source := range []string{"port abc", "port {x 1}", "port", "port (1 {})"} {
+		fs := flag.NewFlagSet("app", flag.ContinueOnError)
+		fs.Int("port", 80, "")
+		err := SetFlags(fs, mustParse(t, source))
+		var conv *ConversionError
+		if !errors.As(err, &conv) || conv.Path != "port" {
+			t.Errorf("%q: error = %v, want a *ConversionError for port", source, err)
+		}
+	}
+}
+
+func TestFlagOverridesLoader(t *testing.T) {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.String("config", "", "")
+	fs.String("log.level", "info", "")
+	if err := fs.Parse([]string{"-config", "app.styx", "-log.level", "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	overrides := FlagOverrides(fs)
+	delete(overrides, "config")
+
+	var l Loader
+	l.AddSource("file", "log.level warn\n")
+	l.AddOverrides(overrides)
+	var cfg struct{ Log struct{ Level string } }
+	if _, err := l.Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Log.Level != "debug" {
+		t.Errorf("level = %q, want debug", cfg.Log.Level)
+	}
+}
@@ -0,0 +1,29 @@
+package styx
+
+import "strings"
+
+// FlatEntry is a leaf value listed by Flatten.
+type FlatEntry struct {
+	// Path joins Keys with dots, as in "server.ports.0".
+	Path string
+	// Keys holds the keys and sequence indices leading to the value.
+	Keys  []string
+	Value *Value
+}
+
+// Flatten lists the document's leaf values in document order with their
+// paths: scalars, units, and empty objects and sequences. Objects and
+// sequences are descended into, sequence items contributing their index
+// as a path segment, so `server {ports (80 443)}` yields "server.ports.0"
+// and "server.ports.1". Tags of objects and sequences are not represented;
+// tagged scalars and units are leaves carrying their tag.
+func (d *Document) Flatten() []FlatEntry {
+	leaves := Select(d, func(path []string, v *Value) bool {
+		return len(queryChildren(QueryMatch{Path: path, Value: v})) == 0
+	})
+	flat := make([]FlatEntry, len(leaves))
+	for i, m := range leaves {
+		flat[i] = FlatEntry{Path: strings.Join(m.Path, "."), Keys: m.Path, Value: m.Value}
+	}
+	return flat
+}
@@ -0,0 +1,30 @@
+package styx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	doc, err := Parse(`server.host localhost
+server.ports (80 443)
+db @postgres{pool 4}
+flags ()
+debug
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range doc.Flatten() {
+		text, _ := doc.SourceFor(e.Value)
+		if e.Value.IsImplicitUnit() {
+			text = "@"
+		}
+		got = append(got, e.Path+"="+text)
+	}
+	want := []string{"server.host=localhost", "server.ports.0=80", "server.ports.1=443", "db.pool=4", "flags=()", "debug=@"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,373 @@
+package styx
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// formatIndent is the indentation of nested object entries written by
+// Format.
+const formatIndent = "    "
+
+// Format writes the document as Styx source. Each entry goes on its own
+// line, preceded by its leading comments and followed by its trailing
+// comment; objects are written with braces, one entry per line, except
+// objects written in attribute syntax, which keep it. Document-level
+// dotted keys are written as dotted keys again, and entries an edit added
+// to the objects they imply get dotted keys of their own; the objects are
+// written in braces after their path when a key of theirs cannot be part
+// of a path. Scalars are
+// written bare when the text allows it and quoted otherwise, so reparsing
+// the output yields a document with no changes by Diff, and the same Hash
+// for a parsed document.
+func Format(doc *Document) []byte {
+	w := formatWriter{}
+	return w.document(doc)
+}
+
+func (w *formatWriter) document(doc *Document) []byte {
+	entries := w.ordered(doc.Entries, true)
+	written := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Key.Implicit || len(entry.path) < 2 {
+			w.entry(entry, 0, true)
+			continue
+		}
+		// Dotted entries sharing a first key are written together, where
+		// the first of them is.
+		first := entry.path[0]
+		if written[first] {
+			continue
+		}
+		written[first] = true
+		var nodes []*dotNode
+		for _, e := range entries {
+			if !e.Key.Implicit && len(e.path) > 1 && e.path[0] == first {
+				nodes = addDotted(nodes, e, e.path[1:], nil, nil)
+			}
+		}
+		for _, n := range nodes {
+			w.dotted(nil, n)
+		}
+	}
+	return []byte(w.b.String())
+}
+
+// FormatValue writes a single value as Styx source, as Format does.
+func FormatValue(v *Value) []byte {
+	w := formatWriter{}
+	w.value(v, 0)
+	return []byte(w.b.String())
+}
+
+type formatWriter struct {
+	b strings.Builder
+	// order, if set, returns the entries of an object, or of the document,
+	// in the order to write them.
+	order func(entries []*Entry, documentLevel bool) []*Entry
+}
+
+func (w *formatWriter) ordered(entries []*Entry, documentLevel bool) []*Entry {
+	if w.order == nil {
+		return entries
+	}
+	return w.order(entries, documentLevel)
+}
+
+func (w *formatWriter) indent(depth int) {
+	w.b.WriteString(strings.Repeat(formatIndent, depth))
+}
+
+// entry writes entry on its own line at depth.
+func (w *formatWriter) entry(entry *Entry, depth int, documentLevel bool) {
+	for _, c := range entry.LeadingComments {
+		w.indent(depth)
+		w.b.WriteString(c.Text + "\n")
+	}
+	w.indent(depth)
+	value := entry.Value
+	if entry.Key.Implicit {
+		w.value(value, depth)
+	} else {
+		w.key(entry.Key, documentLevel)
+	}
+	if !entry.Key.Implicit && !value.IsImplicitUnit() {
+		w.b.WriteByte(' ')
+		w.value(value, depth)
+	}
+	if entry.TrailingComment != nil {
+		w.b.WriteString(" " + entry.TrailingComment.Text)
+	}
+	w.b.WriteByte('\n')
+}
+
+// dotNode is an entry below a document-level dotted prefix. The objects
+// that several dotted keys imply for the same path are merged, so that
+// their entries are written together.
+type dotNode struct {
+	entry *Entry
+	// leading and trailing are the comments of the line the entry is
+	// written on, including those of the dotted key it ends.
+	leading  []*Comment
+	trailing *Comment
+	// children is set when the value of entry is an object implied by a
+	// dotted key; it holds the merged entries of such objects.
+	children []*dotNode
+}
+
+// addDotted adds e to nodes. The value of e implies objects for the keys in
+// rest, unless an edit such as SetPath replaced them; leading and trailing
+// are the comments of the dotted key, which go with the entry continuing
+// its path, or the first entry if an edit removed that one.
+func addDotted(nodes []*dotNode, e *Entry, rest []string, leading []*Comment, trailing *Comment) []*dotNode {
+	leading = append(leading[:len(leading):len(leading)], e.LeadingComments...)
+	if e.TrailingComment != nil {
+		trailing = e.TrailingComment
+	}
+	value := e.Value
+	if len(rest) == 0 || value.Tag != nil || value.PayloadKind != PayloadObject || len(value.Object.Entries) == 0 {
+		return append(nodes, &dotNode{entry: e, leading: leading, trailing: trailing})
+	}
+	var n *dotNode
+	for _, m := range nodes {
+		if m.children != nil && m.entry.KeyText() == e.KeyText() {
+			n = m
+		}
+	}
+	if n == nil {
+		n = &dotNode{entry: e}
+		nodes = append(nodes, n)
+	}
+	next, nextRest := value.Object.Entries[0], []string(nil)
+	for _, c := range value.Object.Entries {
+		if c.KeyText() == rest[0] {
+			next, nextRest = c, rest[1:]
+			break
+		}
+	}
+	for _, c := range value.Object.Entries {
+		if c == next {
+			n.children = addDotted(n.children, c, nextRest, leading, trailing)
+		} else {
+			n.children = addDotted(n.children, c, nil, nil, nil)
+		}
+	}
+	return nodes
+}
+
+// dotted writes n below the dotted path keys. Entries of implied objects
+// are written on lines of their own with their full path, as separate
+// dotted keys sharing a prefix would be, when all their keys can be part of
+// a path. Otherwise the whole object is written in braces after its path,
+// as writing only some of its entries in braces would reopen a path that
+// other lines continue.
+func (w *formatWriter) dotted(keys []string, n *dotNode) {
+	path := append(keys[:len(keys):len(keys)], n.entry.KeyText())
+	children := w.orderedNodes(n.children)
+	if n.children != nil && dottableKeys(nodeEntries(children)) {
+		for _, c := range children {
+			w.dotted(path, c)
+		}
+		return
+	}
+	for _, c := range n.leading {
+		w.b.WriteString(c.Text + "\n")
+	}
+	w.b.WriteString(strings.Join(path, "."))
+	if n.children != nil {
+		w.b.WriteString(" {\n")
+		for _, c := range children {
+			w.node(c, 1)
+		}
+		w.b.WriteByte('}')
+	} else if value := n.entry.Value; !value.IsImplicitUnit() {
+		w.b.WriteByte(' ')
+		w.value(value, 0)
+	}
+	if n.trailing != nil {
+		w.b.WriteString(" " + n.trailing.Text)
+	}
+	w.b.WriteByte('\n')
+}
+
+// node writes n on its own line at depth, inside the braces of an object
+// implied by a dotted key.
+func (w *formatWriter) node(n *dotNode, depth int) {
+	for _, c := range n.leading {
+		w.indent(depth)
+		w.b.WriteString(c.Text + "\n")
+	}
+	w.indent(depth)
+	w.key(n.entry.Key, false)
+	if n.children != nil {
+		w.b.WriteString(" {\n")
+		for _, c := range w.orderedNodes(n.children) {
+			w.node(c, depth+1)
+		}
+		w.indent(depth)
+		w.b.WriteByte('}')
+	} else if value := n.entry.Value; !value.IsImplicitUnit() {
+		w.b.WriteByte(' ')
+		w.value(value, depth)
+	}
+	if n.trailing != nil {
+		w.b.WriteString(" " + n.trailing.Text)
+	}
+	w.b.WriteByte('\n')
+}
+
+// orderedNodes returns nodes in the order their entries are written.
+func (w *formatWriter) orderedNodes(nodes []*dotNode) []*dotNode {
+	byEntry := make(map[*Entry]*dotNode, len(nodes))
+	for _, n := range nodes {
+		byEntry[n.entry] = n
+	}
+	ordered := w.ordered(nodeEntries(nodes), false)
+	out := make([]*dotNode, len(ordered))
+	for i, e := range ordered {
+		out[i] = byEntry[e]
+	}
+	return out
+}
+
+func nodeEntries(nodes []*dotNode) []*Entry {
+	entries := make([]*Entry, len(nodes))
+	for i, n := range nodes {
+		entries[i] = n.entry
+	}
+	return entries
+}
+
+// dottableKeys reports whether entries are not empty and their keys can
+// each be written as part of a dotted path.
+func dottableKeys(entries []*Entry) bool {
+	for _, e := range entries {
+		key := e.Key
+		if key.Tag != nil || key.PayloadKind != PayloadScalar || !canBeBare(key.Scalar.Text) || strings.Contains(key.Scalar.Text, ".") {
+			return false
+		}
+	}
+	return len(entries) > 0
+}
+
+// key writes a key. A bare document-level key containing a dot would read
+// back as a dotted path, so it is quoted.
+func (w *formatWriter) key(key *Value, documentLevel bool) {
+	if key.PayloadKind == PayloadScalar && documentLevel && strings.Contains(key.Scalar.Text, ".") {
+		w.tag(key)
+		w.quoted(key.Scalar.Text)
+		return
+	}
+	w.value(key, 0)
+}
+
+func (w *formatWriter) tag(v *Value) {
+	if v.Tag != nil {
+		w.b.WriteString("@" + v.Tag.Name)
+	}
+}
+
+func (w *formatWriter) value(v *Value, depth int) {
+	w.tag(v)
+	switch v.PayloadKind {
+	case PayloadNone:
+		if v.Tag == nil {
+			w.b.WriteByte('@')
+		}
+	case PayloadScalar:
+		if v.Tag == nil && canBeBare(v.Scalar.Text) {
+			w.b.WriteString(v.Scalar.Text)
+		} else {
+			w.quoted(v.Scalar.Text)
+		}
+	case PayloadSequence:
+		w.b.WriteByte('(')
+		for i, item := range v.Sequence.Items {
+			if i > 0 {
+				w.b.WriteByte(' ')
+			}
+			w.value(item, depth)
+		}
+		w.b.WriteByte(')')
+	case PayloadObject:
+		if v.Tag == nil && attributeSyntax(v.Object) {
+			for i, entry := range w.ordered(v.Object.Entries, false) {
+				if i > 0 {
+					w.b.WriteByte(' ')
+				}
+				w.value(entry.Key, depth)
+				w.b.WriteByte('>')
+				w.value(entry.Value, depth)
+			}
+			return
+		}
+		if len(v.Object.Entries) == 0 {
+			w.b.WriteString("{}")
+			return
+		}
+		w.b.WriteString("{\n")
+		for _, entry := range w.ordered(v.Object.Entries, false) {
+			w.entry(entry, depth+1, false)
+		}
+		w.indent(depth)
+		w.b.WriteByte('}')
+	}
+}
+
+// quoted writes text as a quoted scalar.
+func (w *formatWriter) quoted(text string) {
+	w.b.WriteByte('"')
+	for _, r := range text {
+		switch r {
+		case '"', '\\':
+			w.b.WriteByte('\\')
+			w.b.WriteRune(r)
+		case '\n':
+			w.b.WriteString(`\n`)
+		case '\r':
+			w.b.WriteString(`\r`)
+		case '\t':
+			w.b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				w.b.WriteString(`\u{` + strconv.FormatInt(int64(r), 16) + `}`)
+			} else {
+				w.b.WriteRune(r)
+			}
+		}
+	}
+	w.b.WriteByte('"')
+}
+
+// canBeBare reports whether text reads back as the same bare scalar: it
+// is not empty, does not start like a comment, raw string or heredoc, and
+// holds no whitespace, control characters, or characters delimiting other
+// syntax.
+func canBeBare(text string) bool {
+	if text == "" || strings.HasPrefix(text, "//") || strings.HasPrefix(text, "r#") || strings.HasPrefix(text, "<<") {
+		return false
+	}
+	for _, r := range text {
+		if isSpecialChar(r) || r == '=' || r == '@' || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// attributeSyntax reports whether obj was written in attribute syntax and
+// can be written so again: its keys are bare and its values untagged
+// payloads.
+func attributeSyntax(obj *Object) bool {
+	if !obj.Attributes || len(obj.Entries) == 0 {
+		return false
+	}
+	for _, entry := range obj.Entries {
+		key, value := entry.Key, entry.Value
+		if key.Tag != nil || key.PayloadKind != PayloadScalar || !canBeBare(key.Scalar.Text) || value.Tag != nil || value.PayloadKind == PayloadNone {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package styx
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	source := `// leading
+name "My App" // trailing
+server.tls.cert /etc/cert.pem
+server.tls.key /etc/key.pem
+"a.b" 1
+db @pg{host localhost, pool 4}
+route method>GET path>/users
+hosts (a "b c" @env"HOST" @ @none)
+empty {}
+flag
+text "line\nbreak \"quoted\" \\ tab\t"
+odd ("" "@x" "//x" "a=b" "x>y" "r#z" "<<z")
+`
+	want := `// leading
+name "My App" // trailing
+server.tls.cert /etc/cert.pem
+server.tls.key /etc/key.pem
+"a.b" 1
+db @pg{
+    host localhost
+    pool 4
+}
+route method>GET path>/users
+hosts (a "b c" @env"HOST" @ @none)
+empty {}
+flag
+text "line\nbreak \"quoted\" \\ tab\t"
+odd ("" "@x" "//x" "a=b" "x>y" "r#z" "<<z")
+`
+	doc, err := Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(Format(doc))
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	again, err := Parse(got)
+	if err != nil {
+		t.Fatalf("formatted document does not parse: %v", err)
+	}
+	if again.Hash() != doc.Hash() {
+		t.Error("formatted document differs")
+	}
+
+	root, _ := Parse("{a {b (1 {c 2})}}")
+	if got := string(Format(root)); got != "{\n    a {\n        b (1 {\n            c 2\n        })\n    }\n}\n" {
+		t.Errorf("root object: %q", got)
+	}
+	if got := string(FormatValue(doc.Get("db"))); got != "@pg{\n    host localhost\n    pool 4\n}" {
+		t.Errorf("FormatValue: %q", got)
+	}
+}
+
+func TestFormatDottedKeys(t *testing.T) {
+	tests := []struct {
+		source string
+		sets   []string
+		want   string
+	}{
+		{"a.b 1\n", []string{"a.c"}, "a.b 1\na.c x\n"},
+		{"server.tls.cert /a\nserver.tls.key /b\n", []string{"server.tls.chain"}, "server.tls.cert /a\nserver.tls.key /b\nserver.tls.chain x\n"},
+		{"a.b.c 1 // one\n", []string{"a.b.d", "a.e.f"}, "a.b.c 1 // one\na.b.d x\na.e {\n    f x\n}\n"},
+		{"a.b.c 1\n", []string{"a.b"}, "a.b x\n"},
+		// A key that cannot be part of a path puts the whole prefix in braces.
+		{"user.name x // n\nuser.email@work y\nother 1\n", nil, "user {\n    name x // n\n    \"email@work\" y\n}\nother 1\n"},
+		{"a.b.c 1\na.b.d@e 2\na.f 3\n", nil, "a.b {\n    c 1\n    \"d@e\" 2\n}\na.f 3\n"},
+	}
+	for _, tt := range tests {
+		doc, err := Parse(tt.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range tt.sets {
+			if err := doc.SetPath(path, scalarValue("x")); err != nil {
+				t.Fatalf("SetPath(%q): %v", path, err)
+			}
+		}
+		got := string(Format(doc))
+		if got != tt.want {
+			t.Errorf("%q: got\n%s\nwant\n%s", tt.source, got, tt.want)
+		}
+		again, err := Parse(got)
+		if err != nil {
+			t.Fatalf("%q: formatted document does not parse: %v", tt.source, err)
+		}
+		if changes := Diff(doc, again); len(changes) > 0 {
+			t.Errorf("%q: formatted document differs: %v", tt.source, changes)
+		}
+	}
+}
+
+// TestFormatCorpus checks that every valid document of the compliance
+// corpus reads back from its formatted source with the same hash.
+func TestFormatCorpus(t *testing.T) {
+	root := findCorpusPath(t)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".styx") {
+			return err
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		doc, err := Parse(string(source))
+		if err != nil {
+			return nil
+		}
+		name, _ := filepath.Rel(root, path)
+		formatted := Format(doc)
+		again, err := Parse(string(formatted))
+		if err != nil {
+			t.Errorf("%s: formatted document does not parse: %v\n%s", name, err, formatted)
+			return nil
+		}
+		if again.Hash() != doc.Hash() {
+			t.Errorf("%s: formatted document differs:\n%s", name, formatted)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
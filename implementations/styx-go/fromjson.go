@@ -0,0 +1,162 @@
+package styx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FromJSON converts a JSON object to a document, reversing ToJSON: members
+// keep their order, strings become quoted scalars, numbers, `true` and
+// `false` bare ones, null the unit value and arrays sequences. Objects
+// written for opts.Tags become tagged values again: with TagWrap and
+// TagDiscriminator, an object holding only a string TagKey member, and
+// possibly a ValueKey member, becomes a tag with that payload, and with
+// TagDiscriminator any other object with a string TagKey member becomes a
+// tagged object without it. InferTypes and Indent are ignored. Duplicate
+// keys fail.
+func FromJSON(data []byte, opts JSONOptions) (*Document, error) {
+	if opts.TagKey == "" {
+		opts.TagKey = DefaultTagKey
+	}
+	if opts.ValueKey == "" {
+		opts.ValueKey = DefaultValueKey
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	r := jsonReader{dec: dec, opts: &opts}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, r.fail(err)
+	}
+	if tok != json.Delim('{') {
+		return nil, errors.New("styx: FromJSON: the document must be a JSON object")
+	}
+	obj, err := r.object()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("styx: FromJSON: offset %d: data after the document", dec.InputOffset())
+	}
+	return &Document{Entries: obj.Entries, Span: noSpan}, nil
+}
+
+type jsonReader struct {
+	dec  *json.Decoder
+	opts *JSONOptions
+}
+
+func (r *jsonReader) fail(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("styx: FromJSON: offset %d: %w", r.dec.InputOffset(), err)
+}
+
+// object reads the members of an object whose `{` has been read.
+func (r *jsonReader) object() (*Object, error) {
+	obj := &Object{Span: noSpan}
+	seen := make(map[string]bool)
+	for r.dec.More() {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, r.fail(err)
+		}
+		key := tok.(string)
+		if seen[key] {
+			return nil, r.fail(fmt.Errorf("duplicate key %q", key))
+		}
+		seen[key] = true
+		value, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		obj.Entries = append(obj.Entries, &Entry{Key: textValue(key), Value: value})
+	}
+	if _, err := r.dec.Token(); err != nil {
+		return nil, r.fail(err)
+	}
+	return obj, nil
+}
+
+func (r *jsonReader) value() (*Value, error) {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return nil, r.fail(err)
+	}
+	switch tok := tok.(type) {
+	case string:
+		return textValue(tok), nil
+	case json.Number:
+		return bareValue(tok.String()), nil
+	case bool:
+		return bareValue(fmt.Sprint(tok)), nil
+	case nil:
+		return &Value{Span: noSpan}, nil
+	}
+	if tok == json.Delim('[') {
+		seq := &Sequence{Span: noSpan}
+		for r.dec.More() {
+			item, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			seq.Items = append(seq.Items, item)
+		}
+		if _, err := r.dec.Token(); err != nil {
+			return nil, r.fail(err)
+		}
+		return &Value{Span: noSpan, PayloadKind: PayloadSequence, Sequence: seq}, nil
+	}
+	obj, err := r.object()
+	if err != nil {
+		return nil, err
+	}
+	return r.untag(obj), nil
+}
+
+// untag returns the value obj represents, recovering its tag as described
+// for FromJSON.
+func (r *jsonReader) untag(obj *Object) *Value {
+	v := &Value{Span: noSpan, PayloadKind: PayloadObject, Object: obj}
+	if r.opts.Tags == TagDrop {
+		return v
+	}
+	var tag, payload *Value
+	rest := 0
+	for _, e := range obj.Entries {
+		switch e.KeyText() {
+		case r.opts.TagKey:
+			tag = e.Value
+		case r.opts.ValueKey:
+			payload = e.Value
+			rest++
+		default:
+			rest++
+		}
+	}
+	if tag == nil || tag.PayloadKind != PayloadScalar || !validTagName(tag.Scalar.Text) {
+		return v
+	}
+	name := &Tag{Name: tag.Scalar.Text, Span: noSpan, NameSpan: noSpan}
+	switch {
+	case rest == 0:
+		return &Value{Span: noSpan, Tag: name}
+	case payload != nil && rest == 1 && payload.Tag == nil:
+		payload.Tag = name
+		return payload
+	case r.opts.Tags == TagDiscriminator:
+		var entries []*Entry
+		for _, e := range obj.Entries {
+			if e.KeyText() != r.opts.TagKey {
+				entries = append(entries, e)
+			}
+		}
+		obj.Entries = entries
+		v.Tag = name
+	}
+	return v
+}
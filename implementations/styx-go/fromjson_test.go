@@ -0,0 +1,77 @@
+package styx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromJSON(t *testing.T) {
+	doc, err := FromJSON([]byte(`{
+  "name": "my app",
+  "port": 8080,
+  "debug": true,
+  "none": null,
+  "hosts": ["a", 1.5e3],
+  "server": {"host": "localhost"},
+  "secret": {"$tag": "env", "$value": "TOKEN"},
+  "marker": {"$tag": "on"},
+  "db": {"$tag": "pg", "host": "db"}
+}`), JSONOptions{Tags: TagDiscriminator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `name "my app"
+port 8080
+debug true
+none @
+hosts (a 1.5e3)
+server {
+    host localhost
+}
+secret @env"TOKEN"
+marker @on
+db @pg{
+    host db
+}
+`
+	if got := string(Format(doc)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFromJSONRoundTrip(t *testing.T) {
+	source := "a (1 {b @x, c true}) // comment\nd @t{e f}\ng @h\"i\"\nj @\n"
+	for _, mode := range []TagMode{TagWrap, TagDiscriminator, TagDrop} {
+		doc := mustParse(t, source)
+		opts := JSONOptions{Tags: mode, InferTypes: true}
+		back, err := FromJSON(ToJSON(doc, opts), opts)
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		if mode == TagDrop {
+			doc = Rewrite(doc, func(v *Value) (*Value, bool) {
+				v.Tag = nil
+				return v, true
+			})
+		}
+		if changes := Diff(doc, back); len(changes) > 0 {
+			t.Errorf("%v: round trip changed the document: %v", mode, changes)
+		}
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	for source, want := range map[string]string{
+		`[1]`:                "must be a JSON object",
+		`{"a": 1, "a": 2}`:   `duplicate key "a"`,
+		`{"a": 1} {}`:        "data after the document",
+		`{"a": `:             "unexpected EOF",
+		`{"a": [1, }`:        "offset",
+		`{"b": {"a": 1, "a"`: `duplicate key "a"`,
+	} {
+		_, err := FromJSON([]byte(source), JSONOptions{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", source, err, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package styx
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// addCorpusSeeds adds every file of the compliance corpus to the fuzz seed
+// corpus.
+func addCorpusSeeds(f *testing.F) {
+	root := "../../compliance/corpus"
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".styx") {
+			return nil
+		}
+		if content, err := os.ReadFile(path); err == nil {
+			f.Add(string(content))
+		}
+		return nil
+	})
+	f.Add("")
+	f.Add("a.b.c 1\na.d 2")
+	f.Add("@tag{a 1} x>y")
+}
+
+func FuzzTokenize(f *testing.F) {
+	addCorpusSeeds(f)
+	f.Fuzz(func(t *testing.T, source string) {
+		l := NewLexer(source)
+		l.SetTrivia(TriviaAll)
+		pos := 0
+		for i := 0; ; i++ {
+			if i > len(source)+1 {
+				t.Fatal("lexer made no progress")
+			}
+			tok, err := l.NextToken()
+			if err != nil {
+				continue
+			}
+			checkSpan(t, source, tok.Span)
+			if tok.Span.Start < pos {
+				t.Fatalf("%v token span %v starts before %d", tok.Type, tok.Span, pos)
+			}
+			pos = tok.Span.End
+			if tok.Type == TokenEOF {
+				break
+			}
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	addCorpusSeeds(f)
+	f.Fuzz(func(t *testing.T, source string) {
+		doc, err := Parse(source)
+		if doc != nil {
+			checkEntrySpans(t, source, doc.Entries)
+		}
+		if err != nil {
+			checkSpan(t, source, err.(*ParseError).Span)
+			return
+		}
+
+		formatted := string(Format(doc))
+		if again, err := Parse(formatted); err != nil {
+			t.Fatalf("formatted document does not parse: %v\n%s", err, formatted)
+		} else if doc.Hash() != again.Hash() {
+			t.Fatalf("formatted document differs:\n%s", formatted)
+		}
+
+		rendered, ok := renderDocument(doc)
+		if !ok {
+			return
+		}
+		again, err := Parse(rendered)
+		if err != nil {
+			t.Fatalf("rendered document does not parse: %v\n%s", err, rendered)
+		}
+		if doc.Hash() != again.Hash() {
+			t.Fatalf("rendered document differs:\n%s", rendered)
+		}
+	})
+}
+
+func checkSpan(t *testing.T, source string, span Span) {
+	t.Helper()
+	if !span.Valid(len(source)) {
+		t.Fatalf("span %v out of bounds for source of length %d", span, len(source))
+	}
+}
+
+func checkEntrySpans(t *testing.T, source string, entries []*Entry) {
+	t.Helper()
+	for _, entry := range entries {
+		if !entry.Key.Implicit {
+			checkValueSpans(t, source, entry.Key)
+		}
+		checkValueSpans(t, source, entry.Value)
+	}
+}
+
+func checkValueSpans(t *testing.T, source string, v *Value) {
+	t.Helper()
+	checkSpan(t, source, v.Span)
+	switch v.PayloadKind {
+	case PayloadSequence:
+		for _, item := range v.Sequence.Items {
+			checkValueSpans(t, source, item)
+		}
+	case PayloadObject:
+		checkEntrySpans(t, source, v.Object.Entries)
+	}
+}
+
+// renderDocument writes doc back out in a plain canonical form: quoted
+// scalars, braces and parentheses. Document-level dotted keys are written as
+// dotted paths so that sibling paths stay valid. It reports false for
+// documents mixing objects in key position with other entries, which have no
+// equivalent rendering.
+func renderDocument(doc *Document) (string, bool) {
+	var b strings.Builder
+	for _, entry := range doc.Entries {
+		if entry.Key.Implicit {
+			if len(doc.Entries) > 1 {
+				return "", false
+			}
+			renderValue(&b, entry.Value)
+			b.WriteByte('\n')
+			continue
+		}
+		value := entry.Value
+		if len(entry.path) > 1 {
+			b.WriteString(strings.Join(entry.path, "."))
+			for range entry.path[1:] {
+				value = value.Object.Entries[0].Value
+			}
+		} else {
+			renderValue(&b, entry.Key)
+		}
+		if !value.Implicit {
+			b.WriteByte(' ')
+			renderValue(&b, value)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), true
+}
+
+func renderValue(b *strings.Builder, v *Value) {
+	if v.Tag != nil {
+		b.WriteString("@" + v.Tag.Name)
+	} else if v.PayloadKind == PayloadNone {
+		b.WriteByte('@')
+	}
+	switch v.PayloadKind {
+	case PayloadScalar:
+		b.WriteByte('"')
+		for _, r := range v.Scalar.Text {
+			switch r {
+			case '"', '\\':
+				b.WriteByte('\\')
+				b.WriteRune(r)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				b.WriteRune(r)
+			}
+		}
+		b.WriteByte('"')
+	case PayloadSequence:
+		b.WriteByte('(')
+		for i, item := range v.Sequence.Items {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			renderValue(b, item)
+		}
+		b.WriteByte(')')
+	case PayloadObject:
+		if v.Object.Attributes {
+			// Attribute keys may repeat, which braces would reject.
+			for i, entry := range v.Object.Entries {
+				if i > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(entry.KeyText() + ">")
+				renderValue(b, entry.Value)
+			}
+			return
+		}
+		// Entries go on separate lines, since an implicit unit value may
+		// only be followed by a line break or the closing brace.
+		b.WriteString("{\n")
+		for _, entry := range v.Object.Entries {
+			renderValue(b, entry.Key)
+			if !entry.Value.Implicit {
+				b.WriteByte(' ')
+				renderValue(b, entry.Value)
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteByte('}')
+	}
+}
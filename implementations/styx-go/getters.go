@@ -0,0 +1,112 @@
+package styx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ConversionError reports a value a typed getter such as Document.GetInt
+// cannot convert.
+type ConversionError struct {
+	// Path is the path passed to the getter.
+	Path string
+	// Type names the requested type, as in "int".
+	Type string
+	// Span locates the value in the source.
+	Span Span
+	// Err is the underlying conversion error.
+	Err error
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("value of %q at %d-%d is not a valid %s: %v", e.Path, e.Span.Start, e.Span.End, e.Type, e.Err)
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errNotScalar   = errors.New("not a scalar")
+	errNotSequence = errors.New("not a sequence")
+	errNotObject   = errors.New("not an object")
+)
+
+// GetString returns the text of the scalar at path, or def if the path is
+// absent. The getters ignore tags, reading the payload of a tagged value,
+// and fail with a *ConversionError when the value has the wrong shape.
+func (d *Document) GetString(path, def string) (string, error) {
+	return getScalar(d, path, "string", def, func(text string) (string, error) {
+		return text, nil
+	})
+}
+
+// GetInt returns the scalar at path as an int, or def if the path is
+// absent. The scalar is read as a Go integer literal, so `0x1F` and
+// `1_000` are accepted.
+func (d *Document) GetInt(path string, def int) (int, error) {
+	return getScalar(d, path, "int", def, func(text string) (int, error) {
+		n, err := strconv.ParseInt(text, 0, strconv.IntSize)
+		return int(n), err
+	})
+}
+
+// GetBool returns the scalar at path as a bool, or def if the path is
+// absent. Only `true` and `false` are accepted.
+func (d *Document) GetBool(path string, def bool) (bool, error) {
+	return getScalar(d, path, "bool", def, func(text string) (bool, error) {
+		switch text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return false, fmt.Errorf("%q is neither true nor false", text)
+	})
+}
+
+// GetDuration returns the scalar at path parsed by time.ParseDuration, as
+// in `30s` or `1h30m`, or def if the path is absent.
+func (d *Document) GetDuration(path string, def time.Duration) (time.Duration, error) {
+	return getScalar(d, path, "duration", def, time.ParseDuration)
+}
+
+// GetStringSlice returns the texts of the sequence of scalars at path, or
+// def if the path is absent.
+func (d *Document) GetStringSlice(path string, def []string) ([]string, error) {
+	v, ok := d.Lookup(path)
+	if !ok {
+		return def, nil
+	}
+	if v.PayloadKind != PayloadSequence {
+		return nil, &ConversionError{Path: path, Type: "string sequence", Span: v.Span, Err: errNotSequence}
+	}
+	texts := make([]string, len(v.Sequence.Items))
+	for i, item := range v.Sequence.Items {
+		if item.PayloadKind != PayloadScalar {
+			return nil, &ConversionError{Path: path, Type: "string sequence", Span: item.Span, Err: errNotScalar}
+		}
+		texts[i] = item.Scalar.Text
+	}
+	return texts, nil
+}
+
+// getScalar looks up the scalar at path and converts its text.
+func getScalar[T any](d *Document, path, typ string, def T, convert func(string) (T, error)) (T, error) {
+	v, ok := d.Lookup(path)
+	if !ok {
+		return def, nil
+	}
+	if v.PayloadKind != PayloadScalar {
+		var zero T
+		return zero, &ConversionError{Path: path, Type: typ, Span: v.Span, Err: errNotScalar}
+	}
+	result, err := convert(v.Scalar.Text)
+	if err != nil {
+		var zero T
+		return zero, &ConversionError{Path: path, Type: typ, Span: v.Scalar.Span, Err: err}
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package styx
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetters(t *testing.T) {
+	doc, err := Parse(`server {
+  host "example.com"
+  port 0x1F90
+  tls true
+  timeout 1m30s
+  aliases (www api)
+}
+bad {port eighty, tls yes, aliases (a {b c}), host (x)}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := doc.GetString("server.host", "localhost"); got != "example.com" || err != nil {
+		t.Errorf("GetString = %q, %v", got, err)
+	}
+	if got, err := doc.GetInt("server.port", 80); got != 8080 || err != nil {
+		t.Errorf("GetInt = %d, %v", got, err)
+	}
+	if got, err := doc.GetBool("server.tls", false); !got || err != nil {
+		t.Errorf("GetBool = %v, %v", got, err)
+	}
+	if got, err := doc.GetDuration("server.timeout", time.Second); got != 90*time.Second || err != nil {
+		t.Errorf("GetDuration = %v, %v", got, err)
+	}
+	if got, err := doc.GetStringSlice("server.aliases", nil); !reflect.DeepEqual(got, []string{"www", "api"}) || err != nil {
+		t.Errorf("GetStringSlice = %v, %v", got, err)
+	}
+
+	// Absent paths yield the default.
+	if got, err := doc.GetString("server.user", "nobody"); got != "nobody" || err != nil {
+		t.Errorf("GetString default = %q, %v", got, err)
+	}
+	if got, err := doc.GetInt("server.workers", 4); got != 4 || err != nil {
+		t.Errorf("GetInt default = %d, %v", got, err)
+	}
+	if got, err := doc.GetStringSlice("server.tags", []string{"x"}); !reflect.DeepEqual(got, []string{"x"}) || err != nil {
+		t.Errorf("GetStringSlice default = %v, %v", got, err)
+	}
+
+	// Present values of the wrong shape are errors.
+	var conv *ConversionError
+	if _, err := doc.GetInt("bad.port", 80); !errors.As(err, &conv) || !errors.Is(err, strconv.ErrSyntax) || conv.Span.Slice(doc.Source()) != "eighty" {
+		t.Errorf("GetInt(bad.port) error = %v", err)
+	}
+	if _, err := doc.GetBool("bad.tls", false); err == nil {
+		t.Error("GetBool(bad.tls): want error")
+	}
+	if _, err := doc.GetString("bad.host", ""); !errors.Is(err, errNotScalar) {
+		t.Errorf("GetString(bad.host) error = %v", err)
+	}
+	if _, err := doc.GetStringSlice("bad.aliases", nil); !errors.As(err, &conv) || conv.Span.Slice(doc.Source()) != "{b c}" {
+		t.Errorf("GetStringSlice(bad.aliases) error = %v", err)
+	}
+	if _, err := doc.GetStringSlice("server.host", nil); !errors.Is(err, errNotSequence) {
+		t.Errorf("GetStringSlice(server.host) error = %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package styx
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+)
+
+// Hash returns a digest of the document's content: its structure, keys, tags
+// and decoded scalar texts. Spans, comments, whitespace and the way a value
+// was written (quoting style, separators, attribute syntax) do not affect the
+// result, so two documents with the same hash are semantically equivalent.
+func (d *Document) Hash() [sha256.Size]byte {
+	h := sha256.New()
+	hashEntries(h, d.Entries)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Hash returns a digest of the value's content, as described for
+// Document.Hash.
+func (v *Value) Hash() [sha256.Size]byte {
+	h := sha256.New()
+	hashValue(h, v)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Node markers keep different shapes from producing the same byte stream.
+const (
+	hashUnit byte = iota
+	hashScalar
+	hashSequence
+	hashObject
+	hashTag
+)
+
+func hashString(h hash.Hash, s string) {
+	hashLen(h, len(s))
+	h.Write([]byte(s))
+}
+
+func hashLen(h hash.Hash, l int) {
+	var n [binary.MaxVarintLen64]byte
+	h.Write(n[:binary.PutUvarint(n[:], uint64(l))])
+}
+
+func hashEntries(h hash.Hash, entries []*Entry) {
+	hashLen(h, len(entries))
+	for _, entry := range entries {
+		hashValue(h, entry.Key)
+		hashValue(h, entry.Value)
+	}
+}
+
+func hashValue(h hash.Hash, v *Value) {
+	if v.Tag != nil {
+		h.Write([]byte{hashTag})
+		hashString(h, v.Tag.Name)
+	}
+	switch v.PayloadKind {
+	case PayloadScalar:
+		h.Write([]byte{hashScalar})
+		hashString(h, v.Scalar.Text)
+	case PayloadSequence:
+		h.Write([]byte{hashSequence})
+		hashLen(h, len(v.Sequence.Items))
+		for _, item := range v.Sequence.Items {
+			hashValue(h, item)
+		}
+	case PayloadObject:
+		h.Write([]byte{hashObject})
+		hashEntries(h, v.Object.Entries)
+	default:
+		h.Write([]byte{hashUnit})
+	}
+}
@@ -0,0 +1,27 @@
+package styx
+
+import "testing"
+
+func TestDocumentHash(t *testing.T) {
+	base := mustParse(t, "server {host localhost, port 8080}\n")
+	same := []string{
+		"// comment\nserver {\n  host \"localhost\"\n  port 8080\n}\n",
+		"server host>localhost port>8080\n",
+	}
+	for _, src := range same {
+		if mustParse(t, src).Hash() != base.Hash() {
+			t.Errorf("%q: hash differs from base", src)
+		}
+	}
+	different := []string{
+		"server {host localhost, port 8081}\n",
+		"server {port 8080, host localhost}\n",
+		"server @tagged{host localhost, port 8080}\n",
+		"server {host localhost, port (8080)}\n",
+	}
+	for _, src := range different {
+		if mustParse(t, src).Hash() == base.Hash() {
+			t.Errorf("%q: hash equals base", src)
+		}
+	}
+}
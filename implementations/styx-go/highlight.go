@@ -0,0 +1,160 @@
+package styx
+
+import "strings"
+
+// HighlightKind classifies a region of source for syntax highlighting.
+type HighlightKind int
+
+const (
+	// HighlightKey marks keys, including attribute keys before `>`.
+	HighlightKey HighlightKind = iota
+	// HighlightScalar marks bare scalars in value position.
+	HighlightScalar
+	// HighlightString marks quoted and raw strings and heredoc content.
+	HighlightString
+	// HighlightTag marks tags, including the `@` unit.
+	HighlightTag
+	// HighlightPunctuation marks braces, parentheses, commas and `>`.
+	HighlightPunctuation
+	// HighlightComment marks line comments.
+	HighlightComment
+	// HighlightHeredocDelimiter marks a heredoc's opening line and closing
+	// delimiter.
+	HighlightHeredocDelimiter
+	// HighlightError marks text the lexer could not tokenize.
+	HighlightError
+)
+
+func (k HighlightKind) String() string {
+	switch k {
+	case HighlightKey:
+		return "key"
+	case HighlightScalar:
+		return "scalar"
+	case HighlightString:
+		return "string"
+	case HighlightTag:
+		return "tag"
+	case HighlightPunctuation:
+		return "punctuation"
+	case HighlightComment:
+		return "comment"
+	case HighlightHeredocDelimiter:
+		return "heredoc-delimiter"
+	case HighlightError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// HighlightSpan is a classified region of source.
+type HighlightSpan struct {
+	Kind HighlightKind
+	Span Span
+}
+
+// Highlight classifies the regions of source for syntax highlighting. It
+// works from the token stream, so it is fast and tolerates invalid input:
+// text that fails to lex is marked HighlightError and highlighting resumes on
+// the next line. Regions are returned in source order and do not overlap;
+// whitespace is not covered.
+func Highlight(source string) []HighlightSpan {
+	l := newLexer(source)
+	l.SetTrivia(TriviaComments)
+	// Lexer errors are kept in place as nil tokens, with their spans.
+	var tokens []*Token
+	var errSpans []Span
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			if pe, ok := err.(*ParseError); ok {
+				tokens = append(tokens, nil)
+				errSpans = append(errSpans, pe.Span)
+			}
+			continue
+		}
+		if tok.Type == TokenEOF {
+			break
+		}
+		tokens = append(tokens, tok)
+	}
+
+	// objects tracks whether each open container is an object, in which
+	// keys appear at the start of entries; the document is one.
+	var spans []HighlightSpan
+	objects := []bool{true}
+	atEntryStart := true
+	for i, tok := range tokens {
+		inObject := objects[len(objects)-1]
+		if tok == nil {
+			spans = append(spans, HighlightSpan{HighlightError, errSpans[0]})
+			errSpans = errSpans[1:]
+			atEntryStart = inObject
+			continue
+		}
+		if tok.HadNewlineBefore && inObject {
+			atEntryStart = true
+		}
+		var next *Token
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		attached := next != nil && !next.HadWhitespaceBefore && !next.HadNewlineBefore
+
+		switch tok.Type {
+		case TokenComment:
+			spans = append(spans, HighlightSpan{HighlightComment, tok.Span})
+		case TokenLBrace, TokenLParen:
+			spans = append(spans, HighlightSpan{HighlightPunctuation, tok.Span})
+			objects = append(objects, tok.Type == TokenLBrace)
+			atEntryStart = tok.Type == TokenLBrace
+		case TokenRBrace, TokenRParen:
+			spans = append(spans, HighlightSpan{HighlightPunctuation, tok.Span})
+			if len(objects) > 1 {
+				objects = objects[:len(objects)-1]
+			}
+			atEntryStart = false
+		case TokenComma:
+			spans = append(spans, HighlightSpan{HighlightPunctuation, tok.Span})
+			atEntryStart = inObject
+		case TokenGT:
+			spans = append(spans, HighlightSpan{HighlightPunctuation, tok.Span})
+			atEntryStart = false
+		case TokenTag, TokenAt:
+			spans = append(spans, HighlightSpan{HighlightTag, tok.Span})
+			// A payload attached to a tagged key is part of the key.
+			atEntryStart = atEntryStart && attached && tok.Type == TokenTag
+		case TokenHeredoc:
+			spans = append(spans, heredocHighlights(source, tok.Span)...)
+			atEntryStart = false
+		default:
+			kind := HighlightScalar
+			if tok.Type != TokenScalar {
+				kind = HighlightString
+			}
+			if atEntryStart || (attached && next.Type == TokenGT) {
+				kind = HighlightKey
+			}
+			spans = append(spans, HighlightSpan{kind, tok.Span})
+			atEntryStart = false
+		}
+	}
+	return spans
+}
+
+// heredocHighlights splits a heredoc's span into its opening line, content
+// and closing delimiter.
+func heredocHighlights(source string, span Span) []HighlightSpan {
+	text := source[span.Start:span.End]
+	openEnd := strings.IndexByte(text, '\n')
+	closeStart := strings.LastIndexByte(text, '\n') + 1
+	closeStart += len(text[closeStart:]) - len(strings.TrimLeft(text[closeStart:], " \t"))
+	opening := strings.TrimSuffix(text[:openEnd], "\r")
+
+	spans := []HighlightSpan{{HighlightHeredocDelimiter, Span{span.Start, span.Start + len(opening)}}}
+	if contentEnd := strings.LastIndexByte(text[:closeStart], '\n'); contentEnd > openEnd {
+		spans = append(spans, HighlightSpan{HighlightString, Span{span.Start + openEnd + 1, span.Start + contentEnd}})
+	}
+	return append(spans, HighlightSpan{HighlightHeredocDelimiter, Span{span.Start + closeStart, span.End}})
+}
@@ -0,0 +1,27 @@
+package styx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	source := "// config\nname \"app\"\nserver {host localhost, port 80}\nlist (a b)\n@tag x\nlink>url opt>@unit\nbody <<EOF\ntext\nEOF\nbad \"\\q\"\n"
+	var got []string
+	for _, h := range Highlight(source) {
+		got = append(got, h.Kind.String()+" "+source[h.Span.Start:h.Span.End])
+	}
+	want := []string{
+		"comment // config",
+		"key name", "string \"app\"",
+		"key server", "punctuation {", "key host", "scalar localhost", "punctuation ,", "key port", "scalar 80", "punctuation }",
+		"key list", "punctuation (", "scalar a", "scalar b", "punctuation )",
+		"tag @tag", "scalar x",
+		"key link", "punctuation >", "scalar url", "key opt", "punctuation >", "tag @unit",
+		"key body", "heredoc-delimiter <<EOF", "string text", "heredoc-delimiter EOF",
+		"key bad", "error \\q",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
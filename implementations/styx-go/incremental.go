@@ -0,0 +1,332 @@
+package styx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Edit replaces the bytes covered by Span in a source with Text.
+type Edit struct {
+	Span Span
+	Text string
+}
+
+// ApplyEdits returns source with edits applied. Edits are given in terms of
+// the original source and must not overlap.
+func ApplyEdits(source string, edits []Edit) (string, error) {
+	sorted, err := sortEdits(source, edits)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	pos := 0
+	for _, e := range sorted {
+		b.WriteString(source[pos:e.Span.Start])
+		b.WriteString(e.Text)
+		pos = e.Span.End
+	}
+	b.WriteString(source[pos:])
+	return b.String(), nil
+}
+
+func sortEdits(source string, edits []Edit) ([]Edit, error) {
+	sorted := append([]Edit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Span.Start < sorted[j].Span.Start
+	})
+	pos := 0
+	for _, e := range sorted {
+		if !e.Span.Valid(len(source)) || e.Span.Start < pos {
+			return nil, fmt.Errorf("styx: invalid edit span %d-%d", e.Span.Start, e.Span.End)
+		}
+		pos = e.Span.End
+	}
+	return sorted, nil
+}
+
+// Reparse returns the document for prev's source with edits applied, as
+// Parse would, but only reparses the document-level entries touched by the
+// edits. Entries before the first edit are shared with prev; entries after
+// the last edit are reused with their spans shifted. prev is not modified.
+//
+// Reparse falls back to a full parse whenever the edit cannot be confined to
+// whole lines of document-level entries, and always when the new source
+// fails to parse, so results and errors match Parse exactly.
+func Reparse(prev *Document, edits []Edit, opts ParseOptions) (*Document, error) {
+	oldSource := prev.source
+	sorted, err := sortEdits(oldSource, edits)
+	if err != nil {
+		return nil, err
+	}
+	newSource, _ := ApplyEdits(oldSource, sorted)
+	if len(sorted) == 0 {
+		return ParseWithOptions(newSource, opts)
+	}
+	if doc := reparse(prev, sorted, newSource, opts); doc != nil {
+		return doc, nil
+	}
+	return ParseWithOptions(newSource, opts)
+}
+
+// reparse attempts an incremental reparse, returning nil if a full parse is
+// required.
+func reparse(prev *Document, edits []Edit, newSource string, opts ParseOptions) *Document {
+	oldSource := prev.source
+	entries := prev.Entries
+	if opts.MaxEntries > 0 || opts.MaxInputSize > 0 || (len(entries) > 0 && entries[0].Key.Implicit) {
+		return nil
+	}
+
+	editStart := edits[0].Span.Start
+	editEnd := edits[len(edits)-1].Span.End
+	delta := len(newSource) - len(oldSource)
+
+	// The reparsed region runs from the line after the last entry ending
+	// before the edits to the first line of the first entry starting after
+	// them, including any comments leading into that entry.
+	first, regionStart := 0, 0
+	for i, e := range entries {
+		end := entryExtentEnd(oldSource, e)
+		if end < 0 || end > editStart {
+			break
+		}
+		first, regionStart = i+1, end
+	}
+	last, regionEnd := len(entries), len(oldSource)
+	for i := len(entries) - 1; i >= first; i-- {
+		start := entryExtentStart(oldSource, entries[i])
+		if start < 0 || start <= editEnd {
+			break
+		}
+		last, regionEnd = i, start
+	}
+	if regionEnd < regionStart {
+		return nil
+	}
+
+	fragment := newSource[regionStart : regionEnd+delta]
+	fragDoc, err := newParser(fragment, opts).parse()
+	if err != nil {
+		return nil
+	}
+	// A leading object is an explicit root object in the fragment but may
+	// not be in the whole document.
+	if len(fragDoc.Entries) > 0 && fragDoc.Entries[0].Key.Implicit {
+		return nil
+	}
+	// Comments after the last reparsed entry would lead into the next
+	// entry, which the fragment does not contain.
+	if last < len(entries) && hasDanglingComments(fragment, fragDoc) {
+		return nil
+	}
+
+	// Rebuild the comment list, keeping entries pointing at the same
+	// Comment values as the document.
+	comments := make([]*Comment, 0, len(prev.Comments)+len(fragDoc.Comments))
+	moved := make(map[*Comment]*Comment)
+	for _, c := range prev.Comments {
+		if c.Span.End <= regionStart {
+			comments = append(comments, c)
+		}
+	}
+	for _, c := range fragDoc.Comments {
+		moved[c] = shiftComment(c, regionStart)
+		comments = append(comments, moved[c])
+	}
+	for _, c := range prev.Comments {
+		if c.Span.Start >= regionEnd {
+			moved[c] = shiftComment(c, delta)
+			comments = append(comments, moved[c])
+		}
+	}
+
+	var warnings []*Diagnostic
+	for _, w := range prev.Warnings {
+		if w.Span.End <= regionStart {
+			warnings = append(warnings, w)
+		}
+	}
+	for _, w := range fragDoc.Warnings {
+		warnings = append(warnings, shiftDiagnostic(w, regionStart))
+	}
+	for _, w := range prev.Warnings {
+		if w.Span.Start >= regionEnd {
+			warnings = append(warnings, shiftDiagnostic(w, delta))
+		}
+	}
+
+	result := make([]*Entry, 0, first+len(fragDoc.Entries)+len(entries)-last)
+	result = append(result, entries[:first]...)
+	for _, e := range fragDoc.Entries {
+		result = append(result, shiftEntry(e, regionStart, moved))
+	}
+	for _, e := range entries[last:] {
+		result = append(result, shiftEntry(e, delta, moved))
+	}
+
+	// Revalidate key paths across the spliced entries.
+	ps := newPathState()
+	for _, e := range result {
+		if e.path == nil {
+			continue
+		}
+		if ps.checkAndUpdate(e.path, e.Key.Span, e.pathKind) != nil {
+			return nil
+		}
+	}
+
+	start := prev.Span.Start
+	if first == 0 {
+		tok, err := newLexer(newSource).nextToken()
+		if err != nil {
+			return nil
+		}
+		start = tok.Span.Start
+	}
+	return &Document{
+		Entries:  result,
+		Span:     Span{start, len(newSource)},
+		Comments: comments,
+		Warnings: warnings,
+		source:   newSource,
+	}
+}
+
+// hasDanglingComments reports whether doc ends with own-line comments that
+// follow its last entry.
+func hasDanglingComments(source string, doc *Document) bool {
+	if len(doc.Comments) == 0 {
+		return false
+	}
+	end := 0
+	if len(doc.Entries) > 0 {
+		end = doc.Entries[len(doc.Entries)-1].Span().End
+	}
+	c := doc.Comments[len(doc.Comments)-1]
+	return c.Span.Start >= end && startsLine(source, c.Span.Start)
+}
+
+// entryExtentEnd returns the offset just after the newline ending the last
+// line of entry, or -1 if the entry is not followed by a newline.
+func entryExtentEnd(source string, e *Entry) int {
+	end := e.Span().End
+	if e.TrailingComment != nil {
+		end = e.TrailingComment.Span.End
+	}
+	idx := strings.IndexByte(source[end:], '\n')
+	if idx < 0 {
+		return -1
+	}
+	return end + idx + 1
+}
+
+// entryExtentStart returns the offset of the start of the line on which
+// entry or its first leading comment begins, or -1 if something other than
+// whitespace precedes it on that line.
+func entryExtentStart(source string, e *Entry) int {
+	start := e.Span().Start
+	if len(e.LeadingComments) > 0 {
+		start = e.LeadingComments[0].Span.Start
+	}
+	if !startsLine(source, start) {
+		return -1
+	}
+	return strings.LastIndexByte(source[:start], '\n') + 1
+}
+
+func shiftSpan(s Span, delta int) Span {
+	if s.Start < 0 {
+		return s
+	}
+	return Span{s.Start + delta, s.End + delta}
+}
+
+func shiftDiagnostic(d *Diagnostic, delta int) *Diagnostic {
+	shifted := *d
+	shifted.Span = shiftSpan(d.Span, delta)
+	shifted.Labels = nil
+	for _, label := range d.Labels {
+		shifted.Labels = append(shifted.Labels, Label{label.Message, shiftSpan(label.Span, delta)})
+	}
+	shifted.Fixes = nil
+	for _, fix := range d.Fixes {
+		edits := make([]Edit, len(fix.Edits))
+		for i, e := range fix.Edits {
+			edits[i] = Edit{shiftSpan(e.Span, delta), e.Text}
+		}
+		shifted.Fixes = append(shifted.Fixes, Fix{fix.Message, edits})
+	}
+	return &shifted
+}
+
+func shiftComment(c *Comment, delta int) *Comment {
+	if delta == 0 {
+		return c
+	}
+	return &Comment{Text: c.Text, Span: shiftSpan(c.Span, delta)}
+}
+
+// shiftEntry returns a copy of e with all spans moved by delta, or e itself
+// when delta is zero. Comments are replaced by their counterparts in moved.
+func shiftEntry(e *Entry, delta int, moved map[*Comment]*Comment) *Entry {
+	if delta == 0 {
+		return e
+	}
+	c := *e
+	c.Key = shiftValue(e.Key, delta, moved)
+	c.Value = shiftValue(e.Value, delta, moved)
+	c.LeadingComments = nil
+	for _, lc := range e.LeadingComments {
+		c.LeadingComments = append(c.LeadingComments, moveComment(lc, delta, moved))
+	}
+	if e.TrailingComment != nil {
+		c.TrailingComment = moveComment(e.TrailingComment, delta, moved)
+	}
+	return &c
+}
+
+func moveComment(c *Comment, delta int, moved map[*Comment]*Comment) *Comment {
+	if m, ok := moved[c]; ok {
+		return m
+	}
+	return shiftComment(c, delta)
+}
+
+func shiftValue(v *Value, delta int, moved map[*Comment]*Comment) *Value {
+	c := *v
+	c.Span = shiftSpan(v.Span, delta)
+	if v.Tag != nil {
+		tag := *v.Tag
+		tag.Span = shiftSpan(tag.Span, delta)
+		tag.NameSpan = shiftSpan(tag.NameSpan, delta)
+		c.Tag = &tag
+	}
+	switch v.PayloadKind {
+	case PayloadScalar:
+		scalar := *v.Scalar
+		scalar.Span = shiftSpan(scalar.Span, delta)
+		if len(scalar.HeredocOptions) > 0 {
+			scalar.HeredocOptions = append([]HeredocOption(nil), scalar.HeredocOptions...)
+			for i := range scalar.HeredocOptions {
+				scalar.HeredocOptions[i].Span = shiftSpan(scalar.HeredocOptions[i].Span, delta)
+			}
+		}
+		c.Scalar = &scalar
+	case PayloadSequence:
+		items := make([]*Value, len(v.Sequence.Items))
+		for i, item := range v.Sequence.Items {
+			items[i] = shiftValue(item, delta, moved)
+		}
+		c.Sequence = &Sequence{Items: items, Span: shiftSpan(v.Sequence.Span, delta)}
+	case PayloadObject:
+		obj := *v.Object
+		obj.Span = shiftSpan(obj.Span, delta)
+		obj.Entries = make([]*Entry, len(v.Object.Entries))
+		for i, e := range v.Object.Entries {
+			obj.Entries[i] = shiftEntry(e, delta, moved)
+		}
+		c.Object = &obj
+	}
+	return &c
+}
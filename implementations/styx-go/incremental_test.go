@@ -0,0 +1,125 @@
+package styx
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// checkSameDocument compares the trees, spans, comment attachment and
+// warnings of two documents.
+func checkSameDocument(t *testing.T, got, want *Document) {
+	t.Helper()
+	if g, w := FormatSexp(got), FormatSexp(want); g != w {
+		t.Fatalf("tree mismatch\n--- got ---\n%s\n--- want ---\n%s", g, w)
+	}
+	if got.Span != want.Span {
+		t.Fatalf("document span = %v, want %v", got.Span, want.Span)
+	}
+	if len(got.Comments) != len(want.Comments) {
+		t.Fatalf("got %d comments, want %d", len(got.Comments), len(want.Comments))
+	}
+	for i := range got.Comments {
+		if *got.Comments[i] != *want.Comments[i] {
+			t.Fatalf("comment %d = %+v, want %+v", i, got.Comments[i], want.Comments[i])
+		}
+	}
+	if fmt.Sprint(got.Warnings) != fmt.Sprint(want.Warnings) {
+		t.Fatalf("warnings = %v, want %v", got.Warnings, want.Warnings)
+	}
+	for i := range got.Entries {
+		g, w := got.Entries[i], want.Entries[i]
+		if len(g.LeadingComments) != len(w.LeadingComments) || (g.TrailingComment == nil) != (w.TrailingComment == nil) {
+			t.Fatalf("entry %d comment attachment differs", i)
+		}
+		if g.TrailingComment != nil && *g.TrailingComment != *w.TrailingComment {
+			t.Fatalf("entry %d trailing comment = %+v, want %+v", i, g.TrailingComment, w.TrailingComment)
+		}
+	}
+}
+
+func TestReparse(t *testing.T) {
+	source := `// config
+name app
+server {
+  host localhost // the host
+  port 8080
+}
+// about paths
+a.b.c 1
+list (1 2 3)
+tags @env"HOME"
+`
+	at := func(substr string) Span {
+		i := strings.Index(source, substr)
+		if i < 0 {
+			t.Fatalf("%q not in source", substr)
+		}
+		return Span{i, i + len(substr)}
+	}
+	end := Span{len(source), len(source)}
+	tests := []struct {
+		name  string
+		edits []Edit
+	}{
+		{"change scalar", []Edit{{at("app"), "application"}}},
+		{"insert entry", []Edit{{Span{at("server").Start, at("server").Start}, "version 2\n"}}},
+		{"delete entry", []Edit{{at("list (1 2 3)\n"), ""}}},
+		{"edit nested", []Edit{{at("8080"), "9090"}}},
+		{"edit comment", []Edit{{at("the host"), "host name"}}},
+		{"two edits", []Edit{{at("app"), "x"}, {at("tags @env\"HOME\"\n"), "tags @none\n"}}},
+		{"append", []Edit{{end, "last @\n"}}},
+		{"join lines", []Edit{{Span{at("app").End, at("app").End + 1}, ", "}}},
+		{"duplicate key", []Edit{{at("list"), "name"}}},
+		{"colon key", []Edit{{at("port"), "port:"}, {at("tags"), "tags:"}}},
+	}
+	prev := mustParse(t, source)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newSource, err := ApplyEdits(source, tt.edits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, wantErr := Parse(newSource)
+			got, gotErr := Reparse(prev, tt.edits, ParseOptions{})
+			if (gotErr == nil) != (wantErr == nil) {
+				t.Fatalf("error = %v, want %v", gotErr, wantErr)
+			}
+			if wantErr == nil {
+				checkSameDocument(t, got, want)
+			}
+		})
+	}
+	// Unaffected leading entries are shared rather than reparsed.
+	got, err := Reparse(prev, []Edit{{end, "last @\n"}}, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Entries[1] != prev.Entries[1] {
+		t.Errorf("entry before the edit was not reused")
+	}
+
+	// prev must be unchanged.
+	checkSameDocument(t, prev, mustParse(t, source))
+}
+
+func TestReparseRandomEdits(t *testing.T) {
+	source := "// header\na 1\nb {c 2, d (x y)} // trailing\n\n// lead\ne.f g\nh @tag{i j}\nk \"quoted\"\n"
+	snippets := []string{"", "z", "\n", "q 9\n", "// c\n", "{", "}", ",", ".", " "}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		start := rng.Intn(len(source) + 1)
+		end := start + rng.Intn(len(source)-start+1)%4
+		edit := Edit{Span{start, end}, snippets[rng.Intn(len(snippets))]}
+		newSource, _ := ApplyEdits(source, []Edit{edit})
+		want, wantErr := Parse(newSource)
+		got, gotErr := Reparse(mustParse(t, source), []Edit{edit}, ParseOptions{})
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Fatalf("%+v: error = %v, want %v", edit, gotErr, wantErr)
+		}
+		if wantErr == nil {
+			checkSameDocument(t, got, want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package styx
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// TagMode controls how tags are represented when converting values to plain
+// Go data.
+type TagMode int
+
+const (
+	// TagWrap represents a tagged value as a Tagged struct.
+	TagWrap TagMode = iota
+	// TagDiscriminator records the tag name under a discriminator key. Tagged
+	// objects get the key added to their map; other payloads are wrapped in a
+	// map holding the tag and the payload.
+	TagDiscriminator
+	// TagDrop discards tags and keeps only the payload.
+	TagDrop
+)
+
+func (m TagMode) String() string {
+	switch m {
+	case TagWrap:
+		return "wrap"
+	case TagDiscriminator:
+		return "discriminator"
+	case TagDrop:
+		return "drop"
+	default:
+		return "unknown"
+	}
+}
+
+// Default keys used by TagDiscriminator.
+const (
+	DefaultTagKey   = "$tag"
+	DefaultValueKey = "$value"
+)
+
+// InterfaceOptions configures conversion of values to plain Go data.
+type InterfaceOptions struct {
+	// Tags selects how tags are represented.
+	Tags TagMode
+	// TagKey is the discriminator key holding the tag name. Defaults to
+	// DefaultTagKey.
+	TagKey string
+	// ValueKey is the key holding a non-object payload in discriminator
+	// mode. Defaults to DefaultValueKey.
+	ValueKey string
+}
+
+// Tagged is the representation of a tagged value in TagWrap mode. Value is
+// nil when the tag has no payload.
+type Tagged struct {
+	Tag   string
+	Value any
+}
+
+// Interface converts the value to plain Go data using the default options:
+// scalars become strings, sequences []any, objects map[string]any, unit
+// values nil and tagged values Tagged.
+func (v *Value) Interface() any {
+	return v.InterfaceWithOptions(InterfaceOptions{})
+}
+
+// InterfaceWithOptions converts the value to plain Go data.
+func (v *Value) InterfaceWithOptions(opts InterfaceOptions) any {
+	if opts.TagKey == "" {
+		opts.TagKey = DefaultTagKey
+	}
+	if opts.ValueKey == "" {
+		opts.ValueKey = DefaultValueKey
+	}
+	return v.toInterface(&opts)
+}
+
+// Interface converts the document to a map[string]any using the default
+// options. A document consisting of an explicit root object yields that
+// object's map.
+func (d *Document) Interface() any {
+	return d.InterfaceWithOptions(InterfaceOptions{})
+}
+
+// InterfaceWithOptions converts the document to plain Go data.
+func (d *Document) InterfaceWithOptions(opts InterfaceOptions) any {
+	if len(d.Entries) == 1 && d.Entries[0].Key.IsImplicitUnit() {
+		return d.Entries[0].Value.InterfaceWithOptions(opts)
+	}
+	root := &Value{Span: d.Span, PayloadKind: PayloadObject, Object: &Object{Entries: d.Entries, Span: d.Span}}
+	return root.InterfaceWithOptions(opts)
+}
+
+func (v *Value) toInterface(opts *InterfaceOptions) any {
+	var payload any
+	switch v.PayloadKind {
+	case PayloadScalar:
+		payload = v.Scalar.Text
+	case PayloadSequence:
+		items := make([]any, len(v.Sequence.Items))
+		for i, item := range v.Sequence.Items {
+			items[i] = item.toInterface(opts)
+		}
+		payload = items
+	case PayloadObject:
+		keys := v.Object.Keys()
+		m := make(map[string]any, len(keys))
+		for _, key := range keys {
+			m[key] = v.Object.Get(key).toInterface(opts)
+		}
+		payload = m
+	}
+
+	if v.Tag == nil {
+		return payload
+	}
+	switch opts.Tags {
+	case TagDiscriminator:
+		if m, ok := payload.(map[string]any); ok {
+			m[opts.TagKey] = v.Tag.Name
+			return m
+		}
+		m := map[string]any{opts.TagKey: v.Tag.Name}
+		if v.PayloadKind != PayloadNone {
+			m[opts.ValueKey] = payload
+		}
+		return m
+	case TagDrop:
+		return payload
+	default:
+		return Tagged{Tag: v.Tag.Name, Value: payload}
+	}
+}
+
+// FromInterface converts plain Go data to a value, reversing Interface:
+// maps with string keys become objects, with keys in sorted order, slices
+// and arrays sequences, nil and nil pointers the unit value and Tagged
+// values tagged ones. Strings become quoted scalars; booleans, numbers,
+// encoding.TextMarshaler implementations such as time.Time and
+// fmt.Stringer implementations such as time.Duration become bare scalars
+// of their text. Other data, such as structs and channels, fails.
+func FromInterface(data any) (*Value, error) {
+	return fromInterface(reflect.ValueOf(data))
+}
+
+func fromInterface(rv reflect.Value) (*Value, error) {
+	if rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	// A nil pointer is the unit value, even if its type has methods such
+	// as MarshalText, which would dereference it.
+	if !rv.IsValid() || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return &Value{Span: noSpan}, nil
+	}
+	switch data := rv.Interface().(type) {
+	case Tagged:
+		if !validTagName(data.Tag) {
+			return nil, fmt.Errorf("invalid tag name %q", data.Tag)
+		}
+		v := &Value{Span: noSpan}
+		if data.Value != nil {
+			var err error
+			if v, err = fromInterface(reflect.ValueOf(data.Value)); err != nil {
+				return nil, err
+			}
+		}
+		v.Tag = &Tag{Name: data.Tag, Span: noSpan, NameSpan: noSpan}
+		return v, nil
+	case encoding.TextMarshaler:
+		text, err := data.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return bareValue(string(text)), nil
+	case fmt.Stringer:
+		return bareValue(data.String()), nil
+	}
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		return fromInterface(rv.Elem())
+	case reflect.String:
+		return textValue(rv.String()), nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return bareValue(fmt.Sprint(rv.Interface())), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return &Value{Span: noSpan}, nil
+		}
+		seq := &Sequence{Span: noSpan}
+		for i := 0; i < rv.Len(); i++ {
+			item, err := fromInterface(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			seq.Items = append(seq.Items, item)
+		}
+		return &Value{Span: noSpan, PayloadKind: PayloadSequence, Sequence: seq}, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if rv.IsNil() {
+			return &Value{Span: noSpan}, nil
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		obj := &Object{Span: noSpan}
+		for _, key := range keys {
+			value, err := fromInterface(rv.MapIndex(key))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key.String(), err)
+			}
+			entry := &Entry{Key: textValue(key.String()), Value: value}
+			obj.Entries = append(obj.Entries, entry)
+		}
+		return &Value{Span: noSpan, PayloadKind: PayloadObject, Object: obj}, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to a Styx value", rv.Type())
+}
+
+// bareValue returns a bare scalar holding text, without a location.
+func bareValue(text string) *Value {
+	v := textValue(text)
+	v.Scalar.Kind = ScalarBare
+	return v
+}
+
+// validTagName reports whether name can follow `@` as a tag.
+func validTagName(name string) bool {
+	for i, ch := range name {
+		if !(i == 0 && isTagStart(ch) || i > 0 && isTagChar(ch)) {
+			return false
+		}
+	}
+	return name != ""
+}
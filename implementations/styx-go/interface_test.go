@@ -0,0 +1,105 @@
+package styx
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInterface(t *testing.T) {
+	doc := mustParse(t, `name app
+ports (80 443)
+db {host localhost}
+secret @env"TOKEN"
+kind @object{a b}
+flag
+server.host localhost
+server.port 8080
+`)
+	want := map[string]any{
+		"name":   "app",
+		"ports":  []any{"80", "443"},
+		"db":     map[string]any{"host": "localhost"},
+		"secret": Tagged{Tag: "env", Value: "TOKEN"},
+		"kind":   Tagged{Tag: "object", Value: map[string]any{"a": "b"}},
+		"flag":   nil,
+		"server": map[string]any{"host": "localhost", "port": "8080"},
+	}
+	if got := doc.Interface(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Interface() = %#v, want %#v", got, want)
+	}
+
+	got := doc.InterfaceWithOptions(InterfaceOptions{Tags: TagDiscriminator}).(map[string]any)
+	if s := got["secret"]; !reflect.DeepEqual(s, map[string]any{"$tag": "env", "$value": "TOKEN"}) {
+		t.Errorf("discriminator scalar = %#v", s)
+	}
+	if k := got["kind"]; !reflect.DeepEqual(k, map[string]any{"$tag": "object", "a": "b"}) {
+		t.Errorf("discriminator object = %#v", k)
+	}
+
+	got = doc.InterfaceWithOptions(InterfaceOptions{Tags: TagDrop}).(map[string]any)
+	if s := got["secret"]; s != "TOKEN" {
+		t.Errorf("drop scalar = %#v", s)
+	}
+}
+
+func TestInterfaceRootObject(t *testing.T) {
+	doc := mustParse(t, "{a b}")
+	want := map[string]any{"a": "b"}
+	if got := doc.Interface(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Interface() = %#v, want %#v", got, want)
+	}
+}
+
+func TestFromInterface(t *testing.T) {
+	v, err := FromInterface(map[string]any{
+		"name":    "my app",
+		"port":    8080,
+		"ratio":   0.5,
+		"debug":   true,
+		"none":    nil,
+		"unset":   (*time.Time)(nil),
+		"hosts":   []string{"a", "b"},
+		"db":      map[string]any{"host": "localhost"},
+		"secret":  Tagged{Tag: "env", Value: "TOKEN"},
+		"marker":  Tagged{Tag: "on"},
+		"timeout": 30 * time.Second,
+		"since":   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `db {
+    host localhost
+}
+debug true
+hosts (a b)
+marker @on
+name "my app"
+none @
+port 8080
+ratio 0.5
+secret @env"TOKEN"
+since 2024-01-02T03:04:05Z
+timeout 30s
+unset @
+`
+	if got := string(Format(&Document{Entries: v.Object.Entries})); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	doc := mustParse(t, "a (1 {b @x}) // comment\nc @t{d e}\n")
+	back, err := FromInterface(doc.Interface())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(doc, &Document{Entries: back.Object.Entries}); len(changes) > 0 {
+		t.Errorf("round trip changed the document: %v", changes)
+	}
+
+	for _, data := range []any{struct{}{}, map[int]any{1: 2}, Tagged{Tag: "1x"}, []any{make(chan int)}} {
+		if _, err := FromInterface(data); err == nil {
+			t.Errorf("%#v: converted without error", data)
+		}
+	}
+}
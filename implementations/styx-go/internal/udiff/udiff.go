@@ -0,0 +1,124 @@
+// Package udiff writes unified diffs, as diff -u does, for the commands
+// that show how a file differs from what was expected.
+package udiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 3
+
+// Unified returns a unified diff turning the lines of a into those of b,
+// labeled with the names given, or "" if they are equal.
+func Unified(a, b, aName, bName string) string {
+	if a == b {
+		return ""
+	}
+	x, y := splitLines(a), splitLines(b)
+	ops := diffLines(x, y)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for start := 0; start < len(ops); {
+		// Find the next change and the run of ops around it, merging
+		// changes separated by fewer than twice the context.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+		from, to := max(start-diffContext, 0), min(end+diffContext, len(ops))
+		ai, bi := ops[from].ai, ops[from].bi
+		var alen, blen int
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				alen++
+			}
+			if op.kind != '-' {
+				blen++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(ai, alen), hunkRange(bi, blen))
+		for _, op := range ops[from:to] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// hunkRange formats the start and length of a hunk's side, numbering
+// lines from 1 as diff does.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOp is a line kept (' '), removed ('-') or added ('+'), with the
+// positions in each side it occurs at.
+type diffOp struct {
+	kind   byte
+	line   string
+	ai, bi int
+}
+
+// diffLines returns the edit script turning x into y along a longest
+// common subsequence.
+func diffLines(x, y []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, diffOp{' ', x[i], i, j})
+			i, j = i+1, j+1
+		case j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', x[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', y[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
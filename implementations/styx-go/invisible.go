@@ -0,0 +1,53 @@
+package styx
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// invisibleCharacters names the characters CheckInvisibleCharacters flags:
+// zero-width characters, bidirectional controls, which can reorder how the
+// surrounding text displays, and non-breaking spaces, which look like the
+// whitespace that ends a bare scalar but do not.
+var invisibleCharacters = map[rune]string{
+	'\u00A0': "NO-BREAK SPACE",
+	'\u061C': "ARABIC LETTER MARK",
+	'\u200B': "ZERO WIDTH SPACE",
+	'\u200C': "ZERO WIDTH NON-JOINER",
+	'\u200D': "ZERO WIDTH JOINER",
+	'\u200E': "LEFT-TO-RIGHT MARK",
+	'\u200F': "RIGHT-TO-LEFT MARK",
+	'\u202A': "LEFT-TO-RIGHT EMBEDDING",
+	'\u202B': "RIGHT-TO-LEFT EMBEDDING",
+	'\u202C': "POP DIRECTIONAL FORMATTING",
+	'\u202D': "LEFT-TO-RIGHT OVERRIDE",
+	'\u202E': "RIGHT-TO-LEFT OVERRIDE",
+	'\u202F': "NARROW NO-BREAK SPACE",
+	'\u2060': "WORD JOINER",
+	'\u2066': "LEFT-TO-RIGHT ISOLATE",
+	'\u2067': "RIGHT-TO-LEFT ISOLATE",
+	'\u2068': "FIRST STRONG ISOLATE",
+	'\u2069': "POP DIRECTIONAL ISOLATE",
+	'\uFEFF': "ZERO WIDTH NO-BREAK SPACE",
+}
+
+// checkInvisible warns of each invisible character in the bare scalar
+// source text, which starts at offset start.
+func (l *Lexer) checkInvisible(text string, start int) {
+	for i, r := range text {
+		name, ok := invisibleCharacters[r]
+		if !ok {
+			continue
+		}
+		span := Span{start + i, start + i + utf8.RuneLen(r)}
+		l.warnings = append(l.warnings, &Diagnostic{
+			Code:     CodeInvisibleCharacter,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("bare scalar contains invisible character U+%04X %s", r, name),
+			Span:     span,
+			Help:     "if the character is intended, quote the scalar and write it as an escape",
+			Fixes:    []Fix{{Message: "remove the character", Edits: []Edit{{Span: span}}}},
+			args:     []string{fmt.Sprintf("U+%04X", r), name},
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package styx
 
 import (
+	"io"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -22,6 +24,10 @@ const (
 	TokenTag
 	TokenGT
 	TokenEOF
+	// TokenComment and TokenWhitespace are only produced in trivia mode;
+	// see Lexer.SetTrivia.
+	TokenComment
+	TokenWhitespace
 )
 
 func (t TokenType) String() string {
@@ -52,34 +58,286 @@ func (t TokenType) String() string {
 		return "gt"
 	case TokenEOF:
 		return "eof"
+	case TokenComment:
+		return "comment"
+	case TokenWhitespace:
+		return "whitespace"
 	default:
 		return "unknown"
 	}
 }
 
-// Token represents a lexer token.
+// MarshalText returns the type's name, as String does.
+func (t TokenType) MarshalText() ([]byte, error) {
+	return marshalEnum(t, "TokenType")
+}
+
+// UnmarshalText sets the type from its name.
+func (t *TokenType) UnmarshalText(text []byte) error {
+	return unmarshalEnum(t, text, "TokenType", TokenWhitespace)
+}
+
+// Token represents a lexer token. Text is the decoded token text; unless
+// decoding changed it (escapes, invalid UTF-8, normalized line endings or
+// heredoc dedenting) it is a slice of the source rather than a copy.
+//
+// Line and Column locate the start of the token. Both are 1-based; Column
+// counts characters, not bytes, from the start of the line.
+//
+// HadCommentBefore reports whether a comment was skipped before the token,
+// and BlankLinesBefore counts the lines holding only whitespace between the
+// previous token's line and this token's.
 type Token struct {
 	Type                TokenType
 	Text                string
 	Span                Span
 	HadWhitespaceBefore bool
 	HadNewlineBefore    bool
+	Line                int
+	Column              int
+	HadCommentBefore    bool
+	BlankLinesBefore    int
 }
 
 // Lexer tokenizes Styx source code.
 type Lexer struct {
-	source  string
-	pos     int // character position
-	bytePos int // byte position for spans
+	source string
+	// pos indexes source; bytePos is the offset in the whole input used for
+	// spans. They differ once a streaming lexer has released consumed input.
+	pos          int
+	bytePos      int
+	comments     []*Comment
+	warnings     []*Diagnostic
+	skipComments bool
+	// maxScalarLength bounds the decoded length of scalar tokens; zero
+	// means unlimited.
+	maxScalarLength int
+	// maxHeredocLength bounds the size of heredoc bodies as they are read;
+	// zero means unlimited.
+	maxHeredocLength int
+	// rejectInvalidUTF8 makes invalid encoding an error instead of
+	// decoding it to U+FFFD; encodingErr holds the pending error.
+	rejectInvalidUTF8 bool
+	encodingErr       *ParseError
+	// normalizeLineEndings converts CRLF to LF in heredoc and raw string
+	// content.
+	normalizeLineEndings bool
+	// extendedTagNames permits `/` and `.` between tag name segments.
+	extendedTagNames bool
+	// extendedEscapes enables `\0` and `\xNN` escapes.
+	extendedEscapes bool
+	// unicodeTagNames permits Unicode letters, digits and marks in tag names.
+	unicodeTagNames bool
+	// checkInvisible warns of invisible characters in bare scalars.
+	warnInvisible bool
+	// trivia selects which trivia are emitted as tokens.
+	trivia TriviaMode
+	// line and column track the zero-based position of the next character;
+	// tokenLine and tokenColumn hold that of the token being scanned.
+	line, column           int
+	tokenLine, tokenColumn int
+	// hadComment and blankLines describe the gap skipped before the token
+	// being scanned.
+	hadComment bool
+	blankLines int
+
+	// When reading from a reader, source is a view of buf, which grows as
+	// more input is needed. readErr holds the error that stopped reading.
+	reader  io.Reader
+	buf     []byte
+	maxSize int
+	readErr error
+	// release drops input before the current token from buf, so that a
+	// streaming lexer holds only the input it still needs.
+	release bool
 }
 
 func newLexer(source string) *Lexer {
 	return &Lexer{source: source}
 }
 
+// NewLexer returns a lexer for source with the default options.
+func NewLexer(source string) *Lexer {
+	return newLexer(source)
+}
+
+// NewLexerWithOptions returns a lexer for source honoring the lexical parse
+// options: SkipComments, MaxScalarLength, SkipBOM, RejectInvalidUTF8,
+// NormalizeLineEndings, ExtendedTagNames, UnicodeTagNames and
+// ExtendedEscapes.
+func NewLexerWithOptions(source string, opts ParseOptions) *Lexer {
+	l := newLexer(source)
+	l.configure(opts)
+	return l
+}
+
+// NextToken returns the next token. Once the source is exhausted it returns
+// a TokenEOF token on every call. After an error the rest of the offending
+// line is skipped, so lexing can resume with the next call.
+func (l *Lexer) NextToken() (*Token, error) {
+	tok, err := l.nextToken()
+	if err != nil {
+		l.skipLine()
+	}
+	return tok, err
+}
+
+// TriviaMode selects which trivia, the text between tokens, a lexer emits as
+// tokens of its own.
+type TriviaMode int
+
+const (
+	// TriviaNone skips comments and whitespace. This is the default.
+	TriviaNone TriviaMode = iota
+	// TriviaComments emits each comment as a TokenComment whose text and
+	// span exclude the line ending.
+	TriviaComments
+	// TriviaAll additionally emits each run of whitespace, line endings
+	// included, as a TokenWhitespace.
+	TriviaAll
+)
+
+// SetTrivia selects which trivia the lexer emits as tokens. Together the
+// tokens emitted with TriviaAll cover the source without gaps, which suits
+// formatters and highlighters. Comments are recorded for Comments in every
+// mode. The parser always lexes without trivia.
+func (l *Lexer) SetTrivia(mode TriviaMode) {
+	l.trivia = mode
+}
+
+// Comments returns the comments lexed so far, in source order.
+func (l *Lexer) Comments() []*Comment {
+	return l.comments
+}
+
+// Warnings returns the warnings found while lexing so far, in source order.
+func (l *Lexer) Warnings() []*Diagnostic {
+	return l.warnings
+}
+
+// Tokenize returns the tokens of source, ending with a TokenEOF token. It
+// stops at the first lexical error, returning the tokens before it.
+func Tokenize(source string) ([]*Token, error) {
+	l := newLexer(source)
+	var tokens []*Token
+	for {
+		tok, err := l.nextToken()
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, tok)
+		if tok.Type == TokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+// configure applies the lexer-level parse options.
+func (l *Lexer) configure(opts ParseOptions) {
+	l.skipComments = opts.SkipComments
+	l.maxScalarLength = opts.MaxScalarLength
+	l.maxHeredocLength = opts.MaxHeredocLength
+	l.rejectInvalidUTF8 = opts.RejectInvalidUTF8
+	l.normalizeLineEndings = opts.NormalizeLineEndings
+	l.extendedTagNames = opts.ExtendedTagNames
+	l.unicodeTagNames = opts.UnicodeTagNames
+	l.extendedEscapes = opts.ExtendedEscapes
+	l.warnInvisible = opts.CheckInvisibleCharacters
+	if opts.SkipBOM {
+		l.skipBOM()
+	}
+}
+
+// newReaderLexer returns a lexer that pulls its source from r as needed.
+// At most maxSize bytes are read when maxSize is positive.
+func newReaderLexer(r io.Reader, maxSize int) *Lexer {
+	return &Lexer{reader: r, maxSize: maxSize}
+}
+
+// NewReaderLexer returns a lexer that reads its source from r as tokens are
+// requested, buffering internally, so tokens can be consumed while a large or
+// slow stream is still arriving. Input is released once it has been lexed:
+// memory use is bounded by the longest token rather than the whole input,
+// though token texts keep the buffers they slice alive. Spans are offsets
+// into the whole input. MaxInputSize bounds how much is read from r, and I/O
+// errors other than io.EOF are returned from NextToken.
+func NewReaderLexer(r io.Reader, opts ParseOptions) *Lexer {
+	l := newReaderLexer(r, opts.MaxInputSize)
+	l.release = true
+	l.configure(opts)
+	return l
+}
+
+// releaseInput drops the already lexed part of the buffer once it is large
+// enough to be worth copying the rest. The remaining input is copied to a new
+// buffer, so strings sliced from the old one stay valid.
+func (l *Lexer) releaseInput() {
+	if !l.release || l.pos < readChunkSize {
+		return
+	}
+	rest := make([]byte, len(l.buf)-l.pos, max(len(l.buf)-l.pos, readChunkSize))
+	copy(rest, l.buf[l.pos:])
+	l.buf = rest
+	l.source = bytesToString(rest)
+	l.pos = 0
+}
+
+// readChunkSize is the number of bytes requested from a reader at a time.
+const readChunkSize = 32 * 1024
+
+// more reports whether any input remains, reading more if necessary.
+func (l *Lexer) more() bool {
+	return l.pos < len(l.source) || l.fill()
+}
+
+// ensure tries to make n bytes available after the current position,
+// reading more input as needed. It reports whether it succeeded.
+func (l *Lexer) ensure(n int) bool {
+	for len(l.source)-l.pos < n {
+		if !l.fill() {
+			return false
+		}
+	}
+	return true
+}
+
+// fill reads the next chunk from the reader into the source buffer and
+// reports whether any input was added. Bytes already in the buffer are never
+// modified, so strings sliced from the source stay valid as it grows.
+func (l *Lexer) fill() bool {
+	if l.reader == nil {
+		return false
+	}
+	for {
+		if len(l.buf) == cap(l.buf) {
+			l.buf = append(l.buf, make([]byte, readChunkSize)...)[:len(l.buf)]
+		}
+		n, err := l.reader.Read(l.buf[len(l.buf):cap(l.buf)])
+		l.buf = l.buf[:len(l.buf)+n]
+		if err != nil {
+			l.reader = nil
+			if err != io.EOF {
+				l.readErr = err
+			}
+		}
+		// Offset of buf in the whole input.
+		offset := l.bytePos - l.pos
+		if l.maxSize > 0 && offset+len(l.buf) > l.maxSize {
+			l.reader = nil
+			l.readErr = &ParseError{Code: CodeInputTooLarge, Message: "input too large", Span: Span{l.maxSize, offset + len(l.buf)}}
+			l.buf = l.buf[:l.maxSize-offset]
+		}
+		if n > 0 || l.reader == nil {
+			grew := len(l.buf) > len(l.source)
+			l.source = bytesToString(l.buf)
+			return grew
+		}
+	}
+}
+
 func (l *Lexer) peek(offset int) rune {
 	idx := l.pos + offset
-	if idx >= len(l.source) {
+	if !l.ensure(offset+utf8.UTFMax) && idx >= len(l.source) {
 		return 0
 	}
 	r, _ := utf8.DecodeRuneInString(l.source[idx:])
@@ -87,32 +345,97 @@ func (l *Lexer) peek(offset int) rune {
 }
 
 func (l *Lexer) advance() rune {
-	if l.pos >= len(l.source) {
+	if !l.ensure(utf8.UTFMax) && l.pos >= len(l.source) {
 		return 0
 	}
 	r, size := utf8.DecodeRuneInString(l.source[l.pos:])
+	if r == utf8.RuneError && size == 1 && l.rejectInvalidUTF8 && l.encodingErr == nil {
+		l.encodingErr = &ParseError{Code: CodeInvalidUTF8, Message: "invalid UTF-8", Span: Span{l.bytePos, l.bytePos + 1}}
+	}
 	l.pos += size
 	l.bytePos += size
+	if r == '\n' {
+		l.line++
+		l.column = 0
+	} else {
+		l.column++
+	}
 	return r
 }
 
+// text returns the source between positions start and end. Valid UTF-8 is
+// returned as a slice of the source without copying; invalid bytes are
+// decoded to U+FFFD one at a time, as advance does.
+func (l *Lexer) text(start, end int) string {
+	s := l.source[start:end]
+	if utf8.ValidString(s) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// textSince returns the text from the absolute offset start to the current
+// position.
+func (l *Lexer) textSince(start int) string {
+	return l.text(start-(l.bytePos-l.pos), l.pos)
+}
+
+// skipBOM skips a UTF-8 byte order mark at the start of the input.
+func (l *Lexer) skipBOM() {
+	const bom = "\uFEFF"
+	l.ensure(len(bom))
+	if l.pos == 0 && strings.HasPrefix(l.source, bom) {
+		l.pos += len(bom)
+		l.bytePos += len(bom)
+	}
+}
+
 func (l *Lexer) skipWhitespaceAndComments() (hadWhitespace, hadNewline bool) {
-	for l.pos < len(l.source) {
+	l.hadComment, l.blankLines = false, 0
+	// lineEmpty tracks whether the gap's current line, after its first
+	// newline, has held only whitespace so far.
+	newlines, lineEmpty := 0, false
+	for l.more() {
 		ch := l.peek(0)
 		switch ch {
 		case ' ', '\t', '\r':
+			if l.trivia == TriviaAll {
+				return
+			}
 			hadWhitespace = true
 			l.advance()
 		case '\n':
+			if l.trivia == TriviaAll {
+				return
+			}
 			hadWhitespace = true
 			hadNewline = true
+			if newlines > 0 && lineEmpty {
+				l.blankLines++
+			}
+			newlines++
+			lineEmpty = true
 			l.advance()
 		case '/':
 			if l.peek(1) == '/' {
+				if l.trivia != TriviaNone {
+					return
+				}
 				hadWhitespace = true
-				for l.pos < len(l.source) && l.peek(0) != '\n' {
+				l.hadComment = true
+				lineEmpty = false
+				start, startPos := l.bytePos, l.pos
+				for l.more() && l.peek(0) != '\n' {
 					l.advance()
 				}
+				if !l.skipComments {
+					text := strings.TrimSuffix(l.source[startPos:l.pos], "\r")
+					l.comments = append(l.comments, &Comment{Text: text, Span: Span{start, start + len(text)}})
+				}
 			} else {
 				return
 			}
@@ -123,6 +446,18 @@ func (l *Lexer) skipWhitespaceAndComments() (hadWhitespace, hadNewline bool) {
 	return
 }
 
+// skipLine discards the rest of the current line, leaving the newline to be
+// lexed as whitespace. It does nothing if the lexer is already at the start
+// of a line.
+func (l *Lexer) skipLine() {
+	if l.pos > 0 && l.source[l.pos-1] == '\n' {
+		return
+	}
+	for l.more() && l.peek(0) != '\n' {
+		l.advance()
+	}
+}
+
 func isTagStart(ch rune) bool {
 	return (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || ch == '_'
 }
@@ -131,6 +466,28 @@ func isTagChar(ch rune) bool {
 	return isTagStart(ch) || (ch >= '0' && ch <= '9') || ch == '-'
 }
 
+// isTagStart and isTagChar extend the package-level tests with Unicode
+// letters, digits and combining marks when unicodeTagNames is set.
+func (l *Lexer) isTagStart(ch rune) bool {
+	if l.unicodeTagNames && ch >= utf8.RuneSelf {
+		return unicode.IsLetter(ch)
+	}
+	return isTagStart(ch)
+}
+
+func (l *Lexer) isTagChar(ch rune) bool {
+	if l.unicodeTagNames && ch >= utf8.RuneSelf {
+		return unicode.In(ch, unicode.Letter, unicode.Digit, unicode.Mn, unicode.Mc)
+	}
+	return isTagChar(ch)
+}
+
+// isTagSeparator reports whether ch may join the segments of an extended
+// tag name, as in `@org/package` or `@org.package`.
+func isTagSeparator(ch rune) bool {
+	return ch == '/' || ch == '.'
+}
+
 func isSpecialChar(ch rune) bool {
 	switch ch {
 	case '{', '}', '(', ')', ',', '"', '>', ' ', '\t', '\n', '\r':
@@ -140,55 +497,95 @@ func isSpecialChar(ch rune) bool {
 }
 
 func (l *Lexer) nextToken() (*Token, error) {
+	tok, err := l.scanToken()
+	if l.readErr != nil {
+		return nil, l.readErr
+	}
+	if l.encodingErr != nil {
+		err := l.encodingErr
+		l.encodingErr = nil
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l.maxScalarLength > 0 && len(tok.Text) > l.maxScalarLength {
+		switch tok.Type {
+		case TokenScalar, TokenQuoted, TokenRaw, TokenHeredoc:
+			return nil, &ParseError{Code: CodeScalarTooLong, Message: "scalar too long", Span: tok.Span}
+		}
+	}
+	return tok, nil
+}
+
+func (l *Lexer) scanToken() (*Token, error) {
+	l.releaseInput()
 	hadWhitespace, hadNewline := l.skipWhitespaceAndComments()
 
-	if l.pos >= len(l.source) {
+	if !l.more() {
 		return &Token{
 			Type:                TokenEOF,
 			Text:                "",
 			Span:                Span{l.bytePos, l.bytePos},
 			HadWhitespaceBefore: hadWhitespace,
 			HadNewlineBefore:    hadNewline,
+			Line:                l.line + 1,
+			Column:              l.column + 1,
+			HadCommentBefore:    l.hadComment,
+			BlankLinesBefore:    l.blankLines,
 		}, nil
 	}
 
 	start := l.bytePos
+	l.tokenLine, l.tokenColumn = l.line, l.column
 	ch := l.peek(0)
 
+	if l.trivia != TriviaNone {
+		if tok := l.scanTrivia(ch, hadWhitespace, hadNewline); tok != nil {
+			return tok, nil
+		}
+	}
+
 	// Single-character tokens
 	switch ch {
 	case '{':
 		l.advance()
-		return &Token{TokenLBrace, "{", Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+		return l.token(TokenLBrace, "{", Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 	case '}':
 		l.advance()
-		return &Token{TokenRBrace, "}", Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+		return l.token(TokenRBrace, "}", Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 	case '(':
 		l.advance()
-		return &Token{TokenLParen, "(", Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+		return l.token(TokenLParen, "(", Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 	case ')':
 		l.advance()
-		return &Token{TokenRParen, ")", Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+		return l.token(TokenRParen, ")", Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 	case ',':
 		l.advance()
-		return &Token{TokenComma, ",", Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+		return l.token(TokenComma, ",", Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 	case '>':
 		l.advance()
-		return &Token{TokenGT, ">", Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+		return l.token(TokenGT, ">", Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 	}
 
 	// @ - either unit or tag
 	if ch == '@' {
 		l.advance()
-		if isTagStart(l.peek(0)) {
+		if l.isTagStart(l.peek(0)) {
 			nameStart := l.pos
-			for isTagChar(l.peek(0)) {
-				l.advance()
+			for {
+				if l.isTagChar(l.peek(0)) {
+					l.advance()
+				} else if l.extendedTagNames && isTagSeparator(l.peek(0)) && l.isTagStart(l.peek(1)) {
+					l.advance()
+				} else {
+					break
+				}
 			}
 			name := l.source[nameStart:l.pos]
-			return &Token{TokenTag, name, Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+			return l.token(TokenTag, name, Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 		}
-		return &Token{TokenAt, "@", Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+		return l.token(TokenAt, "@", Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 	}
 
 	// Quoted string
@@ -212,10 +609,11 @@ func (l *Lexer) nextToken() (*Token, error) {
 		l.advance() // <
 		errorEnd := l.bytePos
 		// Skip rest of line for recovery
-		for l.pos < len(l.source) && l.peek(0) != '\n' {
+		for l.more() && l.peek(0) != '\n' {
 			l.advance()
 		}
 		return nil, &ParseError{
+			Code:    CodeInvalidHeredoc,
 			Message: "unexpected token",
 			Span:    Span{start, errorEnd},
 		}
@@ -225,21 +623,65 @@ func (l *Lexer) nextToken() (*Token, error) {
 	return l.readBareScalar(start, hadWhitespace, hadNewline)
 }
 
+// token returns a token positioned where the current token started.
+func (l *Lexer) token(typ TokenType, text string, span Span, hadWhitespace, hadNewline bool) *Token {
+	return &Token{typ, text, span, hadWhitespace, hadNewline, l.tokenLine + 1, l.tokenColumn + 1, l.hadComment, l.blankLines}
+}
+
+// scanTrivia lexes a comment or whitespace token starting with ch, or
+// returns nil if there is none at the current position.
+func (l *Lexer) scanTrivia(ch rune, hadWhitespace, hadNewline bool) *Token {
+	start, startPos := l.bytePos, l.pos
+	switch {
+	case ch == '/' && l.peek(1) == '/':
+		for l.more() && l.peek(0) != '\n' && !(l.peek(0) == '\r' && l.peek(1) == '\n') {
+			l.advance()
+		}
+		text := l.text(startPos, l.pos)
+		if !l.skipComments {
+			l.comments = append(l.comments, &Comment{Text: text, Span: Span{start, l.bytePos}})
+		}
+		return l.token(TokenComment, text, Span{start, l.bytePos}, hadWhitespace, hadNewline)
+	case l.trivia == TriviaAll && (ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'):
+		for l.more() {
+			if ch := l.peek(0); ch != ' ' && ch != '\t' && ch != '\r' && ch != '\n' {
+				break
+			}
+			l.advance()
+		}
+		return l.token(TokenWhitespace, l.source[startPos:l.pos], Span{start, l.bytePos}, hadWhitespace, hadNewline)
+	}
+	return nil
+}
+
 func (l *Lexer) readQuotedString(start int, hadWhitespace, hadNewline bool) (*Token, error) {
 	l.advance() // opening "
+	contentStart := l.pos
+	// Text is only built once an escape is seen; until then the content is
+	// a slice of the source.
 	var text strings.Builder
+	escaped := false
 
-	for l.pos < len(l.source) {
+	for l.more() {
 		ch := l.peek(0)
 		if ch == '"' {
+			content := l.pos
 			l.advance()
-			return &Token{TokenQuoted, text.String(), Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+			result := text.String()
+			if !escaped {
+				result = l.text(contentStart, content)
+			}
+			return l.token(TokenQuoted, result, Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 		}
 		if ch == '\\' {
+			if !escaped {
+				text.WriteString(l.text(contentStart, l.pos))
+				escaped = true
+			}
 			escapeStart := l.bytePos
 			l.advance()
-			escaped := l.advance()
-			switch escaped {
+			esc := l.advance()
+			switch esc {
 			case 'n':
 				text.WriteByte('\n')
 			case 'r':
@@ -251,79 +693,117 @@ func (l *Lexer) readQuotedString(start int, hadWhitespace, hadNewline bool) (*To
 			case '"':
 				text.WriteByte('"')
 			case 'u':
-				r, err := l.readUnicodeEscape()
+				r, err := l.readUnicodeEscape(escapeStart)
 				if err != nil {
 					return nil, err
 				}
 				text.WriteRune(r)
-			default:
-				return nil, &ParseError{
-					Message: "invalid escape sequence: \\" + string(escaped),
-					Span:    Span{escapeStart, l.bytePos},
+			case '0':
+				if !l.extendedEscapes {
+					return nil, invalidEscape(esc, escapeStart, l.bytePos)
+				}
+				text.WriteByte(0)
+			case 'x':
+				if !l.extendedEscapes {
+					return nil, invalidEscape(esc, escapeStart, l.bytePos)
+				}
+				r, err := l.readHexEscape(escapeStart)
+				if err != nil {
+					return nil, err
 				}
+				text.WriteRune(r)
+			default:
+				return nil, invalidEscape(esc, escapeStart, l.bytePos)
 			}
 		} else if ch == '\n' || ch == '\r' {
 			// Unterminated string - include the newline in the span
 			l.advance()
 			return nil, &ParseError{
+				Code:    CodeUnclosedString,
 				Message: "unexpected token",
 				Span:    Span{start, l.bytePos},
 			}
-		} else {
+		} else if escaped {
 			text.WriteRune(l.advance())
+		} else {
+			l.advance()
 		}
 	}
 
 	// EOF without closing quote - error
 	return nil, &ParseError{
+		Code:    CodeUnclosedString,
 		Message: "unexpected token",
 		Span:    Span{start, l.bytePos},
 	}
 }
 
-func (l *Lexer) readUnicodeEscape() (rune, error) {
-	if l.peek(0) == '{' {
-		l.advance()
-		var hexStr strings.Builder
-		for l.peek(0) != '}' && l.pos < len(l.source) {
-			hexStr.WriteRune(l.advance())
-		}
-		l.advance() // }
-		var r rune
-		_, err := parseHex(hexStr.String(), &r)
-		if err != nil {
-			return 0, err
-		}
-		return r, nil
-	}
-
-	var hexStr strings.Builder
-	for i := 0; i < 4; i++ {
-		hexStr.WriteRune(l.advance())
+func invalidEscape(escaped rune, start, end int) *ParseError {
+	return &ParseError{
+		Code:    CodeInvalidEscape,
+		Message: "invalid escape sequence: \\" + string(escaped),
+		Span:    Span{start, end},
+		Args:    []string{"\\" + string(escaped)},
 	}
-	var r rune
-	_, err := parseHex(hexStr.String(), &r)
-	if err != nil {
-		return 0, err
-	}
-	return r, nil
 }
 
-func parseHex(s string, r *rune) (int, error) {
-	var val rune
-	for _, ch := range s {
-		val *= 16
+// readHexDigits consumes up to max hex digits and returns their value and
+// count.
+func (l *Lexer) readHexDigits(max int) (value rune, n int) {
+	for n < max {
+		ch := l.peek(0)
+		var digit rune
 		switch {
 		case ch >= '0' && ch <= '9':
-			val += ch - '0'
+			digit = ch - '0'
 		case ch >= 'a' && ch <= 'f':
-			val += ch - 'a' + 10
+			digit = ch - 'a' + 10
 		case ch >= 'A' && ch <= 'F':
-			val += ch - 'A' + 10
+			digit = ch - 'A' + 10
+		default:
+			return value, n
 		}
+		l.advance()
+		value = value*16 + digit
+		n++
+	}
+	return value, n
+}
+
+// readHexEscape reads the two hex digits of a `\xNN` escape, which must
+// denote an ASCII character.
+func (l *Lexer) readHexEscape(escapeStart int) (rune, error) {
+	r, n := l.readHexDigits(2)
+	if n != 2 || r > 0x7F {
+		return 0, &ParseError{Code: CodeInvalidEscape, Message: "invalid hex escape", Span: Span{escapeStart, l.bytePos}, Args: []string{l.textSince(escapeStart)}}
 	}
-	*r = val
-	return len(s), nil
+	return r, nil
+}
+
+// readUnicodeEscape reads the code point of a `\uXXXX` or `\u{X...}` escape:
+// exactly four hex digits, or one to six in braces with the closing brace
+// present, denoting a code point that is neither a surrogate nor above
+// U+10FFFF.
+func (l *Lexer) readUnicodeEscape(escapeStart int) (rune, error) {
+	var r rune
+	var ok bool
+	if l.peek(0) == '{' {
+		l.advance()
+		var n int
+		r, n = l.readHexDigits(6)
+		ok = n > 0 && l.peek(0) == '}'
+		if ok {
+			l.advance()
+		}
+	} else {
+		var n int
+		r, n = l.readHexDigits(4)
+		ok = n == 4
+	}
+	if !ok || r > unicode.MaxRune || (r >= 0xD800 && r <= 0xDFFF) {
+		return 0, &ParseError{Code: CodeInvalidEscape, Message: "invalid unicode escape", Span: Span{escapeStart, l.bytePos}, Args: []string{l.textSince(escapeStart)}}
+	}
+	return r, nil
 }
 
 func (l *Lexer) readRawString(start int, hadWhitespace, hadNewline bool) (*Token, error) {
@@ -334,23 +814,37 @@ func (l *Lexer) readRawString(start int, hadWhitespace, hadNewline bool) (*Token
 		hashes++
 	}
 	l.advance() // opening "
+	openEnd := l.bytePos
 
-	var text strings.Builder
+	contentStart := l.pos
 	closePattern := "\"" + strings.Repeat("#", hashes)
 
-	for l.pos < len(l.source) {
+	for l.more() {
+		l.ensure(len(closePattern))
 		if strings.HasPrefix(l.source[l.pos:], closePattern) {
+			text := l.text(contentStart, l.pos)
+			if l.normalizeLineEndings {
+				text = strings.ReplaceAll(text, "\r\n", "\n")
+			}
 			for i := 0; i < len(closePattern); i++ {
 				l.advance()
 			}
-			return &Token{TokenRaw, text.String(), Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+			return l.token(TokenRaw, text, Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 		}
-		text.WriteRune(l.advance())
+		l.advance()
 	}
 
 	return nil, &ParseError{
-		Message: "unclosed raw string",
-		Span:    Span{start, l.bytePos},
+		Code:     CodeUnclosedRawString,
+		Message:  "unclosed raw string (missing `" + closePattern + "`)",
+		Args:     []string{closePattern},
+		Span:     Span{start, l.bytePos},
+		Expected: closePattern,
+		Help:     "close the raw string with `" + closePattern + "`",
+		Related: []Label{
+			{Message: "raw string opened here", Span: Span{start, openEnd}},
+			{Message: "expected `" + closePattern + "` before the end of input", Span: Span{l.bytePos, l.bytePos}},
+		},
 	}
 }
 
@@ -358,58 +852,128 @@ func (l *Lexer) readHeredoc(start int, hadWhitespace, hadNewline bool) (*Token,
 	l.advance() // <
 	l.advance() // <
 
-	var delimiter strings.Builder
-	for l.pos < len(l.source) && l.peek(0) != '\n' {
-		delimiter.WriteRune(l.advance())
+	delimiterStart := l.pos
+	for l.more() && l.peek(0) != '\n' {
+		l.advance()
 	}
-	if l.pos < len(l.source) {
+	delimiter := l.text(delimiterStart, l.pos)
+	openEnd := l.bytePos - (len(delimiter) - len(strings.TrimSuffix(delimiter, "\r")))
+	if l.more() {
 		l.advance() // newline
 	}
 
 	// Track content start (after the opening line)
 	contentStart := l.bytePos
-
-	var text strings.Builder
-	delimStr := delimiter.String()
-	bareDelimiter := strings.SplitN(delimStr, ",", 2)[0]
-
-	for l.pos < len(l.source) {
-		var line strings.Builder
-		for l.pos < len(l.source) && l.peek(0) != '\n' {
-			line.WriteRune(l.advance())
+	textStart := l.pos
+
+	// A CRLF line ending is not part of the delimiter.
+	bareDelimiter, _ := splitHeredocOpening(strings.TrimSuffix(delimiter, "\r"))
+
+	// spaces and tabs record whether the indentation of the non-blank lines
+	// read so far used them; mixed is where both were first seen.
+	spaces, tabs := false, false
+	mixed := Span{-1, -1}
+	for l.more() {
+		lineStart, lineOffset := l.pos, l.bytePos
+		// indent and rest measure the line read so far, so that the size
+		// limit can be enforced without waiting for a line that may never
+		// end, while still allowing for an indented closing delimiter.
+		indent, rest := 0, 0
+		lineSpaces, lineTabs := false, false
+		for l.more() && l.peek(0) != '\n' {
+			if ch := l.advance(); rest == 0 && (ch == ' ' || ch == '\t') {
+				indent++
+				lineSpaces = lineSpaces || ch == ' '
+				lineTabs = lineTabs || ch == '\t'
+			} else {
+				rest++
+			}
+			if l.heredocTooLong(contentStart) && (indent > l.maxHeredocLength || rest > len(bareDelimiter)+1) {
+				return nil, l.heredocTooLongError(start, openEnd)
+			}
 		}
 
-		lineStr := line.String()
-
-		// Check for exact match (no indentation)
-		if lineStr == bareDelimiter {
-			result := text.String()
-			return &Token{TokenHeredoc, result, Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+		if rest > 0 && mixed.Start < 0 {
+			spaces, tabs = spaces || lineSpaces, tabs || lineTabs
+			if spaces && tabs {
+				mixed = Span{lineOffset, lineOffset + indent}
+			}
 		}
 
-		// Check for indented closing delimiter
-		stripped := strings.TrimLeft(lineStr, " \t")
+		lineStr := l.text(lineStart, l.pos)
+		content := strings.TrimSuffix(lineStr, "\r")
+		end := l.bytePos - (len(lineStr) - len(content))
+
+		// The content is every line before the closing delimiter.
+		stripped := strings.TrimLeft(content, " \t")
 		if stripped == bareDelimiter {
-			indentLen := len(lineStr) - len(stripped)
-			// Dedent the content by stripping up to indentLen from each line
-			result := dedentHeredoc(text.String(), indentLen)
-			return &Token{TokenHeredoc, result, Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+			text := l.text(textStart, lineStart)
+			if l.normalizeLineEndings {
+				text = strings.ReplaceAll(text, "\r\n", "\n")
+			}
+			// Dedent the content by stripping up to the closing
+			// delimiter's indentation from each line
+			if indentLen := len(content) - len(stripped); indentLen > 0 {
+				text = dedentHeredoc(text, indentLen)
+				if mixed.Start >= 0 {
+					l.warnings = append(l.warnings, &Diagnostic{
+						Code:     CodeMixedIndentation,
+						Severity: SeverityWarning,
+						Message:  "heredoc indentation mixes tabs and spaces",
+						Span:     mixed,
+						Help:     "dedenting counts a tab as one character; indent with either tabs or spaces",
+					})
+				}
+			}
+			return l.token(TokenHeredoc, text, Span{start, end}, hadWhitespace, hadNewline), nil
 		}
 
-		text.WriteString(lineStr)
-		if l.pos < len(l.source) && l.peek(0) == '\n' {
+		if l.heredocTooLong(contentStart) {
+			return nil, l.heredocTooLongError(start, openEnd)
+		}
+		if l.more() && l.peek(0) == '\n' {
 			l.advance()
-			text.WriteByte('\n')
 		}
 	}
 
-	// EOF without closing delimiter - error points at the unmatched content
+	// EOF without closing delimiter - error points at the unmatched content,
+	// as the reference implementation reports it
 	return nil, &ParseError{
-		Message: "unexpected token",
-		Span:    Span{contentStart, l.bytePos},
+		Code:     CodeUnclosedHeredoc,
+		Message:  "unexpected token",
+		Span:     Span{contentStart, l.bytePos},
+		Expected: bareDelimiter,
+		Args:     []string{bareDelimiter},
+		Help:     "end the heredoc with a line holding only `" + bareDelimiter + "`",
+		Related: []Label{
+			{Message: "heredoc opened here; missing closing `" + bareDelimiter + "`", Span: Span{start, openEnd}},
+			{Message: "expected `" + bareDelimiter + "` on its own line before the end of input", Span: Span{l.bytePos, l.bytePos}},
+		},
+	}
+}
+
+// heredocTooLong reports whether the heredoc body starting at contentStart
+// has grown past maxHeredocLength.
+func (l *Lexer) heredocTooLong(contentStart int) bool {
+	return l.maxHeredocLength > 0 && l.bytePos-contentStart > l.maxHeredocLength
+}
+
+func (l *Lexer) heredocTooLongError(start, openEnd int) *ParseError {
+	return &ParseError{
+		Code:    CodeScalarTooLong,
+		Message: "heredoc too long",
+		Span:    Span{start, openEnd},
+		Related: []Label{{Message: "body exceeds the size limit here", Span: Span{l.bytePos, l.bytePos}}},
 	}
 }
 
+// splitHeredocOpening splits the text following `<<` on a heredoc's opening
+// line into the delimiter and the comma-separated options after it.
+func splitHeredocOpening(opening string) (delimiter string, options []string) {
+	parts := strings.Split(opening, ",")
+	return parts[0], parts[1:]
+}
+
 // dedentHeredoc strips up to indentLen whitespace characters from the start of each line.
 func dedentHeredoc(content string, indentLen int) string {
 	lines := strings.Split(content, "\n")
@@ -432,13 +996,16 @@ func dedentHeredoc(content string, indentLen int) string {
 }
 
 func (l *Lexer) readBareScalar(start int, hadWhitespace, hadNewline bool) (*Token, error) {
-	var text strings.Builder
-	for l.pos < len(l.source) {
+	textStart := l.pos
+	for l.more() {
 		ch := l.peek(0)
 		if isSpecialChar(ch) {
 			break
 		}
-		text.WriteRune(l.advance())
+		l.advance()
+	}
+	if l.warnInvisible {
+		l.checkInvisible(l.source[textStart:l.pos], start)
 	}
-	return &Token{TokenScalar, text.String(), Span{start, l.bytePos}, hadWhitespace, hadNewline}, nil
+	return l.token(TokenScalar, l.text(textStart, l.pos), Span{start, l.bytePos}, hadWhitespace, hadNewline), nil
 }
@@ -0,0 +1,153 @@
+package styx
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize(`a {b "c"} // note`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TokenType{TokenScalar, TokenLBrace, TokenScalar, TokenQuoted, TokenRBrace, TokenEOF}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		if tok.Type != want[i] {
+			t.Errorf("token %d: got %v, want %v", i, tok.Type, want[i])
+		}
+	}
+	if tokens[3].Text != "c" || tokens[3].Span != (Span{5, 8}) {
+		t.Errorf("quoted token: got %q at %v", tokens[3].Text, tokens[3].Span)
+	}
+
+	tokens, err = Tokenize("a \"\\q\"\nb")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(tokens) != 1 {
+		t.Errorf("expected tokens before the error, got %d", len(tokens))
+	}
+}
+
+func TestLexerNextToken(t *testing.T) {
+	l := NewLexerWithOptions("a \"\\q\" x\nb // c\n", ParseOptions{})
+	var types []TokenType
+	errs := 0
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			errs++
+			continue
+		}
+		types = append(types, tok.Type)
+		if tok.Type == TokenEOF {
+			break
+		}
+	}
+	if errs != 1 || len(types) != 3 {
+		t.Errorf("got %d errors and tokens %v", errs, types)
+	}
+	if len(l.Comments()) != 1 || l.Comments()[0].Text != "// c" {
+		t.Errorf("unexpected comments %v", l.Comments())
+	}
+}
+
+func TestTokensShareSource(t *testing.T) {
+	source := "key \"quoted\" r#\"raw\"# @tag <<EOF\nheredoc\nEOF"
+	tokens, err := Tokenize(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := uintptr(unsafe.Pointer(unsafe.StringData(source)))
+	for _, tok := range tokens[:len(tokens)-1] {
+		p := uintptr(unsafe.Pointer(unsafe.StringData(tok.Text)))
+		if p < base || p >= base+uintptr(len(source)) {
+			t.Errorf("%v token %q was copied", tok.Type, tok.Text)
+		}
+	}
+
+	tokens, err = Tokenize(`"a\tb"`)
+	if err != nil || tokens[0].Text != "a\tb" {
+		t.Errorf("escaped string: got %q, %v", tokens[0].Text, err)
+	}
+}
+
+func TestLexerTrivia(t *testing.T) {
+	source := "a b // c\r\n  d {}\n"
+	lex := func(mode TriviaMode) []*Token {
+		l := NewLexer(source)
+		l.SetTrivia(mode)
+		var tokens []*Token
+		for {
+			tok, err := l.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens = append(tokens, tok)
+			if tok.Type == TokenEOF {
+				return tokens
+			}
+		}
+	}
+
+	var texts []string
+	for _, tok := range lex(TriviaComments) {
+		texts = append(texts, tok.Type.String()+":"+tok.Text)
+	}
+	want := []string{"scalar:a", "scalar:b", "comment:// c", "scalar:d", "lbrace:{", "rbrace:}", "eof:"}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("comments mode: got %v, want %v", texts, want)
+	}
+
+	// Every byte is covered by exactly one token.
+	pos := 0
+	for _, tok := range lex(TriviaAll) {
+		if tok.Span.Start != pos {
+			t.Fatalf("gap before %v token at %d", tok.Type, tok.Span.Start)
+		}
+		pos = tok.Span.End
+	}
+	if pos != len(source) {
+		t.Errorf("tokens end at %d, want %d", pos, len(source))
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	tokens, err := Tokenize("a b\n  é {\r\n\t<<EOF\nx\nEOF\n c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type pos struct{ line, column int }
+	want := []pos{{1, 1}, {1, 3}, {2, 3}, {2, 5}, {3, 2}, {6, 2}, {6, 3}}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		if got := (pos{tok.Line, tok.Column}); got != want[i] {
+			t.Errorf("%v token %q: got %v, want %v", tok.Type, tok.Text, got, want[i])
+		}
+	}
+}
+
+func TestTokenGapFlags(t *testing.T) {
+	tokens, err := Tokenize("a 1\n\n  \n// c\n\nb 2 // t\nc 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type gap struct {
+		comment bool
+		blank   int
+	}
+	got := map[string]gap{}
+	for _, tok := range tokens {
+		got[tok.Text] = gap{tok.HadCommentBefore, tok.BlankLinesBefore}
+	}
+	want := map[string]gap{"a": {}, "1": {}, "b": {true, 3}, "2": {}, "c": {true, 0}, "3": {}, "": {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,198 @@
+package styx
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Loader builds a configuration from layers, each overriding the ones
+// added before it: typically embedded defaults, then files, then
+// environment variables and programmatic overrides.
+//
+//	var l styx.Loader
+//	l.AddSource("defaults", defaults)
+//	l.AddFile("/etc/app/config.styx")
+//	l.AddOptionalFile("config.local.styx")
+//	l.AddEnv("APP_")
+//	loaded, err := l.Load(&cfg)
+type Loader struct {
+	// Merge configures how layers are merged.
+	Merge MergeOptions
+	// Parse configures how sources and files are parsed. Filename is set
+	// for each file.
+	Parse  ParseOptions
+	layers []loaderLayer
+}
+
+// loaderLayer produces a layer's document from the configuration merged
+// from the layers before it.
+type loaderLayer struct {
+	name string
+	load func(current *Document) (*Document, error)
+}
+
+func (l *Loader) add(name string, load func(current *Document) (*Document, error)) {
+	l.layers = append(l.layers, loaderLayer{name, load})
+}
+
+// AddSource adds a layer parsed from source, such as defaults embedded in
+// the program. name identifies the layer in Loaded.Origin.
+func (l *Loader) AddSource(name, source string) {
+	l.add(name, func(*Document) (*Document, error) {
+		return ParseWithOptions(source, l.Parse)
+	})
+}
+
+// AddDocument adds a parsed document as a layer.
+func (l *Loader) AddDocument(name string, doc *Document) {
+	l.add(name, func(*Document) (*Document, error) {
+		return doc, nil
+	})
+}
+
+// AddFile adds a layer read from the file at path, which names the layer.
+// Loading fails if the file does not exist.
+func (l *Loader) AddFile(path string) {
+	l.add(path, func(*Document) (*Document, error) {
+		return l.parseFile(path)
+	})
+}
+
+// AddOptionalFile is like AddFile but skips the layer if the file does not
+// exist.
+func (l *Loader) AddOptionalFile(path string) {
+	l.add(path, func(*Document) (*Document, error) {
+		doc, err := l.parseFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return &Document{}, nil
+		}
+		return doc, err
+	})
+}
+
+func (l *Loader) parseFile(path string) (*Document, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	opts := l.Parse
+	opts.Filename = path
+	return ParseWithOptions(string(content), opts)
+}
+
+// AddEnv adds a layer of environment variables overriding the scalars and
+// units already configured. The variable for a path is prefix followed by
+// the path in upper case with every character other than a letter or digit
+// replaced by `_`: with the prefix "APP_", APP_SERVER_PORT overrides
+// `server.port`. Variables only override paths defined by earlier layers,
+// which keeps names unambiguous. The layer is named "env".
+func (l *Loader) AddEnv(prefix string) {
+	l.add("env", func(current *Document) (*Document, error) {
+		env := &Document{}
+		for _, leaf := range current.Flatten() {
+			if leaf.Value.PayloadKind != PayloadScalar && leaf.Value.PayloadKind != PayloadNone {
+				continue
+			}
+			if text, ok := os.LookupEnv(prefix + envName(leaf.Keys)); ok {
+				env.Entries = append(env.Entries, pathEntry(leaf.Keys, textValue(text)))
+			}
+		}
+		return env, nil
+	})
+}
+
+func envName(keys []string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, strings.Join(keys, "_"))
+}
+
+// AddOverrides adds a layer setting each dotted path of overrides to a
+// scalar, as for command-line flags. The layer is named "overrides".
+func (l *Loader) AddOverrides(overrides map[string]string) {
+	l.add("overrides", func(*Document) (*Document, error) {
+		doc := &Document{}
+		paths := make([]string, 0, len(overrides))
+		for path := range overrides {
+			paths = append(paths, path)
+		}
+		slices.Sort(paths)
+		for _, path := range paths {
+			if err := doc.SetPath(path, textValue(overrides[path])); err != nil {
+				return nil, err
+			}
+		}
+		return doc, nil
+	})
+}
+
+// textValue returns a scalar holding text, without a location.
+func textValue(text string) *Value {
+	return &Value{Span: noSpan, PayloadKind: PayloadScalar, Scalar: &Scalar{Text: text, Kind: ScalarQuoted, Span: noSpan}}
+}
+
+// Loaded is a configuration assembled by Loader.Load.
+type Loaded struct {
+	// Document is the merged configuration.
+	Document *Document
+	origins  map[string]string
+}
+
+// Origin returns the name of the layer that set the leaf value at path,
+// as listed by Document.Flatten, and whether the path has a value.
+func (c *Loaded) Origin(path string) (string, bool) {
+	name, ok := c.origins[path]
+	return name, ok
+}
+
+// Load merges the layers in order and, if target is not nil, decodes the
+// result into it as Document.Decode does. Errors name the layer they come
+// from.
+func (l *Loader) Load(target any) (*Loaded, error) {
+	merged := &Document{}
+	origins := make(map[string]string)
+	for _, layer := range l.layers {
+		doc, err := layer.load(merged)
+		if err != nil {
+			return nil, &LoadError{Layer: layer.name, Err: err}
+		}
+		if merged, err = Merge(merged, doc, l.Merge); err != nil {
+			return nil, &LoadError{Layer: layer.name, Err: err}
+		}
+		for _, leaf := range doc.Flatten() {
+			origins[leaf.Path] = layer.name
+		}
+	}
+	if target != nil {
+		if err := merged.Decode(target); err != nil {
+			return nil, err
+		}
+	}
+	// Drop the origins of values later layers replaced with other shapes.
+	final := make(map[string]string, len(origins))
+	for _, leaf := range merged.Flatten() {
+		final[leaf.Path] = origins[leaf.Path]
+	}
+	return &Loaded{Document: merged, origins: final}, nil
+}
+
+// LoadError reports a layer that failed to load or merge.
+type LoadError struct {
+	Layer string
+	Err   error
+}
+
+func (e *LoadError) Error() string {
+	return "loading " + e.Layer + ": " + e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,71 @@
+package styx
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.styx")
+	if err := os.WriteFile(file, []byte("server.port 8080\nhosts (a b)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_SERVER_HOST", "example.com")
+	t.Setenv("APP_LOG_LEVEL", "debug")
+
+	var l Loader
+	l.AddSource("defaults", "server {host localhost, port 80}\nlog.level info\nhosts (x)\n")
+	l.AddFile(file)
+	l.AddOptionalFile(filepath.Join(dir, "missing.styx"))
+	l.AddEnv("APP_")
+	l.AddOverrides(map[string]string{"log.level": "warn", "server.tls": "true"})
+
+	var cfg struct {
+		Server struct {
+			Host string
+			Port int
+			TLS  bool
+		}
+		Log   struct{ Level string }
+		Hosts []string
+	}
+	loaded, err := l.Load(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Host != "example.com" || cfg.Server.Port != 8080 || !cfg.Server.TLS || cfg.Log.Level != "warn" || len(cfg.Hosts) != 2 {
+		t.Errorf("got %+v", cfg)
+	}
+	for path, want := range map[string]string{
+		"server.host": "env",
+		"server.port": file,
+		"server.tls":  "overrides",
+		"log.level":   "overrides",
+		"hosts.1":     file,
+	} {
+		if got, ok := loaded.Origin(path); !ok || got != want {
+			t.Errorf("Origin(%s) = %q, %v; want %q", path, got, ok, want)
+		}
+	}
+	if _, ok := loaded.Origin("hosts.2"); ok {
+		t.Error("Origin(hosts.2): want none")
+	}
+
+	var missing Loader
+	missing.AddFile(filepath.Join(dir, "missing.styx"))
+	_, err = missing.Load(nil)
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: got %v", err)
+	}
+
+	var bad Loader
+	bad.AddSource("broken", "a {")
+	if _, err := bad.Load(nil); !errors.As(err, &loadErr) || loadErr.Layer != "broken" || !errors.Is(err, ErrUnclosedObject) {
+		t.Errorf("broken source: got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package styx
+
+import "strings"
+
+// Get returns the value at path, a dotted key path such as
+// "server.tls.cert", or nil if the document has no such value. Each segment
+// names a key of the object reached so far; objects written with dotted keys
+// (`server.tls.cert x`), braces, attribute syntax or behind a tag are
+// navigated alike. A document consisting of an explicit root object is
+// searched from that object. Use LookupKeys for keys containing dots.
+//
+// The value returned is the document's own, so changes to it show in the
+// document, except for a key shared by several dotted entries, such as
+// `server` in `server.host a` and `server.port 1`: its value is a copy
+// combining their objects, a read-only view. The values below it, such as
+// that of `server.host`, are again the document's own.
+func (d *Document) Get(path string) *Value {
+	v, _ := d.Lookup(path)
+	return v
+}
+
+// Has reports whether the document has a value at path, as for Get.
+func (d *Document) Has(path string) bool {
+	_, ok := d.Lookup(path)
+	return ok
+}
+
+// Lookup returns the value at path, as for Get, and whether it exists.
+func (d *Document) Lookup(path string) (*Value, bool) {
+	return d.LookupKeys(strings.Split(path, ".")...)
+}
+
+// LookupKeys returns the value reached by following keys, one key per
+// level, and whether it exists. Unlike Lookup, keys may contain dots.
+func (d *Document) LookupKeys(keys ...string) (*Value, bool) {
+	entries := d.Entries
+	if len(entries) == 1 && entries[0].Key.IsImplicitUnit() {
+		root := entries[0].Value
+		if root.PayloadKind != PayloadObject {
+			return nil, false
+		}
+		entries = root.Object.Entries
+	}
+	return lookupEntries(entries, keys)
+}
+
+// Get returns the value at path below an object value, as for
+// Document.Get, or nil.
+func (v *Value) Get(path string) *Value {
+	found, _ := v.Lookup(path)
+	return found
+}
+
+// Lookup returns the value at path below an object value, as for
+// Document.Lookup, and whether it exists.
+func (v *Value) Lookup(path string) (*Value, bool) {
+	return v.LookupKeys(strings.Split(path, ".")...)
+}
+
+// LookupKeys returns the value reached from an object value by following
+// keys, as for Document.LookupKeys, and whether it exists.
+func (v *Value) LookupKeys(keys ...string) (*Value, bool) {
+	if v.PayloadKind != PayloadObject {
+		return nil, false
+	}
+	return lookupEntries(v.Object.Entries, keys)
+}
+
+// lookupEntries follows keys from entries. Dotted keys leave one entry per
+// line for a shared prefix (`a.b 1` and `a.c 2` both define `a`), so the
+// entries sharing a key are searched in turn, and combined only when the
+// shared key is the last.
+func lookupEntries(entries []*Entry, keys []string) (*Value, bool) {
+	if len(keys) == 0 {
+		return nil, false
+	}
+	var matches []*Entry
+	for _, entry := range entries {
+		if !entry.Key.Implicit && entry.KeyText() == keys[0] {
+			matches = append(matches, entry)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return nil, false
+	case len(keys) == 1 && len(matches) == 1:
+		return matches[0].Value, true
+	case len(keys) == 1:
+		return combineSplitEntries(matches)[0].Value, true
+	}
+	for _, entry := range matches {
+		if v, ok := entry.Value.LookupKeys(keys[1:]...); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Keys returns the object's keys in order, each once: a key shared by
+// several dotted entries, or repeated in attribute syntax, is listed at
+// its first appearance. Entries without a key text, such as an object in
+// key position, are skipped.
+func (o *Object) Keys() []string {
+	return entryKeys(o.Entries)
+}
+
+// Len returns the number of keys of the object, as listed by Keys.
+func (o *Object) Len() int {
+	return len(o.Keys())
+}
+
+// Get returns the value under key, or nil. Unlike Document.Get, the key
+// is not split at dots. The objects of dotted entries sharing the key are
+// combined into a read-only copy, as for Document.Get.
+func (o *Object) Get(key string) *Value {
+	v, _ := lookupEntries(o.Entries, []string{key})
+	return v
+}
+
+// Has reports whether the object has key.
+func (o *Object) Has(key string) bool {
+	_, ok := lookupEntries(o.Entries, []string{key})
+	return ok
+}
+
+// Keys returns the document's top-level keys as Object.Keys does, or those
+// of its root object for a document consisting of one.
+func (d *Document) Keys() []string {
+	entries, _ := d.rootEntries()
+	return entryKeys(*entries)
+}
+
+// Len returns the number of the document's top-level keys, as listed by
+// Keys.
+func (d *Document) Len() int {
+	return len(d.Keys())
+}
+
+func entryKeys(entries []*Entry) []string {
+	var keys []string
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		key := entry.KeyText()
+		if entry.Key.Implicit || key == "" && entry.Key.PayloadKind != PayloadScalar || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
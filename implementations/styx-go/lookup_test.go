@@ -0,0 +1,89 @@
+package styx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	doc, err := Parse(`server.tls.cert /etc/cert.pem
+server.tls.key /etc/key.pem
+app {port 8080}
+route method>GET path>/users
+db @postgres{host localhost}
+"a.b" {c 1}
+flag
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"server.tls.cert", "/etc/cert.pem"},
+		{"server.tls.key", "/etc/key.pem"},
+		{"app.port", "8080"},
+		{"route.path", "/users"},
+		{"db.host", "localhost"},
+	}
+	for _, tt := range tests {
+		v := doc.Get(tt.path)
+		if v == nil || v.Scalar == nil || v.Scalar.Text != tt.want {
+			t.Errorf("Get(%q) = %v, want %q", tt.path, v, tt.want)
+		}
+	}
+
+	for _, path := range []string{"server.tls.chain", "app.port.x", "missing", "a.b.c", ""} {
+		if v, ok := doc.Lookup(path); ok || v != nil || doc.Has(path) {
+			t.Errorf("Lookup(%q) = %v, %v; want nothing", path, v, ok)
+		}
+	}
+	if v := doc.Get("flag"); v == nil || !v.IsImplicitUnit() {
+		t.Errorf("Get(flag) = %v", v)
+	}
+	if v, ok := doc.LookupKeys("a.b", "c"); !ok || v.Scalar.Text != "1" {
+		t.Errorf("LookupKeys(a.b, c) = %v, %v", v, ok)
+	}
+	if v := doc.Get("server.tls").Get("cert"); v == nil || v.Scalar.Text != "/etc/cert.pem" {
+		t.Errorf("Value.Get(cert) = %v", v)
+	}
+
+	// Values below a split key are the document's own.
+	doc.Get("server.tls.cert").Scalar.Text = "/new.pem"
+	if v := doc.Entries[0].Value.Object.Entries[0].Value.Object.Entries[0].Value; v.Scalar.Text != "/new.pem" {
+		t.Errorf("change through Get not kept: %v", v)
+	}
+	if doc.Get("app") != doc.Entries[2].Value {
+		t.Error("Get(app) returned a copy")
+	}
+
+	root, _ := Parse("{name app}")
+	if v := root.Get("name"); v == nil || v.Scalar.Text != "app" {
+		t.Errorf("root object: Get(name) = %v", v)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	doc, err := Parse("a.b 1\na.c 2\nd {x 1, y {z 2, w 3}, \"q.r\" 4}\n\"\" empty\nr method>GET method>POST\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Keys(); !reflect.DeepEqual(got, []string{"a", "d", "", "r"}) || doc.Len() != 4 {
+		t.Errorf("Document.Keys() = %q, Len() = %d", got, doc.Len())
+	}
+	if got := doc.Get("a").Object.Keys(); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Errorf("combined a: Keys() = %q", got)
+	}
+
+	d := doc.Get("d").Object
+	if got := d.Keys(); !reflect.DeepEqual(got, []string{"x", "y", "q.r"}) || d.Len() != 3 {
+		t.Errorf("Object.Keys() = %q, Len() = %d", got, d.Len())
+	}
+	if y := d.Get("y"); y == nil || y.Object.Len() != 2 || !d.Has("x") || d.Has("y.z") || d.Get("q.r") == nil {
+		t.Errorf("Object.Get(y) = %v", y)
+	}
+	if got := doc.Get("r").Object.Keys(); !reflect.DeepEqual(got, []string{"method"}) {
+		t.Errorf("attributes: Keys() = %q", got)
+	}
+}
@@ -0,0 +1,189 @@
+package styx
+
+import (
+	"strings"
+)
+
+// SequenceMerge selects how Merge combines two sequences at the same path.
+type SequenceMerge int
+
+const (
+	// SequenceReplace keeps the overlay's sequence.
+	SequenceReplace SequenceMerge = iota
+	// SequenceAppend appends the overlay's items to the base's.
+	SequenceAppend
+)
+
+// ConflictPolicy selects how Merge treats two values at the same path that
+// cannot be merged, such as two different scalars or an object and a
+// sequence.
+type ConflictPolicy int
+
+const (
+	// ConflictOverlayWins keeps the overlay's value.
+	ConflictOverlayWins ConflictPolicy = iota
+	// ConflictError fails the merge with a *MergeError.
+	ConflictError
+)
+
+// MergeOptions configures Merge. The zero value merges objects key by key,
+// lets the overlay replace sequences and win conflicts, and only merges
+// values carrying the same tag.
+type MergeOptions struct {
+	Sequences SequenceMerge
+	Conflicts ConflictPolicy
+	// IgnoreTags merges objects and sequences whatever their tags, keeping
+	// the overlay's tag if it has one and the base's otherwise. By default
+	// values with different tags conflict, as `@postgres{...}` and
+	// `@sqlite{...}` describe different things.
+	IgnoreTags bool
+}
+
+// MergeError reports a conflict under ConflictError.
+type MergeError struct {
+	// Path holds the keys and sequence indices leading to the values.
+	Path    []string
+	Base    *Value
+	Overlay *Value
+}
+
+func (e *MergeError) Error() string {
+	return "merge conflict at " + strings.Join(e.Path, ".")
+}
+
+// Merge returns a document combining base with overlay, as when applying
+// environment overrides to defaults. Objects are merged key by key: keys
+// of either side are kept, in base order followed by keys new in the
+// overlay, and values under a key present in both are merged in turn.
+// Entries split by dotted keys (`a.b 1` and `a.c 2`) are first combined
+// into one object. Scalars and unit values are replaced by the overlay's
+// unless they are equal.
+//
+// Neither document is modified. The result has no source of its own: its
+// nodes are copies keeping the spans of the document they came from.
+func Merge(base, overlay *Document, opts MergeOptions) (*Document, error) {
+	baseEntries, _ := base.rootEntries()
+	overlayEntries, _ := overlay.rootEntries()
+	m := merger{opts: opts}
+	entries, err := m.entries(nil, copyEntries(*baseEntries), copyEntries(*overlayEntries))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		e.path, e.pathKind = []string{e.KeyText()}, pathKindOf(e.Value)
+	}
+	return &Document{Entries: entries, Span: noSpan}, nil
+}
+
+type merger struct {
+	opts MergeOptions
+}
+
+// entries merges two entry lists that are the mutable copies of objects.
+func (m *merger) entries(path []string, base, overlay []*Entry) ([]*Entry, error) {
+	result := combineSplitEntries(base)
+	index := make(map[string]int, len(result))
+	for i, e := range result {
+		index[e.KeyText()] = i
+	}
+	for _, e := range combineSplitEntries(overlay) {
+		i, ok := index[e.KeyText()]
+		if !ok {
+			index[e.KeyText()] = len(result)
+			result = append(result, e)
+			continue
+		}
+		merged, err := m.value(append(path[:len(path):len(path)], e.KeyText()), result[i].Value, e.Value)
+		if err != nil {
+			return nil, err
+		}
+		result[i].Value = merged
+	}
+	return result, nil
+}
+
+func (m *merger) value(path []string, base, overlay *Value) (*Value, error) {
+	tagsMatch := tagName(base) == tagName(overlay)
+	if base.PayloadKind == overlay.PayloadKind && (tagsMatch || m.opts.IgnoreTags) {
+		merged := *overlay
+		if merged.Tag == nil {
+			merged.Tag = base.Tag
+		}
+		switch base.PayloadKind {
+		case PayloadObject:
+			entries, err := m.entries(path, base.Object.Entries, overlay.Object.Entries)
+			if err != nil {
+				return nil, err
+			}
+			obj := *overlay.Object
+			obj.Entries = entries
+			merged.Object = &obj
+			return &merged, nil
+		case PayloadSequence:
+			if m.opts.Sequences == SequenceAppend {
+				seq := *overlay.Sequence
+				seq.Items = append(append([]*Value{}, base.Sequence.Items...), overlay.Sequence.Items...)
+				merged.Sequence = &seq
+			}
+			return &merged, nil
+		}
+	}
+	if tagsMatch && base.PayloadKind == overlay.PayloadKind && sameScalar(base, overlay) {
+		return overlay, nil
+	}
+	if m.opts.Conflicts == ConflictError {
+		return nil, &MergeError{Path: path, Base: base, Overlay: overlay}
+	}
+	return overlay, nil
+}
+
+// combineSplitEntries joins the objects of entries sharing a key, as dotted
+// keys leave them, into a copy of the first such entry. The entries are
+// not modified.
+func combineSplitEntries(entries []*Entry) []*Entry {
+	result := make([]*Entry, 0, len(entries))
+	index := make(map[string]int, len(entries))
+	for _, e := range entries {
+		if e.Key.Implicit {
+			continue
+		}
+		i, ok := index[e.KeyText()]
+		if !ok || result[i].Value.PayloadKind != PayloadObject || e.Value.PayloadKind != PayloadObject {
+			index[e.KeyText()] = len(result)
+			result = append(result, e)
+			continue
+		}
+		prev := result[i]
+		obj := *prev.Value.Object
+		obj.Entries = combineSplitEntries(append(obj.Entries[:len(obj.Entries):len(obj.Entries)], e.Value.Object.Entries...))
+		value := *prev.Value
+		value.Object = &obj
+		combined := *prev
+		combined.Value = &value
+		result[i] = &combined
+	}
+	return result
+}
+
+func tagName(v *Value) string {
+	if v.Tag == nil {
+		return ""
+	}
+	return "@" + v.Tag.Name
+}
+
+// sameScalar reports whether two values of the same payload kind are equal
+// scalars or both lack a payload.
+func sameScalar(a, b *Value) bool {
+	switch a.PayloadKind {
+	case PayloadNone:
+		return true
+	case PayloadScalar:
+		return a.Scalar.Text == b.Scalar.Text
+	}
+	return false
+}
+
+func copyEntries(entries []*Entry) []*Entry {
+	return rewriteEntries(entries, func(v *Value) (*Value, bool) { return v, true })
+}
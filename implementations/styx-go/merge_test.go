@@ -0,0 +1,76 @@
+package styx
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base, err := Parse(`server.host localhost
+server.port 8080
+hosts (a b)
+db @postgres{host db, pool 4}
+log info
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay, err := Parse(`server {port 9090, tls true}
+hosts (c)
+db @postgres{pool 8}
+env prod
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := Merge(base, overlay, MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"server": map[string]any{"host": "localhost", "port": "9090", "tls": "true"},
+		"hosts":  []any{"c"},
+		"db":     Tagged{Tag: "postgres", Value: map[string]any{"host": "db", "pool": "8"}},
+		"log":    "info",
+		"env":    "prod",
+	}
+	if got := merged.Interface(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+	var keys []string
+	for _, e := range merged.Entries {
+		keys = append(keys, e.KeyText())
+	}
+	if !reflect.DeepEqual(keys, []string{"server", "hosts", "db", "log", "env"}) {
+		t.Errorf("key order %v", keys)
+	}
+	if base.Get("server.port").Scalar.Text != "8080" || overlay.Get("server.host") != nil {
+		t.Error("inputs were modified")
+	}
+
+	appended, _ := Merge(base, overlay, MergeOptions{Sequences: SequenceAppend})
+	if got, _ := appended.GetStringSlice("hosts", nil); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("SequenceAppend: hosts = %v", got)
+	}
+
+	_, err = Merge(base, overlay, MergeOptions{Conflicts: ConflictError})
+	var conflict *MergeError
+	if !errors.As(err, &conflict) || err.Error() != "merge conflict at server.port" {
+		t.Errorf("ConflictError: got %v", err)
+	}
+	same, _ := Parse("log info\nserver.host localhost")
+	if _, err := Merge(base, same, MergeOptions{Conflicts: ConflictError}); err != nil {
+		t.Errorf("equal scalars: %v", err)
+	}
+
+	// Different tags replace rather than merge, unless tags are ignored.
+	sqlite, _ := Parse("db @sqlite{path /tmp/db}")
+	if merged, _ := Merge(base, sqlite, MergeOptions{}); merged.Has("db.host") {
+		t.Error("differently tagged objects were merged")
+	}
+	if merged, _ := Merge(base, sqlite, MergeOptions{IgnoreTags: true}); !merged.Has("db.host") || merged.Get("db").Tag.Name != "sqlite" {
+		t.Error("IgnoreTags: objects were not merged")
+	}
+}
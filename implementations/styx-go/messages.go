@@ -0,0 +1,75 @@
+package styx
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MessageCatalog maps error and warning codes to message templates, for
+// translating or restyling messages. In a template, {0}, {1}, ... stand
+// for the Args of the error, or of the warning as ParseError converts it.
+// A code may cover several English messages, such as the plain "unexpected
+// token" and "expected `}`, got EOF", whose Args differ; a template using
+// an argument the error lacks is not applied to it, leaving its English
+// message.
+//
+//	catalog := styx.MessageCatalog{
+//		styx.CodeDuplicateKey:   "clé en double",
+//		styx.CodeReopenedPath:   "impossible de rouvrir le chemin `{0}`",
+//	}
+//	doc, err := styx.ParseWithOptions(source, styx.ParseOptions{Messages: catalog.Message})
+type MessageCatalog map[Code]string
+
+// Message returns the catalog's message for e, or e's own message if the
+// catalog has no template for its code.
+func (c MessageCatalog) Message(e *ParseError) string {
+	template, ok := c[e.Code]
+	if !ok {
+		return e.Message
+	}
+	for _, m := range placeholder.FindAllStringSubmatch(template, -1) {
+		if i, err := strconv.Atoi(m[1]); err != nil || i >= len(e.Args) {
+			return e.Message
+		}
+	}
+	replacements := make([]string, 0, 2*len(e.Args))
+	for i, arg := range e.Args {
+		replacements = append(replacements, "{"+strconv.Itoa(i)+"}", arg)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+var placeholder = regexp.MustCompile(`\{([0-9]+)\}`)
+
+// finishError completes err, if it is a *ParseError, before it is returned
+// to the caller: it resolves the error's position in source, which may be
+// empty if the source is not available, and applies opts.Messages.
+func finishError(err error, source string, opts ParseOptions) error {
+	pe, ok := err.(*ParseError)
+	if !ok || pe == nil {
+		return err
+	}
+	pe.Filename = opts.Filename
+	if source != "" && pe.Span.Valid(len(source)) {
+		pe.Line, pe.Column = position(source, pe.Span.Start)
+		pe.Excerpt = excerpt(pe.Span.Slice(source))
+	}
+	if opts.Messages != nil {
+		pe.Message = opts.Messages(pe)
+	}
+	return err
+}
+
+// maxExcerptLength bounds the source text quoted in error strings.
+const maxExcerptLength = 40
+
+// excerpt returns text for quoting in an error string, or "" if it is empty,
+// spans lines or is too long to read at a glance.
+func excerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || len(text) > maxExcerptLength || strings.ContainsAny(text, "\r\n") {
+		return ""
+	}
+	return text
+}
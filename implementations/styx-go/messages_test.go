@@ -0,0 +1,51 @@
+package styx
+
+import "testing"
+
+func TestMessageCatalog(t *testing.T) {
+	catalog := MessageCatalog{
+		CodeDuplicateKey:    "clé en double",
+		CodeReopenedPath:    "impossible de rouvrir `{0}`",
+		CodeUnexpectedToken: "attendu {0}, trouvé {1}",
+		CodeInvalidEscape:   "échappement invalide {0}",
+	}
+	opts := ParseOptions{Messages: catalog.Message}
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"a 1\na 2", "clé en double"},
+		{"a.b 1\nc 2\na.d 3", "impossible de rouvrir `a`"},
+		{"a (1 }", "attendu scalar, trouvé rbrace"},
+		{`a "\q"`, `échappement invalide \q`},
+		{`a "\u12"`, `échappement invalide \u12`},
+		// Codes missing from the catalog keep their message.
+		{"a {", "unclosed object (missing `}`)"},
+	}
+	for _, tt := range tests {
+		_, err := ParseWithOptions(tt.source, opts)
+		pe, ok := err.(*ParseError)
+		if !ok || pe.Message != tt.want {
+			t.Errorf("%q: got %v, want %q", tt.source, err, tt.want)
+		}
+	}
+
+	_, errs := ParseRecover("a 1\na 2\nb 1\nb 2", opts)
+	if len(errs) != 2 || errs[0].Message != "clé en double" || errs[1].Message != "clé en double" {
+		t.Errorf("recovered errors: %v", errs)
+	}
+	// Warnings are translated too, and templates needing more arguments
+	// than an error has are not applied to it.
+	catalog[CodeKeyEndsWithColon] = "la clé `{0}` se termine par `:`"
+	doc, err := ParseWithOptions("a: 1", opts)
+	if err != nil || len(doc.Warnings) != 1 || doc.Warnings[0].Message != "la clé `a:` se termine par `:`" {
+		t.Errorf("warning: %v %v", err, doc.Warnings)
+	}
+	if _, err := ParseWithOptions("a (1 }", ParseOptions{Messages: MessageCatalog{CodeUnexpectedToken: "{0} {1} {2}"}.Message}); err.(*ParseError).Message != "expected scalar, got rbrace" {
+		t.Errorf("missing argument: got %v", err)
+	}
+
+	if _, err := ParseValueWithOptions("(1,2)", ParseOptions{Messages: func(*ParseError) string { return "x" }}); err.(*ParseError).Message != "x" {
+		t.Errorf("ParseValue: got %v", err)
+	}
+}
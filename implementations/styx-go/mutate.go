@@ -0,0 +1,194 @@
+package styx
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrPathNotFound is returned, wrapped in a *PathError, by DeletePath for a
+// path the document does not have.
+var ErrPathNotFound = errors.New("path not found")
+
+// PathError records a failed SetPath or DeletePath.
+type PathError struct {
+	// Op is "set" or "delete".
+	Op string
+	// Path is the dotted path passed to the operation.
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// noSpan is the location of nodes created by SetPath, which have no
+// source text.
+var noSpan = Span{-1, -1}
+
+// SetPath sets the value at path, a dotted key path as for Get, replacing
+// any value there. Missing objects along the path are created, so
+// setting "server.tls.cert" in a document without `server` adds the entry
+// `server.tls.cert value`. A key split over several dotted entries is
+// extended in the entry holding most of the path. A scalar, sequence or
+// unit value along the path cannot hold keys: SetPath then fails with a
+// *PathError wrapping ErrNestIntoTerminal and leaves the document
+// unchanged.
+//
+// The document's source is not updated: existing nodes keep their spans
+// and created nodes have none.
+func (d *Document) SetPath(path string, value *Value) error {
+	keys := strings.Split(path, ".")
+	entries, documentLevel := d.rootEntries()
+	var top *Entry // the document-level entry holding the path
+	for depth := range keys {
+		e := bestEntry(*entries, keys[depth:])
+		if e == nil {
+			created := pathEntry(keys[depth:], value)
+			if documentLevel && top == nil {
+				created.path, created.pathKind = keys, pathKindOf(value)
+			}
+			*entries = append(*entries, created)
+			return nil
+		}
+		if documentLevel && top == nil {
+			top = e
+		}
+		if depth == len(keys)-1 {
+			e.Value = value
+			// Replacing an object implied by a dotted key shortens the
+			// key to the replaced path.
+			if top != nil && len(top.path) >= len(keys) && slices.Equal(top.path[:len(keys)], keys) {
+				top.path, top.pathKind = keys, pathKindOf(value)
+			}
+			return nil
+		}
+		if e.Value.PayloadKind != PayloadObject {
+			prefix := strings.Join(keys[:depth+1], ".")
+			return &PathError{Op: "set", Path: path, Err: fmt.Errorf("`%s`: %w", prefix, ErrNestIntoTerminal)}
+		}
+		entries = &e.Value.Object.Entries
+	}
+	return nil
+}
+
+// DeletePath removes the entry at path, a dotted key path as for Get. The
+// objects implied by a document-level dotted key, as `a` and `a.b` in
+// `a.b.c 1`, are removed with their last entry, as deleting the line would.
+// A missing path fails with a *PathError wrapping ErrPathNotFound.
+func (d *Document) DeletePath(path string) error {
+	keys := strings.Split(path, ".")
+	entries, documentLevel := d.rootEntries()
+	implied := func(e *Entry) int {
+		if documentLevel {
+			return len(e.path) - 1
+		}
+		return 0
+	}
+	if !deleteEntry(entries, keys, implied) {
+		return &PathError{Op: "delete", Path: path, Err: ErrPathNotFound}
+	}
+	return nil
+}
+
+// deleteEntry removes the entry reached by keys from *entries. implied
+// gives the number of object levels below an entry of *entries that only
+// exist to hold a dotted key; those left empty are removed too.
+func deleteEntry(entries *[]*Entry, keys []string, implied func(*Entry) int) bool {
+	for i, e := range *entries {
+		if e.Key.Implicit || e.KeyText() != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			*entries = slices.Delete(*entries, i, i+1)
+			return true
+		}
+		if e.Value.PayloadKind != PayloadObject {
+			continue
+		}
+		levels := implied(e)
+		nested := &e.Value.Object.Entries
+		if !deleteEntry(nested, keys[1:], func(*Entry) int { return levels - 1 }) {
+			continue
+		}
+		if levels > 0 && len(*nested) == 0 {
+			*entries = slices.Delete(*entries, i, i+1)
+		}
+		return true
+	}
+	return false
+}
+
+// rootEntries returns the entries paths are resolved against, as for
+// LookupKeys, and whether they are the document's own entries, whose key
+// paths the parser records.
+func (d *Document) rootEntries() (*[]*Entry, bool) {
+	if len(d.Entries) == 1 && d.Entries[0].Key.IsImplicitUnit() && d.Entries[0].Value.PayloadKind == PayloadObject {
+		return &d.Entries[0].Value.Object.Entries, false
+	}
+	return &d.Entries, true
+}
+
+// bestEntry returns the entry of entries keyed keys[0] through which most
+// of keys already exist, preferring later entries on ties, or nil.
+func bestEntry(entries []*Entry, keys []string) *Entry {
+	var best *Entry
+	bestDepth := -1
+	for _, e := range entries {
+		if e.Key.Implicit || e.KeyText() != keys[0] {
+			continue
+		}
+		if depth := existingDepth(e, keys[1:]); depth >= bestDepth {
+			best, bestDepth = e, depth
+		}
+	}
+	return best
+}
+
+// existingDepth returns how many of keys exist below e.
+func existingDepth(e *Entry, keys []string) int {
+	if len(keys) == 0 || e.Value.PayloadKind != PayloadObject {
+		return 0
+	}
+	depth := 0
+	for _, child := range e.Value.Object.Entries {
+		if !child.Key.Implicit && child.KeyText() == keys[0] {
+			depth = max(depth, 1+existingDepth(child, keys[1:]))
+		}
+	}
+	return depth
+}
+
+// pathEntry builds the entry `keys[0]` holding value through objects
+// keyed by the rest of keys.
+func pathEntry(keys []string, value *Value) *Entry {
+	for i := len(keys) - 1; i > 0; i-- {
+		value = &Value{
+			Span:        noSpan,
+			PayloadKind: PayloadObject,
+			Object:      &Object{Entries: []*Entry{{Key: keyValue(keys[i]), Value: value}}, Span: noSpan},
+		}
+	}
+	return &Entry{Key: keyValue(keys[0]), Value: value}
+}
+
+func keyValue(key string) *Value {
+	return &Value{
+		Span:        noSpan,
+		PayloadKind: PayloadScalar,
+		Scalar:      &Scalar{Text: key, Kind: ScalarBare, Span: noSpan},
+	}
+}
+
+func pathKindOf(v *Value) pathValueKind {
+	if v.PayloadKind == PayloadObject {
+		return pathValueObject
+	}
+	return pathValueTerminal
+}
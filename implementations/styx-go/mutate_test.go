@@ -0,0 +1,80 @@
+package styx
+
+import (
+	"errors"
+	"testing"
+)
+
+func scalarValue(text string) *Value {
+	return &Value{Span: noSpan, PayloadKind: PayloadScalar, Scalar: &Scalar{Text: text, Kind: ScalarBare, Span: noSpan}}
+}
+
+func TestSetPath(t *testing.T) {
+	doc, err := Parse("server.tls.cert /a\nserver.tls.key /b\nname app\ndb {host x}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets := []struct{ path, text string }{
+		{"server.tls.cert", "/c"},
+		{"server.tls.chain", "/d"},
+		{"db.port", "5432"},
+		{"log.level", "debug"},
+		{"name", "web"},
+	}
+	for _, s := range sets {
+		if err := doc.SetPath(s.path, scalarValue(s.text)); err != nil {
+			t.Fatalf("SetPath(%q): %v", s.path, err)
+		}
+	}
+	for _, s := range sets {
+		if v := doc.Get(s.path); v == nil || v.Scalar.Text != s.text {
+			t.Errorf("Get(%q) = %v, want %q", s.path, v, s.text)
+		}
+	}
+	if v := doc.Get("server.tls.key"); v == nil || v.Scalar.Text != "/b" {
+		t.Errorf("Get(server.tls.key) = %v", v)
+	}
+	if len(doc.Entries) != 5 {
+		t.Errorf("got %d entries, want 5", len(doc.Entries))
+	}
+	// The created entry records its key path like a parsed dotted key.
+	ps := newPathState()
+	for _, e := range doc.Entries {
+		if err := ps.checkAndUpdate(e.path, e.Key.Span, e.pathKind); err != nil {
+			t.Errorf("entry %s: %v", e.KeyText(), err)
+		}
+	}
+
+	err = doc.SetPath("name.first", scalarValue("x"))
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) || !errors.Is(err, ErrNestIntoTerminal) || pathErr.Op != "set" {
+		t.Errorf("SetPath(name.first) error = %v", err)
+	}
+	if err.Error() != "set name.first: `name`: cannot nest into terminal value" {
+		t.Errorf("error string: %q", err)
+	}
+}
+
+func TestDeletePath(t *testing.T) {
+	doc, err := Parse("a.b.c 1\nx {y 1, z 2}\nroot 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.DeletePath("x.y"); err != nil || doc.Has("x.y") || !doc.Has("x.z") {
+		t.Errorf("DeletePath(x.y): %v", err)
+	}
+	// Deleting the only key of a dotted path removes the implied objects.
+	if err := doc.DeletePath("a.b.c"); err != nil || doc.Has("a") {
+		t.Errorf("DeletePath(a.b.c): %v, a remains: %v", err, doc.Has("a"))
+	}
+	// Braced objects stay even when emptied.
+	if err := doc.DeletePath("x.z"); err != nil || !doc.Has("x") {
+		t.Errorf("DeletePath(x.z): %v, x removed: %v", err, !doc.Has("x"))
+	}
+	if err := doc.DeletePath("root.q"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("DeletePath(root.q) error = %v", err)
+	}
+	if len(doc.Entries) != 2 {
+		t.Errorf("got %d entries, want 2", len(doc.Entries))
+	}
+}
@@ -0,0 +1,66 @@
+package styx
+
+// NodeAt returns the innermost node covering the byte offset, an *Entry or
+// a *Value, and the nodes enclosing it, outermost first: for the offset of
+// `8080` in `server {port 8080}` the node is the value 8080 and the
+// ancestors are the `server` entry, its object value and the `port` entry.
+// Keys are values of their entries. A node covers the offsets from its
+// start through its end inclusive, so a cursor just after a token finds
+// it. NodeAt returns nil when no node covers the offset.
+//
+// Dotted keys such as `a.b 1` expand to nested objects spanning the key
+// alone, so the search does not assume a node lies within its parent's
+// span.
+func (d *Document) NodeAt(offset int) (node any, ancestors []any) {
+	for _, entry := range d.Entries {
+		if chain := entryAt(entry, offset); chain != nil {
+			return chain[len(chain)-1], chain[:len(chain)-1]
+		}
+	}
+	return nil, nil
+}
+
+func covers(span Span, offset int) bool {
+	return span.Start >= 0 && span.Start <= offset && offset <= span.End
+}
+
+// entryAt returns the chain of nodes from entry down to the innermost one
+// covering offset, or nil.
+func entryAt(entry *Entry, offset int) []any {
+	if !entry.Key.Implicit {
+		if chain := valueAt(entry.Key, offset); chain != nil {
+			return append([]any{entry}, chain...)
+		}
+	}
+	if chain := valueAt(entry.Value, offset); chain != nil {
+		return append([]any{entry}, chain...)
+	}
+	if covers(entry.Span(), offset) {
+		return []any{entry}
+	}
+	return nil
+}
+
+// valueAt returns the chain of nodes from v down to the innermost one
+// covering offset, or nil. Implicit values have no text of their own and
+// are never found.
+func valueAt(v *Value, offset int) []any {
+	switch v.PayloadKind {
+	case PayloadObject:
+		for _, entry := range v.Object.Entries {
+			if chain := entryAt(entry, offset); chain != nil {
+				return append([]any{v}, chain...)
+			}
+		}
+	case PayloadSequence:
+		for _, item := range v.Sequence.Items {
+			if chain := valueAt(item, offset); chain != nil {
+				return append([]any{v}, chain...)
+			}
+		}
+	}
+	if !v.Implicit && covers(v.FullSpan(), offset) {
+		return []any{v}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package styx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNodeAt(t *testing.T) {
+	source := "server {port 8080, hosts (a b)}\ntls.cert /x\nflag\n"
+	doc, err := Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	describe := func(n any) string {
+		switch n := n.(type) {
+		case *Entry:
+			return "entry " + n.KeyText()
+		case *Value:
+			text, _ := doc.SourceFor(n)
+			return "value " + text
+		}
+		return "nil"
+	}
+	tests := []struct {
+		at        string // text whose first byte is the offset
+		node      string
+		ancestors string
+	}{
+		{"8080", "value 8080", "entry server, value {port 8080, hosts (a b)}, entry port"},
+		{"port", "value port", "entry server, value {port 8080, hosts (a b)}, entry port"},
+		{"b)", "value b", "entry server, value {port 8080, hosts (a b)}, entry hosts, value (a b)"},
+		{"server", "value server", "entry server"},
+		{" {port", "value server", "entry server"},
+		{"/x", "value /x", "entry tls, value tls.cert, entry cert"},
+		{"cert", "value cert", "entry tls, value tls.cert, entry cert"},
+		{"flag", "value flag", "entry flag"},
+	}
+	for _, tt := range tests {
+		node, ancestors := doc.NodeAt(strings.Index(source, tt.at))
+		var names []string
+		for _, a := range ancestors {
+			names = append(names, describe(a))
+		}
+		if describe(node) != tt.node || strings.Join(names, ", ") != tt.ancestors {
+			t.Errorf("at %q: got %s in [%s], want %s in [%s]", tt.at, describe(node), strings.Join(names, ", "), tt.node, tt.ancestors)
+		}
+	}
+
+	if node, _ := doc.NodeAt(len(source)); node != nil {
+		t.Errorf("at end: got %s", describe(node))
+	}
+}
@@ -1,6 +1,9 @@
 package styx
 
 import (
+	"context"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -14,8 +17,14 @@ const (
 
 // pathState tracks dotted path state for validation.
 type pathState struct {
+	// limit bounds the total work spent joining path segments, guarding
+	// against quadratic behavior on keys with huge numbers of segments. Zero
+	// means unlimited.
+	limit int
+	work  int
+
 	currentPath   []string
-	closedPaths   map[string]bool // key is joined path
+	closedPaths   map[string]Span // key is joined path, value is the closing sibling
 	assignedPaths map[string]struct {
 		kind pathValueKind
 		span Span
@@ -24,7 +33,7 @@ type pathState struct {
 
 func newPathState() *pathState {
 	return &pathState{
-		closedPaths: make(map[string]bool),
+		closedPaths: make(map[string]Span),
 		assignedPaths: make(map[string]struct {
 			kind pathValueKind
 			span Span
@@ -32,34 +41,74 @@ func newPathState() *pathState {
 	}
 }
 
+// reset clears the state for reuse, keeping the allocated maps.
+func (ps *pathState) reset() {
+	clear(ps.closedPaths)
+	clear(ps.assignedPaths)
+	ps.currentPath = nil
+	ps.limit, ps.work = 0, 0
+}
+
 func joinPath(segments []string) string {
 	return strings.Join(segments, ".")
 }
 
+// pathLen returns the length of the joined path.
+func pathLen(segments []string) int {
+	n := len(segments)
+	for _, s := range segments {
+		n += len(s)
+	}
+	return n
+}
+
 // checkAndUpdate validates a path and updates the state.
 // Returns an error if the path is invalid.
 func (ps *pathState) checkAndUpdate(path []string, span Span, kind pathValueKind) error {
+	if ps.limit > 0 {
+		// Every prefix of both paths may be joined below.
+		cost := len(path)*pathLen(path) + len(ps.currentPath)*pathLen(ps.currentPath)
+		ps.work += cost
+		if ps.work > ps.limit {
+			return &ParseError{Code: CodeTooComplex, Message: "input too complex", Span: span}
+		}
+	}
+
 	pathKey := joinPath(path)
 
 	// 1. Check for duplicate (exact same path)
-	if _, exists := ps.assignedPaths[pathKey]; exists {
-		return &ParseError{Message: "duplicate key", Span: span}
+	if first, exists := ps.assignedPaths[pathKey]; exists {
+		return &ParseError{
+			Code:    CodeDuplicateKey,
+			Message: "duplicate key",
+			Span:    span,
+			Related: []Label{{Message: "first defined here", Span: first.span}},
+		}
 	}
 
 	// 2. Check if any proper prefix is closed or has a terminal value
 	for i := 1; i < len(path); i++ {
 		prefix := path[:i]
 		prefixKey := joinPath(prefix)
-		if ps.closedPaths[prefixKey] {
+		if sibling, closed := ps.closedPaths[prefixKey]; closed {
 			return &ParseError{
+				Code:    CodeReopenedPath,
 				Message: "cannot reopen path `" + prefixKey + "` after sibling appeared",
+				Args:    []string{prefixKey},
 				Span:    span,
+				Related: []Label{
+					{Message: "`" + prefixKey + "` first defined here", Span: ps.assignedPaths[prefixKey].span},
+					{Message: "sibling appeared here", Span: sibling},
+				},
 			}
 		}
 		if assigned, exists := ps.assignedPaths[prefixKey]; exists && assigned.kind == pathValueTerminal {
 			return &ParseError{
+				Code:    CodeNestIntoTerminal,
 				Message: "cannot nest into `" + prefixKey + "` which has a terminal value",
+				Args:    []string{prefixKey},
 				Span:    span,
+				Related: []Label{{Message: "`" + prefixKey + "` assigned here", Span: assigned.span}},
 			}
 		}
 	}
@@ -77,7 +126,7 @@ func (ps *pathState) checkAndUpdate(path []string, span Span, kind pathValueKind
 	// 4. Close paths beyond the common prefix
 	for i := commonLen; i < len(ps.currentPath); i++ {
 		closed := joinPath(ps.currentPath[:i+1])
-		ps.closedPaths[closed] = true
+		ps.closedPaths[closed] = span
 	}
 
 	// 5. Record intermediate path segments as objects (if not already assigned)
@@ -104,22 +153,110 @@ func (ps *pathState) checkAndUpdate(path []string, span Span, kind pathValueKind
 
 type parser struct {
 	lexer   *Lexer
-	source  string
+	opts    ParseOptions
 	current *Token
 	peeked  *Token
 	err     error
+	depth   int
+	entries int
+
+	// recovering enables error recovery: errors are collected in errors
+	// and parsing continues after synchronizing.
+	recovering bool
+	errors     []*ParseError
+	// firstError keeps only the first error in recovery mode, for a parse
+	// that fails at the first error but still returns a partial document.
+	// recorded counts the errors seen, kept or not.
+	firstError bool
+	recorded   int
+	// tooManyErrors is set once the error budget is spent, which ends the
+	// token stream.
+	tooManyErrors bool
+
+	// warnings collects the parser's warnings; the lexer keeps its own.
+	warnings    []*Diagnostic
+	warnedDepth bool
+
+	// ctx, when set, is checked between entries.
+	ctx     context.Context
+	aborted bool
+
+	// paths, when set, is reused for document-level path validation.
+	paths *pathState
 }
 
-func newParser(source string) *parser {
-	p := &parser{lexer: newLexer(source), source: source}
-	tok, err := p.lexer.nextToken()
-	if err != nil {
-		p.err = err
-		p.current = &Token{Type: TokenEOF, Span: Span{0, 0}}
-	} else {
-		p.current = tok
+func newParser(source string, opts ParseOptions) *parser {
+	return newParserWithLexer(newLexer(source), opts)
+}
+
+func newParserWithLexer(lexer *Lexer, opts ParseOptions) *parser {
+	lexer.configure(opts)
+	return &parser{lexer: lexer, opts: opts}
+}
+
+// start reads the first token. It is separate from newParser so that
+// recovery mode can be enabled before any lexer error is seen.
+func (p *parser) start() {
+	p.current = p.nextToken()
+}
+
+// complexityFactor scales the work the parser may spend per byte of input
+// before reporting "input too complex".
+const complexityFactor = 64
+
+// checkPath validates a document-level key path, bounding the work spent in
+// proportion to the input read so far.
+func (p *parser) checkPath(ps *pathState, path []string, span Span, kind pathValueKind) error {
+	ps.limit = complexityFactor*len(p.lexer.source) + 1<<24
+	return ps.checkAndUpdate(path, span, kind)
+}
+
+// enter records descent into a nested object or sequence opened at span.
+func (p *parser) enter(span Span) error {
+	p.depth++
+	maxDepth := p.opts.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if maxDepth > 0 && p.depth > maxDepth {
+		return &ParseError{Code: CodeTooDeep, Message: "maximum nesting depth exceeded", Span: span}
+	}
+	warnDepth := p.opts.NestingWarningDepth
+	if warnDepth == 0 {
+		warnDepth = DefaultNestingWarningDepth
+	}
+	if warnDepth > 0 && p.depth > warnDepth && !p.warnedDepth {
+		// One warning per document is enough to point at the problem.
+		p.warnedDepth = true
+		p.warnings = append(p.warnings, &Diagnostic{
+			Code:     CodeDeepNesting,
+			Severity: SeverityWarning,
+			Message:  "nesting deeper than " + strconv.Itoa(warnDepth) + " levels",
+			Span:     span,
+			Help:     "consider flattening the structure, for example with dotted keys",
+			args:     []string{strconv.Itoa(warnDepth)},
+		})
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// noProgress reports a loop iteration that consumed no input. It guards the
+// entry loops against hanging on inputs the parser does not anticipate.
+func (p *parser) noProgress() error {
+	return &ParseError{Code: CodeTooComplex, Message: "input too complex", Span: p.current.Span}
+}
+
+// countEntry enforces ParseOptions.MaxEntries across the whole document.
+func (p *parser) countEntry(entry *Entry) error {
+	p.entries++
+	if p.opts.MaxEntries > 0 && p.entries > p.opts.MaxEntries {
+		return &ParseError{Code: CodeTooManyEntries, Message: "too many entries", Span: entry.Span()}
 	}
-	return p
+	return nil
 }
 
 func (p *parser) advance() *Token {
@@ -128,28 +265,145 @@ func (p *parser) advance() *Token {
 		p.current = p.peeked
 		p.peeked = nil
 	} else {
-		tok, err := p.lexer.nextToken()
-		if err != nil {
-			p.err = err
-			p.current = &Token{Type: TokenEOF, Span: Span{p.lexer.bytePos, p.lexer.bytePos}}
-		} else {
-			p.current = tok
-		}
+		p.current = p.nextToken()
 	}
 	return prev
 }
 
 func (p *parser) peek() *Token {
 	if p.peeked == nil {
+		p.peeked = p.nextToken()
+	}
+	return p.peeked
+}
+
+// nextToken reads the next token from the lexer. A lexer error either stops
+// the parse, by setting p.err and returning EOF, or in recovery mode is
+// recorded before lexing resumes at the next line.
+func (p *parser) nextToken() *Token {
+	for {
+		if p.tooManyErrors {
+			return &Token{Type: TokenEOF, Span: Span{p.lexer.bytePos, p.lexer.bytePos}}
+		}
 		tok, err := p.lexer.nextToken()
-		if err != nil {
+		if err == nil {
+			return tok
+		}
+		if !p.recovering {
 			p.err = err
-			p.peeked = &Token{Type: TokenEOF, Span: Span{p.lexer.bytePos, p.lexer.bytePos}}
-		} else {
-			p.peeked = tok
+			return &Token{Type: TokenEOF, Span: Span{p.lexer.bytePos, p.lexer.bytePos}}
+		}
+		p.recordError(err)
+		p.lexer.skipLine()
+		if p.tooManyErrors {
+			continue
 		}
+		if tok, err = p.lexer.nextToken(); err == nil {
+			if tok.Type != TokenEOF {
+				tok.HadNewlineBefore = true
+			}
+			return tok
+		}
+		p.recordError(err)
+		p.lexer.skipLine()
+	}
+}
+
+// checkContext reports cancellation of p.ctx. Once it fails the parse is
+// aborted, even in recovery mode.
+func (p *parser) checkContext() error {
+	if p.ctx == nil {
+		return nil
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.aborted = true
+		return err
+	}
+	return nil
+}
+
+// parseToFirstError parses the document in recovery mode and returns the
+// first error found along with the partial document, as ParseRecover would
+// build it, so that a failed parse needs no second pass to produce one.
+func (p *parser) parseToFirstError() (*Document, error) {
+	p.recovering, p.firstError = true, true
+	doc, err := p.parse()
+	if err != nil {
+		if p.aborted {
+			return nil, err
+		}
+		p.recordError(err)
+	}
+	if len(p.errors) > 0 {
+		return doc, p.errors[0]
+	}
+	return doc, nil
+}
+
+// recordError adds err to the errors collected in recovery mode. Once
+// ParseOptions.MaxErrors errors are recorded it adds a final "too many
+// errors" error and stops the parse: the token stream ends, so the parser
+// unwinds and returns what it has, and later errors are dropped.
+func (p *parser) recordError(err error) {
+	if p.tooManyErrors {
+		return
+	}
+	pe, ok := err.(*ParseError)
+	if !ok {
+		// A read error can come before the first token.
+		span := Span{p.lexer.bytePos, p.lexer.bytePos}
+		if p.current != nil {
+			span = p.current.Span
+		}
+		pe = &ParseError{Message: err.Error(), Span: span, cause: err}
+	}
+	p.recorded++
+	if !p.firstError || len(p.errors) == 0 {
+		p.errors = append(p.errors, pe)
+	}
+
+	maxErrors := p.opts.MaxErrors
+	if maxErrors == 0 {
+		maxErrors = DefaultMaxErrors
+	}
+	if maxErrors > 0 && p.recorded >= maxErrors {
+		p.tooManyErrors = true
+		if p.firstError {
+			return
+		}
+		p.errors = append(p.errors, &ParseError{
+			Code:    CodeTooManyErrors,
+			Message: "too many errors",
+			Span:    pe.Span,
+			Help:    "parsing stopped after " + strconv.Itoa(maxErrors) + " errors; fix them or raise ParseOptions.MaxErrors",
+		})
+	}
+}
+
+// synchronize skips tokens after an error in recovery mode, stopping at the
+// first token other than start that begins a new line, at the `}` closing the
+// current object, or at EOF. Nested objects and sequences are skipped whole.
+func (p *parser) synchronize(start *Token) {
+	nesting := 0
+	for !p.check(TokenEOF) {
+		if nesting == 0 && p.current != start {
+			if p.current.HadNewlineBefore {
+				return
+			}
+			if p.check(TokenRBrace) && p.depth > 0 {
+				return
+			}
+		}
+		switch p.current.Type {
+		case TokenLBrace, TokenLParen:
+			nesting++
+		case TokenRBrace, TokenRParen:
+			if nesting > 0 {
+				nesting--
+			}
+		}
+		p.advance()
 	}
-	return p.peeked
 }
 
 func (p *parser) check(types ...TokenType) bool {
@@ -164,7 +418,9 @@ func (p *parser) check(types ...TokenType) bool {
 func (p *parser) expect(tokenType TokenType) (*Token, error) {
 	if p.current.Type != tokenType {
 		return nil, &ParseError{
+			Code:    CodeUnexpectedToken,
 			Message: "expected " + tokenType.String() + ", got " + p.current.Type.String(),
+			Args:    []string{tokenType.String(), p.current.Type.String()},
 			Span:    p.current.Span,
 		}
 	}
@@ -172,13 +428,19 @@ func (p *parser) expect(tokenType TokenType) (*Token, error) {
 }
 
 func (p *parser) parse() (*Document, error) {
+	p.start()
 	if p.err != nil {
 		return nil, p.err
 	}
 
 	entries := []*Entry{}
 	start := p.current.Span.Start
-	ps := newPathState()
+	ps := p.paths
+	if ps == nil {
+		ps = newPathState()
+	} else {
+		ps.reset()
+	}
 
 	// Skip any leading commas
 	for p.check(TokenComma) {
@@ -193,7 +455,7 @@ func (p *parser) parse() (*Document, error) {
 			return nil, err
 		}
 		objValue := &Value{Span: obj.Span, PayloadKind: PayloadObject, Object: obj}
-		unitKey := &Value{Span: Span{-1, -1}}
+		unitKey := &Value{Span: Span{-1, -1}, Implicit: true}
 		entries = append(entries, &Entry{Key: unitKey, Value: objValue})
 
 		// After explicit root object, only whitespace/comments/EOF are allowed
@@ -211,35 +473,112 @@ func (p *parser) parse() (*Document, error) {
 				p.advance()
 			}
 			trailingEnd := p.current.Span.Start
-			return nil, &ParseError{
+			err := &ParseError{
+				Code:    CodeTrailingContent,
 				Message: "trailing content after explicit root object",
 				Span:    Span{trailingStart, trailingEnd},
 			}
+			if !p.recovering {
+				return nil, err
+			}
+			p.recordError(err)
 		}
 
-		return &Document{
-			Entries: entries,
-			Span:    Span{start, p.current.Span.End},
-		}, nil
+		if p.err != nil {
+			return nil, p.err
+		}
+		return p.document(entries, start), nil
 	}
 
 	for !p.check(TokenEOF) {
 		if p.err != nil {
 			return nil, p.err
 		}
+		if err := p.checkContext(); err != nil {
+			return nil, err
+		}
+		startTok := p.current
 		entry, err := p.parseEntryWithPathCheck(ps)
+		if err == nil && entry != nil {
+			err = p.countEntry(entry)
+		}
+		if err == nil && p.current == startTok {
+			err = p.noProgress()
+		}
 		if err != nil {
-			return nil, err
+			if !p.recovering || p.aborted {
+				return nil, err
+			}
+			p.recordError(err)
+			p.synchronize(startTok)
+			continue
 		}
 		if entry != nil {
 			entries = append(entries, entry)
 		}
 	}
+	if p.err != nil {
+		return nil, p.err
+	}
 
+	return p.document(entries, start), nil
+}
+
+// parseStandaloneValue parses a source consisting of exactly one value.
+func (p *parser) parseStandaloneValue() (*Value, error) {
+	p.start()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.check(TokenEOF) {
+		return nil, &ParseError{Code: CodeExpectedValue, Message: "expected a value", Span: p.current.Span}
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if !p.check(TokenEOF) {
+		trailingStart := p.current.Span.Start
+		for !p.check(TokenEOF) {
+			p.advance()
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		return nil, &ParseError{
+			Code:    CodeTrailingContent,
+			Message: "trailing content after value",
+			Span:    Span{trailingStart, p.current.Span.Start},
+		}
+	}
+	return value, nil
+}
+
+// document builds the parsed document, attaching comments to entries.
+func (p *parser) document(entries []*Entry, start int) *Document {
+	attachComments(p.lexer.source, entries, p.lexer.comments)
+	var warnings []*Diagnostic
+	if len(p.lexer.warnings)+len(p.warnings) > 0 {
+		warnings = append(append(warnings, p.lexer.warnings...), p.warnings...)
+		sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].Span.Start < warnings[j].Span.Start })
+	}
+	if p.opts.Messages != nil {
+		for _, w := range warnings {
+			pe := w.ParseError()
+			finishError(pe, p.lexer.source, p.opts)
+			w.Message = pe.Message
+		}
+	}
 	return &Document{
-		Entries: entries,
-		Span:    Span{start, p.current.Span.End},
-	}, nil
+		Entries:  entries,
+		Span:     Span{start, p.current.Span.End},
+		Comments: p.lexer.comments,
+		Warnings: warnings,
+		source:   p.lexer.source,
+	}
 }
 
 func (p *parser) parseEntryWithPathCheck(ps *pathState) (*Entry, error) {
@@ -251,9 +590,12 @@ func (p *parser) parseEntryWithPathCheck(ps *pathState) (*Entry, error) {
 		return nil, p.err
 	}
 
-	if p.check(TokenEOF, TokenRBrace) {
+	if p.check(TokenEOF) {
 		return nil, nil
 	}
+	if p.check(TokenRBrace) {
+		return nil, &ParseError{Code: CodeUnexpectedToken, Message: "unexpected token", Span: p.current.Span}
+	}
 
 	key, err := p.parseValue()
 	if err != nil {
@@ -268,7 +610,7 @@ func (p *parser) parseEntryWithPathCheck(ps *pathState) (*Entry, error) {
 		if !p.current.HadNewlineBefore && !p.check(TokenEOF, TokenRBrace, TokenComma) {
 			p.parseValue() // Drop trailing value
 		}
-		unitKey := &Value{Span: Span{-1, -1}}
+		unitKey := &Value{Span: Span{-1, -1}, Implicit: true}
 		return &Entry{Key: unitKey, Value: key}, nil
 	}
 
@@ -285,17 +627,19 @@ func (p *parser) parseEntryWithPathCheck(ps *pathState) (*Entry, error) {
 	}
 
 	// Get key text for path tracking
-	keyText := p.getKeyText(key)
+	keyText := keyText(key)
 
 	// Check for implicit unit
 	if p.current.HadNewlineBefore || p.check(TokenEOF, TokenRBrace) {
 		// Validate path
+		entry := &Entry{Key: key, Value: &Value{Span: key.Span, Implicit: true}}
 		if keyText != "" {
-			if err := ps.checkAndUpdate([]string{keyText}, key.Span, pathValueTerminal); err != nil {
+			if err := p.checkPath(ps, []string{keyText}, key.Span, pathValueTerminal); err != nil {
 				return nil, err
 			}
+			entry.path, entry.pathKind = []string{keyText}, pathValueTerminal
 		}
-		return &Entry{Key: key, Value: &Value{Span: key.Span}}, nil
+		return entry, nil
 	}
 
 	value, err := p.parseValue()
@@ -304,17 +648,19 @@ func (p *parser) parseEntryWithPathCheck(ps *pathState) (*Entry, error) {
 	}
 
 	// Determine value kind and validate path
+	entry := &Entry{Key: key, Value: value}
 	if keyText != "" {
 		kind := pathValueTerminal
 		if value.PayloadKind == PayloadObject {
 			kind = pathValueObject
 		}
-		if err := ps.checkAndUpdate([]string{keyText}, key.Span, kind); err != nil {
+		if err := p.checkPath(ps, []string{keyText}, key.Span, kind); err != nil {
 			return nil, err
 		}
+		entry.path, entry.pathKind = []string{keyText}, kind
 	}
 
-	return &Entry{Key: key, Value: value}, nil
+	return entry, nil
 }
 
 func (p *parser) parseEntryWithDupCheck(seenKeys map[string]Span) (*Entry, error) {
@@ -343,15 +689,20 @@ func (p *parser) parseEntryWithDupCheck(seenKeys map[string]Span) (*Entry, error
 		if !p.current.HadNewlineBefore && !p.check(TokenEOF, TokenRBrace, TokenComma) {
 			p.parseValue() // Drop trailing value
 		}
-		unitKey := &Value{Span: Span{-1, -1}}
+		unitKey := &Value{Span: Span{-1, -1}, Implicit: true}
 		return &Entry{Key: unitKey, Value: key}, nil
 	}
 
 	// Check for duplicate key
-	keyText := p.getKeyText(key)
+	keyText := keyText(key)
 	if keyText != "" {
-		if _, exists := seenKeys[keyText]; exists {
-			return nil, &ParseError{Message: "duplicate key", Span: key.Span}
+		if first, exists := seenKeys[keyText]; exists {
+			return nil, &ParseError{
+				Code:    CodeDuplicateKey,
+				Message: "duplicate key",
+				Span:    key.Span,
+				Related: []Label{{Message: "first defined here", Span: first}},
+			}
 		}
 		seenKeys[keyText] = key.Span
 	}
@@ -362,7 +713,7 @@ func (p *parser) parseEntryWithDupCheck(seenKeys map[string]Span) (*Entry, error
 
 	// Check for implicit unit
 	if p.current.HadNewlineBefore || p.check(TokenEOF, TokenRBrace) {
-		return &Entry{Key: key, Value: &Value{Span: key.Span}}, nil
+		return &Entry{Key: key, Value: &Value{Span: key.Span, Implicit: true}}, nil
 	}
 
 	value, err := p.parseValue()
@@ -372,31 +723,40 @@ func (p *parser) parseEntryWithDupCheck(seenKeys map[string]Span) (*Entry, error
 	return &Entry{Key: key, Value: value}, nil
 }
 
-func (p *parser) getKeyText(key *Value) string {
-	if key.PayloadKind == PayloadScalar {
-		return key.Scalar.Text
-	}
-	if key.Tag != nil && key.PayloadKind == PayloadNone {
-		return "@" + key.Tag.Name
-	}
-	return ""
-}
-
 func (p *parser) validateKey(key *Value) error {
 	if key.PayloadKind == PayloadSequence {
-		return &ParseError{Message: "invalid key", Span: key.Span}
+		return &ParseError{Code: CodeInvalidKey, Message: "invalid key", Span: key.Span}
 	}
 	if key.PayloadKind == PayloadScalar && key.Scalar.Kind == ScalarHeredoc {
 		// Point at just the opening marker (<<TAG), not the whole content
 		errorSpan := p.heredocStartSpan(key.Scalar.Span)
-		return &ParseError{Message: "invalid key", Span: errorSpan}
+		return &ParseError{Code: CodeInvalidKey, Message: "invalid key", Span: errorSpan}
+	}
+	if key.PayloadKind == PayloadScalar && key.Scalar.Kind == ScalarBare {
+		if text := key.Scalar.Text; len(text) > 1 && strings.HasSuffix(text, ":") {
+			colon := Span{key.Span.End - 1, key.Span.End}
+			p.warnings = append(p.warnings, &Diagnostic{
+				Code:     CodeKeyEndsWithColon,
+				Severity: SeverityWarning,
+				Message:  "key `" + text + "` ends with `:`",
+				Span:     colon,
+				Help:     "keys and values are separated by whitespace alone; the `:` is part of the key",
+				Fixes:    []Fix{{Message: "remove the `:`", Edits: []Edit{{Span: colon}}}},
+				args:     []string{text},
+			})
+		}
 	}
 	return nil
 }
 
+// sourceText returns the source text covered by span.
+func (p *parser) sourceText(span Span) string {
+	return span.Slice(p.lexer.source)
+}
+
 // heredocStartSpan returns the span of just the heredoc opening marker (<<TAG\n).
 func (p *parser) heredocStartSpan(heredocSpan Span) Span {
-	text := p.source[heredocSpan.Start:heredocSpan.End]
+	text := heredocSpan.Slice(p.lexer.source)
 	newlineIdx := strings.Index(text, "\n")
 	endOffset := len(text)
 	if newlineIdx >= 0 {
@@ -410,14 +770,16 @@ func (p *parser) expandDottedPathWithState(pathText string, span Span, ps *pathS
 
 	for _, s := range segments {
 		if s == "" {
-			return nil, &ParseError{Message: "invalid key", Span: span}
+			return nil, &ParseError{Code: CodeInvalidKey, Message: "invalid key", Span: span}
 		}
 	}
 
-	// Calculate spans for each segment
+	// Calculate spans for each segment from the source, whose length differs
+	// from the decoded text's when it holds invalid UTF-8
+	rawSegments := strings.Split(p.sourceText(span), ".")
 	segmentSpans := make([]Span, len(segments))
 	offset := span.Start
-	for i, segment := range segments {
+	for i, segment := range rawSegments {
 		segmentBytes := len(segment)
 		segmentSpans[i] = Span{offset, offset + segmentBytes}
 		offset += segmentBytes + 1 // +1 for the dot
@@ -435,7 +797,7 @@ func (p *parser) expandDottedPathWithState(pathText string, span Span, ps *pathS
 	}
 
 	// Validate path with state
-	if err := ps.checkAndUpdate(segments, span, kind); err != nil {
+	if err := p.checkPath(ps, segments, span, kind); err != nil {
 		return nil, err
 	}
 
@@ -448,7 +810,7 @@ func (p *parser) expandDottedPathWithState(pathText string, span Span, ps *pathS
 		segmentKey := &Value{
 			Span:        segSpan,
 			PayloadKind: PayloadScalar,
-			Scalar:      &Scalar{Text: segments[i], Kind: ScalarBare, Span: segSpan},
+			Scalar:      &Scalar{Text: segments[i], Raw: rawSegments[i], Kind: ScalarBare, Span: segSpan},
 		}
 		// Object span starts at the previous segment's position
 		objStart := segmentSpans[i-1].Start
@@ -467,10 +829,10 @@ func (p *parser) expandDottedPathWithState(pathText string, span Span, ps *pathS
 	outerKey := &Value{
 		Span:        firstSpan,
 		PayloadKind: PayloadScalar,
-		Scalar:      &Scalar{Text: segments[0], Kind: ScalarBare, Span: firstSpan},
+		Scalar:      &Scalar{Text: segments[0], Raw: rawSegments[0], Kind: ScalarBare, Span: firstSpan},
 	}
 
-	return &Entry{Key: outerKey, Value: result}, nil
+	return &Entry{Key: outerKey, Value: result, path: segments, pathKind: kind}, nil
 }
 
 func (p *parser) parseAttributeValue() (*Value, error) {
@@ -505,7 +867,11 @@ func (p *parser) parseAttributeValue() (*Value, error) {
 func (p *parser) parseTagValue() (*Value, error) {
 	start := p.current.Span.Start
 	tagToken := p.advance()
-	tag := &Tag{Name: tagToken.Text, Span: tagToken.Span}
+	tag := &Tag{
+		Name:     tagToken.Text,
+		Span:     tagToken.Span,
+		NameSpan: Span{tagToken.Span.Start + 1, tagToken.Span.End},
+	}
 
 	if !p.current.HadWhitespaceBefore {
 		// Check for invalid tag continuation (e.g., @org/package where / is not a valid tag char)
@@ -514,6 +880,7 @@ func (p *parser) parseTagValue() (*Value, error) {
 			// This means there was a character that broke the tag name (like /)
 			// Error span starts at the @ and ends at the invalid scalar
 			return nil, &ParseError{
+				Code:    CodeInvalidTagName,
 				Message: "invalid tag name",
 				Span:    Span{start, p.current.Span.End},
 			}
@@ -557,7 +924,7 @@ func (p *parser) parseValue() (*Value, error) {
 		atToken := p.advance()
 		if !p.current.HadWhitespaceBefore && !p.check(TokenEOF, TokenRBrace, TokenRParen, TokenComma, TokenLBrace, TokenLParen) {
 			// Error span includes the @ (it's part of the tag)
-			return nil, &ParseError{Message: "invalid tag name", Span: Span{atToken.Span.Start, p.current.Span.End}}
+			return nil, &ParseError{Code: CodeInvalidTagName, Message: "invalid tag name", Span: Span{atToken.Span.Start, p.current.Span.End}}
 		}
 		return &Value{Span: Span{atToken.Span.Start, atToken.Span.End}}, nil
 	}
@@ -594,8 +961,10 @@ func (p *parser) parseValue() (*Value, error) {
 			if afterGT.HadNewlineBefore || afterGT.HadWhitespaceBefore || p.check(TokenEOF, TokenRBrace, TokenRParen, TokenComma) {
 				// Error: trailing > without a value
 				return nil, &ParseError{
+					Code:    CodeExpectedValue,
 					Message: "expected a value",
 					Span:    gtToken.Span,
+					Fixes:   []Fix{p.trailingGTFix(gtToken)},
 				}
 			}
 			// Valid attribute - parse value (we already consumed >)
@@ -607,6 +976,7 @@ func (p *parser) parseValue() (*Value, error) {
 			PayloadKind: PayloadScalar,
 			Scalar: &Scalar{
 				Text: scalarToken.Text,
+				Raw:  p.sourceText(scalarToken.Span),
 				Kind: ScalarBare,
 				Span: scalarToken.Span,
 			},
@@ -630,6 +1000,7 @@ func (p *parser) parseAttributesAfterGT(firstKeyToken *Token) (*Value, error) {
 		PayloadKind: PayloadScalar,
 		Scalar: &Scalar{
 			Text: firstKeyToken.Text,
+			Raw:  p.sourceText(firstKeyToken.Span),
 			Kind: ScalarBare,
 			Span: firstKeyToken.Span,
 		},
@@ -638,7 +1009,11 @@ func (p *parser) parseAttributesAfterGT(firstKeyToken *Token) (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	attrs = append(attrs, &Entry{Key: firstKey, Value: firstValue})
+	firstEntry := &Entry{Key: firstKey, Value: firstValue}
+	if err := p.countEntry(firstEntry); err != nil {
+		return nil, err
+	}
+	attrs = append(attrs, firstEntry)
 
 	endSpan := firstValue.Span
 
@@ -656,8 +1031,10 @@ func (p *parser) parseAttributesAfterGT(firstKeyToken *Token) (*Value, error) {
 		afterGT := p.current
 		if afterGT.HadNewlineBefore || afterGT.HadWhitespaceBefore || p.check(TokenEOF, TokenRBrace, TokenRParen, TokenComma) {
 			return nil, &ParseError{
+				Code:    CodeExpectedValue,
 				Message: "expected a value",
 				Span:    gtToken.Span,
+				Fixes:   []Fix{p.trailingGTFix(gtToken)},
 			}
 		}
 
@@ -666,6 +1043,7 @@ func (p *parser) parseAttributesAfterGT(firstKeyToken *Token) (*Value, error) {
 			PayloadKind: PayloadScalar,
 			Scalar: &Scalar{
 				Text: keyToken.Text,
+				Raw:  p.sourceText(keyToken.Span),
 				Kind: ScalarBare,
 				Span: keyToken.Span,
 			},
@@ -675,13 +1053,18 @@ func (p *parser) parseAttributesAfterGT(firstKeyToken *Token) (*Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		attrs = append(attrs, &Entry{Key: attrKey, Value: attrValue})
+		attrEntry := &Entry{Key: attrKey, Value: attrValue}
+		if err := p.countEntry(attrEntry); err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attrEntry)
 		endSpan = attrValue.Span
 	}
 
 	obj := &Object{
-		Entries: attrs,
-		Span:    Span{startSpan.Start, endSpan.End},
+		Entries:    attrs,
+		Span:       Span{startSpan.Start, endSpan.End},
+		Attributes: true,
 	}
 
 	return &Value{Span: obj.Span, PayloadKind: PayloadObject, Object: obj}, nil
@@ -702,13 +1085,76 @@ func (p *parser) parseScalar() (*Scalar, error) {
 		kind = ScalarHeredoc
 	default:
 		return nil, &ParseError{
+			Code:    CodeUnexpectedToken,
 			Message: "expected scalar, got " + token.Type.String(),
+			Args:    []string{"scalar", token.Type.String()},
 			Span:    token.Span,
 		}
 	}
 
 	p.advance()
-	return &Scalar{Text: token.Text, Kind: kind, Span: token.Span}, nil
+	scalar := &Scalar{Text: token.Text, Raw: p.sourceText(token.Span), Kind: kind, Span: token.Span}
+	if kind == ScalarHeredoc {
+		if err := p.parseHeredocOpening(scalar); err != nil {
+			return nil, err
+		}
+	}
+	return scalar, nil
+}
+
+// parseHeredocOpening fills in the heredoc fields of scalar from its opening
+// line.
+func (p *parser) parseHeredocOpening(scalar *Scalar) error {
+	opening := strings.TrimPrefix(scalar.Raw, "<<")
+	if idx := strings.IndexByte(opening, '\n'); idx >= 0 {
+		opening = strings.TrimSuffix(opening[:idx], "\r")
+	}
+	delimiter, options := splitHeredocOpening(opening)
+	scalar.HeredocDelimiter = delimiter
+
+	offset := scalar.Span.Start + len("<<") + len(delimiter)
+	for _, text := range options {
+		offset++ // comma
+		option := HeredocOption{Name: text, Span: Span{offset, offset + len(text)}}
+		offset += len(text)
+		if name, value, ok := strings.Cut(text, "="); ok {
+			option.Name, option.Value = name, value
+		}
+		scalar.HeredocOptions = append(scalar.HeredocOptions, option)
+
+		var language string
+		switch {
+		case option.Name == "lang" && option.Value != "":
+			language = option.Value
+		case !strings.Contains(text, "="):
+			language = option.Name
+		}
+		if p.opts.ValidateHeredocOptions {
+			if language == "" || scalar.HeredocLanguage != "" {
+				return &ParseError{Code: CodeInvalidHeredoc, Message: "unknown heredoc option `" + text + "`", Span: option.Span, Args: []string{text}}
+			}
+			if !isLanguageHint(language) {
+				return &ParseError{Code: CodeInvalidHeredoc, Message: "invalid heredoc language hint `" + language + "`", Span: option.Span, Args: []string{language}}
+			}
+		}
+		if scalar.HeredocLanguage == "" {
+			scalar.HeredocLanguage = language
+		}
+	}
+	return nil
+}
+
+// isLanguageHint reports whether s matches `[a-z][a-z0-9_.-]*`.
+func isLanguageHint(s string) bool {
+	for i, ch := range s {
+		switch {
+		case ch >= 'a' && ch <= 'z':
+		case i > 0 && (ch >= '0' && ch <= '9' || ch == '_' || ch == '.' || ch == '-'):
+		default:
+			return false
+		}
+	}
+	return s != ""
 }
 
 func (p *parser) parseObject() (*Object, error) {
@@ -716,14 +1162,33 @@ func (p *parser) parseObject() (*Object, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := p.enter(openBrace.Span); err != nil {
+		return nil, err
+	}
+	defer p.leave()
 	start := openBrace.Span.Start
 	entries := []*Entry{}
 	seenKeys := make(map[string]Span)
 
 	for !p.check(TokenRBrace, TokenEOF) {
+		if err := p.checkContext(); err != nil {
+			return nil, err
+		}
+		startTok := p.current
 		entry, err := p.parseEntryWithDupCheck(seenKeys)
+		if err == nil && entry != nil {
+			err = p.countEntry(entry)
+		}
+		if err == nil && p.current == startTok {
+			err = p.noProgress()
+		}
 		if err != nil {
-			return nil, err
+			if !p.recovering || p.aborted {
+				return nil, err
+			}
+			p.recordError(err)
+			p.synchronize(startTok)
+			continue
 		}
 		if entry != nil {
 			entries = append(entries, entry)
@@ -736,10 +1201,16 @@ func (p *parser) parseObject() (*Object, error) {
 	}
 
 	if p.check(TokenEOF) {
-		return nil, &ParseError{
+		err := &ParseError{
+			Code:    CodeUnclosedObject,
 			Message: "unclosed object (missing `}`)",
 			Span:    openBrace.Span,
 		}
+		if !p.recovering || p.err != nil {
+			return nil, err
+		}
+		p.recordError(err)
+		return &Object{Entries: entries, Span: Span{start, p.current.Span.Start}}, nil
 	}
 
 	closeBrace, err := p.expect(TokenRBrace)
@@ -754,16 +1225,28 @@ func (p *parser) parseSequence() (*Sequence, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := p.enter(openParen.Span); err != nil {
+		return nil, err
+	}
+	defer p.leave()
 	start := openParen.Span.Start
 	items := []*Value{}
 
 	for !p.check(TokenRParen, TokenEOF) {
 		// Check for comma - not allowed in sequences
 		if p.check(TokenComma) {
-			return nil, &ParseError{
+			err := &ParseError{
+				Code:    CodeCommaInSequence,
 				Message: "unexpected `,` in sequence (sequences are whitespace-separated, not comma-separated)",
 				Span:    p.current.Span,
+				Fixes:   []Fix{p.commaFix()},
 			}
+			if !p.recovering {
+				return nil, err
+			}
+			p.recordError(err)
+			p.advance()
+			continue
 		}
 		item, err := p.parseValue()
 		if err != nil {
@@ -773,10 +1256,16 @@ func (p *parser) parseSequence() (*Sequence, error) {
 	}
 
 	if p.check(TokenEOF) {
-		return nil, &ParseError{
+		err := &ParseError{
+			Code:    CodeUnclosedSequence,
 			Message: "unclosed sequence (missing `)`)",
 			Span:    openParen.Span,
 		}
+		if !p.recovering || p.err != nil {
+			return nil, err
+		}
+		p.recordError(err)
+		return &Sequence{Items: items, Span: Span{start, p.current.Span.Start}}, nil
 	}
 
 	closeParen, err := p.expect(TokenRParen)
@@ -785,3 +1274,24 @@ func (p *parser) parseSequence() (*Sequence, error) {
 	}
 	return &Sequence{Items: items, Span: Span{start, closeParen.Span.End}}, nil
 }
+
+// commaFix suggests removing the `,` at the current token, or replacing it
+// with a space when it is the only separator between two items.
+func (p *parser) commaFix() Fix {
+	comma := p.current
+	next := p.peek()
+	if comma.HadWhitespaceBefore || next.HadWhitespaceBefore || next.Type == TokenRParen {
+		return Fix{Message: "remove the `,`", Edits: []Edit{{Span: comma.Span}}}
+	}
+	return Fix{Message: "replace the `,` with a space", Edits: []Edit{{Span: comma.Span, Text: " "}}}
+}
+
+// trailingGTFix suggests a fix for an attribute `>` without a value: joining
+// the value separated from it by spaces, or removing the `>`.
+func (p *parser) trailingGTFix(gt *Token) Fix {
+	next := p.current
+	if next.HadWhitespaceBefore && !next.HadNewlineBefore && !p.check(TokenEOF, TokenRBrace, TokenRParen, TokenComma) {
+		return Fix{Message: "remove the space after `>`", Edits: []Edit{{Span: Span{gt.Span.End, next.Span.Start}}}}
+	}
+	return Fix{Message: "remove the `>`", Edits: []Edit{{Span: gt.Span}}}
+}
@@ -0,0 +1,171 @@
+package styx
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrPathExists is returned, wrapped in a *PatchError, by an add operation
+// for a path the document already has.
+var ErrPathExists = errors.New("path already exists")
+
+// PatchOp is the kind of a patch operation.
+type PatchOp int
+
+const (
+	// PatchAdd sets a path the document does not have yet.
+	PatchAdd PatchOp = iota
+	// PatchRemove deletes an existing path.
+	PatchRemove
+	// PatchReplace sets an existing path.
+	PatchReplace
+)
+
+func (op PatchOp) String() string {
+	switch op {
+	case PatchAdd:
+		return "add"
+	case PatchRemove:
+		return "remove"
+	case PatchReplace:
+		return "replace"
+	}
+	return "unknown"
+}
+
+// PatchOperation is one step of a Patch.
+type PatchOperation struct {
+	Op PatchOp
+	// Path is a dotted key path, as for Document.Get.
+	Path string
+	// Value is the value set by add and replace operations.
+	Value *Value
+}
+
+// Patch is a list of operations applied in order by ApplyPatch, in the
+// manner of JSON Patch. Patches are written in Styx as a sequence of
+// objects tagged with their operation:
+//
+//	(
+//	  @add{path server.tls.cert, value /etc/cert.pem}
+//	  @replace{path server.port, value 9090}
+//	  @remove{path debug}
+//	)
+type Patch []PatchOperation
+
+// ParsePatch parses a patch written as described for Patch.
+func ParsePatch(source string) (Patch, error) {
+	v, err := ParseValue(source)
+	if err != nil {
+		return nil, err
+	}
+	if v.Tag != nil || v.PayloadKind != PayloadSequence {
+		return nil, patchSyntaxError(source, "a patch is a sequence of operations", v.FullSpan())
+	}
+	patch := make(Patch, 0, len(v.Sequence.Items))
+	for _, item := range v.Sequence.Items {
+		op, err := parsePatchOperation(source, item)
+		if err != nil {
+			return nil, err
+		}
+		patch = append(patch, op)
+	}
+	return patch, nil
+}
+
+func parsePatchOperation(source string, item *Value) (PatchOperation, error) {
+	var op PatchOperation
+	if item.Tag == nil || item.PayloadKind != PayloadObject {
+		return op, patchSyntaxError(source, "expected an operation such as @add{path ..., value ...}", item.FullSpan())
+	}
+	switch item.Tag.Name {
+	case "add":
+		op.Op = PatchAdd
+	case "remove":
+		op.Op = PatchRemove
+	case "replace":
+		op.Op = PatchReplace
+	default:
+		return op, patchSyntaxError(source, "unknown patch operation `@"+item.Tag.Name+"`", item.Tag.Span)
+	}
+	path, ok := item.LookupKeys("path")
+	if !ok || path.Tag != nil || path.PayloadKind != PayloadScalar {
+		return op, patchSyntaxError(source, "operation needs a `path` scalar", item.FullSpan())
+	}
+	op.Path = path.Scalar.Text
+	op.Value, ok = item.LookupKeys("value")
+	if ok != (op.Op != PatchRemove) {
+		if ok {
+			return op, patchSyntaxError(source, "remove operation takes no `value`", op.Value.FullSpan())
+		}
+		return op, patchSyntaxError(source, "operation needs a `value`", item.FullSpan())
+	}
+	for _, entry := range item.Object.Entries {
+		if key := entry.KeyText(); key != "path" && key != "value" {
+			return op, patchSyntaxError(source, "unknown operation field `"+key+"`", entry.Key.Span)
+		}
+	}
+	return op, nil
+}
+
+func patchSyntaxError(source, msg string, span Span) error {
+	return finishError(&ParseError{Message: msg, Span: span}, source, ParseOptions{})
+}
+
+// PatchError reports the operation that made ApplyPatch fail.
+type PatchError struct {
+	// Index is the position of the operation in the patch.
+	Index int
+	Op    PatchOperation
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return "patch operation " + strconv.Itoa(e.Index) + " (" + e.Op.Op.String() + " " + e.Op.Path + "): " + e.Err.Error()
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyPatch returns a copy of doc with the patch applied. An add
+// operation fails with ErrPathExists if the path exists, and replace and
+// remove operations with ErrPathNotFound if it does not; setting a path
+// fails as for SetPath. The patch is applied entirely or not at all: on
+// failure doc is unchanged and the *PatchError names the operation.
+func ApplyPatch(doc *Document, patch Patch) (*Document, error) {
+	result := &Document{
+		Entries:  copyEntries(doc.Entries),
+		Span:     doc.Span,
+		Comments: doc.Comments,
+		source:   doc.source,
+	}
+	for i, op := range patch {
+		var err error
+		switch op.Op {
+		case PatchAdd:
+			if result.Has(op.Path) {
+				err = ErrPathExists
+			} else {
+				err = result.SetPath(op.Path, op.Value)
+			}
+		case PatchReplace:
+			if !result.Has(op.Path) {
+				err = ErrPathNotFound
+			} else {
+				err = result.SetPath(op.Path, op.Value)
+			}
+		case PatchRemove:
+			err = result.DeletePath(op.Path)
+		}
+		if err != nil {
+			// SetPath and DeletePath name the path already.
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				err = pathErr.Err
+			}
+			return nil, &PatchError{Index: i, Op: op, Err: err}
+		}
+	}
+	return result, nil
+}
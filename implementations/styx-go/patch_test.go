@@ -0,0 +1,73 @@
+package styx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	doc, err := Parse("server.host localhost\nserver.port 8080\ndebug true\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := ParsePatch(`(
+  @add{path server.tls.cert, value /etc/cert.pem}
+  @replace{path server.port, value 9090}
+  @remove{path debug}
+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patch) != 3 || patch[1].Op != PatchReplace || patch[1].Path != "server.port" || patch[2].Value != nil {
+		t.Fatalf("ParsePatch = %+v", patch)
+	}
+
+	patched, err := ApplyPatch(doc, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := patched.Get("server.tls.cert"); got == nil || got.Scalar.Text != "/etc/cert.pem" {
+		t.Errorf("server.tls.cert = %v", got)
+	}
+	if got := patched.Get("server.port"); got == nil || got.Scalar.Text != "9090" {
+		t.Errorf("server.port = %v", got)
+	}
+	if patched.Has("debug") || !patched.Has("server.host") {
+		t.Error("debug not removed or server.host lost")
+	}
+	if !doc.Has("debug") || doc.Get("server.port").Scalar.Text != "8080" {
+		t.Error("original document was modified")
+	}
+
+	failing := Patch{patch[2], {Op: PatchAdd, Path: "server.host", Value: patch[0].Value}}
+	_, err = ApplyPatch(doc, failing)
+	var patchErr *PatchError
+	if !errors.As(err, &patchErr) || patchErr.Index != 1 || !errors.Is(err, ErrPathExists) {
+		t.Errorf("add existing: got %v", err)
+	}
+	if err.Error() != "patch operation 1 (add server.host): path already exists" {
+		t.Errorf("error string: %q", err)
+	}
+	if !doc.Has("debug") {
+		t.Error("failed patch modified the document")
+	}
+	if _, err := ApplyPatch(doc, Patch{{Op: PatchReplace, Path: "missing", Value: patch[0].Value}}); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("replace missing: got %v", err)
+	}
+	if _, err := ApplyPatch(doc, Patch{{Op: PatchAdd, Path: "debug.level", Value: patch[0].Value}}); !errors.Is(err, ErrNestIntoTerminal) {
+		t.Errorf("add below terminal: got %v", err)
+	}
+
+	for source, msg := range map[string]string{
+		"{path a}":                       "1:1: a patch is a sequence of operations \"{path a}\"",
+		"(@move{path a})":                "1:2: unknown patch operation `@move` \"@move\"",
+		"(@add{path a})":                 "1:2: operation needs a `value` \"@add{path a}\"",
+		"(@remove{path a, value 1})":     "1:24: remove operation takes no `value` \"1\"",
+		"(@replace{path a, value 1, x})": "1:28: unknown operation field `x` \"x\"",
+		"(@add{value 1})":                "1:2: operation needs a `path` scalar \"@add{value 1}\"",
+	} {
+		if _, err := ParsePatch(source); err == nil || err.Error() != msg {
+			t.Errorf("%s: got %v, want %s", source, err, msg)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package styx
+
+// Parser parses Styx documents and can be reused for many sources, which
+// avoids reallocating its internal state on every parse. Services parsing at
+// high rates can keep Parsers in a sync.Pool. A Parser is not safe for
+// concurrent use.
+//
+// Documents returned by Parse do not share memory with the Parser, so they
+// stay valid after Reset.
+type Parser struct {
+	opts   ParseOptions
+	source string
+	lexer  Lexer
+	paths  *pathState
+	parser parser
+}
+
+// NewParser returns a Parser for source using the given options.
+func NewParser(source string, opts ParseOptions) *Parser {
+	return &Parser{opts: opts, source: source, paths: newPathState()}
+}
+
+// Reset prepares the parser to parse source with the same options.
+func (p *Parser) Reset(source string) {
+	p.source = source
+}
+
+// ResetWithOptions prepares the parser to parse source with new options.
+func (p *Parser) ResetWithOptions(source string, opts ParseOptions) {
+	p.source, p.opts = source, opts
+}
+
+// Parse parses the parser's source. It behaves like ParseWithOptions,
+// including returning a partial document on error. Parse may be called more
+// than once; each call parses the source from the start.
+func (p *Parser) Parse() (*Document, error) {
+	if err := checkOptions(p.source, p.opts); err != nil {
+		return nil, finishError(err, p.source, p.opts)
+	}
+	if p.paths == nil {
+		p.paths = newPathState()
+	}
+	p.lexer = Lexer{source: p.source}
+	p.lexer.configure(p.opts)
+	p.parser = parser{lexer: &p.lexer, opts: p.opts, paths: p.paths}
+	doc, err := p.parser.parseToFirstError()
+	if err != nil {
+		return doc, finishError(err, p.source, p.opts)
+	}
+	return doc, nil
+}
@@ -0,0 +1,50 @@
+package styx
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestParserReuse(t *testing.T) {
+	sources := []string{
+		"a.b 1\na.c 2",
+		"// doc\nx {y 1}\n",
+		"a.b 1\nc 2\na.d 3",
+		"a.b 1\na.c 2",
+	}
+	p := NewParser("", ParseOptions{})
+	for _, src := range sources {
+		p.Reset(src)
+		got, gotErr := p.Parse()
+		want, wantErr := Parse(src)
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Fatalf("%q: got error %v, want %v", src, gotErr, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: reused parser produced a different document", src)
+		}
+	}
+}
+
+func TestParserPool(t *testing.T) {
+	pool := sync.Pool{New: func() any { return new(Parser) }}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				p := pool.Get().(*Parser)
+				p.ResetWithOptions("a.b 1\na.c 2 // c\n", ParseOptions{})
+				doc, err := p.Parse()
+				pool.Put(p)
+				if err != nil || len(doc.Entries) != 2 || doc.Entries[1].TrailingComment == nil {
+					t.Errorf("unexpected result: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,152 @@
+package styx
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Query is a compiled path query, matching values of a document by a
+// pattern of dot-separated segments:
+//
+//	services.api.port   a key at each level
+//	services.*.port     `*` matches any key or sequence item
+//	**.enabled          `**` matches any number of levels, including none
+//	ports.0             a number also indexes sequences
+//
+// A key containing dots cannot be named in a query.
+type Query struct {
+	expr     string
+	segments []string
+}
+
+// CompileQuery parses a query expression.
+func CompileQuery(expr string) (*Query, error) {
+	segments := strings.Split(expr, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, errors.New("invalid query " + strconv.Quote(expr) + ": empty segment")
+		}
+	}
+	return &Query{expr: expr, segments: segments}, nil
+}
+
+// String returns the query's expression.
+func (q *Query) String() string {
+	return q.expr
+}
+
+// QueryMatch is a value matched by a query.
+type QueryMatch struct {
+	// Path holds the keys and sequence indices leading to the value.
+	Path []string
+	// Entry is the entry holding the value, or nil for a sequence item.
+	Entry *Entry
+	Value *Value
+}
+
+// Span returns the byte range of the matched value.
+func (m QueryMatch) Span() Span {
+	return m.Value.FullSpan()
+}
+
+// Query compiles expr and returns the document's matching values.
+func (d *Document) Query(expr string) ([]QueryMatch, error) {
+	q, err := CompileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Match(d), nil
+}
+
+// Match returns the values of doc matching the query, in document order.
+// The document's entries are searched as an object, or those of its root
+// object for a document consisting of one. A key split over several
+// dotted entries (`a.b 1` and `a.c 2`) matches once per entry.
+func (q *Query) Match(doc *Document) []QueryMatch {
+	entries := doc.Entries
+	if len(entries) == 1 && entries[0].Key.IsImplicitUnit() {
+		entries = nil
+		if root := doc.Entries[0].Value; root.PayloadKind == PayloadObject {
+			entries = root.Object.Entries
+		}
+	}
+	root := &Value{Span: doc.Span, PayloadKind: PayloadObject, Object: &Object{Entries: entries, Span: doc.Span}}
+	m := queryMatcher{seen: map[*Value]bool{}}
+	m.match(QueryMatch{Value: root}, q.segments)
+	return m.matches
+}
+
+type queryMatcher struct {
+	matches []QueryMatch
+	// seen drops the duplicates `**` produces when it can match the same
+	// value in several ways.
+	seen map[*Value]bool
+}
+
+func (m *queryMatcher) match(node QueryMatch, segments []string) {
+	if len(segments) == 0 {
+		if !m.seen[node.Value] {
+			m.seen[node.Value] = true
+			m.matches = append(m.matches, node)
+		}
+		return
+	}
+	segment, rest := segments[0], segments[1:]
+	if segment == "**" {
+		m.match(node, rest)
+	}
+	for _, child := range queryChildren(node) {
+		switch {
+		case segment == "**":
+			m.match(child, segments)
+		case segment == "*" || segment == child.Path[len(child.Path)-1]:
+			m.match(child, rest)
+		}
+	}
+}
+
+// queryChildren returns the entries of an object node, or the items of a
+// sequence node, each with its path extended.
+func queryChildren(node QueryMatch) []QueryMatch {
+	var children []QueryMatch
+	extend := func(step string) []string {
+		return append(node.Path[:len(node.Path):len(node.Path)], step)
+	}
+	switch node.Value.PayloadKind {
+	case PayloadObject:
+		for _, entry := range node.Value.Object.Entries {
+			if entry.Key.IsImplicitUnit() {
+				continue
+			}
+			children = append(children, QueryMatch{Path: extend(entry.KeyText()), Entry: entry, Value: entry.Value})
+		}
+	case PayloadSequence:
+		for i, item := range node.Value.Sequence.Items {
+			children = append(children, QueryMatch{Path: extend(strconv.Itoa(i)), Value: item})
+		}
+	}
+	return children
+}
+
+// Select returns every value of doc for which keep returns true, with its
+// path, in document order. keep is called once for each value below the
+// document's entries, objects and sequences, with the keys and sequence
+// indices leading to it; keys themselves are not visited.
+func Select(doc *Document, keep func(path []string, v *Value) bool) []QueryMatch {
+	var selected []QueryMatch
+	for _, m := range (&Query{segments: []string{"**"}}).Match(doc) {
+		if len(m.Path) > 0 && keep(m.Path, m.Value) {
+			selected = append(selected, m)
+		}
+	}
+	return selected
+}
+
+// FindTagged returns every value tagged `@name` with its path, in document
+// order, including values nested in other matches. Keys are not searched.
+func (d *Document) FindTagged(name string) []QueryMatch {
+	return Select(d, func(_ []string, v *Value) bool {
+		return v.Tag != nil && v.Tag.Name == name
+	})
+}
@@ -0,0 +1,101 @@
+package styx
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	doc, err := Parse(`services {
+  api {port 8080, enabled true}
+  web {port 80, enabled false}
+}
+ports (8080 80)
+cache.enabled true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr  string
+		paths []string
+		texts []string
+	}{
+		{"services.*.port", []string{"services.api.port", "services.web.port"}, []string{"8080", "80"}},
+		{"**.enabled", []string{"services.api.enabled", "services.web.enabled", "cache.enabled"}, []string{"true", "false", "true"}},
+		{"ports.1", []string{"ports.1"}, []string{"80"}},
+		{"ports.*", []string{"ports.0", "ports.1"}, []string{"8080", "80"}},
+		{"**.**.port", []string{"services.api.port", "services.web.port"}, []string{"8080", "80"}},
+		{"services.db.port", nil, nil},
+		{"ports.2", nil, nil},
+	}
+	for _, tt := range tests {
+		matches, err := doc.Query(tt.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		var paths, texts []string
+		for _, m := range matches {
+			paths = append(paths, strings.Join(m.Path, "."))
+			texts = append(texts, m.Span().Slice(doc.Source()))
+		}
+		if !reflect.DeepEqual(paths, tt.paths) || !reflect.DeepEqual(texts, tt.texts) {
+			t.Errorf("%q: got %v %v, want %v %v", tt.expr, paths, texts, tt.paths, tt.texts)
+		}
+	}
+
+	matches, _ := doc.Query("services.api")
+	if len(matches) != 1 || matches[0].Entry == nil || matches[0].Entry.KeyText() != "api" {
+		t.Errorf("services.api: got %v", matches)
+	}
+	if _, err := CompileQuery("a..b"); err == nil {
+		t.Error("a..b: want error")
+	}
+}
+
+func TestFindTagged(t *testing.T) {
+	doc, err := Parse(`db {password @secret"hunter2", user admin}
+keys (@secret{id 1} plain @secret{id @secret"nested"})
+api @secret
+@secret key
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, m := range doc.FindTagged("secret") {
+		paths = append(paths, strings.Join(m.Path, "."))
+	}
+	want := []string{"db.password", "keys.0", "keys.2", "keys.2.id", "api"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+	if found := doc.FindTagged("missing"); found != nil {
+		t.Errorf("missing tag: got %v", found)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	doc, err := Parse("services {api {port 8080}, web {port 80}, db {port 70000}}\nports (22 443)\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	badPorts := Select(doc, func(path []string, v *Value) bool {
+		if v.PayloadKind != PayloadScalar {
+			return false
+		}
+		n, err := strconv.Atoi(v.Scalar.Text)
+		return err == nil && (n < 1024 || n > 65535)
+	})
+	var got []string
+	for _, m := range badPorts {
+		got = append(got, strings.Join(m.Path, ".")+"@"+m.Span().Slice(doc.Source()))
+	}
+	want := []string{"services.web.port@80", "services.db.port@70000", "ports.0@22", "ports.1@443"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
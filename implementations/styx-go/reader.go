@@ -0,0 +1,32 @@
+package styx
+
+import "io"
+
+// ParseReader parses a Styx document read from r. Input is read in chunks as
+// the parser needs it, so parsing proceeds while a slow stream is still
+// arriving. The document keeps the source text for spans and raw scalars, so
+// the input is retained in memory once read. I/O errors other than io.EOF
+// are returned as is.
+func ParseReader(r io.Reader) (*Document, error) {
+	return ParseReaderWithOptions(r, ParseOptions{})
+}
+
+// ParseReaderWithOptions is like ParseReader but uses the given options.
+// MaxInputSize bounds how much is read from r.
+func ParseReaderWithOptions(r io.Reader, opts ParseOptions) (*Document, error) {
+	lexer := newReaderLexer(r, opts.MaxInputSize)
+	p := newParserWithLexer(lexer, opts)
+	doc, err := p.parseToFirstError()
+	if lexer.readErr != nil {
+		// The parse recorded read errors as parse errors; return them as
+		// is, and no partial document for input that could not be read.
+		if pe, ok := err.(*ParseError); ok && pe.cause == lexer.readErr {
+			err = lexer.readErr
+		}
+		return nil, finishError(err, "", opts)
+	}
+	if err != nil {
+		return doc, finishError(err, lexer.source, opts)
+	}
+	return doc, nil
+}
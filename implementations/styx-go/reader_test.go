@@ -0,0 +1,126 @@
+package styx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseReader(t *testing.T) {
+	source := "name \"app\"\nscript <<EOT\n" + strings.Repeat("echo hello\n", 10000) + "EOT\nraw r#\"a\"#\n"
+	want := mustParse(t, source)
+
+	// OneByteReader exercises refilling in the middle of every token.
+	doc, err := ParseReader(iotest.OneByteReader(strings.NewReader(source)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Hash() != want.Hash() {
+		t.Errorf("document from reader differs from Parse")
+	}
+	if doc.Source() != source {
+		t.Errorf("source not retained")
+	}
+	if raw := doc.Entries[2].Value.Scalar.Raw; raw != `r#"a"#` {
+		t.Errorf("Raw = %q", raw)
+	}
+}
+
+func TestParseReaderErrors(t *testing.T) {
+	readErr := errors.New("boom")
+	_, err := ParseReader(io.MultiReader(strings.NewReader("a b\n"), iotest.ErrReader(readErr)))
+	if err != readErr {
+		t.Errorf("read error: got %v", err)
+	}
+
+	_, err = ParseReaderWithOptions(strings.NewReader("key value\n"), ParseOptions{MaxInputSize: 5})
+	if pe, ok := err.(*ParseError); !ok || pe.Message != "input too large" {
+		t.Errorf("size limit: got %v", err)
+	}
+
+	doc, err := ParseReader(strings.NewReader("a {b 1\n"))
+	if err == nil || doc == nil || len(doc.Entries) != 1 {
+		t.Errorf("partial document: got %v, %v", doc, err)
+	}
+}
+
+func TestReaderLexer(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&b, "key%d \"value %d\" // note\n", i, i)
+	}
+	source := b.String()
+	want, err := Tokenize(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewReaderLexer(iotest.OneByteReader(strings.NewReader(source)), ParseOptions{})
+	for i := 0; ; i++ {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *tok != *want[i] {
+			t.Fatalf("token %d: got %+v, want %+v", i, tok, want[i])
+		}
+		if tok.Type == TokenEOF {
+			break
+		}
+	}
+	if len(l.Comments()) != 20000 || l.Comments()[19999].Span.Slice(source) != "// note" {
+		t.Errorf("got %d comments", len(l.Comments()))
+	}
+	if cap(l.buf) > 2*readChunkSize {
+		t.Errorf("lexer retained %d bytes", cap(l.buf))
+	}
+
+	l = NewReaderLexer(strings.NewReader(source), ParseOptions{MaxInputSize: 100000})
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			if pe, ok := err.(*ParseError); !ok || pe.Message != "input too large" {
+				t.Fatalf("got %v", err)
+			}
+			break
+		}
+		if tok.Type == TokenEOF {
+			t.Fatal("read past MaxInputSize")
+		}
+	}
+}
+
+// countingReader yields n bytes of the same value, counting those read.
+type countingReader struct {
+	b    byte
+	n    int
+	read int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	if r.read == r.n {
+		return 0, io.EOF
+	}
+	p = p[:min(len(p), r.n-r.read)]
+	for i := range p {
+		p[i] = r.b
+	}
+	r.read += len(p)
+	return len(p), nil
+}
+
+func TestReaderLexerHeredocLimit(t *testing.T) {
+	body := &countingReader{b: 'x', n: 64 << 20}
+	l := NewReaderLexer(io.MultiReader(strings.NewReader("k <<EOT\n"), body), ParseOptions{MaxHeredocLength: 1 << 20})
+	l.nextToken()
+	_, err := l.nextToken()
+	if pe, ok := err.(*ParseError); !ok || pe.Message != "heredoc too long" || pe.Span != (Span{2, 7}) {
+		t.Fatalf("got %v", err)
+	}
+	if body.read > 2<<20 {
+		t.Errorf("read %d bytes of the heredoc body", body.read)
+	}
+}
@@ -0,0 +1,145 @@
+package styx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func errorMessages(errs []*ParseError) []string {
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Message)
+	}
+	return msgs
+}
+
+func TestParseRecover(t *testing.T) {
+	source := `a 1
+b "bad\q"
+c 3
+server {
+  host localhost
+  host again
+  port (80, 443)
+}
+}
+d @/x
+e 5
+`
+	doc, errs := ParseRecover(source, ParseOptions{})
+	if doc == nil {
+		t.Fatal("no document")
+	}
+	want := []string{
+		"invalid escape sequence: \\q",
+		"duplicate key",
+		"unexpected `,` in sequence (sequences are whitespace-separated, not comma-separated)",
+		"unexpected token",
+		"invalid tag name",
+	}
+	got := errorMessages(errs)
+	if len(got) != len(want) {
+		t.Fatalf("errors = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("error %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	var keys []string
+	for _, entry := range doc.Entries {
+		keys = append(keys, entry.KeyText())
+	}
+	wantKeys := []string{"a", "b", "c", "server", "e"}
+	if len(keys) != len(wantKeys) {
+		t.Fatalf("keys = %q, want %q", keys, wantKeys)
+	}
+	for i := range wantKeys {
+		if keys[i] != wantKeys[i] {
+			t.Errorf("key %d = %q, want %q", i, keys[i], wantKeys[i])
+		}
+	}
+	server := doc.Entries[3].Value.Object
+	if n := len(server.Entries); n != 2 {
+		t.Errorf("server has %d entries, want 2", n)
+	}
+}
+
+func TestParseRecoverUnclosed(t *testing.T) {
+	doc, errs := ParseRecover("a {b 1\nc 2\n", ParseOptions{})
+	if len(errs) != 1 || errs[0].Message != "unclosed object (missing `}`)" {
+		t.Fatalf("errors = %q", errorMessages(errs))
+	}
+	if obj := doc.Entries[0].Value.Object; len(obj.Entries) != 2 {
+		t.Errorf("object has %d entries, want 2", len(obj.Entries))
+	}
+}
+
+func TestStrayCloseBrace(t *testing.T) {
+	_, err := Parse("a b\n}\n")
+	if pe, ok := err.(*ParseError); !ok || pe.Span != (Span{4, 5}) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestParsePartialDocument(t *testing.T) {
+	doc, err := Parse("name app\nserver {\n  host localhost\n  port\n")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if doc == nil {
+		t.Fatal("no partial document")
+	}
+	if n := len(doc.Entries); n != 2 {
+		t.Fatalf("partial document has %d entries, want 2", n)
+	}
+	if obj := doc.Entries[1].Value.Object; obj == nil || len(obj.Entries) != 2 {
+		t.Errorf("partial server object = %+v", obj)
+	}
+
+	// The partial document is the one ParseRecover builds, and the error
+	// its first.
+	for _, source := range []string{"a 1\nb (x \"y\nc 2\n", "iter>s (x\n", "co>nfig {\n", "a {b 1\n}\n}\nc 3"} {
+		doc, err := Parse(source)
+		recovered, errs := ParseRecover(source, ParseOptions{})
+		if err == nil || len(errs) == 0 || err.Error() != errs[0].Error() {
+			t.Errorf("%q: got %v, want %v", source, err, errorMessages(errs))
+		}
+		if doc == nil || recovered == nil || doc.Hash() != recovered.Hash() {
+			t.Errorf("%q: partial document differs from ParseRecover's", source)
+		}
+	}
+}
+
+func TestParseRecoverMaxErrors(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&b, "k%d \"\\q\"\n", i)
+	}
+	b.WriteString("x {\n  (1, 2)\n")
+	source := b.String()
+
+	doc, errs := ParseRecover(source, ParseOptions{})
+	if doc == nil || len(errs) != DefaultMaxErrors+1 {
+		t.Fatalf("got %d errors", len(errs))
+	}
+	last := errs[len(errs)-1]
+	if last.Code != CodeTooManyErrors || last.Span != errs[len(errs)-2].Span {
+		t.Errorf("last error = %+v", last)
+	}
+	if len(doc.Entries) != DefaultMaxErrors {
+		t.Errorf("got %d entries", len(doc.Entries))
+	}
+
+	_, errs = ParseRecover(source, ParseOptions{MaxErrors: 3})
+	if len(errs) != 4 || !errors.Is(errs[3], ErrTooManyErrors) {
+		t.Errorf("MaxErrors 3: got %v", errorMessages(errs))
+	}
+	_, errs = ParseRecover(source, ParseOptions{MaxErrors: -1})
+	if len(errs) != 103 {
+		t.Errorf("unlimited: got %d errors", len(errs))
+	}
+}
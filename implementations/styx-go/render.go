@@ -0,0 +1,271 @@
+package styx
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSnippetLines is the number of source lines shown for a span before the
+// middle of the span is elided.
+const maxSnippetLines = 4
+
+// RenderDiagnostic formats err for display, quoting the offending source
+// with carets under the error span and dashes under related spans:
+//
+//	error[STYX0011]: duplicate key
+//	 --> 2:1
+//	2 | a 2
+//	  | ^
+//	 --> 1:1: first defined here
+//	1 | a 1
+//	  | -
+//
+// Spans covering several lines are underlined on each line. Errors that do
+// not wrap a *ParseError are rendered as their message alone.
+func RenderDiagnostic(source string, err error) string {
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		return err.Error() + "\n"
+	}
+	return pe.Diagnostic().Render(source)
+}
+
+// Render formats the diagnostic like RenderDiagnostic.
+func (d *Diagnostic) Render(source string) string {
+	return RenderDiagnostics(source, []*Diagnostic{d}, RenderOptions{})
+}
+
+// RenderOptions configures RenderDiagnostics.
+type RenderOptions struct {
+	// Color highlights the output with ANSI escape sequences. ColorEnabled
+	// tells whether a terminal wants it.
+	Color bool
+	// Context is the number of source lines shown before and after each
+	// quoted span.
+	Context int
+	// Filename names the source in locations, as in "--> config.styx:2:1".
+	Filename string
+}
+
+// ColorEnabled reports whether output written to f should be colored: f is
+// a terminal, NO_COLOR is unset or empty, and TERM is not "dumb".
+func ColorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// RenderDiagnostics formats diags for a terminal in the style of
+// RenderDiagnostic, separating them with blank lines.
+func RenderDiagnostics(source string, diags []*Diagnostic, opts RenderOptions) string {
+	r := snippetRenderer{source: source, opts: opts}
+	for i, d := range diags {
+		if i > 0 {
+			r.b.WriteByte('\n')
+		}
+		r.diagnostic(d)
+	}
+	return r.b.String()
+}
+
+// ANSI styles used when RenderOptions.Color is set.
+const (
+	styleReset  = "\x1b[0m"
+	styleBold   = "\x1b[1m"
+	styleError  = "\x1b[1;31m"
+	styleWarn   = "\x1b[1;33m"
+	styleInfo   = "\x1b[1;36m"
+	styleGutter = "\x1b[1;34m"
+)
+
+type snippetRenderer struct {
+	source string
+	opts   RenderOptions
+	gutter int
+	b      strings.Builder
+}
+
+// style writes text in the given style, or plainly without colors.
+func (r *snippetRenderer) style(style, text string) {
+	if r.opts.Color && text != "" {
+		r.b.WriteString(style + text + styleReset)
+	} else {
+		r.b.WriteString(text)
+	}
+}
+
+func (r *snippetRenderer) diagnostic(d *Diagnostic) {
+	spans := []Span{d.Span}
+	for _, label := range d.Labels {
+		spans = append(spans, label.Span)
+	}
+	r.gutter = 1
+	for _, span := range spans {
+		line, _ := r.position(span.End)
+		if width := len(strconv.Itoa(line + r.opts.Context)); width > r.gutter {
+			r.gutter = width
+		}
+	}
+
+	severityStyle := styleError
+	switch d.Severity {
+	case SeverityWarning:
+		severityStyle = styleWarn
+	case SeverityInfo:
+		severityStyle = styleInfo
+	}
+	header := d.Severity.String()
+	if d.Code != "" {
+		header += "[" + string(d.Code) + "]"
+	}
+	r.style(severityStyle, header)
+	r.style(styleBold, ": "+d.Message)
+	r.b.WriteByte('\n')
+	r.snippet(d.Span, "", '^', severityStyle)
+	for _, label := range d.Labels {
+		r.snippet(label.Span, label.Message, '-', styleGutter)
+	}
+	if d.Help != "" {
+		r.note("help", d.Help)
+	}
+	for _, fix := range d.Fixes {
+		r.note("fix", fix.Message)
+	}
+}
+
+func (r *snippetRenderer) note(kind, text string) {
+	r.b.WriteString(strings.Repeat(" ", r.gutter) + " ")
+	r.style(styleGutter, "=")
+	r.style(styleBold, " "+kind+":")
+	r.b.WriteString(" " + text + "\n")
+}
+
+func (r *snippetRenderer) position(offset int) (line, column int) {
+	return position(r.source, offset)
+}
+
+// position returns the 1-based line and character column of offset in
+// source, counting columns like Token.Column.
+func position(source string, offset int) (line, column int) {
+	offset = min(max(offset, 0), len(source))
+	lineStart := strings.LastIndexByte(source[:offset], '\n') + 1
+	line = strings.Count(source[:lineStart], "\n") + 1
+	return line, utf8.RuneCountInString(source[lineStart:offset]) + 1
+}
+
+// sourceLine locates a line of the source: its 1-based number and the
+// offsets of its first byte and of its line break or the end of input.
+type sourceLine struct{ number, start, end int }
+
+// lineAt returns the line starting at offset start.
+func (r *snippetRenderer) lineAt(number, start int) sourceLine {
+	end := strings.IndexByte(r.source[start:], '\n')
+	if end < 0 {
+		return sourceLine{number, start, len(r.source)}
+	}
+	return sourceLine{number, start, start + end}
+}
+
+// snippet writes the location of span followed by the source lines it
+// covers, each underlined with marker, and RenderOptions.Context lines
+// around them.
+func (r *snippetRenderer) snippet(span Span, msg string, marker byte, markerStyle string) {
+	location := r.opts.Filename
+	if location != "" {
+		location += ":"
+	}
+	if !span.Valid(len(r.source)) {
+		location = "[invalid span " + strconv.Itoa(span.Start) + "-" + strconv.Itoa(span.End) + "]"
+	} else {
+		line, column := r.position(span.Start)
+		location += strconv.Itoa(line) + ":" + strconv.Itoa(column)
+	}
+	r.b.WriteString(strings.Repeat(" ", r.gutter))
+	r.style(styleGutter, "-->")
+	r.b.WriteString(" " + location)
+	if msg != "" {
+		r.b.WriteString(": " + msg)
+	}
+	r.b.WriteByte('\n')
+	if !span.Valid(len(r.source)) {
+		return
+	}
+
+	number, _ := r.position(span.Start)
+	var lines []sourceLine
+	for l := r.lineAt(number, strings.LastIndexByte(r.source[:span.Start], '\n')+1); ; {
+		lines = append(lines, l)
+		// A span ending with a line break does not cover the next line.
+		if l.end+1 >= span.End || l.end == len(r.source) {
+			break
+		}
+		l = r.lineAt(l.number+1, l.end+1)
+	}
+
+	// Context before the span, nearest line last.
+	var before []sourceLine
+	for start := lines[0].start; len(before) < r.opts.Context && start > 0; {
+		prev := strings.LastIndexByte(r.source[:start-1], '\n') + 1
+		before = append([]sourceLine{r.lineAt(lines[0].number-len(before)-1, prev)}, before...)
+		start = prev
+	}
+	for _, l := range before {
+		r.line(l, -1, -1, marker, markerStyle)
+	}
+
+	for i, l := range lines {
+		if len(lines) > maxSnippetLines && i >= maxSnippetLines-1 && i < len(lines)-1 {
+			if i == maxSnippetLines-1 {
+				r.b.WriteString(strings.Repeat(" ", r.gutter) + " ")
+				r.style(styleGutter, "|")
+				r.b.WriteString(" ...\n")
+			}
+			continue
+		}
+		r.line(l, span.Start-l.start, span.End-l.start, marker, markerStyle)
+	}
+
+	last := lines[len(lines)-1]
+	// The empty remainder after a final line break is not a line.
+	for i := 0; i < r.opts.Context && last.end+1 < len(r.source); i++ {
+		last = r.lineAt(last.number+1, last.end+1)
+		r.line(last, -1, -1, marker, markerStyle)
+	}
+}
+
+// line writes one numbered source line with marker under bytes [from, to).
+// An empty range is marked with a single marker, and a negative one, for
+// context lines, with none.
+func (r *snippetRenderer) line(l sourceLine, from, to int, marker byte, markerStyle string) {
+	text := strings.TrimSuffix(r.source[l.start:l.end], "\r")
+	num := strconv.Itoa(l.number)
+	r.style(styleGutter, strings.Repeat(" ", r.gutter-len(num))+num+" |")
+	if text != "" {
+		r.b.WriteString(" " + text)
+	}
+	r.b.WriteByte('\n')
+	if to < 0 {
+		return
+	}
+
+	to = min(to, len(text))
+	from = min(max(from, 0), to)
+	r.b.WriteString(strings.Repeat(" ", r.gutter) + " ")
+	r.style(styleGutter, "|")
+	r.b.WriteByte(' ')
+	// Tabs are kept so that the markers line up with the text above.
+	for _, ch := range text[:from] {
+		if ch == '\t' {
+			r.b.WriteByte('\t')
+		} else {
+			r.b.WriteByte(' ')
+		}
+	}
+	r.style(markerStyle, strings.Repeat(string(marker), max(utf8.RuneCountInString(text[from:to]), 1)))
+	r.b.WriteByte('\n')
+}
@@ -0,0 +1,86 @@
+package styx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderDiagnostic(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"a 1\na 2", "" +
+			"error[STYX0011]: duplicate key\n" +
+			" --> 2:1\n" +
+			"2 | a 2\n" +
+			"  | ^\n" +
+			" --> 1:1: first defined here\n" +
+			"1 | a 1\n" +
+			"  | -\n"},
+		{"a 1\nb \"é\\q\"", "" +
+			"error[STYX0009]: invalid escape sequence: \\q\n" +
+			" --> 2:5\n" +
+			"2 | b \"é\\q\"\n" +
+			"  |     ^^\n"},
+		{"x r\"a\n\n\nb\nc\nd", "" +
+			"error[STYX0006]: unclosed raw string (missing `\"`)\n" +
+			" --> 1:3\n" +
+			"1 | x r\"a\n" +
+			"  |   ^^^\n" +
+			"2 |\n" +
+			"  | ^\n" +
+			"3 |\n" +
+			"  | ^\n" +
+			"  | ...\n" +
+			"6 | d\n" +
+			"  | ^\n" +
+			" --> 1:3: raw string opened here\n" +
+			"1 | x r\"a\n" +
+			"  |   --\n" +
+			" --> 6:2: expected `\"` before the end of input\n" +
+			"6 | d\n" +
+			"  |  -\n" +
+			"  = help: close the raw string with `\"`\n"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.source)
+		if got := RenderDiagnostic(tt.source, err); got != tt.want {
+			t.Errorf("%q: got\n%s\nwant\n%s", tt.source, got, tt.want)
+		}
+	}
+
+	if got := RenderDiagnostic("", errors.New("read failed")); got != "read failed\n" {
+		t.Errorf("plain error rendered as %q", got)
+	}
+}
+
+func TestRenderDiagnostics(t *testing.T) {
+	source := "a 1\nb 2\nc 3\nd \"\\q\"\ne 5\nf 6\n"
+	_, diags := Diagnose(source+"warn: 1\n", ParseOptions{})
+	got := RenderDiagnostics(source+"warn: 1\n", diags, RenderOptions{Context: 1, Filename: "x.styx"})
+	want := "" +
+		"error[STYX0009]: invalid escape sequence: \\q\n" +
+		" --> x.styx:4:4\n" +
+		"3 | c 3\n" +
+		"4 | d \"\\q\"\n" +
+		"  |    ^^\n" +
+		"5 | e 5\n" +
+		"\n" +
+		"warning[STYX0023]: key `warn:` ends with `:`\n" +
+		" --> x.styx:7:5\n" +
+		"6 | f 6\n" +
+		"7 | warn: 1\n" +
+		"  |     ^\n" +
+		"  = help: keys and values are separated by whitespace alone; the `:` is part of the key\n" +
+		"  = fix: remove the `:`\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	colored := RenderDiagnostics(source, diags[:1], RenderOptions{Color: true})
+	if !strings.Contains(colored, styleError+"error[STYX0009]"+styleReset) || !strings.Contains(colored, styleError+"^^"+styleReset) {
+		t.Errorf("colored output lacks styles: %q", colored)
+	}
+}
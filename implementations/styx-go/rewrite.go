@@ -0,0 +1,69 @@
+package styx
+
+// RewriteFunc transforms a value during Rewrite. It receives a copy of the
+// value whose children have already been rewritten and returns the value to
+// use in its place, which may be the argument itself after modification.
+// Returning false as the second result removes the value: an item is dropped
+// from its sequence, and an entry is dropped from its object when either its
+// key or its value is removed.
+type RewriteFunc func(v *Value) (*Value, bool)
+
+// Rewrite returns a copy of doc with fn applied to every key and value,
+// bottom-up: children are rewritten before their parents. The original
+// document is left untouched, so fn may freely modify the values it is given.
+func Rewrite(doc *Document, fn RewriteFunc) *Document {
+	return &Document{
+		Entries: rewriteEntries(doc.Entries, fn),
+		Span:    doc.Span,
+	}
+}
+
+// RewriteValue is like Rewrite but operates on a single value subtree. It
+// returns false if fn removed the root value.
+func RewriteValue(v *Value, fn RewriteFunc) (*Value, bool) {
+	return rewriteValue(v, fn)
+}
+
+func rewriteEntries(entries []*Entry, fn RewriteFunc) []*Entry {
+	result := make([]*Entry, 0, len(entries))
+	for _, entry := range entries {
+		key, ok := rewriteValue(entry.Key, fn)
+		if !ok {
+			continue
+		}
+		value, ok := rewriteValue(entry.Value, fn)
+		if !ok {
+			continue
+		}
+		rewritten := *entry
+		rewritten.Key, rewritten.Value = key, value
+		result = append(result, &rewritten)
+	}
+	return result
+}
+
+func rewriteValue(v *Value, fn RewriteFunc) (*Value, bool) {
+	c := *v
+	if v.Tag != nil {
+		tag := *v.Tag
+		c.Tag = &tag
+	}
+	switch v.PayloadKind {
+	case PayloadScalar:
+		scalar := *v.Scalar
+		c.Scalar = &scalar
+	case PayloadSequence:
+		items := make([]*Value, 0, len(v.Sequence.Items))
+		for _, item := range v.Sequence.Items {
+			if rewritten, ok := rewriteValue(item, fn); ok {
+				items = append(items, rewritten)
+			}
+		}
+		c.Sequence = &Sequence{Items: items, Span: v.Sequence.Span}
+	case PayloadObject:
+		obj := *v.Object
+		obj.Entries = rewriteEntries(v.Object.Entries, fn)
+		c.Object = &obj
+	}
+	return fn(&c)
+}
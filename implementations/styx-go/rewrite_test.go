@@ -0,0 +1,41 @@
+package styx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	doc := mustParse(t, "token @env\"TOKEN\"\nitems (a @drop b)\nnested {kind @lower}\n")
+
+	rewritten := Rewrite(doc, func(v *Value) (*Value, bool) {
+		if v.Tag != nil && v.Tag.Name == "drop" {
+			return nil, false
+		}
+		if v.Tag != nil && v.Tag.Name == "env" {
+			return &Value{Span: v.Span, PayloadKind: PayloadScalar, Scalar: &Scalar{Text: "resolved", Kind: ScalarQuoted, Span: v.Span}}, true
+		}
+		if v.Tag != nil {
+			v.Tag.Name = strings.ToUpper(v.Tag.Name)
+		}
+		return v, true
+	})
+
+	want := map[string]any{
+		"token":  "resolved",
+		"items":  []any{"a", "b"},
+		"nested": map[string]any{"kind": Tagged{Tag: "LOWER"}},
+	}
+	if got := rewritten.Interface(); !reflect.DeepEqual(got, want) {
+		t.Errorf("rewritten = %#v, want %#v", got, want)
+	}
+
+	// The original document is unchanged.
+	if name := doc.Entries[2].Value.Object.Entries[0].Value.Tag.Name; name != "lower" {
+		t.Errorf("original tag modified: %q", name)
+	}
+	if n := len(doc.Entries[1].Value.Sequence.Items); n != 3 {
+		t.Errorf("original sequence modified: %d items", n)
+	}
+}
@@ -0,0 +1,203 @@
+package styx
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+)
+
+// SARIF output follows the SARIF 2.1.0 standard, as consumed by GitHub code
+// scanning and other CI dashboards.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string            `json:"ruleId,omitempty"`
+	RuleIndex        *int              `json:"ruleIndex,omitempty"`
+	Level            string            `json:"level"`
+	Message          sarifMessage      `json:"message"`
+	Locations        []sarifLocation   `json:"locations"`
+	RelatedLocations []sarifLocation   `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix        `json:"fixes,omitempty"`
+	Properties       map[string]string `json:"properties,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion   `json:"deletedRegion"`
+	InsertedContent *sarifMessage `json:"insertedContent,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+	ByteOffset  int `json:"byteOffset"`
+	ByteLength  int `json:"byteLength"`
+}
+
+// WriteSARIF writes diags to w as a SARIF 2.1.0 log with a single run.
+// Each distinct error code becomes a rule. Diagnostics should carry a file
+// name, which becomes the artifact URI of their locations.
+func WriteSARIF(w io.Writer, diags []JSONDiagnostic) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "styx-go",
+			InformationURI: "https://github.com/bearcove/styx",
+		}},
+		// Columns count characters, as in JSONSpan.
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+	ruleIndex := make(map[Code]int)
+	for _, d := range diags {
+		result := sarifResult{
+			Level:     sarifLevel(d.Severity),
+			Message:   sarifMessage{d.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysical(d.File, d.Span)}},
+		}
+		if d.Code != "" {
+			index, ok := ruleIndex[d.Code]
+			if !ok {
+				index = len(run.Tool.Driver.Rules)
+				ruleIndex[d.Code] = index
+				rule := sarifRule{ID: string(d.Code), ShortDescription: sarifMessage{string(d.Code)}}
+				if sentinel := codeErrors[d.Code]; sentinel != nil {
+					rule.ShortDescription.Text = sentinel.Error()
+				} else if summary, ok := warningSummaries[d.Code]; ok {
+					rule.ShortDescription.Text = summary
+				}
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+			}
+			result.RuleID, result.RuleIndex = string(d.Code), &index
+		}
+		for i, label := range d.Labels {
+			id := i + 1
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				ID:               &id,
+				PhysicalLocation: sarifPhysical(d.File, label.Span),
+				Message:          &sarifMessage{label.Message},
+			})
+		}
+		for _, fix := range d.Fixes {
+			change := sarifArtifactChange{ArtifactLocation: sarifArtifact(d.File)}
+			for _, edit := range fix.Edits {
+				replacement := sarifReplacement{DeletedRegion: sarifRegionOf(edit.Span)}
+				if edit.Text != "" {
+					replacement.InsertedContent = &sarifMessage{edit.Text}
+				}
+				change.Replacements = append(change.Replacements, replacement)
+			}
+			result.Fixes = append(result.Fixes, sarifFix{
+				Description:     sarifMessage{fix.Message},
+				ArtifactChanges: []sarifArtifactChange{change},
+			})
+		}
+		if d.Help != "" {
+			result.Properties = map[string]string{"help": d.Help}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case "warning":
+		return "warning"
+	case "info":
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+// sarifPhysical locates span in file. A diagnostic without a valid span,
+// such as one about the input as a whole, locates the file alone.
+func sarifPhysical(file string, span JSONSpan) sarifPhysicalLocation {
+	loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifact(file)}
+	if span.Start >= 0 && span.End >= span.Start {
+		region := sarifRegionOf(span)
+		loc.Region = &region
+	}
+	return loc
+}
+
+func sarifArtifact(file string) sarifArtifactLocation {
+	// Relative paths are relative URI references; SARIF consumers resolve
+	// them against the repository root.
+	return sarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(file)}).String()}
+}
+
+func sarifRegionOf(span JSONSpan) sarifRegion {
+	return sarifRegion{
+		StartLine:   span.StartLine,
+		StartColumn: span.StartColumn,
+		EndLine:     span.EndLine,
+		EndColumn:   span.EndColumn,
+		ByteOffset:  span.Start,
+		ByteLength:  span.End - span.Start,
+	}
+}
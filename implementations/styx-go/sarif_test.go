@@ -0,0 +1,54 @@
+package styx
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	source := "a 1\na 2\nb {"
+	_, diags := Diagnose(source, ParseOptions{})
+	var out []JSONDiagnostic
+	for _, d := range diags {
+		out = append(out, d.JSON("dir/my config.styx", source))
+	}
+	var b strings.Builder
+	if err := WriteSARIF(&b, out); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal([]byte(b.String()), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("got %s", b.String())
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 2 {
+		t.Fatalf("got %d rules and %d results", len(run.Tool.Driver.Rules), len(run.Results))
+	}
+	dup := run.Results[0]
+	if dup.RuleID != "STYX0011" || *dup.RuleIndex != 0 || dup.Level != "error" || len(dup.RelatedLocations) != 1 {
+		t.Errorf("got %+v", dup)
+	}
+	loc := dup.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "dir/my%20config.styx" || loc.Region == nil || *loc.Region != (sarifRegion{2, 1, 2, 2, 4, 1}) {
+		t.Errorf("got location %+v", loc)
+	}
+	if run.Tool.Driver.Rules[1].ID != "STYX0003" || *run.Results[1].RuleIndex != 1 {
+		t.Errorf("got rules %+v", run.Tool.Driver.Rules)
+	}
+}
+
+func TestWriteSARIFWithoutSpan(t *testing.T) {
+	d := Diagnostic{Severity: SeverityError, Message: "too large", Span: noSpan}
+	var b strings.Builder
+	if err := WriteSARIF(&b, []JSONDiagnostic{d.JSON("big.styx", "")}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "region") || strings.Contains(b.String(), "-1") {
+		t.Errorf("got %s", b.String())
+	}
+}
@@ -0,0 +1,218 @@
+package styx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// durationPattern matches the durations time.ParseDuration accepts.
+const durationPattern = `^[-+]?(0|([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the documents
+// Value.Decode accepts into values of type t, as converted by ToJSON with
+// InferTypes and TagDrop, so that a configuration format can be published
+// and checked by editors and validators. Properties follow the struct's
+// fields, named as Decode matches them, and are all optional, as Decode
+// leaves fields without a key untouched; other keys are allowed, as Decode
+// ignores them. Named struct types other than t are described under
+// "$defs", which lets recursive types refer to themselves. Types Decode
+// cannot fill, such as channels and maps with non-string keys, fail.
+func JSONSchema(t reflect.Type) ([]byte, error) {
+	g := schemaGenerator{names: make(map[reflect.Type]string), taken: make(map[string]bool)}
+	root, err := g.schema(t, true)
+	if err != nil {
+		return nil, err
+	}
+	root.Schema = "https://json-schema.org/draft/2020-12/schema"
+	root.Title = t.Name()
+	root.Defs = g.defs
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// jsonSchema is the subset of JSON Schema JSONSchema writes, in the order
+// it writes it.
+type jsonSchema struct {
+	Schema               string           `json:"$schema,omitempty"`
+	Ref                  string           `json:"$ref,omitempty"`
+	Title                string           `json:"title,omitempty"`
+	Type                 string           `json:"type,omitempty"`
+	Format               string           `json:"format,omitempty"`
+	Pattern              string           `json:"pattern,omitempty"`
+	Minimum              *float64         `json:"minimum,omitempty"`
+	Maximum              *float64         `json:"maximum,omitempty"`
+	Items                *jsonSchema      `json:"items,omitempty"`
+	Properties           schemaProperties `json:"properties,omitempty"`
+	AdditionalProperties *jsonSchema      `json:"additionalProperties,omitempty"`
+	Defs                 schemaProperties `json:"$defs,omitempty"`
+}
+
+// schemaProperties are named schemas, written as a JSON object in order.
+type schemaProperties []namedSchema
+
+type namedSchema struct {
+	name   string
+	schema *jsonSchema
+}
+
+func (p schemaProperties) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(prop.name)
+		b.Write(name)
+		b.WriteByte(':')
+		schema, err := json.Marshal(prop.schema)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(schema)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+type schemaGenerator struct {
+	// path holds the keys of the fields being described, for errors.
+	path []string
+	// names holds the $defs name of each named struct type seen.
+	names map[reflect.Type]string
+	taken map[string]bool
+	defs  schemaProperties
+}
+
+// schema describes t. Named struct types are described once under $defs
+// and referred to, except at the root.
+func (g *schemaGenerator) schema(t reflect.Type, root bool) (*jsonSchema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &jsonSchema{Type: "string", Format: "date-time"}, nil
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return &jsonSchema{Type: "string"}, nil
+	case t == durationType:
+		return &jsonSchema{Type: "string", Pattern: durationPattern}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return &jsonSchema{}, nil
+		}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := &jsonSchema{Type: "integer"}
+		if t.Bits() < 64 {
+			lo, hi := float64(int64(-1)<<(t.Bits()-1)), float64(int64(1)<<(t.Bits()-1)-1)
+			s.Minimum, s.Maximum = &lo, &hi
+		}
+		return s, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		lo := 0.0
+		s := &jsonSchema{Type: "integer", Minimum: &lo}
+		if t.Bits() < 64 {
+			hi := float64(uint64(1)<<t.Bits() - 1)
+			s.Maximum = &hi
+		}
+		return s, nil
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}, nil
+	case reflect.Slice:
+		items, err := g.schema(t.Elem(), false)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonSchema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		values, err := g.schema(t.Elem(), false)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonSchema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if root || t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.defName(t)
+			g.names[t] = name
+			i := len(g.defs)
+			g.defs = append(g.defs, namedSchema{name: name})
+			s, err := g.structSchema(t)
+			if err != nil {
+				return nil, err
+			}
+			g.defs[i].schema = s
+		}
+		return &jsonSchema{Ref: "#/$defs/" + name}, nil
+	}
+	if len(g.path) == 0 {
+		return nil, fmt.Errorf("styx: JSONSchema: unsupported type %s", t)
+	}
+	return nil, fmt.Errorf("styx: JSONSchema: %s: unsupported type %s", strings.Join(g.path, "."), t)
+}
+
+// defName returns an unused $defs name for t: its name, qualified by its
+// package if another type took the name, and numbered if need be.
+func (g *schemaGenerator) defName(t reflect.Type) string {
+	name := t.Name()
+	if g.taken[name] {
+		name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + t.Name()
+	}
+	for i, base := 2, name; g.taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.taken[name] = true
+	return name
+}
+
+// structSchema describes the fields of t that Decode fills.
+func (g *schemaGenerator) structSchema(t reflect.Type) (*jsonSchema, error) {
+	s := &jsonSchema{Type: "object", Properties: schemaProperties{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _ := f.Tag.Lookup("styx")
+		name, _, _ = strings.Cut(name, ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		g.path = append(g.path, name)
+		prop, err := g.schema(f.Type, false)
+		g.path = g.path[:len(g.path)-1]
+		if err != nil {
+			return nil, err
+		}
+		s.Properties = append(s.Properties, namedSchema{name, prop})
+	}
+	return s, nil
+}
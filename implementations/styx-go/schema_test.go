@@ -0,0 +1,140 @@
+package styx
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaNode struct {
+	Name     string
+	Children []*schemaNode
+}
+
+type schemaConfig struct {
+	Name     string
+	Port     uint16 `styx:"listen_port"`
+	Debug    bool
+	Ratio    float64
+	Timeout  time.Duration
+	Since    time.Time
+	Hosts    []string
+	Labels   map[string]string
+	Server   *struct{ Host string }
+	Tree     schemaNode
+	Extra    any
+	Ignored  string `styx:"-"`
+	internal string
+}
+
+func TestJSONSchema(t *testing.T) {
+	out, err := JSONSchema(reflect.TypeFor[schemaConfig]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "schemaConfig",
+  "type": "object",
+  "properties": {
+    "Name": {
+      "type": "string"
+    },
+    "listen_port": {
+      "type": "integer",
+      "minimum": 0,
+      "maximum": 65535
+    },
+    "Debug": {
+      "type": "boolean"
+    },
+    "Ratio": {
+      "type": "number"
+    },
+    "Timeout": {
+      "type": "string",
+      "pattern": "^[-+]?(0|([0-9]*(\\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$"
+    },
+    "Since": {
+      "type": "string",
+      "format": "date-time"
+    },
+    "Hosts": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      }
+    },
+    "Labels": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "string"
+      }
+    },
+    "Server": {
+      "type": "object",
+      "properties": {
+        "Host": {
+          "type": "string"
+        }
+      }
+    },
+    "Tree": {
+      "$ref": "#/$defs/schemaNode"
+    },
+    "Extra": {}
+  },
+  "$defs": {
+    "schemaNode": {
+      "type": "object",
+      "properties": {
+        "Name": {
+          "type": "string"
+        },
+        "Children": {
+          "type": "array",
+          "items": {
+            "$ref": "#/$defs/schemaNode"
+          }
+        }
+      }
+    }
+  }
+}
+`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	if !json.Valid(out) {
+		t.Error("output is not valid JSON")
+	}
+}
+
+func TestJSONSchemaDecodable(t *testing.T) {
+	// Every type the schema accepts is one Decode fills.
+	doc := mustParse(t, "listen_port 8080\nTimeout 1m30s\nSince 2024-01-02T03:04:05Z\nTree {Name root, Children ({Name leaf})}\n")
+	var cfg schemaConfig
+	if err := doc.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8080 || cfg.Timeout != 90*time.Second || cfg.Tree.Children[0].Name != "leaf" {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestJSONSchemaErrors(t *testing.T) {
+	type bad struct {
+		Outer struct {
+			Events chan int
+		}
+	}
+	_, err := JSONSchema(reflect.TypeFor[bad]())
+	if err == nil || !strings.Contains(err.Error(), "Outer.Events: unsupported type chan int") {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := JSONSchema(reflect.TypeFor[map[int]string]()); err == nil {
+		t.Error("map with int keys accepted")
+	}
+}
@@ -0,0 +1,109 @@
+package styx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FormatSexp renders doc as the s-expression tree used by the compliance
+// suite, which the reference implementation prints with
+// `styx tree --format sexp`. Every node carries its span, so the dump
+// compares parsers exactly:
+//
+//	(document [-1, -1]
+//	  (entry
+//	    (scalar [0, 4] bare "name")
+//	    (scalar [5, 10] quoted "app")))
+func FormatSexp(doc *Document) string {
+	if len(doc.Entries) == 0 {
+		return "(document [-1, -1]\n)"
+	}
+	var entries []string
+	for _, entry := range doc.Entries {
+		entries = append(entries, sexpEntry(entry, 1))
+	}
+	return fmt.Sprintf("(document [-1, -1]\n%s\n)", strings.Join(entries, "\n"))
+}
+
+// FormatErrorSexp renders a parse error as the compliance suite's error
+// node. Errors that do not wrap a *ParseError have no span.
+func FormatErrorSexp(err error) string {
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		return fmt.Sprintf("(error [-1, -1] \"parse error: %s\")", escapeSexp(err.Error()))
+	}
+	return fmt.Sprintf("(error [%d, %d] \"parse error at %d-%d: %s\")", pe.Span.Start, pe.Span.End, pe.Span.Start, pe.Span.End, escapeSexp(pe.Message))
+}
+
+func escapeSexp(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	s = strings.ReplaceAll(s, "\r", "\\r")
+	s = strings.ReplaceAll(s, "\t", "\\t")
+	return s
+}
+
+func sexpEntry(entry *Entry, indent int) string {
+	prefix := strings.Repeat("  ", indent)
+	keyStr := sexpValue(entry.Key, indent+1)
+	valueStr := sexpValue(entry.Value, indent+1)
+	return fmt.Sprintf("%s(entry\n%s  %s\n%s  %s)", prefix, prefix, keyStr, prefix, valueStr)
+}
+
+func sexpValue(value *Value, indent int) string {
+	prefix := strings.Repeat("  ", indent)
+
+	// Unit value (no tag, no payload)
+	if value.Tag == nil && value.PayloadKind == PayloadNone {
+		return fmt.Sprintf("(unit [%d, %d])", value.Span.Start, value.Span.End)
+	}
+
+	// Tag only (no payload)
+	if value.Tag != nil && value.PayloadKind == PayloadNone {
+		return fmt.Sprintf("(tag [%d, %d] \"%s\")", value.Span.Start, value.Span.End, value.Tag.Name)
+	}
+
+	// Tag with payload
+	if value.Tag != nil {
+		payloadStr := sexpPayload(value, indent+1)
+		return fmt.Sprintf("(tag [%d, %d] \"%s\"\n%s  %s)", value.Span.Start, value.Span.End, value.Tag.Name, prefix, payloadStr)
+	}
+
+	return sexpPayload(value, indent)
+}
+
+func sexpPayload(value *Value, indent int) string {
+	prefix := strings.Repeat("  ", indent)
+
+	switch value.PayloadKind {
+	case PayloadScalar:
+		escaped := escapeSexp(value.Scalar.Text)
+		return fmt.Sprintf("(scalar [%d, %d] %s \"%s\")", value.Scalar.Span.Start, value.Scalar.Span.End, value.Scalar.Kind, escaped)
+
+	case PayloadSequence:
+		seq := value.Sequence
+		if len(seq.Items) == 0 {
+			return fmt.Sprintf("(sequence [%d, %d])", seq.Span.Start, seq.Span.End)
+		}
+		var items []string
+		for _, item := range seq.Items {
+			items = append(items, fmt.Sprintf("%s  %s", prefix, sexpValue(item, indent+1)))
+		}
+		return fmt.Sprintf("(sequence [%d, %d]\n%s)", seq.Span.Start, seq.Span.End, strings.Join(items, "\n"))
+
+	case PayloadObject:
+		obj := value.Object
+		if len(obj.Entries) == 0 {
+			return fmt.Sprintf("(object [%d, %d])", obj.Span.Start, obj.Span.End)
+		}
+		var entries []string
+		for _, entry := range obj.Entries {
+			entries = append(entries, sexpEntry(entry, indent+1))
+		}
+		return fmt.Sprintf("(object [%d, %d]\n%s\n%s)", obj.Span.Start, obj.Span.End, strings.Join(entries, "\n"), prefix)
+	}
+
+	return "(unknown)"
+}
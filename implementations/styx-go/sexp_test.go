@@ -0,0 +1,28 @@
+package styx
+
+import "testing"
+
+func TestFormatSexp(t *testing.T) {
+	doc, err := Parse("name \"a\\tb\"\nlist (@x @)\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `(document [-1, -1]
+  (entry
+    (scalar [0, 4] bare "name")
+    (scalar [5, 11] quoted "a\tb"))
+  (entry
+    (scalar [12, 16] bare "list")
+    (sequence [17, 23]
+      (tag [18, 20] "x")
+      (unit [21, 22])))
+)`
+	if got := FormatSexp(doc); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	_, err = Parse("a 1\na 2\n")
+	if got, want := FormatErrorSexp(err), `(error [4, 5] "parse error at 4-5: duplicate key")`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -1,7 +1,11 @@
 // Package styx provides a parser for the Styx configuration language.
 package styx
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"strings"
+)
 
 // Span represents a byte range in the source.
 type Span struct {
@@ -9,14 +13,80 @@ type Span struct {
 	End   int
 }
 
+// Valid reports whether the span lies within a source of the given length.
+// Synthesized nodes use the span {-1, -1}, which is never valid.
+func (s Span) Valid(length int) bool {
+	return s.Start >= 0 && s.Start <= s.End && s.End <= length
+}
+
+// Slice returns the text of source covered by the span, or "" if the span
+// does not lie within source.
+func (s Span) Slice(source string) string {
+	if !s.Valid(len(source)) {
+		return ""
+	}
+	return source[s.Start:s.End]
+}
+
 // ParseError represents a parse error with location information.
 type ParseError struct {
 	Message string
 	Span    Span
+	// Expected names the missing closing delimiter of an unterminated
+	// construct, such as `"##` or a heredoc's delimiter word. It is empty
+	// for other errors.
+	Expected string
+	// Related holds secondary locations that help explain the error, such
+	// as where a missing delimiter was expected or where a duplicate key was
+	// first defined.
+	Related []Label
+	// Code identifies the kind of error independently of its message.
+	Code Code
+	// Args holds the values interpolated into Message, in order, such as
+	// the path of a reopened path or the text of an invalid escape. It is
+	// empty for messages without variable parts.
+	Args []string
+	// Help suggests how to fix the error; it may be empty.
+	Help string
+	// Filename, Line and Column locate the error for people: Filename comes
+	// from ParseOptions.Filename, and Line and Column, 1-based with columns
+	// counted in characters, are resolved from Span when the parse function
+	// has the source. Line is zero when unknown.
+	Filename string
+	Line     int
+	Column   int
+	// Excerpt is the source text under Span when it is short and on one
+	// line, quoted by Error.
+	Excerpt string
+	// Fixes holds machine-applicable edits that resolve the error, for
+	// editors offering quick fixes. Most errors have none.
+	Fixes []Fix
+
+	cause error
 }
 
+// Label is a secondary location attached to an error or diagnostic.
+type Label struct {
+	Message string
+	Span    Span
+}
+
+// Error formats the error as "file:line:column: message", followed by the
+// quoted excerpt if there is one. The file is left out when unnamed. Errors
+// without a line fall back to byte offsets: "parse error at 4-5: message".
 func (e *ParseError) Error() string {
-	return fmt.Sprintf("parse error at %d-%d: %s", e.Span.Start, e.Span.End, e.Message)
+	if e.Line == 0 {
+		return fmt.Sprintf("parse error at %d-%d: %s", e.Span.Start, e.Span.End, e.Message)
+	}
+	var b strings.Builder
+	if e.Filename != "" {
+		b.WriteString(e.Filename + ":")
+	}
+	fmt.Fprintf(&b, "%d:%d: %s", e.Line, e.Column, e.Message)
+	if e.Excerpt != "" {
+		fmt.Fprintf(&b, " %q", e.Excerpt)
+	}
+	return b.String()
 }
 
 // ScalarKind represents the kind of scalar value.
@@ -44,23 +114,138 @@ func (k ScalarKind) String() string {
 	}
 }
 
+// MarshalText returns the kind's name, as String does.
+func (k ScalarKind) MarshalText() ([]byte, error) {
+	return marshalEnum(k, "ScalarKind")
+}
+
+// UnmarshalText sets the kind from its name.
+func (k *ScalarKind) UnmarshalText(text []byte) error {
+	return unmarshalEnum(k, text, "ScalarKind", ScalarHeredoc)
+}
+
+// enum is implemented by the enumerations with names, which count up from
+// zero.
+type enum interface {
+	~int
+	String() string
+}
+
+// marshalEnum returns the name of k, failing for values without one.
+func marshalEnum[K enum](k K, typ string) ([]byte, error) {
+	name := k.String()
+	if name == "unknown" {
+		return nil, fmt.Errorf("styx: invalid %s %d", typ, int(k))
+	}
+	return []byte(name), nil
+}
+
+// unmarshalEnum sets *k to the value up to last named text.
+func unmarshalEnum[K enum](k *K, text []byte, typ string, last K) error {
+	for v := K(0); v <= last; v++ {
+		if v.String() == string(text) {
+			*k = v
+			return nil
+		}
+	}
+	return fmt.Errorf("styx: unknown %s %q", typ, text)
+}
+
 // Scalar represents a scalar value.
 type Scalar struct {
+	// Text is the decoded value, with escapes resolved and delimiters removed.
 	Text string
+	// Raw is the scalar exactly as written in the source, including quotes,
+	// hashes, escapes and heredoc delimiters.
+	Raw  string
 	Kind ScalarKind
 	Span Span
+	// HeredocDelimiter is the delimiter word of a heredoc scalar (`SQL` in
+	// `<<SQL,sql`); it is empty for other kinds.
+	HeredocDelimiter string
+	// HeredocOptions holds the comma-separated options following the
+	// heredoc delimiter, such as a language hint.
+	HeredocOptions []HeredocOption
+	// HeredocLanguage is the heredoc's language hint: a bare option, as in
+	// `<<SQL,sql`, or the value of a lang option, as in `<<CFG,lang=toml`.
+	// It is empty if there is none.
+	HeredocLanguage string
+}
+
+// HeredocOption is one option following a heredoc delimiter. An option
+// written `name=value` is split at the first `=`; a bare word such as a
+// language hint has only a Name.
+type HeredocOption struct {
+	Name  string
+	Value string
+	// Span covers the option's text, excluding the preceding comma.
+	Span Span
 }
 
 // Tag represents a tag annotation.
 type Tag struct {
 	Name string
+	// Span covers the whole tag including the leading `@`.
 	Span Span
+	// NameSpan covers just the tag name, without the `@`.
+	NameSpan Span
 }
 
 // Entry represents a key-value entry in an object.
 type Entry struct {
 	Key   *Value
 	Value *Value
+	// LeadingComments are the comments on their own lines directly before
+	// the entry, including doc comments.
+	LeadingComments []*Comment
+	// TrailingComment is the comment following the entry on its last line.
+	TrailingComment *Comment
+
+	// path records the key path of a document-level entry, as validated by
+	// the parser, so that Reparse can revalidate spliced documents.
+	path     []string
+	pathKind pathValueKind
+}
+
+// Span returns the byte range covering the entry's key through its value.
+// Implicit unit keys (as produced for a root object) have no location, so the
+// entry then starts at its value.
+func (e *Entry) Span() Span {
+	start := e.Key.Span.Start
+	if start < 0 {
+		start = e.Value.Span.Start
+	}
+	end := e.Value.Span.End
+	if end < e.Key.Span.End {
+		end = e.Key.Span.End
+	}
+	return Span{start, end}
+}
+
+// KeySpan returns the byte range of the entry's key.
+func (e *Entry) KeySpan() Span {
+	return e.Key.Span
+}
+
+// ValueSpan returns the byte range of the entry's value.
+func (e *Entry) ValueSpan() Span {
+	return e.Value.Span
+}
+
+// KeyText returns the text used to identify the entry's key: the scalar text
+// for scalar keys, "@name" for tag-only keys, and "" otherwise.
+func (e *Entry) KeyText() string {
+	return keyText(e.Key)
+}
+
+func keyText(key *Value) string {
+	if key.PayloadKind == PayloadScalar {
+		return key.Scalar.Text
+	}
+	if key.Tag != nil && key.PayloadKind == PayloadNone {
+		return "@" + key.Tag.Name
+	}
+	return ""
 }
 
 // Sequence represents a sequence of values.
@@ -73,6 +258,9 @@ type Sequence struct {
 type Object struct {
 	Entries []*Entry
 	Span    Span
+	// Attributes is true when the object was written in attribute syntax
+	// (`method>GET path>/users`) rather than with braces.
+	Attributes bool
 }
 
 // PayloadKind identifies the type of payload in a Value.
@@ -85,6 +273,31 @@ const (
 	PayloadObject
 )
 
+func (k PayloadKind) String() string {
+	switch k {
+	case PayloadNone:
+		return "none"
+	case PayloadScalar:
+		return "scalar"
+	case PayloadSequence:
+		return "sequence"
+	case PayloadObject:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText returns the kind's name, as String does.
+func (k PayloadKind) MarshalText() ([]byte, error) {
+	return marshalEnum(k, "PayloadKind")
+}
+
+// UnmarshalText sets the kind from its name.
+func (k *PayloadKind) UnmarshalText(text []byte) error {
+	return unmarshalEnum(k, text, "PayloadKind", PayloadObject)
+}
+
 // Value represents a Styx value - can have a tag and/or payload.
 type Value struct {
 	Span        Span
@@ -93,6 +306,10 @@ type Value struct {
 	Scalar      *Scalar
 	Sequence    *Sequence
 	Object      *Object
+	// Implicit is true when the value was not written in the source but
+	// supplied by the parser: the unit value of a key without a value, or
+	// the unit key of an object in key position.
+	Implicit bool
 }
 
 // IsUnit returns true if this is a unit value (no tag, no payload).
@@ -100,14 +317,277 @@ func (v *Value) IsUnit() bool {
 	return v.Tag == nil && v.PayloadKind == PayloadNone
 }
 
+// PayloadSpan returns the byte range of the value's payload. For a tagged
+// value this excludes the tag; a tag without a payload yields an empty span
+// just after the tag.
+func (v *Value) PayloadSpan() Span {
+	switch v.PayloadKind {
+	case PayloadScalar:
+		return v.Scalar.Span
+	case PayloadSequence:
+		return v.Sequence.Span
+	case PayloadObject:
+		return v.Object.Span
+	}
+	if v.Tag != nil && v.Span.Start < v.Tag.Span.End {
+		return Span{v.Tag.Span.End, v.Tag.Span.End}
+	}
+	return v.Span
+}
+
+// FullSpan returns the byte range covering both the tag (if any) and the
+// payload of the value.
+func (v *Value) FullSpan() Span {
+	span := v.Span
+	if v.Tag != nil {
+		if v.Tag.Span.Start < span.Start {
+			span.Start = v.Tag.Span.Start
+		}
+		if v.Tag.Span.End > span.End {
+			span.End = v.Tag.Span.End
+		}
+	}
+	return span
+}
+
+// IsImplicitUnit returns true if this is a unit value supplied by the parser.
+func (v *Value) IsImplicitUnit() bool {
+	return v.IsUnit() && v.Implicit
+}
+
+// IsExplicitUnit returns true if this is a unit value written as `@`.
+func (v *Value) IsExplicitUnit() bool {
+	return v.IsUnit() && !v.Implicit
+}
+
 // Document represents a parsed Styx document.
 type Document struct {
 	Entries []*Entry
 	Span    Span
+	// Comments holds every comment in the source, in order, whether or not
+	// it was attached to an entry.
+	Comments []*Comment
+	// Warnings holds problems that do not stop the parse, such as a
+	// YAML-style `key: value` line, in source order.
+	Warnings []*Diagnostic
+	source   string
+}
+
+// Source returns the source text the document was parsed from.
+func (d *Document) Source() string {
+	return d.source
+}
+
+// SourceFor returns the source text of an AST node: a *Value, *Entry,
+// *Scalar, *Tag, *Sequence, *Object or a Span. It returns false if the node
+// has no valid location in the document's source.
+func (d *Document) SourceFor(node any) (string, bool) {
+	var span Span
+	switch n := node.(type) {
+	case *Value:
+		span = n.FullSpan()
+	case *Entry:
+		span = n.Span()
+	case *Scalar:
+		span = n.Span
+	case *Tag:
+		span = n.Span
+	case *Sequence:
+		span = n.Span
+	case *Object:
+		span = n.Span
+	case Span:
+		span = n
+	default:
+		return "", false
+	}
+	if !span.Valid(len(d.source)) {
+		return "", false
+	}
+	return span.Slice(d.source), true
 }
 
-// Parse parses a Styx document from the source string.
+// DefaultMaxDepth is the nesting depth limit used when
+// ParseOptions.MaxDepth is zero.
+const DefaultMaxDepth = 512
+
+// DefaultMaxErrors is the number of errors after which ParseRecover stops
+// when ParseOptions.MaxErrors is zero.
+const DefaultMaxErrors = 20
+
+// DefaultNestingWarningDepth is the nesting depth past which a warning is
+// reported when ParseOptions.NestingWarningDepth is zero.
+const DefaultNestingWarningDepth = 64
+
+// ParseOptions configures parsing. The zero value gives the strict default
+// behavior used by Parse.
+type ParseOptions struct {
+	// SkipComments disables recording comments, leaving Document.Comments
+	// and the comment fields of entries empty.
+	SkipComments bool
+	// MaxDepth limits how deeply objects and sequences may nest. Zero
+	// selects DefaultMaxDepth and a negative value removes the limit.
+	// The remaining limits are disabled when zero.
+	MaxDepth int
+	// MaxInputSize limits the size of the source in bytes.
+	MaxInputSize int
+	// MaxScalarLength limits the decoded length in bytes of any scalar,
+	// including heredoc bodies.
+	MaxScalarLength int
+	// MaxHeredocLength limits the size in bytes of heredoc bodies as
+	// written, line endings and indentation included. Unlike
+	// MaxScalarLength it is enforced while the body is read, so an unclosed
+	// heredoc fails as soon as it passes the limit instead of consuming the
+	// rest of the input.
+	MaxHeredocLength int
+	// MaxEntries limits the total number of entries in the document,
+	// counting entries of nested objects.
+	MaxEntries int
+	// MaxErrors is the number of errors after which ParseRecover and
+	// Diagnose stop, adding a final "too many errors" error. Zero selects
+	// DefaultMaxErrors and a negative value removes the limit.
+	MaxErrors int
+	// SkipBOM ignores a UTF-8 byte order mark at the start of the source.
+	// Spans still count the BOM's bytes.
+	SkipBOM bool
+	// RejectInvalidUTF8 reports invalid UTF-8 as an error pointing at the
+	// first offending byte. By default such bytes decode to U+FFFD.
+	RejectInvalidUTF8 bool
+	// NormalizeLineEndings converts CRLF line endings inside heredoc and
+	// raw string content to LF, so that a document yields the same scalar
+	// text whichever line endings it was saved with. By default content is
+	// kept as written. Either way, CRLF counts as a single line break
+	// everywhere else: it separates entries, ends comments, and may follow a
+	// heredoc's opening line and closing delimiter, whose span excludes it.
+	NormalizeLineEndings bool
+	// ExtendedTagNames permits namespaced tag names such as `@org/package`
+	// and `@org.package`: `/` and `.` may join segments that each follow
+	// the usual tag name rules. This is an extension; the specification
+	// rejects such names.
+	ExtendedTagNames bool
+	// ExtendedEscapes accepts `\0` (U+0000) and `\xNN` (an ASCII character
+	// given by two hex digits) in quoted scalars. Malformed escapes are
+	// reported with the escape's span. This is an extension; the
+	// specification rejects `\0` and `\x`.
+	ExtendedEscapes bool
+	// ValidateHeredocOptions rejects heredoc options other than a single
+	// language hint, written bare or as `lang=`, matching
+	// `[a-z][a-z0-9_.-]*` as the specification requires. By default any
+	// options are accepted and exposed on the scalar for tools to interpret.
+	ValidateHeredocOptions bool
+	// UnicodeTagNames permits non-ASCII letters in tag names: a name may
+	// start with any Unicode letter and continue with letters, digits and
+	// combining marks, as in `@名前` or `@café`. This is an extension; the
+	// specification only allows ASCII. Bare scalars, and so bare keys,
+	// accept any character other than whitespace and punctuation in every
+	// mode, so they need no option.
+	UnicodeTagNames bool
+	// NestingWarningDepth is the nesting depth past which a warning is
+	// added to the document. Zero selects DefaultNestingWarningDepth and a
+	// negative value disables the warning.
+	NestingWarningDepth int
+	// CheckInvisibleCharacters warns of zero-width characters,
+	// bidirectional controls and non-breaking spaces in bare scalars,
+	// keys included. Such characters make a document read differently
+	// than it behaves.
+	CheckInvisibleCharacters bool
+	// Filename names the source in error strings.
+	Filename string
+	// Messages, when set, replaces the message of every error returned,
+	// and of the document's warnings, for products that translate or
+	// restyle messages. It receives the error, or the warning converted by
+	// Diagnostic.ParseError, with its default English message.
+	// MessageCatalog.Message can serve as the hook. Only messages are
+	// replaced: related labels, help and fixes stay in English.
+	Messages func(*ParseError) string
+}
+
+// Parse parses a Styx document from the source string. Like
+// ParseWithOptions, it returns a partial document alongside any error.
 func Parse(source string) (*Document, error) {
-	p := newParser(source)
-	return p.parse()
+	return ParseWithOptions(source, ParseOptions{})
+}
+
+// ParseWithOptions parses a Styx document from the source string using the
+// given options.
+//
+// On failure the returned error describes the first problem found, and the
+// document, if not nil, is a best-effort partial tree holding everything that
+// could be parsed around the errors, as ParseRecover would produce. Editors
+// can use it to keep offering structure-based features while a file is being
+// typed.
+func ParseWithOptions(source string, opts ParseOptions) (*Document, error) {
+	return ParseWithOptionsContext(context.Background(), source, opts)
+}
+
+// ParseContext parses a Styx document like Parse, checking ctx between
+// entries. If ctx is canceled or its deadline passes, parsing stops and
+// ctx.Err() is returned with a nil document.
+func ParseContext(ctx context.Context, source string) (*Document, error) {
+	return ParseWithOptionsContext(ctx, source, ParseOptions{})
+}
+
+// ParseWithOptionsContext combines ParseWithOptions and ParseContext.
+func ParseWithOptionsContext(ctx context.Context, source string, opts ParseOptions) (*Document, error) {
+	if err := checkOptions(source, opts); err != nil {
+		return nil, finishError(err, source, opts)
+	}
+	p := newParser(source, opts)
+	p.ctx = ctx
+	doc, err := p.parseToFirstError()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return doc, finishError(err, source, opts)
+	}
+	return doc, nil
+}
+
+// ParseValue parses a single standalone value: a scalar, sequence, object,
+// tagged value or unit. Anything other than whitespace and comments after the
+// value is an error.
+func ParseValue(source string) (*Value, error) {
+	return ParseValueWithOptions(source, ParseOptions{})
+}
+
+// ParseValueWithOptions is like ParseValue but uses the given options.
+func ParseValueWithOptions(source string, opts ParseOptions) (*Value, error) {
+	if err := checkOptions(source, opts); err != nil {
+		return nil, finishError(err, source, opts)
+	}
+	p := newParser(source, opts)
+	v, err := p.parseStandaloneValue()
+	return v, finishError(err, source, opts)
+}
+
+// ParseRecover parses a Styx document without stopping at the first error.
+// After an error the parser skips to the next line or closing brace and
+// carries on, so the returned slice holds every error found, in order. The
+// document contains the entries that parsed successfully; it is nil only if
+// parsing could not proceed at all.
+func ParseRecover(source string, opts ParseOptions) (*Document, []*ParseError) {
+	if err := checkOptions(source, opts); err != nil {
+		finishError(err, source, opts)
+		return nil, []*ParseError{err}
+	}
+	p := newParser(source, opts)
+	p.recovering = true
+	doc, err := p.parse()
+	if err != nil {
+		p.recordError(err)
+	}
+	for _, err := range p.errors {
+		finishError(err, source, opts)
+	}
+	return doc, p.errors
+}
+
+// checkOptions reports input over opts.MaxInputSize. ParseReader checks
+// the size as it reads instead.
+func checkOptions(source string, opts ParseOptions) *ParseError {
+	if opts.MaxInputSize > 0 && len(source) > opts.MaxInputSize {
+		return &ParseError{Code: CodeInputTooLarge, Message: "input too large", Span: Span{opts.MaxInputSize, len(source)}}
+	}
+	return nil
 }
@@ -0,0 +1,697 @@
+package styx
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, source string) *Document {
+	t.Helper()
+	doc, err := Parse(source)
+	if err != nil {
+		t.Fatalf("parse %q: %v", source, err)
+	}
+	return doc
+}
+
+func TestEntrySpan(t *testing.T) {
+	tests := []struct {
+		source  string
+		span    Span
+		keyText string
+	}{
+		{"name value", Span{0, 10}, "name"},
+		{"flag", Span{0, 4}, "flag"},
+		{"@tag value", Span{0, 10}, "@tag"},
+		{"\"quoted key\" {a b}", Span{0, 18}, "quoted key"},
+		{"{a b}", Span{0, 5}, ""},
+	}
+	for _, tt := range tests {
+		doc := mustParse(t, tt.source)
+		entry := doc.Entries[0]
+		if got := entry.Span(); got != tt.span {
+			t.Errorf("%q: Span() = %v, want %v", tt.source, got, tt.span)
+		}
+		if got := entry.KeyText(); got != tt.keyText {
+			t.Errorf("%q: KeyText() = %q, want %q", tt.source, got, tt.keyText)
+		}
+	}
+}
+
+func TestUnitOrigin(t *testing.T) {
+	doc := mustParse(t, "a\nb @\n")
+	if v := doc.Entries[0].Value; !v.IsImplicitUnit() || v.IsExplicitUnit() {
+		t.Errorf("bare key: want implicit unit, got %+v", v)
+	}
+	if v := doc.Entries[1].Value; !v.IsExplicitUnit() || v.IsImplicitUnit() {
+		t.Errorf("`@`: want explicit unit, got %+v", v)
+	}
+
+	doc = mustParse(t, "{a b}")
+	if k := doc.Entries[0].Key; !k.IsImplicitUnit() {
+		t.Errorf("root object key: want implicit unit, got %+v", k)
+	}
+}
+
+func TestAttributeObject(t *testing.T) {
+	doc := mustParse(t, "route method>GET path>/users\nother {method GET}\n")
+	if obj := doc.Entries[0].Value.Object; !obj.Attributes {
+		t.Errorf("attribute syntax: Attributes = false")
+	}
+	if obj := doc.Entries[1].Value.Object; obj.Attributes {
+		t.Errorf("brace syntax: Attributes = true")
+	}
+}
+
+func TestScalarRaw(t *testing.T) {
+	tests := []struct {
+		source string
+		text   string
+		raw    string
+	}{
+		{`k bare`, "bare", "bare"},
+		{`k "a\tb"`, "a\tb", `"a\tb"`},
+		{`k r#"x"y"#`, `x"y`, `r#"x"y"#`},
+		{"k <<EOT\nline\nEOT", "line\n", "<<EOT\nline\nEOT"},
+	}
+	for _, tt := range tests {
+		doc := mustParse(t, tt.source)
+		sc := doc.Entries[0].Value.Scalar
+		if sc.Text != tt.text || sc.Raw != tt.raw {
+			t.Errorf("%q: got Text=%q Raw=%q, want Text=%q Raw=%q", tt.source, sc.Text, sc.Raw, tt.text, tt.raw)
+		}
+	}
+}
+
+func TestHeredocDelimiter(t *testing.T) {
+	doc := mustParse(t, "code <<CODE,rust\nfn main() {}\nCODE\nplain <<EOT\nx\nEOT\n")
+	sc := doc.Entries[0].Value.Scalar
+	if sc.HeredocDelimiter != "CODE" || len(sc.HeredocOptions) != 1 || sc.HeredocOptions[0].Name != "rust" {
+		t.Errorf("got delimiter %q options %q", sc.HeredocDelimiter, sc.HeredocOptions)
+	}
+	sc = doc.Entries[1].Value.Scalar
+	if sc.HeredocDelimiter != "EOT" || len(sc.HeredocOptions) != 0 {
+		t.Errorf("got delimiter %q options %q", sc.HeredocDelimiter, sc.HeredocOptions)
+	}
+}
+
+func TestHeredocOptions(t *testing.T) {
+	source := "a <<CFG,lang=toml,dedent\nx\nCFG\nb <<SQL,sql\nx\nSQL\n"
+	doc := mustParse(t, source)
+	sc := doc.Entries[0].Value.Scalar
+	want := []HeredocOption{{"lang", "toml", Span{8, 17}}, {"dedent", "", Span{18, 24}}}
+	if !reflect.DeepEqual(sc.HeredocOptions, want) || sc.HeredocLanguage != "toml" {
+		t.Errorf("got options %+v language %q", sc.HeredocOptions, sc.HeredocLanguage)
+	}
+	if sc := doc.Entries[1].Value.Scalar; sc.HeredocLanguage != "sql" {
+		t.Errorf("got language %q", sc.HeredocLanguage)
+	}
+
+	strict := ParseOptions{ValidateHeredocOptions: true}
+	for _, tc := range []struct{ source, message string }{
+		{"a <<CFG,lang=toml\nx\nCFG", ""},
+		{"a <<SQL,sql\nx\nSQL", ""},
+		{"a <<CFG,lang=toml,dedent\nx\nCFG", "unknown heredoc option `dedent`"},
+		{"a <<SQL,sql,rust\nx\nSQL", "unknown heredoc option `rust`"},
+		{"a <<SQL,SQL\nx\nSQL", "invalid heredoc language hint `SQL`"},
+	} {
+		_, err := ParseWithOptions(tc.source, strict)
+		if tc.message == "" && err != nil {
+			t.Errorf("%q: %v", tc.source, err)
+		} else if tc.message != "" && (err == nil || err.(*ParseError).Message != tc.message) {
+			t.Errorf("%q: got %v, want %s", tc.source, err, tc.message)
+		}
+	}
+}
+
+func TestTagSpans(t *testing.T) {
+	tests := []struct {
+		source   string
+		nameSpan Span
+		payload  Span
+		full     Span
+	}{
+		{"k @env\"HOME\"", Span{3, 6}, Span{6, 12}, Span{2, 12}},
+		{"k @seq(a b)", Span{3, 6}, Span{6, 11}, Span{2, 11}},
+		{"k @none", Span{3, 7}, Span{7, 7}, Span{2, 7}},
+		{"k @unit@", Span{3, 7}, Span{7, 8}, Span{2, 8}},
+	}
+	for _, tt := range tests {
+		v := mustParse(t, tt.source).Entries[0].Value
+		if v.Tag.NameSpan != tt.nameSpan {
+			t.Errorf("%q: NameSpan = %v, want %v", tt.source, v.Tag.NameSpan, tt.nameSpan)
+		}
+		if got := v.PayloadSpan(); got != tt.payload {
+			t.Errorf("%q: PayloadSpan() = %v, want %v", tt.source, got, tt.payload)
+		}
+		if got := v.FullSpan(); got != tt.full {
+			t.Errorf("%q: FullSpan() = %v, want %v", tt.source, got, tt.full)
+		}
+	}
+}
+
+func TestSpanSlice(t *testing.T) {
+	source := "hello world"
+	tests := []struct {
+		span Span
+		want string
+	}{
+		{Span{0, 5}, "hello"},
+		{Span{6, 11}, "world"},
+		{Span{-1, -1}, ""},
+		{Span{5, 2}, ""},
+		{Span{6, 50}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.span.Slice(source); got != tt.want {
+			t.Errorf("%v.Slice() = %q, want %q", tt.span, got, tt.want)
+		}
+	}
+}
+
+func TestSourceFor(t *testing.T) {
+	doc := mustParse(t, "key @tag{a \"b\"}\n")
+	entry := doc.Entries[0]
+	tests := []struct {
+		node any
+		want string
+	}{
+		{entry, "key @tag{a \"b\"}"},
+		{entry.Key, "key"},
+		{entry.Value, "@tag{a \"b\"}"},
+		{entry.Value.Tag, "@tag"},
+		{entry.Value.Object.Entries[0].Value.Scalar, "\"b\""},
+	}
+	for _, tt := range tests {
+		got, ok := doc.SourceFor(tt.node)
+		if !ok || got != tt.want {
+			t.Errorf("SourceFor(%T) = %q, %v; want %q", tt.node, got, ok, tt.want)
+		}
+	}
+	if _, ok := mustParse(t, "{a b}").SourceFor(&Value{Span: Span{-1, -1}}); ok {
+		t.Errorf("SourceFor(synthesized) succeeded")
+	}
+}
+
+func TestParseWithOptionsSkipComments(t *testing.T) {
+	doc, err := ParseWithOptions("// comment\nkey value // trailing\n", ParseOptions{SkipComments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Comments) != 0 || doc.Entries[0].LeadingComments != nil || doc.Entries[0].TrailingComment != nil {
+		t.Errorf("comments recorded despite SkipComments")
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	deep := strings.Repeat("(", 100000) + strings.Repeat(")", 100000)
+	_, err := Parse("k " + deep)
+	if pe, ok := err.(*ParseError); !ok || pe.Message != "maximum nesting depth exceeded" {
+		t.Fatalf("deep sequence: got %v", err)
+	}
+
+	nested := "k " + strings.Repeat("{a ", 5) + strings.Repeat("}", 5)
+	if _, err := ParseWithOptions(nested, ParseOptions{MaxDepth: 5}); err != nil {
+		t.Errorf("depth 5 with limit 5: %v", err)
+	}
+	_, err = ParseWithOptions(nested, ParseOptions{MaxDepth: 4})
+	if pe, ok := err.(*ParseError); !ok || pe.Span != (Span{14, 15}) {
+		t.Errorf("depth 5 with limit 4: got %v", err)
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	tests := []struct {
+		source string
+		opts   ParseOptions
+		msg    string
+	}{
+		{"key value", ParseOptions{MaxInputSize: 5}, "input too large"},
+		{"key value", ParseOptions{MaxScalarLength: 4}, "scalar too long"},
+		{"key \"quoted\"", ParseOptions{MaxScalarLength: 4}, "scalar too long"},
+		{"key <<EOT\nlong body\nEOT", ParseOptions{MaxScalarLength: 4}, "scalar too long"},
+		{"key <<EOT\nlong body\nEOT", ParseOptions{MaxHeredocLength: 4}, "heredoc too long"},
+		{"key <<EOT\nunclosed", ParseOptions{MaxHeredocLength: 4}, "heredoc too long"},
+		{"a 1\nb {c 2}", ParseOptions{MaxEntries: 2}, "too many entries"},
+		{"a x>1 y>2", ParseOptions{MaxEntries: 2}, "too many entries"},
+	}
+	for _, tt := range tests {
+		_, err := ParseWithOptions(tt.source, tt.opts)
+		if pe, ok := err.(*ParseError); !ok || pe.Message != tt.msg {
+			t.Errorf("%q: got %v, want %q", tt.source, err, tt.msg)
+		}
+	}
+	if _, err := ParseWithOptions("a 1\nb {c 2}", ParseOptions{MaxInputSize: 11, MaxScalarLength: 1, MaxEntries: 3}); err != nil {
+		t.Errorf("within limits: %v", err)
+	}
+	if _, err := ParseWithOptions("k <<EOT\nbody\nEOT", ParseOptions{MaxHeredocLength: 5}); err != nil {
+		t.Errorf("heredoc within limit: %v", err)
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	if _, err := ParseContext(context.Background(), "a {b c}"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doc, err := ParseContext(ctx, "a {b c}")
+	if err != context.Canceled || doc != nil {
+		t.Errorf("canceled: got %v, %v", doc, err)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	src := []byte("name \"app\"\n")
+	doc, err := ParseBytes(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Entries[0].Value.Scalar.Text; got != "app" {
+		t.Errorf("Text = %q, want %q", got, "app")
+	}
+	if doc, err := ParseBytes(nil); err != nil || len(doc.Entries) != 0 {
+		t.Errorf("empty input: %v, %v", doc, err)
+	}
+}
+
+func TestEncodingOptions(t *testing.T) {
+	const bom = "\uFEFF"
+	doc := mustParse(t, bom+"key value")
+	if got := doc.Entries[0].KeyText(); got != bom+"key" {
+		t.Errorf("default: key = %q", got)
+	}
+	doc, err := ParseWithOptions(bom+"key value", ParseOptions{SkipBOM: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, span := doc.Entries[0].KeyText(), doc.Entries[0].Key.Span; got != "key" || span != (Span{3, 6}) {
+		t.Errorf("SkipBOM: key = %q at %v", got, span)
+	}
+
+	invalid := "key va\xfflue"
+	doc = mustParse(t, invalid)
+	if got := doc.Entries[0].Value.Scalar.Text; got != "va\uFFFDlue" {
+		t.Errorf("default: value = %q", got)
+	}
+	_, err = ParseWithOptions(invalid, ParseOptions{RejectInvalidUTF8: true})
+	if pe, ok := err.(*ParseError); !ok || pe.Message != "invalid UTF-8" || pe.Span != (Span{6, 7}) {
+		t.Errorf("RejectInvalidUTF8: got %v", err)
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	source := "a 1\r\nsql <<SQL,sql\r\nSELECT 1\r\nFROM t\r\nSQL\r\nraw r\"x\r\ny\"\r\nb 2 // note\r\n"
+	lf := strings.ReplaceAll(source, "\r\n", "\n")
+
+	doc := mustParse(t, source)
+	if n := len(doc.Entries); n != 4 {
+		t.Fatalf("got %d entries, want 4", n)
+	}
+	heredoc := doc.Entries[1].Value.Scalar
+	if heredoc.Text != "SELECT 1\r\nFROM t\r\n" {
+		t.Errorf("heredoc text = %q", heredoc.Text)
+	}
+	if !strings.HasSuffix(heredoc.Raw, "SQL") || heredoc.HeredocDelimiter != "SQL" {
+		t.Errorf("heredoc raw = %q, delimiter = %q", heredoc.Raw, heredoc.HeredocDelimiter)
+	}
+	if c := doc.Entries[3].TrailingComment; c == nil || c.Text != "// note" {
+		t.Errorf("trailing comment = %v", c)
+	}
+
+	doc, err := ParseWithOptions(source, ParseOptions{NormalizeLineEndings: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Hash() != mustParse(t, lf).Hash() {
+		t.Errorf("normalized CRLF document differs from LF document")
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		source string
+		want   any
+	}{
+		{"8080", "8080"},
+		{`"hello world"`, "hello world"},
+		{"(a b c)", []any{"a", "b", "c"}},
+		{"{host localhost, port 80}", map[string]any{"host": "localhost", "port": "80"}},
+		{"@env\"HOME\" // comment", Tagged{Tag: "env", Value: "HOME"}},
+		{"  @  ", nil},
+		{"method>GET path>/", map[string]any{"method": "GET", "path": "/"}},
+	}
+	for _, tt := range tests {
+		v, err := ParseValue(tt.source)
+		if err != nil {
+			t.Errorf("%q: %v", tt.source, err)
+			continue
+		}
+		if got := v.Interface(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %#v, want %#v", tt.source, got, tt.want)
+		}
+	}
+
+	errors := []struct {
+		source string
+		msg    string
+		span   Span
+	}{
+		{"", "expected a value", Span{0, 0}},
+		{"a b", "trailing content after value", Span{2, 3}},
+		{"{a b} c\n", "trailing content after value", Span{6, 8}},
+		{"(a, b)", "unexpected `,` in sequence (sequences are whitespace-separated, not comma-separated)", Span{2, 3}},
+	}
+	for _, tt := range errors {
+		_, err := ParseValue(tt.source)
+		if pe, ok := err.(*ParseError); !ok || pe.Message != tt.msg || pe.Span != tt.span {
+			t.Errorf("%q: got %v, want %q at %v", tt.source, err, tt.msg, tt.span)
+		}
+	}
+}
+
+func TestExtendedTagNames(t *testing.T) {
+	source := "dep @org/package{version 1}\nother @a.b.c\n"
+	if _, err := Parse(source); err == nil {
+		t.Fatal("strict mode accepted @org/package")
+	}
+	doc, err := ParseWithOptions(source, ParseOptions{ExtendedTagNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag := doc.Entries[0].Value.Tag; tag.Name != "org/package" || tag.NameSpan != (Span{5, 16}) {
+		t.Errorf("tag = %+v", tag)
+	}
+	if tag := doc.Entries[1].Value.Tag; tag.Name != "a.b.c" {
+		t.Errorf("tag = %+v", tag)
+	}
+	if _, err := ParseWithOptions("k @org/", ParseOptions{ExtendedTagNames: true}); err == nil {
+		t.Error("accepted trailing separator")
+	}
+}
+
+func TestPathologicalInputs(t *testing.T) {
+	inputs := map[string]string{
+		"long dotted key":  strings.Repeat("a.", 200000) + "a v",
+		"dotted then flat": strings.Repeat("a.", 200000) + "a v\nb v",
+		"many commas":      "{" + strings.Repeat(",", 1000000) + "}",
+		"long scalar":      "a " + strings.Repeat("x", 1<<20),
+		"unterminated":     "a {" + strings.Repeat("(", 100) + strings.Repeat("\"", 1001),
+		"many comments":    strings.Repeat("// c\nk v // t\n", 50000),
+		"deep paths":       strings.Repeat("x.y.z v\n", 50000),
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				ParseWithOptions(input, ParseOptions{MaxDepth: -1})
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("parse did not finish")
+			}
+		})
+	}
+
+	_, err := Parse(strings.Repeat("a.", 200000) + "a v")
+	if err == nil || err.(*ParseError).Message != "input too complex" {
+		t.Errorf("expected input too complex, got %v", err)
+	}
+	if _, err := Parse(strings.Repeat("a.", 1000) + "a v"); err != nil {
+		t.Errorf("long but reasonable key: %v", err)
+	}
+}
+
+func TestUnicodeTagNames(t *testing.T) {
+	source := "名前 @タグ{値 1}\nx @café\n"
+	if _, err := Parse(source); err == nil {
+		t.Fatal("strict mode accepted a Unicode tag name")
+	}
+	doc, err := ParseWithOptions(source, ParseOptions{UnicodeTagNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := doc.Entries[0].KeyText(); key != "名前" {
+		t.Errorf("key = %q", key)
+	}
+	if tag := doc.Entries[0].Value.Tag; tag.Name != "タグ" || tag.Span.Slice(source) != "@タグ" {
+		t.Errorf("tag = %+v", tag)
+	}
+	if tag := doc.Entries[1].Value.Tag; tag.Name != "café" {
+		t.Errorf("tag = %+v", tag)
+	}
+}
+
+func TestExtendedEscapes(t *testing.T) {
+	source := `a "\0\x41\u{1F600}é"`
+	if _, err := Parse(source); err == nil {
+		t.Fatal("strict mode accepted \\0")
+	}
+	ext := ParseOptions{ExtendedEscapes: true}
+	doc, err := ParseWithOptions(source, ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Entries[0].Value.Scalar.Text; got != "\x00A\U0001F600é" {
+		t.Errorf("got %q", got)
+	}
+
+	for _, tc := range []struct {
+		source, message string
+		span            Span
+	}{
+		{`a "\x4"`, "invalid hex escape", Span{3, 6}},
+		{`a "\x80"`, "invalid hex escape", Span{3, 7}},
+	} {
+		_, err := ParseWithOptions(tc.source, ext)
+		pe, ok := err.(*ParseError)
+		if !ok || pe.Message != tc.message || pe.Span != tc.span {
+			t.Errorf("%s: got %v, want %s at %v", tc.source, err, tc.message, tc.span)
+		}
+	}
+}
+
+func TestUnicodeEscapeValidation(t *testing.T) {
+	doc := mustParse(t, `a "\u00e9\u{1F600}\u{10FFFF}"`)
+	if got := doc.Entries[0].Value.Scalar.Text; got != "é\U0001F600\U0010FFFF" {
+		t.Errorf("got %q", got)
+	}
+
+	for _, tc := range []struct {
+		source string
+		span   Span
+	}{
+		{`a "\u{}"`, Span{3, 6}},
+		{`a "\u{41"`, Span{3, 8}},
+		{`a "\u{1234567}"`, Span{3, 12}},
+		{`a "\u{D800}"`, Span{3, 11}},
+		{`a "\uDFFF"`, Span{3, 9}},
+		{`a "\u{110000}"`, Span{3, 13}},
+		{`a "\u00g0"`, Span{3, 7}},
+		{`a "\u12"`, Span{3, 7}},
+	} {
+		_, err := Parse(tc.source)
+		pe, ok := err.(*ParseError)
+		if !ok || pe.Message != "invalid unicode escape" || pe.Span != tc.span {
+			t.Errorf("%s: got %v, want invalid unicode escape at %v", tc.source, err, tc.span)
+		}
+	}
+}
+
+func TestUnterminatedDiagnostics(t *testing.T) {
+	_, err := Parse("a r##\"text\"# more")
+	pe := err.(*ParseError)
+	if pe.Message != "unclosed raw string (missing `\"##`)" || pe.Expected != "\"##" {
+		t.Errorf("raw string: got %q expected %q", pe.Message, pe.Expected)
+	}
+	want := []Label{
+		{"raw string opened here", Span{2, 6}},
+		{"expected `\"##` before the end of input", Span{17, 17}},
+	}
+	if !reflect.DeepEqual(pe.Related, want) {
+		t.Errorf("raw string related = %+v", pe.Related)
+	}
+
+	_, err = Parse("a <<EOF,sh\r\nx\nEOFX\n")
+	pe = err.(*ParseError)
+	if pe.Expected != "EOF" || len(pe.Related) != 2 || pe.Related[0].Span != (Span{2, 10}) || pe.Related[1].Span != (Span{19, 19}) {
+		t.Errorf("heredoc: got %+v", pe)
+	}
+}
+
+func TestConflictingEntryLabels(t *testing.T) {
+	for _, tc := range []struct {
+		source string
+		want   []Label
+	}{
+		{"a 1\na 2", []Label{{"first defined here", Span{0, 1}}}},
+		{"x {a 1, a 2}", []Label{{"first defined here", Span{3, 4}}}},
+		{"a.b 1\nc 2\na.d 3", []Label{
+			{"`a` first defined here", Span{0, 3}},
+			{"sibling appeared here", Span{6, 7}},
+		}},
+		{"a 1\na.b 2", []Label{{"`a` assigned here", Span{0, 1}}}},
+	} {
+		_, err := Parse(tc.source)
+		pe, ok := err.(*ParseError)
+		if !ok || !reflect.DeepEqual(pe.Related, tc.want) {
+			t.Errorf("%q: got %+v", tc.source, err)
+		}
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	tests := []struct {
+		source string
+		opts   ParseOptions
+		want   string
+	}{
+		{"host a\nport 1\nport 2", ParseOptions{Filename: "config.styx"}, `config.styx:3:1: duplicate key "port"`},
+		{"é {\n", ParseOptions{}, "1:3: unclosed object (missing `}`) \"{\""},
+		{"a r#\"x\ny", ParseOptions{}, "1:3: unclosed raw string (missing `\"#`)"},
+	}
+	for _, tt := range tests {
+		_, err := ParseWithOptions(tt.source, tt.opts)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q: got %v, want %s", tt.source, err, tt.want)
+		}
+	}
+
+	_, err := ParseReaderWithOptions(strings.NewReader("a 1\na 2"), ParseOptions{Filename: "r.styx"})
+	if err == nil || err.Error() != `r.styx:2:1: duplicate key "a"` {
+		t.Errorf("reader: got %v", err)
+	}
+
+	// Without a position the error falls back to byte offsets.
+	pe := &ParseError{Message: "duplicate key", Span: Span{4, 5}}
+	if pe.Error() != "parse error at 4-5: duplicate key" {
+		t.Errorf("got %q", pe.Error())
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		source string
+		code   Code
+		span   Span
+	}{
+		{"name: app\nport 1", CodeKeyEndsWithColon, Span{4, 5}},
+		{"x {a: 1}", CodeKeyEndsWithColon, Span{4, 5}},
+		{"t <<EOF\n\t  a\n  \n\t  EOF", CodeMixedIndentation, Span{8, 11}},
+		{"t <<EOF\n\ta\n  b\n  EOF", CodeMixedIndentation, Span{11, 13}},
+		{"a " + strings.Repeat("(", 70) + strings.Repeat(")", 70), CodeDeepNesting, Span{66, 67}},
+	}
+	for _, tt := range tests {
+		doc, err := Parse(tt.source)
+		if err != nil {
+			t.Errorf("%q: %v", tt.source, err)
+			continue
+		}
+		if len(doc.Warnings) != 1 || doc.Warnings[0].Code != tt.code || doc.Warnings[0].Span != tt.span || doc.Warnings[0].Severity != SeverityWarning {
+			t.Errorf("%q: got warnings %v", tt.source, doc.Warnings)
+		}
+	}
+
+	for _, source := range []string{
+		"a 1\nurl http://x",
+		"a b:c",
+		":",
+		"t <<EOF\n\ta\n\t\n  \n\tEOF",
+		"t <<EOF\n\ta\n  b\nEOF",
+	} {
+		if doc, err := Parse(source); err != nil || len(doc.Warnings) != 0 {
+			t.Errorf("%q: got %v, warnings %v", source, err, doc.Warnings)
+		}
+	}
+
+	_, diags := Diagnose("a: 1\nb {", ParseOptions{})
+	if len(diags) != 2 || diags[0].Code != CodeKeyEndsWithColon || diags[1].Code != CodeUnclosedObject {
+		t.Errorf("Diagnose: got %v", diags)
+	}
+	doc, _ := ParseWithOptions("a ((1))", ParseOptions{NestingWarningDepth: 1})
+	if len(doc.Warnings) != 1 {
+		t.Errorf("NestingWarningDepth: got %v", doc.Warnings)
+	}
+}
+
+func TestInvisibleCharacters(t *testing.T) {
+	opts := ParseOptions{CheckInvisibleCharacters: true}
+	tests := []struct {
+		source string
+		spans  []Span
+	}{
+		{"admin\u200b true", []Span{{5, 8}}},
+		{"a.b\u202e.c 1", []Span{{3, 6}}},
+		{"k x\u00a0y", []Span{{3, 5}}},
+		{"k @t{\u2066a\u2069 1}", []Span{{5, 8}, {9, 12}}},
+		{"k \"x\u200by\"", nil},
+		{"k plain", nil},
+	}
+	for _, tt := range tests {
+		doc, err := ParseWithOptions(tt.source, opts)
+		if err != nil {
+			t.Errorf("%q: %v", tt.source, err)
+			continue
+		}
+		var spans []Span
+		for _, w := range doc.Warnings {
+			if w.Code != CodeInvisibleCharacter {
+				t.Errorf("%q: unexpected warning %v", tt.source, w)
+			}
+			spans = append(spans, w.Span)
+		}
+		if !reflect.DeepEqual(spans, tt.spans) {
+			t.Errorf("%q: got spans %v, want %v", tt.source, spans, tt.spans)
+		}
+	}
+
+	doc, _ := ParseWithOptions("admin\u200b true", opts)
+	if got := doc.Warnings[0].Message; got != "bare scalar contains invisible character U+200B ZERO WIDTH SPACE" {
+		t.Errorf("message: %q", got)
+	}
+	if doc, _ := Parse("admin\u200b true"); len(doc.Warnings) != 0 {
+		t.Errorf("unchecked: got %v", doc.Warnings)
+	}
+}
+
+func TestEnumText(t *testing.T) {
+	type dump struct {
+		Token    TokenType
+		Scalar   ScalarKind
+		Payload  PayloadKind
+		Severity Severity
+	}
+	in := dump{TokenHeredoc, ScalarRaw, PayloadSequence, SeverityWarning}
+	out, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Token":"heredoc","Scalar":"raw","Payload":"sequence","Severity":"warning"}`; string(out) != want {
+		t.Errorf("Marshal = %s, want %s", out, want)
+	}
+	var back dump
+	if err := json.Unmarshal(out, &back); err != nil || back != in {
+		t.Errorf("Unmarshal = %+v, %v; want %+v", back, err, in)
+	}
+
+	for tok := TokenScalar; tok <= TokenWhitespace; tok++ {
+		text, err := tok.MarshalText()
+		var got TokenType
+		if err != nil || got.UnmarshalText(text) != nil || got != tok {
+			t.Errorf("%v does not round-trip: %q, %v", tok, text, err)
+		}
+	}
+	if _, err := ScalarKind(99).MarshalText(); err == nil {
+		t.Error("invalid ScalarKind marshaled")
+	}
+	var k PayloadKind
+	if err := k.UnmarshalText([]byte("unknown")); err == nil {
+		t.Error(`PayloadKind "unknown" unmarshaled`)
+	}
+}
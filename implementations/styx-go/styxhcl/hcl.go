@@ -0,0 +1,480 @@
+// Package styxhcl converts between HCL2 native syntax, as used by
+// Terraform, and Styx, so that existing configurations can serve as Styx
+// test input.
+//
+// A body becomes an object. Attributes become entries: literal strings
+// quoted scalars, numbers and booleans bare ones, null the unit value and
+// tuples sequences. Object constructors become objects tagged `@map`, and
+// any other expression, such as a reference or function call, is kept as
+// its source text tagged `@expr`. Blocks follow the convention of HCL's
+// JSON syntax: the block type and each label open a nested object, and
+// blocks sharing type and labels make a sequence, so
+//
+//	resource "aws_instance" "web" {
+//	  ami = var.ami
+//	}
+//
+// becomes
+//
+//	resource {
+//	    aws_instance {
+//	        web {
+//	            ami @expr"var.ami"
+//	        }
+//	    }
+//	}
+//
+// Comments are not carried over, as HCL's syntax tree does not keep them.
+package styxhcl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// noSpan locates nodes converted from HCL, which have no Styx source.
+var noSpan = styx.Span{Start: -1, End: -1}
+
+// FromHCL converts an HCL file to Styx. filename names the source in
+// diagnostics, which are returned as hcl.Diagnostics.
+func FromHCL(src []byte, filename string) (*styx.Document, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	c := converter{src: src}
+	obj, err := c.body(file.Body.(*hclsyntax.Body))
+	if err != nil {
+		return nil, err
+	}
+	return &styx.Document{Entries: obj.Entries, Span: noSpan}, nil
+}
+
+type converter struct {
+	src []byte
+}
+
+func (c *converter) text(r hcl.Range) string {
+	return string(r.SliceBytes(c.src))
+}
+
+// body converts the attributes and blocks of b, in source order.
+func (c *converter) body(b *hclsyntax.Body) (*styx.Object, error) {
+	type item struct {
+		start int
+		attr  *hclsyntax.Attribute
+		block *hclsyntax.Block
+	}
+	var items []item
+	for _, attr := range b.Attributes {
+		items = append(items, item{start: attr.SrcRange.Start.Byte, attr: attr})
+	}
+	for _, block := range b.Blocks {
+		items = append(items, item{start: block.TypeRange.Start.Byte, block: block})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].start < items[j].start })
+
+	obj := newObject()
+	for _, it := range items {
+		if it.attr != nil {
+			value, err := c.expr(it.attr.Expr)
+			if err != nil {
+				return nil, err
+			}
+			obj.Entries = append(obj.Entries, &styx.Entry{Key: scalar(it.attr.Name, styx.ScalarBare), Value: value})
+			continue
+		}
+		if err := c.block(obj, it.block); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// block adds block to obj below its type and labels. A second block at the
+// same place turns the value there into a sequence of bodies.
+func (c *converter) block(obj *styx.Object, block *hclsyntax.Block) error {
+	body, err := c.body(block.Body)
+	if err != nil {
+		return err
+	}
+	keys := append([]string{block.Type}, block.Labels...)
+	for _, key := range keys[:len(keys)-1] {
+		existing := obj.Get(key)
+		if existing == nil {
+			existing = objectValue(newObject())
+			obj.Entries = append(obj.Entries, &styx.Entry{Key: scalar(key, styx.ScalarBare), Value: existing})
+		}
+		if existing.PayloadKind != styx.PayloadObject || existing.Tag != nil {
+			return fmt.Errorf("%s: block %q conflicts with attribute %q", block.TypeRange, block.Type, key)
+		}
+		obj = existing.Object
+	}
+
+	key := keys[len(keys)-1]
+	value := objectValue(body)
+	for _, e := range obj.Entries {
+		if e.KeyText() != key {
+			continue
+		}
+		switch {
+		case e.Value.PayloadKind == styx.PayloadObject && e.Value.Tag == nil:
+			e.Value = sequenceValue(e.Value, value)
+		case e.Value.PayloadKind == styx.PayloadSequence && e.Value.Tag == nil:
+			e.Value.Sequence.Items = append(e.Value.Sequence.Items, value)
+		default:
+			return fmt.Errorf("%s: block %q conflicts with attribute %q", block.TypeRange, block.Type, key)
+		}
+		return nil
+	}
+	obj.Entries = append(obj.Entries, &styx.Entry{Key: scalar(key, styx.ScalarBare), Value: value})
+	return nil
+}
+
+func (c *converter) expr(e hclsyntax.Expression) (*styx.Value, error) {
+	switch e := e.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		return c.literal(e.Val, e.SrcRange), nil
+	case *hclsyntax.UnaryOpExpr:
+		if lit, ok := e.Val.(*hclsyntax.LiteralValueExpr); ok && e.Op == hclsyntax.OpNegate && lit.Val.Type() == cty.Number {
+			return scalar(c.text(e.SrcRange), styx.ScalarBare), nil
+		}
+	case *hclsyntax.TemplateExpr:
+		if e.IsStringLiteral() {
+			v, diags := e.Value(nil)
+			if !diags.HasErrors() {
+				return scalar(v.AsString(), styx.ScalarQuoted), nil
+			}
+		}
+	case *hclsyntax.TupleConsExpr:
+		seq := &styx.Sequence{Span: noSpan}
+		for _, item := range e.Exprs {
+			v, err := c.expr(item)
+			if err != nil {
+				return nil, err
+			}
+			seq.Items = append(seq.Items, v)
+		}
+		return &styx.Value{Span: noSpan, PayloadKind: styx.PayloadSequence, Sequence: seq}, nil
+	case *hclsyntax.ObjectConsExpr:
+		obj := newObject()
+		for _, item := range e.Items {
+			key, err := c.objectKey(item.KeyExpr)
+			if err != nil {
+				return nil, err
+			}
+			if obj.Has(key) {
+				return nil, fmt.Errorf("%s: duplicate object key %q", item.KeyExpr.Range(), key)
+			}
+			v, err := c.expr(item.ValueExpr)
+			if err != nil {
+				return nil, err
+			}
+			obj.Entries = append(obj.Entries, &styx.Entry{Key: scalar(key, styx.ScalarBare), Value: v})
+		}
+		v := objectValue(obj)
+		v.Tag = tag("map")
+		return v, nil
+	}
+	v := scalar(c.text(e.Range()), styx.ScalarQuoted)
+	v.Tag = tag("expr")
+	return v, nil
+}
+
+func (c *converter) literal(v cty.Value, r hcl.Range) *styx.Value {
+	switch {
+	case v.IsNull():
+		return &styx.Value{Span: noSpan}
+	case v.Type() == cty.String:
+		return scalar(v.AsString(), styx.ScalarQuoted)
+	case v.Type() == cty.Bool:
+		return scalar(fmt.Sprint(v.True()), styx.ScalarBare)
+	}
+	return scalar(c.text(r), styx.ScalarBare)
+}
+
+// objectKey returns the text of an object constructor key: a bare name or
+// a constant string.
+func (c *converter) objectKey(e hclsyntax.Expression) (string, error) {
+	if name := hcl.ExprAsKeyword(e); name != "" {
+		return name, nil
+	}
+	if key, ok := e.(*hclsyntax.ObjectConsKeyExpr); ok {
+		e = key.Wrapped
+	}
+	if tmpl, ok := e.(*hclsyntax.TemplateExpr); ok && tmpl.IsStringLiteral() {
+		v, diags := tmpl.Value(nil)
+		if !diags.HasErrors() {
+			return v.AsString(), nil
+		}
+	}
+	if lit, ok := e.(*hclsyntax.LiteralValueExpr); ok && !lit.Val.IsNull() {
+		return c.text(lit.SrcRange), nil
+	}
+	return "", fmt.Errorf("%s: object key %s is not a constant", e.Range(), c.text(e.Range()))
+}
+
+// ToOptions configures ToHCL.
+type ToOptions struct {
+	// Labels gives the number of labels each block type takes, which
+	// ToHCL reads from the nested objects FromHCL makes of them. A key is
+	// a block type, wherever the block is, or the types of nested blocks
+	// joined by dots, such as "resource.provisioner", for the innermost
+	// block inside the others; the longest key that matches applies.
+	// Block types not listed take none.
+	Labels map[string]int
+}
+
+// TerraformLabels lists the labels of Terraform's block types.
+var TerraformLabels = map[string]int{
+	"resource":    2,
+	"data":        2,
+	"module":      1,
+	"provider":    1,
+	"variable":    1,
+	"output":      1,
+	"provisioner": 1,
+	"dynamic":     1,
+	"lifecycle":   0,
+}
+
+// ToHCL converts doc to HCL native syntax, reversing FromHCL: untagged
+// objects, and sequences of them, become blocks, and other values
+// attributes. Keys must be valid HCL identifiers where HCL needs a name.
+// Other tags than `@map` and `@expr` have no HCL form, and are rejected.
+func ToHCL(doc *styx.Document, opts ToOptions) ([]byte, error) {
+	entries := doc.Entries
+	if len(entries) == 1 && entries[0].Key.IsImplicitUnit() {
+		root := entries[0].Value
+		if root.PayloadKind != styx.PayloadObject || root.Tag != nil {
+			return nil, errors.New("an HCL body must be an object")
+		}
+	}
+	var fields []field
+	for _, key := range doc.Keys() {
+		value, _ := doc.LookupKeys(key)
+		fields = append(fields, field{key, value})
+	}
+	f := hclwrite.NewEmptyFile()
+	w := writer{labels: opts.Labels}
+	if err := w.body(f.Body(), nil, nil, fields); err != nil {
+		return nil, err
+	}
+	return hclwrite.Format(f.Bytes()), nil
+}
+
+type field struct {
+	key   string
+	value *styx.Value
+}
+
+func objectFields(obj *styx.Object) []field {
+	var fields []field
+	for _, key := range obj.Keys() {
+		fields = append(fields, field{key, obj.Get(key)})
+	}
+	return fields
+}
+
+type writer struct {
+	labels map[string]int
+}
+
+// labelCount returns the number of labels of the innermost of the nested
+// block types types.
+func (w *writer) labelCount(types []string) int {
+	for i := range types {
+		if n, ok := w.labels[strings.Join(types[i:], ".")]; ok {
+			return n
+		}
+	}
+	return 0
+}
+
+// body writes fields to b, the body of blocks of the nested types types,
+// attributes first, as HCL style has them.
+func (w *writer) body(b *hclwrite.Body, path, types []string, fields []field) error {
+	for _, f := range fields {
+		if isBlock(f.value) {
+			continue
+		}
+		if !hclsyntax.ValidIdentifier(f.key) {
+			return fmt.Errorf("%s: %q is not a valid HCL attribute name", formatPath(path), f.key)
+		}
+		tokens, err := w.tokens(appendPath(path, f.key), f.value)
+		if err != nil {
+			return err
+		}
+		b.SetAttributeRaw(f.key, tokens)
+	}
+	for _, f := range fields {
+		if !isBlock(f.value) {
+			continue
+		}
+		if !hclsyntax.ValidIdentifier(f.key) {
+			return fmt.Errorf("%s: %q is not a valid HCL block type", formatPath(path), f.key)
+		}
+		inner := appendPath(types, f.key)
+		if err := w.blocks(b, appendPath(path, f.key), inner, nil, w.labelCount(inner), f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blocks writes the blocks found in v, of the innermost of the nested block
+// types types, reading another remaining labels from nested object keys.
+func (w *writer) blocks(b *hclwrite.Body, path, types, labels []string, remaining int, v *styx.Value) error {
+	typ := types[len(types)-1]
+	if remaining > 0 {
+		if v.PayloadKind != styx.PayloadObject || v.Tag != nil {
+			return fmt.Errorf("%s: block %q needs %d more labels", formatPath(path), typ, remaining)
+		}
+		for _, f := range objectFields(v.Object) {
+			label := append(labels[:len(labels):len(labels)], f.key)
+			if err := w.blocks(b, appendPath(path, f.key), types, label, remaining-1, f.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	bodies := []*styx.Value{v}
+	if v.PayloadKind == styx.PayloadSequence {
+		bodies = v.Sequence.Items
+	}
+	for _, body := range bodies {
+		if body.PayloadKind != styx.PayloadObject || body.Tag != nil {
+			return fmt.Errorf("%s: block %q needs an object body", formatPath(path), typ)
+		}
+		if len(b.Attributes()) > 0 || len(b.Blocks()) > 0 {
+			b.AppendNewline()
+		}
+		block := b.AppendNewBlock(typ, labels)
+		if err := w.body(block.Body(), path, types, objectFields(body.Object)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBlock reports whether v is written as blocks: an untagged object or a
+// non-empty sequence of them.
+func isBlock(v *styx.Value) bool {
+	if v.Tag != nil {
+		return false
+	}
+	switch v.PayloadKind {
+	case styx.PayloadObject:
+		return true
+	case styx.PayloadSequence:
+		for _, item := range v.Sequence.Items {
+			if item.PayloadKind != styx.PayloadObject || item.Tag != nil {
+				return false
+			}
+		}
+		return len(v.Sequence.Items) > 0
+	}
+	return false
+}
+
+var number = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// tokens returns the expression for an attribute value.
+func (w *writer) tokens(path []string, v *styx.Value) (hclwrite.Tokens, error) {
+	if v.Tag != nil {
+		switch {
+		case v.Tag.Name == "expr" && v.PayloadKind == styx.PayloadScalar:
+			src := []byte(v.Scalar.Text)
+			if _, diags := hclsyntax.ParseExpression(src, "", hcl.InitialPos); diags.HasErrors() {
+				return nil, fmt.Errorf("%s: invalid HCL expression %q", formatPath(path), v.Scalar.Text)
+			}
+			return hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: src}}, nil
+		case v.Tag.Name == "map" && v.PayloadKind == styx.PayloadObject:
+			var attrs []hclwrite.ObjectAttrTokens
+			for _, f := range objectFields(v.Object) {
+				value, err := w.tokens(appendPath(path, f.key), f.value)
+				if err != nil {
+					return nil, err
+				}
+				name := hclwrite.TokensForValue(cty.StringVal(f.key))
+				if hclsyntax.ValidIdentifier(f.key) {
+					name = hclwrite.TokensForIdentifier(f.key)
+				}
+				attrs = append(attrs, hclwrite.ObjectAttrTokens{Name: name, Value: value})
+			}
+			return hclwrite.TokensForObject(attrs), nil
+		}
+		return nil, fmt.Errorf("%s: HCL has no form for @%s", formatPath(path), v.Tag.Name)
+	}
+	switch v.PayloadKind {
+	case styx.PayloadScalar:
+		text := v.Scalar.Text
+		if v.Scalar.Kind == styx.ScalarBare {
+			switch {
+			case text == "true" || text == "false":
+				return hclwrite.TokensForValue(cty.BoolVal(text == "true")), nil
+			case number.MatchString(text):
+				return hclwrite.Tokens{{Type: hclsyntax.TokenNumberLit, Bytes: []byte(text)}}, nil
+			}
+		}
+		return hclwrite.TokensForValue(cty.StringVal(text)), nil
+	case styx.PayloadSequence:
+		var items []hclwrite.Tokens
+		for i, item := range v.Sequence.Items {
+			tokens, err := w.tokens(appendPath(path, fmt.Sprint(i)), item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, tokens)
+		}
+		return hclwrite.TokensForTuple(items), nil
+	case styx.PayloadNone:
+		return hclwrite.TokensForValue(cty.NullVal(cty.DynamicPseudoType)), nil
+	}
+	return nil, fmt.Errorf("%s: objects inside attribute values must be tagged @map", formatPath(path))
+}
+
+func appendPath(path []string, key string) []string {
+	return append(path[:len(path):len(path)], key)
+}
+
+func formatPath(path []string) string {
+	if len(path) == 0 {
+		return "document"
+	}
+	return strings.Join(path, ".")
+}
+
+func newObject() *styx.Object {
+	return &styx.Object{Span: noSpan}
+}
+
+func objectValue(obj *styx.Object) *styx.Value {
+	return &styx.Value{Span: noSpan, PayloadKind: styx.PayloadObject, Object: obj}
+}
+
+// sequenceValue makes a sequence of the bodies of repeated blocks.
+func sequenceValue(items ...*styx.Value) *styx.Value {
+	return &styx.Value{Span: noSpan, PayloadKind: styx.PayloadSequence, Sequence: &styx.Sequence{Items: items, Span: noSpan}}
+}
+
+func tag(name string) *styx.Tag {
+	return &styx.Tag{Name: name, Span: noSpan, NameSpan: noSpan}
+}
+
+func scalar(text string, kind styx.ScalarKind) *styx.Value {
+	return &styx.Value{
+		Span:        noSpan,
+		PayloadKind: styx.PayloadScalar,
+		Scalar:      &styx.Scalar{Text: text, Kind: kind, Span: noSpan},
+	}
+}
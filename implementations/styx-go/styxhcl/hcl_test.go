@@ -0,0 +1,221 @@
+package styxhcl
+
+import (
+	"strings"
+	"testing"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+const terraform = `region = "us-east-1"
+count  = 3
+ratio  = -0.5
+debug  = true
+none   = null
+zones  = ["a", "b"]
+labels = { Name = "web", "kubernetes.io/role" = "node" }
+ami    = var.ami
+greet  = "hello ${var.name}"
+
+resource "aws_instance" "web" {
+  ami = var.ami
+
+  ebs_block_device {
+    size = 10
+  }
+  ebs_block_device {
+    size = 20
+  }
+}
+
+resource "aws_instance" "db" {
+  ami = "ami-123"
+}
+`
+
+func TestFromHCL(t *testing.T) {
+	doc, err := FromHCL([]byte(terraform), "main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `region us-east-1
+count 3
+ratio -0.5
+debug true
+none @
+zones (a b)
+labels @map{
+    Name web
+    kubernetes.io/role node
+}
+ami @expr"var.ami"
+greet @expr"\"hello ${var.name}\""
+resource {
+    aws_instance {
+        web {
+            ami @expr"var.ami"
+            ebs_block_device ({
+                size 10
+            } {
+                size 20
+            })
+        }
+        db {
+            ami ami-123
+        }
+    }
+}
+`
+	if got := string(styx.Format(doc)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHCLRoundTrip(t *testing.T) {
+	doc, err := FromHCL([]byte(terraform), "main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ToHCL(doc, ToOptions{Labels: TerraformLabels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromHCL(out, "out.tf")
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if diffs := styx.Diff(doc, back); len(diffs) > 0 {
+		t.Errorf("round trip changed the document: %v\n%s", diffs, out)
+	}
+	if !strings.Contains(string(out), `resource "aws_instance" "web" {`) {
+		t.Errorf("labels not restored:\n%s", out)
+	}
+}
+
+func TestHCLRoundTripNestedLabels(t *testing.T) {
+	source := `resource "aws_security_group" "web" {
+  name = "web"
+
+  dynamic "ingress" {
+    for_each = var.ports
+    content {
+      from_port = ingress.value
+    }
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+resource "aws_instance" "web" {
+  ami = var.ami
+
+  provisioner "local-exec" {
+    command = "echo hello"
+  }
+  provisioner "remote-exec" {
+    inline = ["uptime"]
+  }
+}
+`
+	doc, err := FromHCL([]byte(source), "main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ToHCL(doc, ToOptions{Labels: TerraformLabels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromHCL(out, "out.tf")
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if diffs := styx.Diff(doc, back); len(diffs) > 0 {
+		t.Errorf("round trip changed the document: %v\n%s", diffs, out)
+	}
+	for _, want := range []string{`dynamic "ingress" {`, `provisioner "local-exec" {`, `provisioner "remote-exec" {`, "lifecycle {"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	// A key naming the enclosing block types applies only inside them.
+	doc, err = styx.Parse("step {run {cmd a}}\njob {step {build {cmd b}}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = ToHCL(doc, ToOptions{Labels: map[string]int{"job.step": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `step {
+  run {
+    cmd = "a"
+  }
+}
+
+job {
+  step "build" {
+    cmd = "b"
+  }
+}
+`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestToHCL(t *testing.T) {
+	doc, err := styx.Parse(`name app
+port 8080
+enabled true
+path "/tmp"
+server {
+  host localhost
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ToHCL(doc, ToOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `name    = "app"
+port    = 8080
+enabled = true
+path    = "/tmp"
+
+server {
+  host = "localhost"
+}
+`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestHCLErrors(t *testing.T) {
+	if _, err := FromHCL([]byte("a = "), "bad.tf"); err == nil || !strings.Contains(err.Error(), "bad.tf:1") {
+		t.Errorf("parse error = %v", err)
+	}
+	if _, err := FromHCL([]byte("a = 1\na {\n}\n"), "conflict.tf"); err == nil {
+		t.Errorf("block over attribute was accepted")
+	}
+	for _, source := range []string{
+		"a @env\"HOME\"",
+		"\"not a name\" 1",
+		"a @expr\"1 +\"",
+		"resource {x 1}",
+	} {
+		doc, err := styx.Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := ToOptions{Labels: TerraformLabels}
+		if _, err := ToHCL(doc, opts); err == nil {
+			t.Errorf("%q: converted without error", source)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package styxkoanf reads Styx configuration into koanf
+// (github.com/knadh/koanf). Parser implements koanf.Parser and Provider
+// koanf.Provider; both match koanf's interfaces by shape, so the package
+// does not depend on koanf:
+//
+//	k := koanf.New(".")
+//	err := k.Load(file.Provider("app.styx"), styxkoanf.Parser())
+//
+// or, without a separate parser,
+//
+//	err := k.Load(styxkoanf.Provider("app.styx"), nil)
+//
+// Scalars are read as strings, which koanf's typed getters convert. Tags
+// are dropped, keeping their payloads, as koanf has no place for them.
+package styxkoanf
+
+import (
+	"os"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+// Styx parses and writes Styx for koanf.
+type Styx struct{}
+
+// Parser returns a koanf parser for Styx.
+func Parser() *Styx {
+	return &Styx{}
+}
+
+// Unmarshal parses b into a nested map. It copies b, which koanf may reuse.
+func (p *Styx) Unmarshal(b []byte) (map[string]any, error) {
+	doc, err := styx.Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+	return toMap(doc), nil
+}
+
+// Marshal writes o as a Styx document, with keys in sorted order.
+func (p *Styx) Marshal(o map[string]any) ([]byte, error) {
+	if len(o) == 0 {
+		return nil, nil
+	}
+	v, err := styx.FromInterface(o)
+	if err != nil {
+		return nil, err
+	}
+	return styx.Format(&styx.Document{Entries: v.Object.Entries}), nil
+}
+
+// File provides the configuration in a Styx file.
+type File struct {
+	path string
+}
+
+// Provider returns a koanf provider reading the Styx file at path.
+func Provider(path string) *File {
+	return &File{path: path}
+}
+
+// ReadBytes returns the contents of the file, for use with a parser.
+func (f *File) ReadBytes() ([]byte, error) {
+	return os.ReadFile(f.path)
+}
+
+// Read parses the file into a nested map. Parse errors name the file.
+func (f *File) Read() (map[string]any, error) {
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := styx.ParseBytesWithOptions(content, styx.ParseOptions{Filename: f.path})
+	if err != nil {
+		return nil, err
+	}
+	return toMap(doc), nil
+}
+
+func toMap(doc *styx.Document) map[string]any {
+	m, _ := doc.InterfaceWithOptions(styx.InterfaceOptions{Tags: styx.TagDrop}).(map[string]any)
+	if m == nil {
+		m = map[string]any{}
+	}
+	return m
+}
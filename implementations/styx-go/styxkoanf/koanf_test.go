@@ -0,0 +1,87 @@
+package styxkoanf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The interfaces of github.com/knadh/koanf/v2, which the adapters satisfy
+// without importing it.
+var (
+	_ interface {
+		Unmarshal([]byte) (map[string]any, error)
+		Marshal(map[string]any) ([]byte, error)
+	} = Parser()
+	_ interface {
+		ReadBytes() ([]byte, error)
+		Read() (map[string]any, error)
+	} = Provider("")
+)
+
+const source = `// app settings
+name app
+server.host localhost
+server.port 8080
+hosts (a b)
+token @env"TOKEN"
+`
+
+var want = map[string]any{
+	"name":   "app",
+	"server": map[string]any{"host": "localhost", "port": "8080"},
+	"hosts":  []any{"a", "b"},
+	"token":  "TOKEN",
+}
+
+func TestParser(t *testing.T) {
+	got, err := Parser().Unmarshal([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal = %#v, want %#v", got, want)
+	}
+
+	out, err := Parser().Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := Parser().Unmarshal(out)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(back, want) {
+		t.Errorf("round trip = %#v, want %#v", back, want)
+	}
+
+	if _, err := Parser().Unmarshal([]byte("a {")); err == nil {
+		t.Error("Unmarshal accepted invalid input")
+	}
+}
+
+func TestProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.styx")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Provider(path).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read = %#v, want %#v", got, want)
+	}
+	if b, err := Provider(path).ReadBytes(); err != nil || string(b) != source {
+		t.Errorf("ReadBytes = %q, %v", b, err)
+	}
+
+	if err := os.WriteFile(path, []byte("a {"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Provider(path).Read(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Read error = %v, want one naming the file", err)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/bearcove/styx/implementations/styx-go/styxtoml
+
+go 1.22
+
+require (
+	github.com/bearcove/styx/implementations/styx-go v0.0.0
+	github.com/pelletier/go-toml/v2 v2.2.4
+)
+
+replace github.com/bearcove/styx/implementations/styx-go => ../
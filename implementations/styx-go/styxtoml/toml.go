@@ -0,0 +1,569 @@
+// Package styxtoml converts between Styx documents and TOML, for projects
+// whose existing configuration is TOML.
+//
+// Tables become objects and arrays of tables sequences of objects. TOML's
+// typed scalars become Styx scalars: strings quoted, integers in decimal,
+// and floats, booleans and date-times as written, with a `T` between date
+// and time. In the other direction a bare Styx scalar that reads as a TOML
+// integer, float, boolean or date-time is written as one, and any other
+// scalar as a string. Key order and comments are preserved, except that
+// TOML needs a table's key/value pairs before its subtables.
+package styxtoml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// noSpan locates nodes converted from TOML, which have no Styx source.
+var noSpan = styx.Span{Start: -1, End: -1}
+
+// ToTOML converts doc to a TOML document. The objects of dotted keys
+// sharing a prefix are combined. Comments are written with their entries,
+// and those of the document's Comments after the last such comment, at the
+// end. TOML has no unit value and no tags, so documents holding either are
+// rejected, as are documents whose root is not an object.
+func ToTOML(doc *styx.Document) ([]byte, error) {
+	entries := doc.Entries
+	if len(entries) == 1 && entries[0].Key.IsImplicitUnit() {
+		root := entries[0].Value
+		if root.PayloadKind != styx.PayloadObject || root.Tag != nil {
+			return nil, errors.New("a TOML document must be a table")
+		}
+		entries = root.Object.Entries
+	}
+	var fields []field
+	for _, key := range doc.Keys() {
+		value, _ := doc.LookupKeys(key)
+		fields = append(fields, field{key, findEntry(entries, key), value})
+	}
+	var w writer
+	if err := w.table(nil, fields); err != nil {
+		return nil, err
+	}
+	for _, c := range finalComments(doc) {
+		w.b.WriteString("#" + strings.TrimLeft(c.Text, "/") + "\n")
+	}
+	return w.b.Bytes(), nil
+}
+
+// finalComments returns the comments of doc.Comments after the last one
+// attached to an entry, which no entry carries.
+func finalComments(doc *styx.Document) []*styx.Comment {
+	attached := make(map[*styx.Comment]bool)
+	var entries func([]*styx.Entry)
+	var value func(*styx.Value)
+	entries = func(es []*styx.Entry) {
+		for _, e := range es {
+			for _, c := range e.LeadingComments {
+				attached[c] = true
+			}
+			if e.TrailingComment != nil {
+				attached[e.TrailingComment] = true
+			}
+			value(e.Value)
+		}
+	}
+	value = func(v *styx.Value) {
+		switch v.PayloadKind {
+		case styx.PayloadObject:
+			entries(v.Object.Entries)
+		case styx.PayloadSequence:
+			for _, item := range v.Sequence.Items {
+				value(item)
+			}
+		}
+	}
+	entries(doc.Entries)
+	i := len(doc.Comments)
+	for i > 0 && !attached[doc.Comments[i-1]] {
+		i--
+	}
+	return doc.Comments[i:]
+}
+
+// field is a key of an object, with the values of its split entries
+// combined, and the first of those entries, which carries its comments.
+type field struct {
+	key   string
+	entry *styx.Entry
+	value *styx.Value
+}
+
+func objectFields(obj *styx.Object) []field {
+	var fields []field
+	for _, key := range obj.Keys() {
+		fields = append(fields, field{key, findEntry(obj.Entries, key), obj.Get(key)})
+	}
+	return fields
+}
+
+func findEntry(entries []*styx.Entry, key string) *styx.Entry {
+	for _, e := range entries {
+		if !e.Key.Implicit && e.KeyText() == key {
+			return e
+		}
+	}
+	return nil
+}
+
+type writer struct {
+	b bytes.Buffer
+}
+
+// table writes the key/value pairs of the table at path, then its
+// subtables and arrays of tables.
+func (w *writer) table(path []string, fields []field) error {
+	for _, f := range fields {
+		if isTable(f.value) || isArrayOfTables(f.value) {
+			continue
+		}
+		w.leadingComments(f.entry)
+		w.b.WriteString(formatKey(f.key) + " = ")
+		if err := w.value(appendPath(path, f.key), f.value); err != nil {
+			return err
+		}
+		w.trailingComment(f.entry)
+	}
+	for _, f := range fields {
+		child := appendPath(path, f.key)
+		switch {
+		case isTable(f.value):
+			w.header(f.entry, "["+formatPath(child)+"]")
+			if err := w.table(child, objectFields(f.value.Object)); err != nil {
+				return err
+			}
+		case isArrayOfTables(f.value):
+			for i, item := range f.value.Sequence.Items {
+				if i == 0 {
+					w.header(f.entry, "[["+formatPath(child)+"]]")
+				} else {
+					w.header(nil, "[["+formatPath(child)+"]]")
+				}
+				if err := w.table(child, objectFields(item.Object)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (w *writer) header(entry *styx.Entry, header string) {
+	if w.b.Len() > 0 {
+		w.b.WriteByte('\n')
+	}
+	w.leadingComments(entry)
+	w.b.WriteString(header)
+	w.trailingComment(entry)
+}
+
+func (w *writer) leadingComments(entry *styx.Entry) {
+	if entry == nil {
+		return
+	}
+	for _, c := range entry.LeadingComments {
+		w.b.WriteString("#" + strings.TrimLeft(c.Text, "/") + "\n")
+	}
+}
+
+func (w *writer) trailingComment(entry *styx.Entry) {
+	if entry != nil && entry.TrailingComment != nil {
+		w.b.WriteString(" #" + strings.TrimLeft(entry.TrailingComment.Text, "/"))
+	}
+	w.b.WriteByte('\n')
+}
+
+// value writes v inline, as the value of a key/value pair or an element
+// of an array.
+func (w *writer) value(path []string, v *styx.Value) error {
+	if v.Tag != nil {
+		return fmt.Errorf("%s: TOML has no tags, cannot convert @%s", formatPath(path), v.Tag.Name)
+	}
+	switch v.PayloadKind {
+	case styx.PayloadScalar:
+		if v.Scalar.Kind == styx.ScalarBare && isTyped(v.Scalar.Text) {
+			w.b.WriteString(v.Scalar.Text)
+		} else {
+			w.b.WriteString(quote(v.Scalar.Text))
+		}
+	case styx.PayloadSequence:
+		w.b.WriteByte('[')
+		for i, item := range v.Sequence.Items {
+			if i > 0 {
+				w.b.WriteString(", ")
+			}
+			if err := w.value(appendPath(path, strconv.Itoa(i)), item); err != nil {
+				return err
+			}
+		}
+		w.b.WriteByte(']')
+	case styx.PayloadObject:
+		w.b.WriteByte('{')
+		for i, f := range objectFields(v.Object) {
+			if i > 0 {
+				w.b.WriteByte(',')
+			}
+			w.b.WriteString(" " + formatKey(f.key) + " = ")
+			if err := w.value(appendPath(path, f.key), f.value); err != nil {
+				return err
+			}
+		}
+		if len(v.Object.Entries) > 0 {
+			w.b.WriteByte(' ')
+		}
+		w.b.WriteByte('}')
+	default:
+		return fmt.Errorf("%s: TOML has no unit value", formatPath(path))
+	}
+	return nil
+}
+
+func isTable(v *styx.Value) bool {
+	return v.PayloadKind == styx.PayloadObject && v.Tag == nil
+}
+
+// isArrayOfTables reports whether v is a non-empty sequence of untagged
+// objects, written as an array of tables.
+func isArrayOfTables(v *styx.Value) bool {
+	if v.PayloadKind != styx.PayloadSequence || v.Tag != nil || len(v.Sequence.Items) == 0 {
+		return false
+	}
+	for _, item := range v.Sequence.Items {
+		if !isTable(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func appendPath(path []string, key string) []string {
+	return append(path[:len(path):len(path)], key)
+}
+
+var (
+	bareKey      = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	tomlInteger  = regexp.MustCompile(`^[+-]?(0|[1-9][0-9]*)$`)
+	tomlFloat    = regexp.MustCompile(`^[+-]?((0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?|inf|nan)$`)
+	dateTimeForm = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02", "15:04:05.999999999"}
+)
+
+// isTyped reports whether text is a TOML integer, float, boolean or
+// date-time, as written by FromTOML.
+func isTyped(text string) bool {
+	if text == "true" || text == "false" {
+		return true
+	}
+	if tomlInteger.MatchString(text) {
+		_, err := strconv.ParseInt(text, 10, 64)
+		return err == nil
+	}
+	if tomlFloat.MatchString(text) {
+		return true
+	}
+	for _, layout := range dateTimeForm {
+		if _, err := time.Parse(layout, text); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func formatKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return quote(key)
+}
+
+func formatPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = formatKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// quote writes s as a TOML basic string.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// FromTOML converts a TOML document to Styx. Comments on their own lines
+// lead the next key/value pair or table header, and comments after one
+// trail it. Like the parser, FromTOML records every comment in the
+// document's Comments, which alone hold those after the last key/value
+// pair or header. The nodes of the result have no location.
+func FromTOML(data []byte) (*styx.Document, error) {
+	p := unstable.Parser{KeepComments: true}
+	p.Reset(data)
+	b := builder{
+		root:    &styx.Object{Span: noSpan},
+		defined: make(map[*styx.Object]bool),
+		inline:  make(map[*styx.Object]bool),
+		arrays:  make(map[*styx.Sequence]bool),
+	}
+	b.current = b.root
+	for p.NextExpression() {
+		expr := p.Expression()
+		var err error
+		switch expr.Kind {
+		case unstable.Comment:
+			b.comments = append(b.comments, b.comment(expr))
+			continue
+		case unstable.KeyValue:
+			err = b.keyValue(expr)
+		case unstable.Table:
+			err = b.table(expr)
+		case unstable.ArrayTable:
+			err = b.arrayTable(expr)
+		}
+		if err != nil {
+			return nil, positionError(&p, expr, err)
+		}
+	}
+	if err := p.Error(); err != nil {
+		var perr *unstable.ParserError
+		if errors.As(err, &perr) {
+			pos := p.Shape(p.Range(perr.Highlight)).Start
+			return nil, fmt.Errorf("line %d, column %d: %s", pos.Line, pos.Column, perr.Message)
+		}
+		return nil, err
+	}
+	return &styx.Document{Entries: b.root.Entries, Span: noSpan, Comments: b.all}, nil
+}
+
+func positionError(p *unstable.Parser, expr *unstable.Node, err error) error {
+	pos := p.Shape(expr.Raw).Start
+	return fmt.Errorf("line %d: %w", pos.Line, err)
+}
+
+// comment converts a TOML comment, recording it for the document.
+func (b *builder) comment(node *unstable.Node) *styx.Comment {
+	c := &styx.Comment{Text: "//" + strings.TrimPrefix(string(node.Data), "#"), Span: noSpan}
+	b.all = append(b.all, c)
+	return c
+}
+
+// builder assembles the Styx tree as TOML expressions arrive, enforcing
+// the rules TOML places on defining tables.
+type builder struct {
+	root    *styx.Object
+	current *styx.Object
+	// defined holds the tables defined by a header, which another header
+	// may not define again.
+	defined map[*styx.Object]bool
+	// inline holds the tables written inline, which may not be extended.
+	inline map[*styx.Object]bool
+	// arrays holds the sequences made by array-of-tables headers, which
+	// later headers extend; other arrays are fixed.
+	arrays map[*styx.Sequence]bool
+	// comments holds the comments awaiting the next entry.
+	comments []*styx.Comment
+	// all holds every comment, in order, for Document.Comments.
+	all []*styx.Comment
+}
+
+// attach gives entry the pending comments, and the comment trailing expr.
+func (b *builder) attach(entry *styx.Entry, expr *unstable.Node) {
+	entry.LeadingComments = append(entry.LeadingComments, b.comments...)
+	b.comments = nil
+	if next := expr.Next(); next != nil && next.Kind == unstable.Comment {
+		entry.TrailingComment = b.comment(next)
+	}
+}
+
+func keyParts(expr *unstable.Node) []string {
+	var keys []string
+	it := expr.Key()
+	for it.Next() {
+		keys = append(keys, string(it.Node().Data))
+	}
+	return keys
+}
+
+// descend returns the table at keys below obj, creating missing tables.
+// Headers descend into the last table of an array of tables; dotted keys
+// may not.
+func (b *builder) descend(obj *styx.Object, keys []string, header bool) (*styx.Object, error) {
+	for i, key := range keys {
+		entry := findEntry(obj.Entries, key)
+		if entry == nil {
+			entry = objectEntry(key)
+			obj.Entries = append(obj.Entries, entry)
+		}
+		switch v := entry.Value; {
+		case v.PayloadKind == styx.PayloadObject && !b.inline[v.Object]:
+			obj = v.Object
+		case header && v.PayloadKind == styx.PayloadSequence && b.arrays[v.Sequence]:
+			obj = v.Sequence.Items[len(v.Sequence.Items)-1].Object
+		default:
+			return nil, fmt.Errorf("cannot extend %q, which is not a table", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return obj, nil
+}
+
+func (b *builder) keyValue(expr *unstable.Node) error {
+	keys := keyParts(expr)
+	obj, err := b.descend(b.current, keys[:len(keys)-1], false)
+	if err != nil {
+		return err
+	}
+	key := keys[len(keys)-1]
+	if findEntry(obj.Entries, key) != nil {
+		return fmt.Errorf("duplicate key %q", strings.Join(keys, "."))
+	}
+	entry := &styx.Entry{Key: scalar(key, styx.ScalarBare)}
+	b.attach(entry, expr)
+	if entry.Value, err = b.value(expr.Value()); err != nil {
+		return err
+	}
+	obj.Entries = append(obj.Entries, entry)
+	return nil
+}
+
+func (b *builder) table(expr *unstable.Node) error {
+	keys := keyParts(expr)
+	parent, err := b.descend(b.root, keys[:len(keys)-1], true)
+	if err != nil {
+		return err
+	}
+	key := keys[len(keys)-1]
+	entry := findEntry(parent.Entries, key)
+	if entry == nil {
+		entry = objectEntry(key)
+		parent.Entries = append(parent.Entries, entry)
+	} else if entry.Value.PayloadKind != styx.PayloadObject || b.defined[entry.Value.Object] || b.inline[entry.Value.Object] {
+		return fmt.Errorf("table %q is already defined", strings.Join(keys, "."))
+	}
+	b.defined[entry.Value.Object] = true
+	b.current = entry.Value.Object
+	b.attach(entry, expr)
+	return nil
+}
+
+func (b *builder) arrayTable(expr *unstable.Node) error {
+	keys := keyParts(expr)
+	parent, err := b.descend(b.root, keys[:len(keys)-1], true)
+	if err != nil {
+		return err
+	}
+	key := keys[len(keys)-1]
+	entry := findEntry(parent.Entries, key)
+	if entry == nil {
+		seq := &styx.Sequence{Span: noSpan}
+		b.arrays[seq] = true
+		entry = &styx.Entry{
+			Key:   scalar(key, styx.ScalarBare),
+			Value: &styx.Value{Span: noSpan, PayloadKind: styx.PayloadSequence, Sequence: seq},
+		}
+		parent.Entries = append(parent.Entries, entry)
+	} else if entry.Value.PayloadKind != styx.PayloadSequence || !b.arrays[entry.Value.Sequence] {
+		return fmt.Errorf("%q is not an array of tables", strings.Join(keys, "."))
+	}
+	item := objectEntry(key).Value
+	entry.Value.Sequence.Items = append(entry.Value.Sequence.Items, item)
+	b.current = item.Object
+	b.attach(entry, expr)
+	return nil
+}
+
+// value converts an inline TOML value.
+func (b *builder) value(node *unstable.Node) (*styx.Value, error) {
+	text := string(node.Data)
+	switch node.Kind {
+	case unstable.String:
+		return scalar(text, styx.ScalarQuoted), nil
+	case unstable.Integer:
+		n, err := strconv.ParseInt(strings.ReplaceAll(text, "_", ""), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("integer %s is out of range", text)
+		}
+		return scalar(strconv.FormatInt(n, 10), styx.ScalarBare), nil
+	case unstable.Float:
+		return scalar(strings.ReplaceAll(text, "_", ""), styx.ScalarBare), nil
+	case unstable.Bool, unstable.LocalDate, unstable.LocalTime:
+		return scalar(text, styx.ScalarBare), nil
+	case unstable.LocalDateTime, unstable.DateTime:
+		if len(text) > 10 && (text[10] == ' ' || text[10] == 't') {
+			text = text[:10] + "T" + text[11:]
+		}
+		return scalar(text, styx.ScalarBare), nil
+	case unstable.Array:
+		seq := &styx.Sequence{Span: noSpan}
+		it := node.Children()
+		for it.Next() {
+			if it.Node().Kind == unstable.Comment {
+				b.comment(it.Node())
+				continue
+			}
+			item, err := b.value(it.Node())
+			if err != nil {
+				return nil, err
+			}
+			seq.Items = append(seq.Items, item)
+		}
+		return &styx.Value{Span: noSpan, PayloadKind: styx.PayloadSequence, Sequence: seq}, nil
+	case unstable.InlineTable:
+		obj := &styx.Object{Span: noSpan}
+		saved := b.current
+		b.current = obj
+		it := node.Children()
+		for it.Next() {
+			if err := b.keyValue(it.Node()); err != nil {
+				return nil, err
+			}
+		}
+		b.current = saved
+		b.inline[obj] = true
+		return &styx.Value{Span: noSpan, PayloadKind: styx.PayloadObject, Object: obj}, nil
+	}
+	return nil, fmt.Errorf("unsupported TOML value %s", node.Kind)
+}
+
+func objectEntry(key string) *styx.Entry {
+	return &styx.Entry{
+		Key:   scalar(key, styx.ScalarBare),
+		Value: &styx.Value{Span: noSpan, PayloadKind: styx.PayloadObject, Object: &styx.Object{Span: noSpan}},
+	}
+}
+
+func scalar(text string, kind styx.ScalarKind) *styx.Value {
+	return &styx.Value{
+		Span:        noSpan,
+		PayloadKind: styx.PayloadScalar,
+		Scalar:      &styx.Scalar{Text: text, Kind: kind, Span: noSpan},
+	}
+}
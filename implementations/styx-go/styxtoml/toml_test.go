@@ -0,0 +1,165 @@
+package styxtoml
+
+import (
+	"strings"
+	"testing"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+func TestFromTOML(t *testing.T) {
+	doc, err := FromTOML([]byte(`# package metadata
+name = "styx" # the crate
+version = "0.1.0"
+hex = 0xff
+big = 1_000_000
+ratio = 1_0.5e3
+on = true
+when = 1979-05-27 07:32:00Z
+point = { x = 1, y = 2 }
+tags = ["a", 1, [true]]
+site."example.com".port = 80
+
+[server]
+host = "localhost"
+
+[server.tls]
+cert = "/etc/cert.pem"
+
+[[bin]]
+name = "a"
+
+[[bin]]
+name = "b"
+[bin.extra]
+x = 1
+
+# end of file
+# really
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// package metadata
+name styx // the crate
+version 0.1.0
+hex 255
+big 1000000
+ratio 10.5e3
+on true
+when 1979-05-27T07:32:00Z
+point {
+    x 1
+    y 2
+}
+tags (a 1 (true))
+site {
+    example.com {
+        port 80
+    }
+}
+server {
+    host localhost
+    tls {
+        cert /etc/cert.pem
+    }
+}
+bin ({
+    name a
+} {
+    name b
+    extra {
+        x 1
+    }
+})
+`
+	if got := string(styx.Format(doc)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if n := len(doc.Comments); n != 4 || doc.Comments[3].Text != "// really" {
+		t.Errorf("comments: %v", doc.Comments)
+	}
+	if out, err := ToTOML(doc); err != nil || !strings.HasSuffix(string(out), "x = 1\n# end of file\n# really\n") {
+		t.Errorf("final comments not written back: %v\n%s", err, out)
+	}
+
+	for _, input := range []string{
+		"a = 1\na = 2\n",
+		"[a]\n[a]\n",
+		"a = 1\n[a]\n",
+		"a = {b = 1}\n[a.c]\n",
+		"a = [1]\n[[a]]\n",
+		"a = 99999999999999999999\n",
+		"a = \n",
+	} {
+		if _, err := FromTOML([]byte(input)); err == nil {
+			t.Errorf("FromTOML(%q): expected an error", input)
+		}
+	}
+	if _, err := FromTOML([]byte("a = 1\nb = ]\n")); err == nil || !strings.HasPrefix(err.Error(), "line 2") {
+		t.Errorf("syntax error: %v", err)
+	}
+}
+
+func TestToTOML(t *testing.T) {
+	doc, err := styx.Parse(`// the crate
+name styx
+version "0.1.0"
+port 8080 // http
+quoted "8080"
+when 1979-05-27T07:32:00Z
+server.host localhost
+server.tls.cert /etc/cert.pem
+tags (a "b\"c" 1.5 {x 1})
+bin ({name a} {name b})
+"odd key" true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToTOML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# the crate
+name = "styx"
+version = "0.1.0"
+port = 8080 # http
+quoted = "8080"
+when = 1979-05-27T07:32:00Z
+tags = ["a", "b\"c", 1.5, { x = 1 }]
+"odd key" = true
+
+[server]
+host = "localhost"
+
+[server.tls]
+cert = "/etc/cert.pem"
+
+[[bin]]
+name = "a"
+
+[[bin]]
+name = "b"
+`
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	back, err := FromTOML(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := styx.Diff(doc, back); len(changes) > 0 {
+		t.Errorf("round trip changed the document:\n%s", styx.Format(back))
+	}
+
+	for _, source := range []string{"a @", "a @env\"HOME\"", "a (1 @)"} {
+		doc, err := styx.Parse(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ToTOML(doc); err == nil {
+			t.Errorf("ToTOML(%q): expected an error", source)
+		}
+	}
+}
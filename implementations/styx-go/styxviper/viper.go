@@ -0,0 +1,45 @@
+// Package styxviper lets viper (github.com/spf13/viper) read and write
+// Styx configuration. Codec implements viper's encoding.Codec by shape, so
+// the package does not depend on viper:
+//
+//	codecs := viper.NewCodecRegistry()
+//	codecs.RegisterCodec("styx", styxviper.Codec{})
+//	v := viper.NewWithOptions(viper.WithCodecRegistry(codecs))
+//	v.SetConfigFile("app.styx")
+//	err := v.ReadInConfig()
+//
+// Scalars are read as strings, which viper's typed getters convert. Tags
+// are dropped, keeping their payloads, as viper has no place for them.
+package styxviper
+
+import (
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+// Codec encodes and decodes Styx for viper.
+type Codec struct{}
+
+// Encode writes v as a Styx document, with keys in sorted order.
+func (Codec) Encode(v map[string]any) ([]byte, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	value, err := styx.FromInterface(v)
+	if err != nil {
+		return nil, err
+	}
+	return styx.Format(&styx.Document{Entries: value.Object.Entries}), nil
+}
+
+// Decode parses b into v. It copies b, which viper may reuse.
+func (Codec) Decode(b []byte, v map[string]any) error {
+	doc, err := styx.Parse(string(b))
+	if err != nil {
+		return err
+	}
+	m, _ := doc.InterfaceWithOptions(styx.InterfaceOptions{Tags: styx.TagDrop}).(map[string]any)
+	for key, value := range m {
+		v[key] = value
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package styxviper
+
+import (
+	"reflect"
+	"testing"
+)
+
+// viper.Codec, from github.com/spf13/viper, which Codec satisfies without
+// importing viper.
+var _ interface {
+	Encode(map[string]any) ([]byte, error)
+	Decode([]byte, map[string]any) error
+} = Codec{}
+
+func TestCodec(t *testing.T) {
+	v := map[string]any{}
+	if err := (Codec{}).Decode([]byte("name app\nserver.host localhost\nserver.port 8080\nmode @prod\n"), v); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":   "app",
+		"server": map[string]any{"host": "localhost", "port": "8080"},
+		"mode":   nil,
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Decode = %#v, want %#v", v, want)
+	}
+
+	out, err := Codec{}.Encode(map[string]any{"name": "my app", "port": 8080, "tags": []any{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "name \"my app\"\nport 8080\ntags (a b)\n"; got != want {
+		t.Errorf("Encode = %q, want %q", got, want)
+	}
+
+	if err := (Codec{}).Decode([]byte("a {"), map[string]any{}); err == nil {
+		t.Error("Decode accepted invalid input")
+	}
+}
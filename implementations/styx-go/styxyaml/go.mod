@@ -0,0 +1,10 @@
+module github.com/bearcove/styx/implementations/styx-go/styxyaml
+
+go 1.22
+
+require (
+	github.com/bearcove/styx/implementations/styx-go v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/bearcove/styx/implementations/styx-go => ../
@@ -0,0 +1,291 @@
+// Package styxyaml converts between Styx documents and YAML, for projects
+// migrating from YAML configuration.
+//
+// Key order and comments are preserved in both directions. Styx tags map
+// to YAML local tags: `@env"HOME"` becomes `!env HOME` and back. Styx
+// scalars are untyped, so a bare Styx scalar is written as a plain YAML
+// scalar that YAML may read as a number or boolean, while a quoted one is
+// written as a YAML string; YAML scalars become Styx scalars holding their
+// text.
+package styxyaml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+	"gopkg.in/yaml.v3"
+)
+
+// noSpan locates nodes converted from YAML, which have no Styx source.
+var noSpan = styx.Span{Start: -1, End: -1}
+
+// ToYAML converts doc to a YAML document. Objects become mappings, with
+// the objects of dotted keys sharing a prefix combined; sequences become
+// sequences, and unit values null.
+func ToYAML(doc *styx.Document) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if len(doc.Entries) == 1 && doc.Entries[0].Key.IsImplicitUnit() {
+		root = toNode(doc.Entries[0].Value)
+	} else {
+		for _, key := range doc.Keys() {
+			value, _ := doc.LookupKeys(key)
+			root.Content = append(root.Content, keyNode(key, findEntry(doc.Entries, key)), toNode(value))
+		}
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// findEntry returns the first entry keyed key, whose comments the YAML key
+// carries.
+func findEntry(entries []*styx.Entry, key string) *styx.Entry {
+	for _, e := range entries {
+		if !e.Key.Implicit && e.KeyText() == key {
+			return e
+		}
+	}
+	return nil
+}
+
+func keyNode(key string, entry *styx.Entry) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: key}
+	if entry == nil {
+		return node
+	}
+	var head []string
+	for _, c := range entry.LeadingComments {
+		head = append(head, "#"+strings.TrimLeft(c.Text, "/"))
+	}
+	node.HeadComment = strings.Join(head, "\n")
+	if entry.TrailingComment != nil {
+		node.LineComment = "#" + strings.TrimLeft(entry.TrailingComment.Text, "/")
+	}
+	return node
+}
+
+func toNode(v *styx.Value) *yaml.Node {
+	node := &yaml.Node{}
+	switch v.PayloadKind {
+	case styx.PayloadScalar:
+		node.Kind, node.Value = yaml.ScalarNode, v.Scalar.Text
+		if v.Scalar.Kind != styx.ScalarBare {
+			node.Tag = "!!str"
+		}
+		if v.Scalar.Kind == styx.ScalarHeredoc {
+			node.Style = yaml.LiteralStyle
+		}
+	case styx.PayloadSequence:
+		node.Kind = yaml.SequenceNode
+		for _, item := range v.Sequence.Items {
+			node.Content = append(node.Content, toNode(item))
+		}
+	case styx.PayloadObject:
+		node.Kind = yaml.MappingNode
+		for _, key := range v.Object.Keys() {
+			node.Content = append(node.Content, keyNode(key, findEntry(v.Object.Entries, key)), toNode(v.Object.Get(key)))
+		}
+	default:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!null", "null"
+	}
+	if v.Tag != nil {
+		node.Tag = "!" + v.Tag.Name
+		if v.PayloadKind == styx.PayloadNone {
+			node.Value = ""
+		}
+	}
+	return node
+}
+
+// FromYAML converts the first document of a YAML stream to Styx. The
+// document must be a mapping, or empty. Mapping keys must be scalars, and
+// are taken as text. Aliases are expanded and merge keys (`<<`) applied.
+// Null becomes the unit value, and local tags such as `!env` Styx tags;
+// the standard tags (`!!str`, `!!int` and so on) are dropped. Comments
+// attach to the entries they precede or follow. The nodes of the result
+// have no location.
+func FromYAML(data []byte) (*styx.Document, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	doc := &styx.Document{Span: noSpan}
+	if node.Kind == 0 {
+		return doc, nil
+	}
+	root := resolve(node.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: a YAML document must be a mapping to convert to Styx", root.Line)
+	}
+	entries, err := fromMapping(root)
+	if err != nil {
+		return nil, err
+	}
+	doc.Entries = entries
+	return doc, nil
+}
+
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// mappingPairs returns the key and value nodes of a mapping with merge
+// keys applied: merged keys come first, unless the mapping sets them.
+func mappingPairs(node *yaml.Node) ([][2]*yaml.Node, error) {
+	var own, merged [][2]*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := resolve(node.Content[i]), node.Content[i+1]
+		if key.Kind == yaml.ScalarNode && key.Tag == "!!merge" {
+			sources := []*yaml.Node{resolve(value)}
+			if sources[0].Kind == yaml.SequenceNode {
+				sources = sources[0].Content
+			}
+			for _, source := range sources {
+				source = resolve(source)
+				if source.Kind != yaml.MappingNode {
+					return nil, fmt.Errorf("line %d: merge key needs a mapping", source.Line)
+				}
+				pairs, err := mappingPairs(source)
+				if err != nil {
+					return nil, err
+				}
+				merged = append(merged, pairs...)
+			}
+			continue
+		}
+		own = append(own, [2]*yaml.Node{node.Content[i], value})
+	}
+	if len(merged) == 0 {
+		return own, nil
+	}
+	seen := make(map[string]bool)
+	for _, pair := range own {
+		seen[resolve(pair[0]).Value] = true
+	}
+	var pairs [][2]*yaml.Node
+	for _, pair := range merged {
+		if key := resolve(pair[0]).Value; !seen[key] {
+			seen[key] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	return append(pairs, own...), nil
+}
+
+func fromMapping(node *yaml.Node) ([]*styx.Entry, error) {
+	pairs, err := mappingPairs(node)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*styx.Entry, 0, len(pairs))
+	seen := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		keyNode := resolve(pair[0])
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: mapping keys must be scalars", keyNode.Line)
+		}
+		if seen[keyNode.Value] {
+			return nil, fmt.Errorf("line %d: duplicate key %q", keyNode.Line, keyNode.Value)
+		}
+		seen[keyNode.Value] = true
+		value, err := fromNode(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		entry := &styx.Entry{Key: scalar(keyNode.Value, styx.ScalarBare), Value: value}
+		for _, line := range commentLines(keyNode.HeadComment) {
+			entry.LeadingComments = append(entry.LeadingComments, &styx.Comment{Text: line, Span: noSpan})
+		}
+		if line := commentLines(keyNode.LineComment + pair[1].LineComment); len(line) > 0 {
+			entry.TrailingComment = &styx.Comment{Text: strings.Join(line, " "), Span: noSpan}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// commentLines converts YAML comment text to Styx comment lines.
+func commentLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "#") {
+			lines = append(lines, "//"+strings.TrimPrefix(line, "#"))
+		}
+	}
+	return lines
+}
+
+func fromNode(node *yaml.Node) (*styx.Value, error) {
+	node = resolve(node)
+	var v *styx.Value
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" || node.Tag == "!!null" {
+			v = &styx.Value{Span: noSpan}
+			break
+		}
+		kind := styx.ScalarBare
+		switch {
+		case node.Style&yaml.LiteralStyle != 0:
+			kind = styx.ScalarHeredoc
+		case node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0:
+			kind = styx.ScalarQuoted
+		}
+		v = scalar(node.Value, kind)
+	case yaml.SequenceNode:
+		seq := &styx.Sequence{Span: noSpan}
+		for _, item := range node.Content {
+			value, err := fromNode(item)
+			if err != nil {
+				return nil, err
+			}
+			seq.Items = append(seq.Items, value)
+		}
+		v = &styx.Value{Span: noSpan, PayloadKind: styx.PayloadSequence, Sequence: seq}
+	case yaml.MappingNode:
+		entries, err := fromMapping(node)
+		if err != nil {
+			return nil, err
+		}
+		v = &styx.Value{Span: noSpan, PayloadKind: styx.PayloadObject, Object: &styx.Object{Entries: entries, Span: noSpan}}
+	default:
+		return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+	if tag := node.Tag; strings.HasPrefix(tag, "!") && !strings.HasPrefix(tag, "!!") {
+		name := strings.TrimPrefix(tag, "!")
+		if !validTagName(name) {
+			return nil, fmt.Errorf("line %d: YAML tag %q is not a valid Styx tag name", node.Line, tag)
+		}
+		v.Tag = &styx.Tag{Name: name, Span: noSpan, NameSpan: noSpan}
+		if node.Kind == yaml.ScalarNode && node.Value == "" && node.Style&^yaml.TaggedStyle == 0 {
+			v.PayloadKind, v.Scalar = styx.PayloadNone, nil
+		}
+	}
+	return v, nil
+}
+
+func scalar(text string, kind styx.ScalarKind) *styx.Value {
+	return &styx.Value{
+		Span:        noSpan,
+		PayloadKind: styx.PayloadScalar,
+		Scalar:      &styx.Scalar{Text: text, Kind: kind, Span: noSpan},
+	}
+}
+
+// validTagName reports whether name reads back as a tag name.
+func validTagName(name string) bool {
+	v, err := styx.ParseValue("@" + name)
+	return err == nil && v.Tag != nil && v.Tag.Name == name && v.PayloadKind == styx.PayloadNone
+}
@@ -0,0 +1,104 @@
+package styxyaml
+
+import (
+	"testing"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+)
+
+func TestToYAML(t *testing.T) {
+	doc, err := styx.Parse(`// the service name
+name "My App" // shown in logs
+server.host localhost
+server.port 8080
+zip "01234"
+home @env"HOME"
+debug @
+hosts (a b)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToYAML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# the service name
+name: My App # shown in logs
+server:
+  host: localhost
+  port: 8080
+zip: "01234"
+home: !env HOME
+debug: null
+hosts:
+  - a
+  - b
+`
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFromYAML(t *testing.T) {
+	doc, err := FromYAML([]byte(`# defaults
+base: &base
+  host: localhost
+  port: 80
+server:
+  <<: *base
+  port: 8080 # overridden
+home: !env HOME
+missing: ~
+quoted: "yes"
+list: [1, two]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(styx.Format(doc))
+	want := `// defaults
+base {
+    host localhost
+    port 80
+}
+server {
+    host localhost
+    port 8080 // overridden
+}
+home @env"HOME"
+missing @
+quoted yes
+list (1 two)
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if _, err := styx.Parse(got); err != nil {
+		t.Errorf("converted document does not parse: %v", err)
+	}
+
+	for _, input := range []string{"- a\n- b\n", "a: 1\na: 2\n", "? [a]\n: 1\n", "x: !a{b} c\n"} {
+		if _, err := FromYAML([]byte(input)); err == nil {
+			t.Errorf("FromYAML(%q): expected an error", input)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	doc, err := styx.Parse("a 1\nb {c \"d\", e (f g)}\nt @tag\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ToYAML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromYAML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Hash() != doc.Hash() {
+		t.Errorf("round trip changed the document:\n%s", styx.Format(back))
+	}
+}
@@ -0,0 +1,153 @@
+package styx
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+)
+
+// JSONOptions configures ToJSON.
+type JSONOptions struct {
+	// Tags selects how tags are represented:
+	//
+	//   - TagWrap wraps every tagged value in an object holding the tag
+	//     name under TagKey and the payload, if any, under ValueKey:
+	//     `@env"HOME"` becomes {"$tag":"env","$value":"HOME"};
+	//   - TagDiscriminator adds the TagKey member to tagged objects, and
+	//     wraps other tagged values as TagWrap does:
+	//     `@pg{host db}` becomes {"$tag":"pg","host":"db"};
+	//   - TagDrop writes the payload alone.
+	Tags TagMode
+	// TagKey and ValueKey default to DefaultTagKey and DefaultValueKey.
+	TagKey   string
+	ValueKey string
+	// InferTypes writes bare scalars that read as JSON numbers, `true` and
+	// `false` as numbers and booleans. By default every scalar is a
+	// string, as Styx does not type scalars; quoted scalars always are.
+	InferTypes bool
+	// Indent, if set, pretty-prints the output with one Indent per level.
+	Indent string
+}
+
+// jsonNumber matches the JSON number grammar.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// ToJSON converts the document to JSON. The document becomes an object,
+// or its root object does for a document consisting of one, with members
+// in document order. Sequences become arrays and unit values null; tags and
+// scalars are written as JSONOptions selects. Objects split over dotted
+// keys are combined. Keys repeated in attribute syntax are repeated in the
+// output, which most JSON decoders resolve by keeping the last.
+func ToJSON(doc *Document, opts JSONOptions) []byte {
+	w := newJSONWriter(&opts)
+	entries, _ := doc.rootEntries()
+	w.object(*entries, nil)
+	return w.bytes()
+}
+
+// ValueToJSON converts a single value to JSON, as ToJSON does.
+func ValueToJSON(v *Value, opts JSONOptions) []byte {
+	w := newJSONWriter(&opts)
+	w.value(v)
+	return w.bytes()
+}
+
+func newJSONWriter(opts *JSONOptions) *jsonWriter {
+	if opts.TagKey == "" {
+		opts.TagKey = DefaultTagKey
+	}
+	if opts.ValueKey == "" {
+		opts.ValueKey = DefaultValueKey
+	}
+	return &jsonWriter{opts: opts}
+}
+
+// bytes returns the output, indented as the options ask.
+func (w *jsonWriter) bytes() []byte {
+	if w.opts.Indent == "" {
+		return w.b.Bytes()
+	}
+	var out bytes.Buffer
+	json.Indent(&out, w.b.Bytes(), "", w.opts.Indent)
+	return out.Bytes()
+}
+
+type jsonWriter struct {
+	opts *JSONOptions
+	b    bytes.Buffer
+}
+
+func (w *jsonWriter) string(s string) {
+	quoted, _ := json.Marshal(s)
+	w.b.Write(quoted)
+}
+
+// object writes entries as an object, starting with the tag member if tag
+// is set.
+func (w *jsonWriter) object(entries []*Entry, tag *Tag) {
+	w.b.WriteByte('{')
+	first := true
+	if tag != nil {
+		w.string(w.opts.TagKey)
+		w.b.WriteByte(':')
+		w.string(tag.Name)
+		first = false
+	}
+	for _, entry := range combineSplitEntries(entries) {
+		if !first {
+			w.b.WriteByte(',')
+		}
+		first = false
+		w.string(entry.KeyText())
+		w.b.WriteByte(':')
+		w.value(entry.Value)
+	}
+	w.b.WriteByte('}')
+}
+
+func (w *jsonWriter) value(v *Value) {
+	if v.Tag != nil && w.opts.Tags != TagDrop {
+		if w.opts.Tags == TagDiscriminator && v.PayloadKind == PayloadObject {
+			w.object(v.Object.Entries, v.Tag)
+			return
+		}
+		w.b.WriteByte('{')
+		w.string(w.opts.TagKey)
+		w.b.WriteByte(':')
+		w.string(v.Tag.Name)
+		if v.PayloadKind != PayloadNone {
+			w.b.WriteByte(',')
+			w.string(w.opts.ValueKey)
+			w.b.WriteByte(':')
+			w.payload(v)
+		}
+		w.b.WriteByte('}')
+		return
+	}
+	w.payload(v)
+}
+
+func (w *jsonWriter) payload(v *Value) {
+	switch v.PayloadKind {
+	case PayloadScalar:
+		text := v.Scalar.Text
+		if w.opts.InferTypes && v.Scalar.Kind == ScalarBare && (text == "true" || text == "false" || jsonNumber.MatchString(text)) {
+			w.b.WriteString(text)
+			return
+		}
+		w.string(text)
+	case PayloadSequence:
+		w.b.WriteByte('[')
+		for i, item := range v.Sequence.Items {
+			if i > 0 {
+				w.b.WriteByte(',')
+			}
+			w.value(item)
+		}
+		w.b.WriteByte(']')
+	case PayloadObject:
+		w.object(v.Object.Entries, nil)
+	default:
+		w.b.WriteString("null")
+	}
+}
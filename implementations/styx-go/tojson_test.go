@@ -0,0 +1,59 @@
+package styx
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	doc, err := Parse(`name app
+port 8080
+ratio "0.5"
+debug true
+version 1.2.3
+db.host localhost
+db.pool @int"4"
+secret @env"TOKEN"
+store @pg{host db}
+off @
+flag
+hosts (a 01 -2.5e3)
+none @none
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		opts JSONOptions
+		want string
+	}{
+		{JSONOptions{}, `{"name":"app","port":"8080","ratio":"0.5","debug":"true","version":"1.2.3","db":{"host":"localhost","pool":{"$tag":"int","$value":"4"}},"secret":{"$tag":"env","$value":"TOKEN"},"store":{"$tag":"pg","$value":{"host":"db"}},"off":null,"flag":null,"hosts":["a","01","-2.5e3"],"none":{"$tag":"none"}}`},
+		{JSONOptions{Tags: TagDiscriminator, TagKey: "type"}, `{"name":"app","port":"8080","ratio":"0.5","debug":"true","version":"1.2.3","db":{"host":"localhost","pool":{"type":"int","$value":"4"}},"secret":{"type":"env","$value":"TOKEN"},"store":{"type":"pg","host":"db"},"off":null,"flag":null,"hosts":["a","01","-2.5e3"],"none":{"type":"none"}}`},
+		{JSONOptions{Tags: TagDrop, InferTypes: true}, `{"name":"app","port":8080,"ratio":"0.5","debug":true,"version":"1.2.3","db":{"host":"localhost","pool":"4"},"secret":"TOKEN","store":{"host":"db"},"off":null,"flag":null,"hosts":["a","01",-2.5e3],"none":null}`},
+	}
+	for _, tt := range tests {
+		got := ToJSON(doc, tt.opts)
+		if string(got) != tt.want {
+			t.Errorf("%+v:\ngot  %s\nwant %s", tt.opts, got, tt.want)
+		}
+		if !json.Valid(got) {
+			t.Errorf("%+v: invalid JSON", tt.opts)
+		}
+	}
+
+	root, _ := Parse("{a (1 2)}")
+	if got := string(ToJSON(root, JSONOptions{Indent: "  "})); got != "{\n  \"a\": [\n    \"1\",\n    \"2\"\n  ]\n}" {
+		t.Errorf("indented: %q", got)
+	}
+}
+
+func TestValueToJSON(t *testing.T) {
+	doc := mustParse(t, "a @pg{host db, ports (1 2)}\nb x\n")
+	opts := JSONOptions{Tags: TagDiscriminator, InferTypes: true}
+	if got, want := string(ValueToJSON(doc.Get("a"), opts)), `{"$tag":"pg","host":"db","ports":[1,2]}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got := string(ValueToJSON(doc.Get("b"), JSONOptions{})); got != `"x"` {
+		t.Errorf("scalar: got %s", got)
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/bearcove/styx/implementations/styx-go/treesitter
+
+go 1.23
+
+require (
+	github.com/bearcove/styx/implementations/styx-go v0.0.0
+	github.com/tree-sitter/go-tree-sitter v0.25.0
+)
+
+require github.com/mattn/go-pointer v0.0.1 // indirect
+
+replace github.com/bearcove/styx/implementations/styx-go => ../
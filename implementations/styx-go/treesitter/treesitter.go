@@ -0,0 +1,50 @@
+// Package treesitter connects the tree-sitter-styx grammar to the styx
+// package: it provides the grammar for go-tree-sitter parsers and converts
+// their trees to styx Documents.
+//
+// The grammar is compiled from the sources in crates/tree-sitter-styx with
+// cgo. It lives in a module of its own so that the styx package stays free
+// of cgo.
+package treesitter
+
+// #cgo CFLAGS: -std=c11 -fPIC
+// #include "../../../crates/tree-sitter-styx/src/parser.c"
+// #include "../../../crates/tree-sitter-styx/src/scanner.c"
+import "C"
+
+import (
+	"unsafe"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+	ts "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Language returns the tree-sitter-styx grammar.
+func Language() *ts.Language {
+	return ts.NewLanguage(unsafe.Pointer(C.tree_sitter_styx()))
+}
+
+// Convert builds the Document for a tree the grammar parsed from source,
+// as styx.FromCST describes. The tree may have been updated incrementally.
+func Convert(tree *ts.Tree, source []byte) (*styx.Document, error) {
+	return styx.FromCST(string(source), node{tree.RootNode()})
+}
+
+// node adapts a go-tree-sitter node to styx.CSTNode.
+type node struct {
+	n *ts.Node
+}
+
+func (n node) Kind() string     { return n.n.Kind() }
+func (n node) StartByte() uint  { return n.n.StartByte() }
+func (n node) EndByte() uint    { return n.n.EndByte() }
+func (n node) IsError() bool    { return n.n.IsError() }
+func (n node) IsMissing() bool  { return n.n.IsMissing() }
+func (n node) HasError() bool   { return n.n.HasError() }
+func (n node) ChildCount() uint { return n.n.ChildCount() }
+func (n node) Child(i uint) styx.CSTNode {
+	return node{n.n.Child(i)}
+}
+func (n node) FieldNameForChild(i uint) string {
+	return n.n.FieldNameForChild(uint32(i))
+}
@@ -0,0 +1,128 @@
+package treesitter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	styx "github.com/bearcove/styx/implementations/styx-go"
+	ts "github.com/tree-sitter/go-tree-sitter"
+)
+
+func newParser(t *testing.T) *ts.Parser {
+	t.Helper()
+	p := ts.NewParser()
+	t.Cleanup(p.Close)
+	if err := p.SetLanguage(Language()); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// checkSame compares a converted document with the one Parse returns.
+func checkSame(t *testing.T, got, want *styx.Document) {
+	t.Helper()
+	if g, w := styx.FormatSexp(got), styx.FormatSexp(want); g != w {
+		t.Fatalf("tree mismatch\n--- got ---\n%s\n--- want ---\n%s", g, w)
+	}
+	if g, w := string(styx.Format(got)), string(styx.Format(want)); g != w {
+		t.Fatalf("comment mismatch\n--- got ---\n%s\n--- want ---\n%s", g, w)
+	}
+	if got.Span != want.Span || len(got.Comments) != len(want.Comments) {
+		t.Fatalf("span %v with %d comments, want %v with %d", got.Span, len(got.Comments), want.Span, len(want.Comments))
+	}
+}
+
+// TestCorpus converts the tree of every compliance corpus file and checks
+// the result against the hand-written parser's.
+func TestCorpus(t *testing.T) {
+	var files []string
+	err := filepath.Walk("../../../compliance/corpus", func(path string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(path, ".styx") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no corpus: %v", err)
+	}
+	p := newParser(t)
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			source, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tree := p.Parse(source, nil)
+			defer tree.Close()
+			got, gotErr := Convert(tree, source)
+			want, wantErr := styx.Parse(string(source))
+			switch {
+			case wantErr != nil:
+				if gotErr == nil || styx.FormatErrorSexp(gotErr) != styx.FormatErrorSexp(wantErr) {
+					t.Fatalf("got error %v, want %v", gotErr, wantErr)
+				}
+			case gotErr != nil:
+				// The grammar rejects a few sources the parser accepts.
+				if !tree.RootNode().HasError() {
+					t.Fatalf("unexpected error: %v", gotErr)
+				}
+			default:
+				checkSame(t, got, want)
+			}
+		})
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	p := newParser(t)
+	source := []byte("// server\nserver {\n    host localhost\n    port 8080\n}\nname app\n")
+	tree := p.Parse(source, nil)
+	defer tree.Close()
+
+	// Replace 8080 with 9090.
+	start := uint(strings.Index(string(source), "8080"))
+	edited := []byte(strings.Replace(string(source), "8080", "9090", 1))
+	tree.Edit(&ts.InputEdit{
+		StartByte: start, OldEndByte: start + 4, NewEndByte: start + 4,
+		StartPosition:  ts.Point{Row: 3, Column: 9},
+		OldEndPosition: ts.Point{Row: 3, Column: 13},
+		NewEndPosition: ts.Point{Row: 3, Column: 13},
+	})
+	newTree := p.Parse(edited, tree)
+	defer newTree.Close()
+
+	got, err := Convert(newTree, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := styx.Parse(string(edited))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSame(t, got, want)
+	if port, _ := got.GetString("server.port", ""); port != "9090" {
+		t.Errorf("port = %q", port)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	p := newParser(t)
+	for _, source := range []string{
+		"a 1\na 2\n",
+		"a.b 1\na.b 2\n",
+		"a {b 1, b 2}\n",
+		"key @123\n",
+		"a (1 2\n",
+		"{a 1} b\n",
+	} {
+		tree := p.Parse([]byte(source), nil)
+		_, got := Convert(tree, []byte(source))
+		tree.Close()
+		_, want := styx.Parse(source)
+		if got == nil || want == nil || got.Error() != want.Error() {
+			t.Errorf("%q: got %v, want %v", source, got, want)
+		}
+	}
+}
@@ -0,0 +1,863 @@
+package styx
+
+import (
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema describes the documents a Styx schema file accepts. A schema file
+// has a meta object and a schema object mapping `@`, the document's root
+// type, and type names to types written as tags:
+//
+//	meta {id https://example.com/server, version 1}
+//	schema {
+//	    @ @object{
+//	        host @string
+//	        port @int{min 1, max 65535}
+//	        tls @optional(@Tls)
+//	    }
+//	    Tls @object{cert @string, key @string}
+//	}
+//
+// The types are @string{minLen maxLen pattern}, @int{min max},
+// @float{min max}, @bool, @unit, @any, @object{...}, @seq(T), @tuple(T...),
+// @map(V) or @map(K V), @union(T...), @optional(T), @enum{variant T...},
+// @one-of(T value...), @flatten(T), @default(value T),
+// @deprecated("reason" T), a scalar standing for itself and @Name for a
+// named type. In an object, a key written as a tag such as `@string`, or
+// `@`, types the fields not declared, and a field of type @flatten(T), T
+// being an object type, stands for T's fields and catch-all, written in
+// the object itself.
+type Schema struct {
+	// ID, Version and Description are those of the meta object.
+	ID          string
+	Version     string
+	Description string
+
+	// types holds the named types, and the root type under "".
+	types map[string]*schemaType
+}
+
+type schemaKind int
+
+const (
+	schemaString schemaKind = iota
+	schemaInt
+	schemaFloat
+	schemaBool
+	schemaUnit
+	schemaAny
+	schemaObject
+	schemaSeq
+	schemaTuple
+	schemaMap
+	schemaUnion
+	schemaOptional
+	schemaEnum
+	schemaOneOf
+	schemaFlatten
+	schemaDefault
+	schemaDeprecated
+	schemaLiteral
+	schemaRef
+)
+
+// schemaType is a compiled type. Wrappers and combinators keep their
+// operands in items: the element type of @seq, the key and value types of
+// @map, the variants of @union and the base type of @one-of.
+type schemaType struct {
+	kind schemaKind
+	// name is the referenced type of @Name, or the text of a literal.
+	name string
+	// span locates the type in the schema source.
+	span Span
+
+	minLen, maxLen *int
+	min, max       *big.Float
+	pattern        *regexp.Regexp
+
+	// fields are the declared fields of an object, or an enum's variants.
+	fields []schemaField
+	// other types an object's undeclared fields.
+	other *schemaType
+	items []*schemaType
+	// values are the allowed values of @one-of.
+	values []string
+	// reason explains a deprecation.
+	reason string
+}
+
+type schemaField struct {
+	name string
+	typ  *schemaType
+}
+
+// ParseSchema parses a schema file. Problems are reported as a *ParseError:
+// the parser's, or one with CodeInvalidSchema for a document that is not a
+// schema.
+func ParseSchema(source string) (*Schema, error) {
+	doc, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{types: make(map[string]*schemaType)}
+	entries, _ := doc.rootEntries()
+	var types *Value
+	for _, e := range combineSplitEntries(*entries) {
+		switch e.KeyText() {
+		case "meta":
+			if e.Value.PayloadKind != PayloadObject {
+				return nil, schemaError("meta must be an object", e.Value)
+			}
+			for _, field := range []struct {
+				key string
+				to  *string
+			}{{"id", &s.ID}, {"version", &s.Version}, {"description", &s.Description}} {
+				if v := e.Value.Object.Get(field.key); v != nil && v.PayloadKind == PayloadScalar {
+					*field.to = v.Scalar.Text
+				}
+			}
+		case "schema":
+			if e.Value.PayloadKind != PayloadObject {
+				return nil, schemaError("schema must be an object", e.Value)
+			}
+			types = e.Value
+		case "imports":
+			return nil, schemaError("imports are not supported", e.Key)
+		default:
+			return nil, schemaError("unexpected key `"+e.KeyText()+"`; a schema file has meta and schema", e.Key)
+		}
+	}
+	if types == nil {
+		return nil, &ParseError{Code: CodeInvalidSchema, Message: "missing schema object", Span: Span{0, 0}}
+	}
+	for _, e := range types.Object.Entries {
+		name := ""
+		if !e.Key.IsUnit() {
+			if e.Key.PayloadKind != PayloadScalar || e.Key.Tag != nil {
+				return nil, schemaError("type names must be bare scalars, or @ for the root type", e.Key)
+			}
+			name = e.Key.Scalar.Text
+		}
+		typ, err := compileSchemaType(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		s.types[name] = typ
+	}
+	if err := s.checkRefs(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func schemaError(message string, at *Value) *ParseError {
+	return &ParseError{Code: CodeInvalidSchema, Message: message, Span: at.FullSpan()}
+}
+
+// compileSchemaType compiles the type written as v.
+func compileSchemaType(v *Value) (*schemaType, error) {
+	t := &schemaType{span: v.FullSpan()}
+	if v.Tag == nil {
+		switch v.PayloadKind {
+		case PayloadNone:
+			t.kind = schemaUnit
+		case PayloadScalar:
+			t.kind, t.name = schemaLiteral, v.Scalar.Text
+		default:
+			return nil, schemaError("expected a type, such as @string", v)
+		}
+		return t, nil
+	}
+
+	name := v.Tag.Name
+	noPayload := func(kind schemaKind) (*schemaType, error) {
+		if v.PayloadKind != PayloadNone {
+			return nil, schemaError("@"+name+" takes no arguments", v)
+		}
+		t.kind = kind
+		return t, nil
+	}
+	switch name {
+	case "string", "int", "float":
+		t.kind = map[string]schemaKind{"string": schemaString, "int": schemaInt, "float": schemaFloat}[name]
+		if v.PayloadKind == PayloadNone {
+			return t, nil
+		}
+		if v.PayloadKind != PayloadObject {
+			return nil, schemaError("constraints of @"+name+" must be an object", v)
+		}
+		return t, t.compileConstraints(v.Object)
+	case "bool":
+		return noPayload(schemaBool)
+	case "unit":
+		return noPayload(schemaUnit)
+	case "any":
+		return noPayload(schemaAny)
+	case "object", "enum":
+		if v.PayloadKind != PayloadObject {
+			return nil, schemaError("@"+name+" takes an object, as in @"+name+"{...}", v)
+		}
+		t.kind = schemaObject
+		if name == "enum" {
+			t.kind = schemaEnum
+		}
+		for _, e := range v.Object.Entries {
+			typ, err := compileSchemaType(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			if e.Key.Tag != nil && e.Key.PayloadKind == PayloadNone || e.Key.IsUnit() {
+				if t.kind == schemaEnum {
+					return nil, schemaError("enum variants must be named", e.Key)
+				}
+				t.other = typ
+				continue
+			}
+			if e.Key.PayloadKind != PayloadScalar {
+				return nil, schemaError("field names must be scalars", e.Key)
+			}
+			t.fields = append(t.fields, schemaField{e.Key.Scalar.Text, typ})
+		}
+		return t, nil
+	}
+
+	// The remaining types take their operands in a sequence.
+	var args []*Value
+	if v.PayloadKind == PayloadSequence {
+		args = v.Sequence.Items
+	} else if v.PayloadKind != PayloadNone {
+		return nil, schemaError("@"+name+" takes its arguments in parentheses", v)
+	}
+	arity := func(kind schemaKind, min, max int) error {
+		t.kind = kind
+		if len(args) < min || max >= 0 && len(args) > max {
+			want := strconv.Itoa(min)
+			switch {
+			case max < 0:
+				want = "at least " + want
+			case max != min:
+				want += " or " + strconv.Itoa(max)
+			}
+			if want == "1" {
+				return schemaError("@"+name+" takes 1 argument", v)
+			}
+			return schemaError("@"+name+" takes "+want+" arguments", v)
+		}
+		return nil
+	}
+	var err error
+	switch name {
+	case "seq":
+		err = arity(schemaSeq, 1, 1)
+	case "tuple":
+		err = arity(schemaTuple, 1, -1)
+	case "map":
+		err = arity(schemaMap, 1, 2)
+	case "union":
+		err = arity(schemaUnion, 1, -1)
+	case "optional":
+		err = arity(schemaOptional, 1, 1)
+	case "flatten":
+		err = arity(schemaFlatten, 1, 1)
+	case "one-of":
+		if err = arity(schemaOneOf, 1, -1); err == nil {
+			for _, value := range args[1:] {
+				if value.PayloadKind != PayloadScalar {
+					return nil, schemaError("values of @one-of must be scalars", value)
+				}
+				t.values = append(t.values, value.Scalar.Text)
+			}
+			args = args[:1]
+		}
+	case "default":
+		// The default value matters when decoding, not validating.
+		if err = arity(schemaDefault, 2, 2); err == nil {
+			args = args[1:]
+		}
+	case "deprecated":
+		if err = arity(schemaDeprecated, 2, 2); err == nil {
+			if args[0].PayloadKind != PayloadScalar {
+				return nil, schemaError("the reason of @deprecated must be a scalar", args[0])
+			}
+			t.reason = args[0].Scalar.Text
+			args = args[1:]
+		}
+	default:
+		if v.PayloadKind != PayloadNone {
+			return nil, schemaError("unknown type @"+name, v)
+		}
+		t.kind, t.name = schemaRef, name
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		typ, err := compileSchemaType(arg)
+		if err != nil {
+			return nil, err
+		}
+		t.items = append(t.items, typ)
+	}
+	return t, nil
+}
+
+// compileConstraints reads the constraints object of @string, @int or
+// @float.
+func (t *schemaType) compileConstraints(obj *Object) error {
+	for _, e := range obj.Entries {
+		key := e.KeyText()
+		if e.Value.PayloadKind != PayloadScalar {
+			return schemaError("constraint `"+key+"` must be a scalar", e.Value)
+		}
+		text := e.Value.Scalar.Text
+		switch {
+		case t.kind == schemaString && (key == "minLen" || key == "maxLen"):
+			n, err := strconv.Atoi(text)
+			if err != nil || n < 0 {
+				return schemaError("`"+key+"` must be a non-negative integer", e.Value)
+			}
+			if key == "minLen" {
+				t.minLen = &n
+			} else {
+				t.maxLen = &n
+			}
+		case t.kind == schemaString && key == "pattern":
+			re, err := regexp.Compile(text)
+			if err != nil {
+				return schemaError("invalid pattern: "+err.Error(), e.Value)
+			}
+			t.pattern = re
+		case t.kind != schemaString && (key == "min" || key == "max"):
+			n, ok := parseNumber(text, t.kind == schemaInt)
+			if !ok {
+				return schemaError("`"+key+"` must be a number", e.Value)
+			}
+			if key == "min" {
+				t.min = n
+			} else {
+				t.max = n
+			}
+		default:
+			return schemaError("unknown constraint `"+key+"`", e.Key)
+		}
+	}
+	return nil
+}
+
+// parseNumber parses an integer of any size, or a float.
+func parseNumber(text string, integer bool) (*big.Float, bool) {
+	if integer {
+		n, ok := new(big.Int).SetString(text, 10)
+		if !ok {
+			return nil, false
+		}
+		return new(big.Float).SetInt(n), true
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, false
+	}
+	return big.NewFloat(f), true
+}
+
+// checkRefs reports references to undefined types, and types that refer
+// to themselves without an object or sequence in between, which would
+// never finish validating.
+func (s *Schema) checkRefs() error {
+	if s.types[""] == nil {
+		return &ParseError{Code: CodeInvalidSchema, Message: "no root type; define it under @ in the schema object", Span: Span{0, 0}}
+	}
+	var flattened []*schemaType
+	// field is set for the types of object fields, the only place
+	// @flatten may appear.
+	var walk func(t *schemaType, field bool) error
+	walk = func(t *schemaType, field bool) error {
+		switch {
+		case t.kind == schemaRef && s.types[t.name] == nil:
+			return &ParseError{Code: CodeInvalidSchema, Message: "unknown type @" + t.name, Span: t.span}
+		case t.kind == schemaFlatten && !field:
+			return &ParseError{Code: CodeInvalidSchema, Message: "@flatten can only be the type of an object field", Span: t.span}
+		case t.kind == schemaFlatten:
+			flattened = append(flattened, t)
+		}
+		for _, f := range t.fields {
+			if err := walk(f.typ, t.kind == schemaObject); err != nil {
+				return err
+			}
+		}
+		for _, item := range append(t.items, t.other) {
+			if item != nil {
+				if err := walk(item, false); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := walk(s.types[name], false); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		if s.refersTo(s.types[name], name, map[string]bool{}) {
+			return &ParseError{Code: CodeInvalidSchema, Message: "type `" + name + "` refers to itself", Span: s.types[name].span}
+		}
+	}
+	for _, t := range flattened {
+		if s.resolve(t.items[0]).kind != schemaObject {
+			return &ParseError{Code: CodeInvalidSchema, Message: "@flatten takes an object type", Span: t.span}
+		}
+	}
+	return nil
+}
+
+// resolve follows references from t to the type they name.
+func (s *Schema) resolve(t *schemaType) *schemaType {
+	for t.kind == schemaRef {
+		t = s.types[t.name]
+	}
+	return t
+}
+
+// flattened returns the object type t with the fields of its @flatten
+// fields in their place, and the first catch-all found if t has none, or
+// t itself if it has no @flatten fields.
+func (s *Schema) flattened(t *schemaType) *schemaType {
+	out := &schemaType{kind: schemaObject, span: t.span, other: t.other}
+	seen := map[*schemaType]bool{t: true}
+	flattens := false
+	var add func(t *schemaType)
+	add = func(t *schemaType) {
+		for _, f := range t.fields {
+			if f.typ.kind != schemaFlatten {
+				out.fields = append(out.fields, f)
+				continue
+			}
+			flattens = true
+			inner := s.resolve(f.typ.items[0])
+			// An object flattening itself adds nothing more.
+			if seen[inner] {
+				continue
+			}
+			seen[inner] = true
+			if out.other == nil {
+				out.other = inner.other
+			}
+			add(inner)
+		}
+	}
+	add(t)
+	if !flattens {
+		return t
+	}
+	return out
+}
+
+// refersTo reports whether t reaches the type name without descending
+// into a value.
+func (s *Schema) refersTo(t *schemaType, name string, seen map[string]bool) bool {
+	switch t.kind {
+	case schemaRef:
+		if t.name == name {
+			return true
+		}
+		if seen[t.name] {
+			return false
+		}
+		seen[t.name] = true
+		return s.refersTo(s.types[t.name], name, seen)
+	case schemaUnion, schemaOptional, schemaOneOf, schemaFlatten, schemaDefault, schemaDeprecated:
+		for _, item := range t.items {
+			if s.refersTo(item, name, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate checks doc against the schema's root type and returns the
+// violations found, ordered by position: errors, and warnings for
+// deprecated values. A root `@schema` entry, which names the schema, is
+// not validated.
+func (s *Schema) Validate(doc *Document) []*Diagnostic {
+	entries, _ := doc.rootEntries()
+	root := &Value{Span: doc.Span, PayloadKind: PayloadObject, Object: &Object{Span: doc.Span}}
+	for _, e := range *entries {
+		if e.KeyText() != "@schema" {
+			root.Object.Entries = append(root.Object.Entries, e)
+		}
+	}
+	v := &validator{schema: s}
+	v.value(root, s.types[""], "")
+	sort.SliceStable(v.diags, func(i, j int) bool { return v.diags[i].Span.Start < v.diags[j].Span.Start })
+	return v.diags
+}
+
+type validator struct {
+	schema *Schema
+	diags  []*Diagnostic
+}
+
+func (v *validator) report(code Code, val *Value, path, message string) *Diagnostic {
+	if path != "" {
+		message = "`" + path + "`: " + message
+	}
+	d := &Diagnostic{Code: code, Severity: SeverityError, Message: message, Span: val.FullSpan()}
+	v.diags = append(v.diags, d)
+	return d
+}
+
+// matches reports whether val is valid as t, without reporting anything.
+func (v *validator) matches(val *Value, t *schemaType) bool {
+	probe := &validator{schema: v.schema}
+	probe.value(val, t, "")
+	for _, d := range probe.diags {
+		if d.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *validator) value(val *Value, t *schemaType, path string) {
+	switch t.kind {
+	case schemaString:
+		text, ok := v.scalar(val, t, path)
+		if !ok {
+			return
+		}
+		n := utf8.RuneCountInString(text)
+		if t.minLen != nil && n < *t.minLen {
+			v.report(CodeInvalidValue, val, path, "string too short (min length: "+strconv.Itoa(*t.minLen)+")")
+		}
+		if t.maxLen != nil && n > *t.maxLen {
+			v.report(CodeInvalidValue, val, path, "string too long (max length: "+strconv.Itoa(*t.maxLen)+")")
+		}
+		if t.pattern != nil && !t.pattern.MatchString(text) {
+			v.report(CodeInvalidValue, val, path, "string does not match pattern `"+t.pattern.String()+"`")
+		}
+	case schemaInt, schemaFloat:
+		text, ok := v.scalar(val, t, path)
+		if !ok {
+			return
+		}
+		n, ok := parseNumber(text, t.kind == schemaInt)
+		if !ok {
+			v.report(CodeTypeMismatch, val, path, "`"+text+"` is not a valid "+schemaTypeName(t))
+			return
+		}
+		if t.min != nil && n.Cmp(t.min) < 0 {
+			v.report(CodeInvalidValue, val, path, "value too small (min: "+t.min.String()+")")
+		}
+		if t.max != nil && n.Cmp(t.max) > 0 {
+			v.report(CodeInvalidValue, val, path, "value too large (max: "+t.max.String()+")")
+		}
+	case schemaBool:
+		if text, ok := v.scalar(val, t, path); ok && text != "true" && text != "false" {
+			v.report(CodeTypeMismatch, val, path, "`"+text+"` is not a valid boolean (expected true or false)")
+		}
+	case schemaUnit:
+		if !val.IsUnit() {
+			v.mismatch(val, t, path)
+		}
+	case schemaAny:
+	case schemaObject:
+		v.object(val, t, path)
+	case schemaSeq, schemaTuple:
+		if val.PayloadKind != PayloadSequence {
+			v.mismatch(val, t, path)
+			return
+		}
+		items := val.Sequence.Items
+		if t.kind == schemaTuple && len(items) != len(t.items) {
+			v.report(CodeInvalidValue, val, path, "expected "+strconv.Itoa(len(t.items))+" elements, got "+strconv.Itoa(len(items)))
+		}
+		for i, item := range items {
+			typ := t.items[0]
+			if t.kind == schemaTuple {
+				if i >= len(t.items) {
+					break
+				}
+				typ = t.items[i]
+			}
+			v.value(item, typ, path+"["+strconv.Itoa(i)+"]")
+		}
+	case schemaMap:
+		if val.PayloadKind != PayloadObject {
+			v.mismatch(val, t, path)
+			return
+		}
+		for _, e := range combineSplitEntries(val.Object.Entries) {
+			if len(t.items) == 2 {
+				v.value(e.Key, t.items[0], path)
+			}
+			v.value(e.Value, t.items[len(t.items)-1], fieldPath(path, keyDisplay(e)))
+		}
+	case schemaUnion:
+		for _, variant := range t.items {
+			if v.matches(val, variant) {
+				v.value(val, variant, path)
+				return
+			}
+		}
+		names := make([]string, len(t.items))
+		for i, variant := range t.items {
+			names[i] = schemaTypeName(variant)
+		}
+		v.report(CodeTypeMismatch, val, path, "value matches none of "+strings.Join(names, ", "))
+	case schemaOptional:
+		if !val.IsUnit() {
+			v.value(val, t.items[0], path)
+		}
+	case schemaEnum:
+		v.enum(val, t, path)
+	case schemaOneOf:
+		if !v.matches(val, t.items[0]) {
+			v.value(val, t.items[0], path)
+			return
+		}
+		if len(t.values) == 0 {
+			return
+		}
+		text, ok := v.scalar(val, t, path)
+		if !ok {
+			return
+		}
+		for _, allowed := range t.values {
+			if text == allowed {
+				return
+			}
+		}
+		d := v.report(CodeInvalidValue, val, path, "`"+text+"` is not one of "+strings.Join(t.values, ", "))
+		if s := suggest(text, t.values); s != "" {
+			d.Help = "did you mean `" + s + "`?"
+		}
+	case schemaDefault:
+		v.value(val, t.items[0], path)
+	case schemaDeprecated:
+		v.value(val, t.items[0], path)
+		d := v.report(CodeDeprecatedField, val, path, "deprecated: "+t.reason)
+		d.Severity = SeverityWarning
+	case schemaLiteral:
+		if text, ok := v.scalar(val, t, path); ok && text != t.name {
+			v.report(CodeInvalidValue, val, path, "expected `"+t.name+"`, got `"+text+"`")
+		}
+	case schemaRef:
+		v.value(val, v.schema.types[t.name], path)
+	}
+}
+
+// scalar returns the text of a scalar value, or reports a type mismatch.
+func (v *validator) scalar(val *Value, t *schemaType, path string) (string, bool) {
+	if val.PayloadKind != PayloadScalar {
+		v.mismatch(val, t, path)
+		return "", false
+	}
+	return val.Scalar.Text, true
+}
+
+func (v *validator) mismatch(val *Value, t *schemaType, path string) {
+	v.report(CodeTypeMismatch, val, path, "expected "+schemaTypeName(t)+", got "+valueTypeName(val))
+}
+
+func (v *validator) object(val *Value, t *schemaType, path string) {
+	if val.PayloadKind != PayloadObject {
+		v.mismatch(val, t, path)
+		return
+	}
+	t = v.schema.flattened(t)
+	seen := make(map[string]bool)
+	for _, e := range combineSplitEntries(val.Object.Entries) {
+		key := keyDisplay(e)
+		seen[key] = true
+		field := t.field(key)
+		switch {
+		case field != nil:
+			v.value(e.Value, field, fieldPath(path, key))
+		case t.other != nil:
+			v.value(e.Value, t.other, fieldPath(path, key))
+		default:
+			names := t.fieldNames()
+			d := v.report(CodeUnknownField, e.Key, path, "unknown field `"+key+"`")
+			if s := suggest(key, names); s != "" {
+				d.Help = "did you mean `" + s + "`?"
+			} else if len(names) > 0 {
+				d.Help = "expected one of " + strings.Join(names, ", ")
+			}
+		}
+	}
+	for _, f := range t.fields {
+		if seen[f.name] || f.typ.kind == schemaOptional || f.typ.kind == schemaDefault {
+			continue
+		}
+		// Point at the object's opening brace rather than all of it, or at
+		// the start of the document or attribute object.
+		at := &Value{Span: Span{val.Object.Span.Start, val.Object.Span.Start}}
+		if path != "" && !val.Object.Attributes {
+			at.Span.End++
+		}
+		v.report(CodeMissingField, at, path, "missing required field `"+f.name+"`")
+	}
+}
+
+func (v *validator) enum(val *Value, t *schemaType, path string) {
+	if val.Tag == nil {
+		// An untagged scalar may still match a variant of scalar type.
+		if val.PayloadKind == PayloadScalar {
+			for _, f := range t.fields {
+				switch f.typ.kind {
+				case schemaString, schemaInt, schemaFloat, schemaBool:
+					if v.matches(val, f.typ) {
+						return
+					}
+				}
+			}
+		}
+		v.report(CodeTypeMismatch, val, path, "expected a variant of "+strings.Join(t.fieldNames(), ", ")+" written as a tag, got "+valueTypeName(val))
+		return
+	}
+	typ := t.field(val.Tag.Name)
+	if typ == nil {
+		d := v.report(CodeUnknownVariant, val, path, "unknown variant @"+val.Tag.Name)
+		names := t.fieldNames()
+		if s := suggest(val.Tag.Name, names); s != "" {
+			d.Help = "did you mean @" + s + "?"
+		} else {
+			d.Help = "expected one of " + strings.Join(names, ", ")
+		}
+		return
+	}
+	payload := *val
+	payload.Tag = nil
+	v.value(&payload, typ, fieldPath(path, val.Tag.Name))
+}
+
+func (t *schemaType) field(name string) *schemaType {
+	for _, f := range t.fields {
+		if f.name == name {
+			return f.typ
+		}
+	}
+	return nil
+}
+
+func (t *schemaType) fieldNames() []string {
+	names := make([]string, len(t.fields))
+	for i, f := range t.fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// keyDisplay names an entry's key in paths and messages, writing a unit
+// key as `@`.
+func keyDisplay(e *Entry) string {
+	if e.Key.IsUnit() {
+		return "@"
+	}
+	return e.KeyText()
+}
+
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// schemaTypeName names a type in messages.
+func schemaTypeName(t *schemaType) string {
+	switch t.kind {
+	case schemaString:
+		return "string"
+	case schemaInt:
+		return "integer"
+	case schemaFloat:
+		return "number"
+	case schemaBool:
+		return "boolean"
+	case schemaUnit:
+		return "unit"
+	case schemaAny:
+		return "any"
+	case schemaObject:
+		return "object"
+	case schemaSeq:
+		return "sequence"
+	case schemaTuple:
+		return "tuple"
+	case schemaMap:
+		return "map"
+	case schemaUnion:
+		return "union"
+	case schemaOptional:
+		return "optional " + schemaTypeName(t.items[0])
+	case schemaEnum:
+		return "enum"
+	case schemaOneOf, schemaFlatten, schemaDefault, schemaDeprecated:
+		return schemaTypeName(t.items[0])
+	case schemaLiteral:
+		return "`" + t.name + "`"
+	default:
+		return "@" + t.name
+	}
+}
+
+// valueTypeName names the type of a value in messages.
+func valueTypeName(v *Value) string {
+	switch {
+	case v.IsUnit():
+		return "unit"
+	case v.PayloadKind == PayloadNone:
+		return "tag @" + v.Tag.Name
+	}
+	return v.PayloadKind.String()
+}
+
+// suggest returns the candidate closest to s, ignoring case, if it is
+// within two edits and not a complete rewrite; otherwise "".
+func suggest(s string, candidates []string) string {
+	best, bestDist := "", 3
+	lower := strings.ToLower(s)
+	for _, c := range candidates {
+		d := levenshtein(lower, strings.ToLower(c))
+		if d < bestDist && d < utf8.RuneCountInString(s) {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b, in runes.
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,174 @@
+package styx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSchema = `meta {id test, version 1, description "A test schema"}
+schema {
+    @ @object{
+        name @string{minLen 1, maxLen 8, pattern "^[a-z]+$"}
+        port @int{min 1, max 65535}
+        ratio @optional(@float{min 0, max 1})
+        debug @default(false @bool)
+        mode @one-of(@string dev prod)
+        tags @optional(@seq(@string))
+        pair @optional(@tuple(@int @string))
+        env @optional(@map(@string))
+        target @optional(@union(@int @Endpoint))
+        action @optional(@Action)
+        legacy @optional(@deprecated("use name" @string))
+        kind @optional(service)
+        extra @optional(@object{@string @int})
+    }
+    Endpoint @object{host @string, port @int}
+    Action @enum{stop @unit, run @object{cmd @string}, wait @int}
+}
+`
+
+func TestValidate(t *testing.T) {
+	schema, err := ParseSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.ID != "test" || schema.Version != "1" || schema.Description != "A test schema" {
+		t.Errorf("meta = %q %q %q", schema.ID, schema.Version, schema.Description)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		// want lists the expected diagnostics as "code message".
+		want []string
+	}{
+		{"valid", "@schema app.schema.styx\nname app\nport 80\nmode dev\nratio 0.5\ntags (a b)\npair (1 x)\nenv {A 1, B 2}\ntarget {host h, port 1}\naction @run{cmd ls}\nkind service\nextra {a 1}", nil},
+		{"dotted keys", "name app\nport 80\nmode dev\ntarget.host h\ntarget.port 1", nil},
+		{"enum without payload", "name app\nport 80\nmode prod\naction @stop", nil},
+		{"enum fallback", "name app\nport 80\nmode prod\naction 5", nil},
+		{"missing", "name app", []string{
+			"STYX0034 missing required field `port`",
+			"STYX0034 missing required field `mode`",
+		}},
+		{"unknown field", "name app\nport 80\nmode dev\nprot 81", []string{"STYX0033 unknown field `prot`"}},
+		{"constraints", `name "Too Long Name"` + "\nport 70000\nmode dev\nratio 2", []string{
+			"STYX0032 `name`: string too long (max length: 8)",
+			"STYX0032 `name`: string does not match pattern `^[a-z]+$`",
+			"STYX0032 `port`: value too large (max: 65535)",
+			"STYX0032 `ratio`: value too large (max: 1)",
+		}},
+		{"types", "name {a b}\nport eighty\nmode dev\ndebug yes\ntags x", []string{
+			"STYX0031 `name`: expected string, got object",
+			"STYX0031 `port`: `eighty` is not a valid integer",
+			"STYX0031 `debug`: `yes` is not a valid boolean (expected true or false)",
+			"STYX0031 `tags`: expected sequence, got scalar",
+		}},
+		{"one-of", "name app\nport 80\nmode prd", []string{"STYX0032 `mode`: `prd` is not one of dev, prod"}},
+		{"tuple", "name app\nport 80\nmode dev\npair (x)", []string{
+			"STYX0032 `pair`: expected 2 elements, got 1",
+			"STYX0031 `pair[0]`: `x` is not a valid integer",
+		}},
+		{"map", "name app\nport 80\nmode dev\nenv {A (1)}", []string{"STYX0031 `env.A`: expected string, got sequence"}},
+		{"union", "name app\nport 80\nmode dev\ntarget {host h}", []string{"STYX0031 `target`: value matches none of integer, @Endpoint"}},
+		{"enum", "name app\nport 80\nmode dev\naction @rn{cmd ls}", []string{"STYX0035 `action`: unknown variant @rn"}},
+		{"enum payload", "name app\nport 80\nmode dev\naction @run{}", []string{"STYX0034 `action.run`: missing required field `cmd`"}},
+		{"deprecated", "name app\nport 80\nmode dev\nlegacy x", []string{"STYX0036 `legacy`: deprecated: use name"}},
+		{"literal", "name app\nport 80\nmode dev\nkind job", []string{"STYX0032 `kind`: expected `service`, got `job`"}},
+		{"catch-all", "name app\nport 80\nmode dev\nextra {a x}", []string{"STYX0031 `extra.a`: `x` is not a valid integer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := schema.Validate(mustParse(t, tt.source))
+			var got []string
+			for _, d := range diags {
+				got = append(got, string(d.Code)+" "+d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+
+	diags := schema.Validate(mustParse(t, "name app\nport 80\nmode dev\nprot 81"))
+	if d := diags[0]; d.Help != "did you mean `port`?" || d.Span != (Span{26, 30}) {
+		t.Errorf("unknown field: help %q, span %v", d.Help, d.Span)
+	}
+	diags = schema.Validate(mustParse(t, "name app\nport 80\nmode dev\nlegacy x"))
+	if diags[0].Severity != SeverityWarning {
+		t.Errorf("deprecated: severity %v, want warning", diags[0].Severity)
+	}
+}
+
+func TestValidateFlatten(t *testing.T) {
+	schema, err := ParseSchema(`schema {
+    @ @object{base @flatten(@Base), name @string}
+    Base @object{host @string, port @int, extra @flatten(@Extra)}
+    Extra @object{debug @optional(@bool), @string @int}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{"name x\nhost h\nport 1", nil},
+		{"name x\nhost h\nport 1\ndebug true\nretries 3", nil},
+		{"name x\nport p\nretries r", []string{
+			"STYX0034 missing required field `host`",
+			"STYX0031 `port`: `p` is not a valid integer",
+			"STYX0031 `retries`: `r` is not a valid integer",
+		}},
+		{"name x\nhost h\nport 1\nbase {host h}", []string{"STYX0031 `base`: expected integer, got object"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range schema.Validate(mustParse(t, tt.source)) {
+			got = append(got, string(d.Code)+" "+d.Message)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%q: got\n%s\nwant\n%s", tt.source, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"meta {id x}", "missing schema object"},
+		{"schema {Foo @string}", "no root type; define it under @ in the schema object"},
+		{"schema {@ @Missing}", "unknown type @Missing"},
+		{"schema {@ @A, A @optional(@B), B @A}", "type `A` refers to itself"},
+		{"schema {@ @seq(@string @int)}", "@seq takes 1 argument"},
+		{"schema {@ @int{min x}}", "`min` must be a number"},
+		{"schema {@ @string{pattern \"(\"}}", "invalid pattern: error parsing regexp: missing closing ): `(`"},
+		{"schema {@ @object{a (b)}}", "expected a type, such as @string"},
+		{"schema {@ @A, A @A}", "type `A` refers to itself"},
+		{"schema {@ @object{a @flatten(@string)}}", "@flatten takes an object type"},
+		{"schema {@ @seq(@flatten(@A)), A @object{}}", "@flatten can only be the type of an object field"},
+		{"other 1", "unexpected key `other`; a schema file has meta and schema"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSchema(tt.source)
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Message != tt.want {
+			t.Errorf("%q: got %v, want %q", tt.source, err, tt.want)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("%q: error does not match ErrInvalidSchema", tt.source)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	names := []string{"port", "enabled", "host"}
+	for s, want := range map[string]string{"prot": "port", "Enbled": "enabled", "hst": "host", "unknown": "", "x": ""} {
+		if got := suggest(s, names); got != want {
+			t.Errorf("suggest(%q) = %q, want %q", s, got, want)
+		}
+	}
+}